@@ -0,0 +1,33 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/SilentPlaces/basicauth.git/internal/infrastructure/di"
+)
+
+// runAuditVerify walks the audit hash chain and exits with 1 when tampering is detected.
+func runAuditVerify(_ []string) int {
+	container, err := di.BuildAuditContainer()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to initialize audit container: %v\n", err)
+		return 2
+	}
+
+	report, err := container.AuditService.Verify()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "audit verification failed: %v\n", err)
+		return 2
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(report)
+
+	if !report.Valid {
+		return 1
+	}
+	return 0
+}
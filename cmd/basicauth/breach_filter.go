@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	breachedpasswordservice "github.com/SilentPlaces/basicauth.git/internal/services/breachedpassword"
+)
+
+// runBreachFilter builds a breached password filter from a Pwned Passwords download
+// (`breach-filter build`) or checks passwords read from stdin against one (`breach-filter check`).
+func runBreachFilter(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: breach-filter build -in <file|dir> -out <file> [-fp 0.001] [-min-count 1] | breach-filter check -filter <file>")
+		return 2
+	}
+	switch args[0] {
+	case "build":
+		return runBreachFilterBuild(args[1:])
+	case "check":
+		return runBreachFilterCheck(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown breach-filter command %q\n", args[0])
+		return 2
+	}
+}
+
+// runBreachFilterBuild reads the input twice: once to size the filter, once to fill it. The filter
+// is written next to -out and renamed into place, so a running service never reads half a file.
+func runBreachFilterBuild(args []string) int {
+	flags := flag.NewFlagSet("breach-filter build", flag.ContinueOnError)
+	in := flags.String("in", "", "SHA-1 hash file (HASH[:COUNT] lines) or directory of HIBP range files")
+	out := flags.String("out", "", "filter file to write")
+	fpRate := flags.Float64("fp", 0.001, "false positive rate")
+	minCount := flags.Int("min-count", 1, "skip hashes seen fewer times in breaches")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *in == "" || *out == "" {
+		fmt.Fprintln(os.Stderr, "-in and -out are required")
+		return 2
+	}
+
+	var n uint64
+	err := breachedpasswordservice.ScanHashes(*in, *minCount, func([sha1.Size]byte) error {
+		n++
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to read hashes: %v\n", err)
+		return 1
+	}
+	filter, err := breachedpasswordservice.NewBloomFilter(n, *fpRate)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 2
+	}
+	err = breachedpasswordservice.ScanHashes(*in, *minCount, func(digest [sha1.Size]byte) error {
+		filter.Add(digest)
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to read hashes: %v\n", err)
+		return 1
+	}
+
+	if err := writeFilter(filter, *out); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write filter: %v\n", err)
+		return 1
+	}
+	fmt.Printf("wrote %s: %d hashes, %d bytes, %d hash functions\n", *out, filter.Entries(), filter.SizeBytes(), filter.HashFunctions())
+	return 0
+}
+
+func writeFilter(filter *breachedpasswordservice.BloomFilter, path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	// CreateTemp makes the file private; the service may run as another user.
+	err = tmp.Chmod(0o644)
+	writer := bufio.NewWriter(tmp)
+	if err == nil {
+		_, err = filter.WriteTo(writer)
+	}
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// runBreachFilterCheck reads one password per line from stdin and prints whether the filter holds
+// it. It exits with 1 when any password was found.
+func runBreachFilterCheck(args []string) int {
+	flags := flag.NewFlagSet("breach-filter check", flag.ContinueOnError)
+	path := flags.String("filter", "", "filter file to check against")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *path == "" {
+		fmt.Fprintln(os.Stderr, "-filter is required")
+		return 2
+	}
+	filter, err := breachedpasswordservice.LoadBloomFilter(*path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 2
+	}
+
+	found := false
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		password := strings.TrimRight(scanner.Text(), "\r")
+		if filter.Contains(sha1.Sum([]byte(password))) {
+			found = true
+			fmt.Println("breached")
+		} else {
+			fmt.Println("not found")
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 2
+	}
+	if found {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/SilentPlaces/basicauth.git/internal/config"
+	"github.com/SilentPlaces/basicauth.git/internal/config/provider"
+	consulservice "github.com/SilentPlaces/basicauth.git/internal/services/consul"
+)
+
+// runConfig prints the effective configuration (`config dump`) or writes the schema defaults to
+// Consul (`config seed`).
+func runConfig(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: config dump | config seed [-overwrite]")
+		return 2
+	}
+	switch args[0] {
+	case "dump":
+		return runConfigDump()
+	case "seed":
+		return runConfigSeed(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown config command %q\n", args[0])
+		return 2
+	}
+}
+
+// runConfigDump prints every config key with the value the service would use, secrets redacted.
+// It exits with 1 when the configuration is invalid.
+func runConfigDump() int {
+	consul := consulservice.NewConsulService(config.LoadAppConfig())
+	// Problems are reported by Validate below, which also checks settings that depend on each other.
+	values, _ := consul.Effective()
+
+	redacted := make(map[string]string, len(values))
+	for _, spec := range config.Schema {
+		redacted[spec.Key] = spec.Redact(values[spec.Key])
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(map[string]interface{}{
+		"source": consul.ProviderName(),
+		"values": redacted,
+	})
+
+	if err := consul.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "configuration is invalid:\n%v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runConfigSeed writes the schema defaults to the Consul KV store at CONSUL_ADDRESS. Existing keys
+// are kept unless -overwrite is given. Required keys without a default are listed for manual setup.
+func runConfigSeed(args []string) int {
+	flags := flag.NewFlagSet("config seed", flag.ContinueOnError)
+	overwrite := flags.Bool("overwrite", false, "replace keys that already exist")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	appCfg := config.LoadAppConfig()
+	if appCfg.ConsulAddress == "" {
+		fmt.Fprintln(os.Stderr, "CONSUL_ADDRESS is not set")
+		return 2
+	}
+	consul, err := provider.NewConsulProvider(appCfg.ConsulAddress, appCfg.ConsulScheme)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 2
+	}
+	writer := consul.(provider.Writer)
+
+	for _, spec := range config.Schema {
+		_, exists, err := consul.Get(spec.Key)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return 2
+		}
+		switch {
+		case exists && !*overwrite:
+			fmt.Printf("kept    %s\n", spec.Key)
+		case spec.Default != "":
+			if err := writer.Put(spec.Key, spec.Default); err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				return 2
+			}
+			fmt.Printf("set     %s = %s\n", spec.Key, spec.Default)
+		case spec.Required:
+			fmt.Printf("missing %s (required, no default)\n", spec.Key)
+		}
+	}
+	return 0
+}
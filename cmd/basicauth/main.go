@@ -2,22 +2,59 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/SilentPlaces/basicauth.git/internal/config"
 	"github.com/SilentPlaces/basicauth.git/internal/infrastructure/di"
 	"github.com/SilentPlaces/basicauth.git/internal/infrastructure/logging"
 )
 
+// commands are the maintenance subcommands. Running the binary without arguments starts the server.
+var commands = map[string]func(args []string) int{
+	"audit-verify":  runAuditVerify,
+	"breach-filter": runBreachFilter,
+	"config":        runConfig,
+}
+
 func main() {
-	logger := logging.NewZeroLogger(config.LoadConsulConfig())
+	if len(os.Args) > 1 {
+		command, ok := commands[os.Args[1]]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "unknown command %q\n", os.Args[1])
+			os.Exit(2)
+		}
+		os.Exit(command(os.Args[2:]))
+	}
+
+	logger := logging.NewZeroLogger(config.LoadAppConfig())
 	container, err := di.BuildContainer()
 	if err != nil {
 		logger.Error(context.Background(), "failed to initialize application container", err, nil)
 		return
 	}
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	for _, job := range container.BackgroundJobs {
+		go job.Run(ctx)
+	}
+
+	server := &http.Server{Addr: ":8080", Handler: container.Router}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
 	logger.Info(context.Background(), "server starting", map[string]interface{}{"port": "8080"})
-	if err := container.Router.Run(":8080"); err != nil {
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		logger.Error(context.Background(), "server terminated", err, nil)
 	}
 }
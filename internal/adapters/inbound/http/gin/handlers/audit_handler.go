@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/SilentPlaces/basicauth.git/internal/adapters/inbound/http/gin/middleware"
+	"github.com/SilentPlaces/basicauth.git/internal/adapters/inbound/http/gin/response"
+	"github.com/SilentPlaces/basicauth.git/internal/application/usecase"
+	auditdto "github.com/SilentPlaces/basicauth.git/internal/dto/audit"
+	mapper "github.com/SilentPlaces/basicauth.git/internal/mappers/audit"
+	"github.com/SilentPlaces/basicauth.git/internal/models/models"
+	appLogger "github.com/SilentPlaces/basicauth.git/internal/shared/logger"
+	"github.com/gin-gonic/gin"
+)
+
+type AuditHandler struct {
+	auditUseCase *usecase.AuditUseCase
+	logger       appLogger.Logger
+}
+
+func NewAuditHandler(auditUseCase *usecase.AuditUseCase, logger appLogger.Logger) *AuditHandler {
+	return &AuditHandler{auditUseCase: auditUseCase, logger: logger}
+}
+
+func (h *AuditHandler) QueryEvents(c *gin.Context) {
+	var req auditdto.AuditQueryReqDTO
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.logger.Warn(c.Request.Context(), "audit query binding failed", map[string]interface{}{"path": c.Request.URL.Path})
+		response.Error(c, http.StatusBadRequest, "Invalid query parameters")
+		return
+	}
+
+	filter := models.AuditEventFilter{
+		Actor:         req.Actor,
+		Subject:       req.Subject,
+		Action:        req.Action,
+		Outcome:       req.Outcome,
+		CorrelationID: req.CorrelationID,
+		Limit:         req.Limit,
+		Offset:        req.Offset,
+	}
+	for _, bound := range []struct {
+		raw    string
+		target **time.Time
+	}{{req.From, &filter.From}, {req.To, &filter.To}} {
+		if bound.raw == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, bound.raw)
+		if err != nil {
+			h.logger.Warn(c.Request.Context(), "audit query invalid time bound", map[string]interface{}{"value": bound.raw})
+			response.Error(c, http.StatusBadRequest, "from and to must be RFC 3339 timestamps")
+			return
+		}
+		*bound.target = &parsed
+	}
+
+	adminID := c.GetString(middleware.UserContextKey)
+	events, err := h.auditUseCase.QueryEvents(c.Request.Context(), adminID, filter)
+	if err != nil {
+		h.logger.Error(c.Request.Context(), "audit query failed", err, map[string]interface{}{"user_id": adminID})
+		response.Error(c, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	response.Success(c, http.StatusOK, mapper.MapAuditEventsToResDTO(events))
+}
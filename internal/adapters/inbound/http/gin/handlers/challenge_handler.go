@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/SilentPlaces/basicauth.git/internal/adapters/inbound/http/gin/response"
+	"github.com/SilentPlaces/basicauth.git/internal/application/usecase"
+	mapper "github.com/SilentPlaces/basicauth.git/internal/mappers/challenge"
+	appLogger "github.com/SilentPlaces/basicauth.git/internal/shared/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// ChallengeResponseHeader carries the solved challenge on routes that require one.
+const ChallengeResponseHeader = "X-Challenge-Response"
+
+// Error codes returned when a route's challenge is not passed.
+const (
+	errorCodeChallengeRequired = "challenge_required"
+	errorCodeChallengeFailed   = "challenge_failed"
+)
+
+type ChallengeHandler struct {
+	challengeUseCase *usecase.ChallengeUseCase
+	logger           appLogger.Logger
+}
+
+func NewChallengeHandler(challengeUseCase *usecase.ChallengeUseCase, logger appLogger.Logger) *ChallengeHandler {
+	return &ChallengeHandler{challengeUseCase: challengeUseCase, logger: logger}
+}
+
+func (h *ChallengeHandler) NewChallenge(c *gin.Context) {
+	challenge, err := h.challengeUseCase.NewChallenge(c.Request.Context())
+	if errors.Is(err, usecase.ErrTooManyAttempts) {
+		response.Error(c, http.StatusTooManyRequests, "Too many challenges requested, try again later")
+		return
+	}
+	if err != nil {
+		response.Error(c, http.StatusServiceUnavailable, "Service temporarily unavailable")
+		return
+	}
+	response.Success(c, http.StatusOK, mapper.MapChallengeToResDTO(challenge))
+}
+
+// passChallenge verifies the challenge response of the request for route. It writes the error
+// response and returns false when the request must not go on.
+func passChallenge(c *gin.Context, challengeUseCase *usecase.ChallengeUseCase, route string) bool {
+	err := challengeUseCase.Verify(c.Request.Context(), route, c.GetHeader(ChallengeResponseHeader))
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, usecase.ErrChallengeRequired):
+		response.ErrorWithCode(c, http.StatusForbidden, errorCodeChallengeRequired, "Challenge response required")
+	case errors.Is(err, usecase.ErrChallengeFailed):
+		response.ErrorWithCode(c, http.StatusForbidden, errorCodeChallengeFailed, "Challenge response is invalid or expired")
+	default:
+		response.Error(c, http.StatusServiceUnavailable, "Service temporarily unavailable")
+	}
+	return false
+}
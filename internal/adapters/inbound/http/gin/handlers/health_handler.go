@@ -28,6 +28,7 @@ func (h *HealthHandler) Readiness(c *gin.Context) {
 		"status": status["status"],
 		"mysql":  status["mysql"],
 		"redis":  status["redis"],
+		"vault":  status["vault"],
 	}
 	if !ready {
 		h.logger.Warn(c.Request.Context(), "readiness probe degraded", logFields)
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/SilentPlaces/basicauth.git/internal/adapters/inbound/http/gin/middleware"
+	"github.com/SilentPlaces/basicauth.git/internal/adapters/inbound/http/gin/response"
+	"github.com/SilentPlaces/basicauth.git/internal/application/usecase"
+	invitationdto "github.com/SilentPlaces/basicauth.git/internal/dto/invitation"
+	mapper "github.com/SilentPlaces/basicauth.git/internal/mappers/invitation"
+	appLogger "github.com/SilentPlaces/basicauth.git/internal/shared/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// InvitationHandler serves the invitation routes for users, who manage their own invitations, and
+// under /admin for admins, who manage all of them.
+type InvitationHandler struct {
+	invitationUseCase *usecase.InvitationUseCase
+	logger            appLogger.Logger
+}
+
+func NewInvitationHandler(invitationUseCase *usecase.InvitationUseCase, logger appLogger.Logger) *InvitationHandler {
+	return &InvitationHandler{invitationUseCase: invitationUseCase, logger: logger}
+}
+
+func (h *InvitationHandler) CreateInvitation(c *gin.Context) {
+	h.createInvitation(c, false)
+}
+
+func (h *InvitationHandler) AdminCreateInvitation(c *gin.Context) {
+	h.createInvitation(c, true)
+}
+
+func (h *InvitationHandler) ListInvitations(c *gin.Context) {
+	h.listInvitations(c, false)
+}
+
+func (h *InvitationHandler) AdminListInvitations(c *gin.Context) {
+	h.listInvitations(c, true)
+}
+
+func (h *InvitationHandler) RevokeInvitation(c *gin.Context) {
+	h.revokeInvitation(c, false)
+}
+
+func (h *InvitationHandler) AdminRevokeInvitation(c *gin.Context) {
+	h.revokeInvitation(c, true)
+}
+
+func (h *InvitationHandler) createInvitation(c *gin.Context, admin bool) {
+	var req invitationdto.CreateInvitationReqDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Warn(c.Request.Context(), "invitation create binding failed", map[string]interface{}{"path": c.Request.URL.Path})
+		response.Error(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	userID := c.GetString(middleware.UserContextKey)
+	invitation, code, err := h.invitationUseCase.CreateInvitation(c.Request.Context(), userID, admin, usecase.CreateInvitationInput{
+		Email:          req.Email,
+		Roles:          req.Roles,
+		MaxUses:        req.MaxUses,
+		ExpiresInHours: req.ExpiresInHours,
+		Locale:         req.Locale,
+		AcceptLanguage: c.GetHeader("Accept-Language"),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrBadRequest):
+			response.Error(c, http.StatusBadRequest, err.Error())
+		case errors.Is(err, usecase.ErrUnauthorized):
+			response.Error(c, http.StatusForbidden, err.Error())
+		case errors.Is(err, usecase.ErrTooManyAttempts):
+			response.Error(c, http.StatusTooManyRequests, err.Error())
+		default:
+			response.Error(c, http.StatusInternalServerError, "Internal Server Error")
+		}
+		return
+	}
+
+	response.Success(c, http.StatusCreated, mapper.MapInvitationToResDTO(invitation, code))
+}
+
+func (h *InvitationHandler) listInvitations(c *gin.Context, admin bool) {
+	var req invitationdto.InvitationQueryReqDTO
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.logger.Warn(c.Request.Context(), "invitation query binding failed", map[string]interface{}{"path": c.Request.URL.Path})
+		response.Error(c, http.StatusBadRequest, "Invalid query parameters")
+		return
+	}
+
+	invitations, err := h.invitationUseCase.ListInvitations(c.Request.Context(), c.GetString(middleware.UserContextKey), admin, req.Limit, req.Offset)
+	if err != nil {
+		h.logger.Error(c.Request.Context(), "invitation list failed", err, nil)
+		response.Error(c, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	response.Success(c, http.StatusOK, mapper.MapInvitationsToResDTO(invitations))
+}
+
+func (h *InvitationHandler) revokeInvitation(c *gin.Context, admin bool) {
+	if err := h.invitationUseCase.RevokeInvitation(c.Request.Context(), c.GetString(middleware.UserContextKey), admin, c.Param("id")); err != nil {
+		if errors.Is(err, usecase.ErrNotFound) {
+			response.Error(c, http.StatusNotFound, "Invitation not found or already revoked")
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	response.Success(c, http.StatusOK, nil)
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/SilentPlaces/basicauth.git/internal/adapters/inbound/http/gin/response"
+	"github.com/SilentPlaces/basicauth.git/internal/application/usecase"
+	maildto "github.com/SilentPlaces/basicauth.git/internal/dto/mail"
+	mapper "github.com/SilentPlaces/basicauth.git/internal/mappers/mail"
+	appLogger "github.com/SilentPlaces/basicauth.git/internal/shared/logger"
+	"github.com/gin-gonic/gin"
+)
+
+type MailQueueHandler struct {
+	mailQueueUseCase *usecase.MailQueueUseCase
+	logger           appLogger.Logger
+}
+
+func NewMailQueueHandler(mailQueueUseCase *usecase.MailQueueUseCase, logger appLogger.Logger) *MailQueueHandler {
+	return &MailQueueHandler{mailQueueUseCase: mailQueueUseCase, logger: logger}
+}
+
+func (h *MailQueueHandler) ListDeadLetters(c *gin.Context) {
+	var req maildto.DeadLetterQueryReqDTO
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.logger.Warn(c.Request.Context(), "mail dead letter query binding failed", map[string]interface{}{"path": c.Request.URL.Path})
+		response.Error(c, http.StatusBadRequest, "Invalid query parameters")
+		return
+	}
+
+	jobs, err := h.mailQueueUseCase.ListDeadLetters(c.Request.Context(), req.Limit)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	response.Success(c, http.StatusOK, mapper.MapMailJobsToResDTO(jobs))
+}
+
+func (h *MailQueueHandler) ReplayDeadLetter(c *gin.Context) {
+	job, err := h.mailQueueUseCase.Replay(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, usecase.ErrNotFound) {
+			response.Error(c, http.StatusNotFound, "Dead letter not found")
+			return
+		}
+		if errors.Is(err, usecase.ErrBadRequest) {
+			response.Error(c, http.StatusConflict, "Dead letter held secrets that were dropped; the user has to request a new mail")
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	response.Success(c, http.StatusAccepted, mapper.MapMailJobToResDTO(job))
+}
@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/SilentPlaces/basicauth.git/internal/adapters/inbound/http/gin/response"
+	customerror "github.com/SilentPlaces/basicauth.git/internal/errors"
+	mapper "github.com/SilentPlaces/basicauth.git/internal/mappers/users"
+	"github.com/gin-gonic/gin"
+)
+
+// respondPasswordPolicyError writes a 400 with the errorCode and, for weak passwords, the strength
+// feedback when err holds a *customerror.PasswordPolicyError. It reports whether it did.
+func respondPasswordPolicyError(c *gin.Context, err error) bool {
+	var passwordErr *customerror.PasswordPolicyError
+	if !errors.As(err, &passwordErr) {
+		return false
+	}
+	if feedback := mapper.MapPasswordPolicyErrorToFeedbackDTO(passwordErr); feedback != nil {
+		response.ErrorWithDetails(c, http.StatusBadRequest, passwordErr.Code, passwordErr.Message, feedback)
+	} else {
+		response.ErrorWithCode(c, http.StatusBadRequest, passwordErr.Code, passwordErr.Message)
+	}
+	return true
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/SilentPlaces/basicauth.git/internal/adapters/inbound/http/gin/middleware"
+	"github.com/SilentPlaces/basicauth.git/internal/adapters/inbound/http/gin/response"
+	"github.com/SilentPlaces/basicauth.git/internal/application/usecase"
+	policydto "github.com/SilentPlaces/basicauth.git/internal/dto/policy"
+	customerror "github.com/SilentPlaces/basicauth.git/internal/errors"
+	mapper "github.com/SilentPlaces/basicauth.git/internal/mappers/policy"
+	policyservice "github.com/SilentPlaces/basicauth.git/internal/services/policy"
+	appLogger "github.com/SilentPlaces/basicauth.git/internal/shared/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// PolicyHandler serves the policy documents in force, the users' consents and, under /admin, the
+// publishing of new versions.
+type PolicyHandler struct {
+	policyUseCase *usecase.PolicyUseCase
+	logger        appLogger.Logger
+}
+
+func NewPolicyHandler(policyUseCase *usecase.PolicyUseCase, logger appLogger.Logger) *PolicyHandler {
+	return &PolicyHandler{policyUseCase: policyUseCase, logger: logger}
+}
+
+func (h *PolicyHandler) CurrentPolicies(c *gin.Context) {
+	documents, err := h.policyUseCase.CurrentPolicies(c.Request.Context())
+	if err != nil {
+		h.logger.Error(c.Request.Context(), "policy current lookup failed", err, nil)
+		response.Error(c, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	response.Success(c, http.StatusOK, mapper.MapPolicyDocumentsToResDTO(documents))
+}
+
+func (h *PolicyHandler) AcceptPolicies(c *gin.Context) {
+	userID := c.GetString(middleware.UserContextKey)
+	var req policydto.AcceptPoliciesReqDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Warn(c.Request.Context(), "policy consent binding failed", map[string]interface{}{"path": c.Request.URL.Path})
+		response.Error(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	consents, err := h.policyUseCase.AcceptPolicies(c.Request.Context(), userID, req.AcceptedPolicies)
+	if err != nil {
+		if errors.Is(err, policyservice.ErrPoliciesNotAccepted) {
+			response.ErrorWithCode(c, http.StatusBadRequest, customerror.SignupPolicyPoliciesNotAccepted, err.Error())
+			return
+		}
+		h.logger.Error(c.Request.Context(), "policy consent failed", err, map[string]interface{}{"user_id": userID})
+		response.Error(c, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	response.Success(c, http.StatusOK, mapper.MapConsentsToResDTO(consents))
+}
+
+func (h *PolicyHandler) ListConsents(c *gin.Context) {
+	userID := c.GetString(middleware.UserContextKey)
+	consents, err := h.policyUseCase.ListConsents(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.Error(c.Request.Context(), "policy consent list failed", err, map[string]interface{}{"user_id": userID})
+		response.Error(c, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	response.Success(c, http.StatusOK, mapper.MapConsentsToResDTO(consents))
+}
+
+func (h *PolicyHandler) AdminPublishPolicy(c *gin.Context) {
+	var req policydto.PublishPolicyReqDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Warn(c.Request.Context(), "policy publish binding failed", map[string]interface{}{"path": c.Request.URL.Path})
+		response.Error(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	document, err := h.policyUseCase.PublishPolicy(c.Request.Context(), c.GetString(middleware.UserContextKey), usecase.PublishPolicyInput{
+		Kind:      req.Kind,
+		Version:   req.Version,
+		URL:       req.URL,
+		Mandatory: req.Mandatory,
+		PublishAt: req.PublishAt,
+	})
+	if err != nil {
+		if errors.Is(err, usecase.ErrBadRequest) {
+			response.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	response.Success(c, http.StatusCreated, mapper.MapPolicyDocumentToResDTO(document))
+}
+
+func (h *PolicyHandler) AdminListPolicies(c *gin.Context) {
+	var req policydto.PolicyQueryReqDTO
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.logger.Warn(c.Request.Context(), "policy query binding failed", map[string]interface{}{"path": c.Request.URL.Path})
+		response.Error(c, http.StatusBadRequest, "Invalid query parameters")
+		return
+	}
+
+	documents, err := h.policyUseCase.ListPolicies(c.Request.Context(), req.Limit, req.Offset)
+	if err != nil {
+		h.logger.Error(c.Request.Context(), "policy list failed", err, nil)
+		response.Error(c, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	response.Success(c, http.StatusOK, mapper.MapPolicyDocumentsToResDTO(documents))
+}
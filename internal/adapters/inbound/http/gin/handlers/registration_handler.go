@@ -6,6 +6,7 @@ import (
 
 	"github.com/SilentPlaces/basicauth.git/internal/adapters/inbound/http/gin/response"
 	"github.com/SilentPlaces/basicauth.git/internal/application/usecase"
+	"github.com/SilentPlaces/basicauth.git/internal/config"
 	registerationdto "github.com/SilentPlaces/basicauth.git/internal/dto/registeration"
 	resendverification "github.com/SilentPlaces/basicauth.git/internal/dto/registeration/resend_verification"
 	verifymaildto "github.com/SilentPlaces/basicauth.git/internal/dto/registeration/verify"
@@ -16,14 +17,19 @@ import (
 
 type RegistrationHandler struct {
 	registrationUseCase *usecase.RegistrationUseCase
+	challengeUseCase    *usecase.ChallengeUseCase
 	logger              appLogger.Logger
 }
 
-func NewRegistrationHandler(registrationUseCase *usecase.RegistrationUseCase, logger appLogger.Logger) *RegistrationHandler {
-	return &RegistrationHandler{registrationUseCase: registrationUseCase, logger: logger}
+func NewRegistrationHandler(registrationUseCase *usecase.RegistrationUseCase, challengeUseCase *usecase.ChallengeUseCase, logger appLogger.Logger) *RegistrationHandler {
+	return &RegistrationHandler{registrationUseCase: registrationUseCase, challengeUseCase: challengeUseCase, logger: logger}
 }
 
 func (h *RegistrationHandler) SignUp(c *gin.Context) {
+	if !passChallenge(c, h.challengeUseCase, config.ChallengeRouteSignup) {
+		return
+	}
+
 	var req registerationdto.RegistrationRequestDTO
 	if err := c.ShouldBindJSON(&req); err != nil {
 		h.logger.Warn(c.Request.Context(), "signup request binding failed", map[string]interface{}{"path": c.Request.URL.Path})
@@ -31,7 +37,21 @@ func (h *RegistrationHandler) SignUp(c *gin.Context) {
 		return
 	}
 
-	if err := h.registrationUseCase.SignUp(c.Request.Context(), req.Email, req.Name, req.Password); err != nil {
+	if err := h.registrationUseCase.SignUp(c.Request.Context(), req.Email, req.Name, req.Password, req.Locale, c.GetHeader("Accept-Language"), req.InviteCode, req.AcceptedPolicies); err != nil {
+		if respondPasswordPolicyError(c, err) {
+			h.logger.Warn(c.Request.Context(), "signup rejected by password policy", map[string]interface{}{"email": req.Email})
+			return
+		}
+		var policyErr *customerror.SignupPolicyError
+		if errors.As(err, &policyErr) {
+			h.logger.Warn(c.Request.Context(), "signup rejected by policy", map[string]interface{}{"email": req.Email, "code": policyErr.Code})
+			status := http.StatusBadRequest
+			if policyErr.Code == customerror.SignupPolicyRegistrationClosed {
+				status = http.StatusForbidden
+			}
+			response.ErrorWithCode(c, status, policyErr.Code, policyErr.Message)
+			return
+		}
 		if errors.Is(err, usecase.ErrBadRequest) {
 			h.logger.Warn(c.Request.Context(), "signup rejected due to bad request", map[string]interface{}{"email": req.Email})
 			response.Error(c, http.StatusBadRequest, err.Error())
@@ -64,7 +84,38 @@ func (h *RegistrationHandler) VerifyMail(c *gin.Context) {
 	response.Success(c, http.StatusOK, nil)
 }
 
+func (h *RegistrationHandler) VerifyCode(c *gin.Context) {
+	var req verifymaildto.VerifyCodeReqDTO
+	if err := c.ShouldBindJSON(&req); err != nil || req.Mail == "" || req.Code == "" {
+		h.logger.Warn(c.Request.Context(), "verify code request binding failed", map[string]interface{}{"path": c.Request.URL.Path})
+		response.Error(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	if err := h.registrationUseCase.VerifyCode(c.Request.Context(), req.Mail, req.Code); err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrTooManyAttempts):
+			h.logger.Warn(c.Request.Context(), "verify code attempts exhausted", map[string]interface{}{"email": req.Mail})
+			response.Error(c, http.StatusTooManyRequests, "maximum number of attempts reached, request a new code")
+		case errors.Is(err, usecase.ErrBadRequest):
+			h.logger.Warn(c.Request.Context(), "verify code failed", map[string]interface{}{"email": req.Mail})
+			response.Error(c, http.StatusBadRequest, "Verification code is not valid")
+		default:
+			h.logger.Error(c.Request.Context(), "verify code failed", err, map[string]interface{}{"email": req.Mail})
+			response.Error(c, http.StatusInternalServerError, "Internal Server Error")
+		}
+		return
+	}
+
+	h.logger.Info(c.Request.Context(), "verify code succeeded", nil)
+	response.Success(c, http.StatusOK, nil)
+}
+
 func (h *RegistrationHandler) ResendVerification(c *gin.Context) {
+	if !passChallenge(c, h.challengeUseCase, config.ChallengeRouteResend) {
+		return
+	}
+
 	var req resendverification.ResendVerificationRequestDTO
 	if err := c.ShouldBindJSON(&req); err != nil {
 		h.logger.Warn(c.Request.Context(), "resend verification request binding failed", map[string]interface{}{"path": c.Request.URL.Path})
@@ -72,12 +123,17 @@ func (h *RegistrationHandler) ResendVerification(c *gin.Context) {
 		return
 	}
 
-	if err := h.registrationUseCase.ResendVerification(c.Request.Context(), req.Email); err != nil {
+	if err := h.registrationUseCase.ResendVerification(c.Request.Context(), req.Email, c.GetHeader("Accept-Language")); err != nil {
 		if errors.Is(err, &customerror.TokenGenerationCountError{}) {
 			h.logger.Warn(c.Request.Context(), "resend verification rate limited", map[string]interface{}{"email": req.Email})
 			response.Error(c, http.StatusTooManyRequests, "maximum number of attempts reached")
 			return
 		}
+		if errors.Is(err, usecase.ErrBadRequest) {
+			h.logger.Warn(c.Request.Context(), "resend verification rejected due to bad request", map[string]interface{}{"email": req.Email})
+			response.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
 		h.logger.Error(c.Request.Context(), "resend verification failed", err, map[string]interface{}{"email": req.Email})
 		response.Error(c, http.StatusInternalServerError, "Internal Server Error")
 		return
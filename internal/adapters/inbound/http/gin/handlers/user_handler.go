@@ -1,28 +1,33 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/SilentPlaces/basicauth.git/internal/adapters/inbound/http/gin/middleware"
 	"github.com/SilentPlaces/basicauth.git/internal/adapters/inbound/http/gin/response"
 	"github.com/SilentPlaces/basicauth.git/internal/application/usecase"
+	"github.com/SilentPlaces/basicauth.git/internal/config"
 	logindto "github.com/SilentPlaces/basicauth.git/internal/dto/auth/login"
 	refreshtokendto "github.com/SilentPlaces/basicauth.git/internal/dto/auth/refresh_token"
+	userdto "github.com/SilentPlaces/basicauth.git/internal/dto/user"
 	appLogger "github.com/SilentPlaces/basicauth.git/internal/shared/logger"
 	"github.com/gin-gonic/gin"
 )
 
 type UserHandler struct {
-	userUseCase *usecase.UserUseCase
-	authUseCase *usecase.AuthUseCase
-	logger      appLogger.Logger
+	userUseCase      *usecase.UserUseCase
+	authUseCase      *usecase.AuthUseCase
+	challengeUseCase *usecase.ChallengeUseCase
+	logger           appLogger.Logger
 }
 
-func NewUserHandler(userUseCase *usecase.UserUseCase, authUseCase *usecase.AuthUseCase, logger appLogger.Logger) *UserHandler {
+func NewUserHandler(userUseCase *usecase.UserUseCase, authUseCase *usecase.AuthUseCase, challengeUseCase *usecase.ChallengeUseCase, logger appLogger.Logger) *UserHandler {
 	return &UserHandler{
-		userUseCase: userUseCase,
-		authUseCase: authUseCase,
-		logger:      logger,
+		userUseCase:      userUseCase,
+		authUseCase:      authUseCase,
+		challengeUseCase: challengeUseCase,
+		logger:           logger,
 	}
 }
 
@@ -46,6 +51,10 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 }
 
 func (h *UserHandler) Login(c *gin.Context) {
+	if !passChallenge(c, h.challengeUseCase, config.ChallengeRouteLogin) {
+		return
+	}
+
 	var req logindto.LoginRequestDTO
 	if err := c.ShouldBindJSON(&req); err != nil {
 		h.logger.Warn(c.Request.Context(), "login request binding failed", map[string]interface{}{"path": c.Request.URL.Path})
@@ -65,6 +74,10 @@ func (h *UserHandler) Login(c *gin.Context) {
 			response.Error(c, http.StatusUnauthorized, "Wrong Email or Password")
 			return
 		}
+		if err == usecase.ErrServiceUnavailable {
+			response.Error(c, http.StatusServiceUnavailable, "Service temporarily unavailable")
+			return
+		}
 		h.logger.Error(c.Request.Context(), "login failed", err, map[string]interface{}{"email": req.Email})
 		response.Error(c, http.StatusInternalServerError, "Internal Server Error")
 		return
@@ -83,6 +96,22 @@ func (h *UserHandler) RefreshToken(c *gin.Context) {
 	}
 
 	data, err := h.authUseCase.RefreshToken(c.Request.Context(), req.RefreshToken)
+	if err == usecase.ErrServiceUnavailable {
+		response.Error(c, http.StatusServiceUnavailable, "Service temporarily unavailable")
+		return
+	}
+	if err == usecase.ErrUnauthorized {
+		response.Error(c, http.StatusUnauthorized, "Session expired or revoked")
+		return
+	}
+	if err == usecase.ErrPasswordChangeRequired {
+		response.ErrorWithCode(c, http.StatusForbidden, middleware.PasswordChangeRequiredCode, "Password change required, log in again")
+		return
+	}
+	if err == usecase.ErrConsentRequired {
+		response.ErrorWithCode(c, http.StatusForbidden, middleware.ConsentRequiredCode, "Policies must be accepted, log in again")
+		return
+	}
 	if err != nil {
 		h.logger.Warn(c.Request.Context(), "refresh token failed", map[string]interface{}{"path": c.Request.URL.Path})
 		response.Error(c, http.StatusInternalServerError, "Internal Server Error")
@@ -92,3 +121,105 @@ func (h *UserHandler) RefreshToken(c *gin.Context) {
 	h.logger.Info(c.Request.Context(), "refresh token succeeded", nil)
 	response.Success(c, http.StatusOK, data)
 }
+
+func (h *UserHandler) ChangePassword(c *gin.Context) {
+	userID := c.GetString(middleware.UserContextKey)
+	if userID == "" {
+		h.logger.Warn(c.Request.Context(), "change password missing context user id", map[string]interface{}{"path": c.Request.URL.Path})
+		response.Error(c, http.StatusUnauthorized, "User ID not found")
+		return
+	}
+
+	var req userdto.ChangePasswordReqDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Warn(c.Request.Context(), "change password request binding failed", map[string]interface{}{"path": c.Request.URL.Path})
+		response.Error(c, http.StatusBadRequest, "Bad request")
+		return
+	}
+
+	if err := h.userUseCase.ChangePassword(c.Request.Context(), userID, req.CurrentPassword, req.NewPassword); err != nil {
+		switch {
+		case respondPasswordPolicyError(c, err):
+		case errors.Is(err, usecase.ErrWrongCredential):
+			response.Error(c, http.StatusUnauthorized, "Current password is wrong")
+		case errors.Is(err, usecase.ErrNotFound):
+			response.Error(c, http.StatusNotFound, "User not found")
+		default:
+			h.logger.Error(c.Request.Context(), "change password failed", err, map[string]interface{}{"user_id": userID})
+			response.Error(c, http.StatusInternalServerError, "Internal Server Error")
+		}
+		return
+	}
+
+	h.logger.Info(c.Request.Context(), "change password succeeded", map[string]interface{}{"user_id": userID})
+	response.Success(c, http.StatusOK, nil)
+}
+
+func (h *UserHandler) AdminRequirePasswordChange(c *gin.Context) {
+	if err := h.userUseCase.RequirePasswordChange(c.Request.Context(), c.GetString(middleware.UserContextKey), c.Param("id")); err != nil {
+		if errors.Is(err, usecase.ErrNotFound) {
+			response.Error(c, http.StatusNotFound, "User not found")
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	response.Success(c, http.StatusOK, nil)
+}
+
+func (h *UserHandler) DeleteAccount(c *gin.Context) {
+	userID := c.GetString(middleware.UserContextKey)
+	if userID == "" {
+		h.logger.Warn(c.Request.Context(), "delete account missing context user id", map[string]interface{}{"path": c.Request.URL.Path})
+		response.Error(c, http.StatusUnauthorized, "User ID not found")
+		return
+	}
+
+	var req userdto.DeleteAccountReqDTO
+	if err := c.ShouldBindJSON(&req); err != nil || req.Password == "" {
+		h.logger.Warn(c.Request.Context(), "delete account request binding failed", map[string]interface{}{"path": c.Request.URL.Path})
+		response.Error(c, http.StatusBadRequest, "Bad request")
+		return
+	}
+
+	deleteAt, err := h.userUseCase.DeleteAccount(c.Request.Context(), userID, req.Password)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrWrongCredential):
+			response.Error(c, http.StatusUnauthorized, "Password is wrong")
+		case errors.Is(err, usecase.ErrNotFound):
+			response.Error(c, http.StatusNotFound, "User not found")
+		default:
+			h.logger.Error(c.Request.Context(), "delete account failed", err, map[string]interface{}{"user_id": userID})
+			response.Error(c, http.StatusInternalServerError, "Internal Server Error")
+		}
+		return
+	}
+
+	h.logger.Info(c.Request.Context(), "delete account scheduled", map[string]interface{}{"user_id": userID})
+	response.Success(c, http.StatusAccepted, userdto.DeleteAccountResDTO{DeletionScheduledAt: deleteAt})
+}
+
+func (h *UserHandler) ExportAccount(c *gin.Context) {
+	userID := c.GetString(middleware.UserContextKey)
+	if userID == "" {
+		h.logger.Warn(c.Request.Context(), "export account missing context user id", map[string]interface{}{"path": c.Request.URL.Path})
+		response.Error(c, http.StatusUnauthorized, "User ID not found")
+		return
+	}
+
+	data, err := h.userUseCase.ExportAccount(c.Request.Context(), userID)
+	if err != nil {
+		if errors.Is(err, usecase.ErrNotFound) {
+			response.Error(c, http.StatusNotFound, "User not found")
+			return
+		}
+		h.logger.Error(c.Request.Context(), "export account failed", err, map[string]interface{}{"user_id": userID})
+		response.Error(c, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="account-export.json"`)
+	response.Success(c, http.StatusOK, data)
+}
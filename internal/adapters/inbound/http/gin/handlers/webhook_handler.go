@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/SilentPlaces/basicauth.git/internal/adapters/inbound/http/gin/response"
+	"github.com/SilentPlaces/basicauth.git/internal/application/usecase"
+	webhookdto "github.com/SilentPlaces/basicauth.git/internal/dto/webhook"
+	mapper "github.com/SilentPlaces/basicauth.git/internal/mappers/webhook"
+	appLogger "github.com/SilentPlaces/basicauth.git/internal/shared/logger"
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookHandler struct {
+	webhookUseCase *usecase.WebhookUseCase
+	logger         appLogger.Logger
+}
+
+func NewWebhookHandler(webhookUseCase *usecase.WebhookUseCase, logger appLogger.Logger) *WebhookHandler {
+	return &WebhookHandler{webhookUseCase: webhookUseCase, logger: logger}
+}
+
+func (h *WebhookHandler) CreateSubscription(c *gin.Context) {
+	var req webhookdto.CreateSubscriptionReqDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Warn(c.Request.Context(), "webhook create binding failed", map[string]interface{}{"path": c.Request.URL.Path})
+		response.Error(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	subscription, err := h.webhookUseCase.CreateSubscription(c.Request.Context(), req.URL, req.EventTypes, req.Secret)
+	if err != nil {
+		if errors.Is(err, usecase.ErrBadRequest) {
+			response.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	response.Success(c, http.StatusCreated, mapper.MapSubscriptionToResDTO(subscription, true))
+}
+
+func (h *WebhookHandler) ListSubscriptions(c *gin.Context) {
+	subscriptions, err := h.webhookUseCase.ListSubscriptions(c.Request.Context())
+	if err != nil {
+		h.logger.Error(c.Request.Context(), "webhook list failed", err, nil)
+		response.Error(c, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	response.Success(c, http.StatusOK, mapper.MapSubscriptionsToResDTO(subscriptions))
+}
+
+func (h *WebhookHandler) DeleteSubscription(c *gin.Context) {
+	if err := h.webhookUseCase.DeleteSubscription(c.Request.Context(), c.Param("id")); err != nil {
+		if errors.Is(err, usecase.ErrNotFound) {
+			response.Error(c, http.StatusNotFound, "Webhook not found")
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	response.Success(c, http.StatusOK, nil)
+}
+
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	var req webhookdto.DeliveryQueryReqDTO
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.logger.Warn(c.Request.Context(), "webhook delivery query binding failed", map[string]interface{}{"path": c.Request.URL.Path})
+		response.Error(c, http.StatusBadRequest, "Invalid query parameters")
+		return
+	}
+
+	deliveries, err := h.webhookUseCase.ListDeliveries(c.Request.Context(), c.Param("id"), req.Status, req.Limit, req.Offset)
+	if err != nil {
+		h.logger.Error(c.Request.Context(), "webhook delivery log failed", err, nil)
+		response.Error(c, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	response.Success(c, http.StatusOK, mapper.MapDeliveriesToResDTO(deliveries))
+}
+
+func (h *WebhookHandler) RetryDelivery(c *gin.Context) {
+	if err := h.webhookUseCase.RetryDelivery(c.Request.Context(), c.Param("id")); err != nil {
+		if errors.Is(err, usecase.ErrNotFound) {
+			response.Error(c, http.StatusNotFound, "Delivery not found or already delivered")
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	response.Success(c, http.StatusAccepted, nil)
+}
@@ -0,0 +1,17 @@
+package middleware
+
+import (
+	"github.com/SilentPlaces/basicauth.git/internal/shared/observability"
+	"github.com/gin-gonic/gin"
+)
+
+// ClientIPMiddleware puts the client IP resolved by gin into request context,
+// so use cases can record it without depending on the HTTP layer. gin only takes it from
+// X-Forwarded-For for requests from the trusted proxies set on the engine.
+func ClientIPMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := observability.WithClientIP(c.Request.Context(), c.ClientIP())
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
@@ -2,17 +2,45 @@ package middleware
 
 import (
 	"net/http"
+	"slices"
 	"strings"
 
 	"github.com/SilentPlaces/basicauth.git/internal/adapters/inbound/http/gin/response"
 	"github.com/SilentPlaces/basicauth.git/internal/application/port"
+	authservice "github.com/SilentPlaces/basicauth.git/internal/services/auth"
 	appLogger "github.com/SilentPlaces/basicauth.git/internal/shared/logger"
 	"github.com/gin-gonic/gin"
 )
 
 const UserContextKey = "user"
 
-func JWTAuthMiddleware(authService port.AuthTokenManager, logger appLogger.Logger) gin.HandlerFunc {
+// errorCodes for restricted tokens used outside of the routes they are meant for.
+const (
+	PasswordChangeRequiredCode = "password_change_required"
+	ConsentRequiredCode        = "consent_required"
+)
+
+// JWTAuthMiddleware accepts full access tokens only.
+func JWTAuthMiddleware(authService port.AuthTokenManager, sessions port.SessionChecker, logger appLogger.Logger) gin.HandlerFunc {
+	return jwtAuth(authService, sessions, logger)
+}
+
+// PasswordChangeAuthMiddleware also accepts the restricted token login issues when the password
+// must be changed. It guards the password change route.
+func PasswordChangeAuthMiddleware(authService port.AuthTokenManager, sessions port.SessionChecker, logger appLogger.Logger) gin.HandlerFunc {
+	return jwtAuth(authService, sessions, logger, authservice.ScopePasswordChange)
+}
+
+// ConsentAuthMiddleware also accepts the restricted token login issues when policies must be
+// accepted. It guards the consent route.
+func ConsentAuthMiddleware(authService port.AuthTokenManager, sessions port.SessionChecker, logger appLogger.Logger) gin.HandlerFunc {
+	return jwtAuth(authService, sessions, logger, authservice.ScopeConsent)
+}
+
+// jwtAuth accepts tokens without a scope and tokens with one of scopes. A token that belongs to a
+// session is rejected once the session has ended, e.g. after a deletion request or a forced
+// password change.
+func jwtAuth(authService port.AuthTokenManager, sessions port.SessionChecker, logger appLogger.Logger, scopes ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -46,6 +74,36 @@ func JWTAuthMiddleware(authService port.AuthTokenManager, logger appLogger.Logge
 			return
 		}
 
+		if claims.Scope != "" && !slices.Contains(scopes, claims.Scope) {
+			logger.Warn(c.Request.Context(), "jwt middleware token scope rejected", map[string]interface{}{"user_id": claims.UserID, "scope": claims.Scope, "path": c.Request.URL.Path})
+			switch claims.Scope {
+			case authservice.ScopePasswordChange:
+				response.ErrorWithCode(c, http.StatusForbidden, PasswordChangeRequiredCode, "Password change required")
+			case authservice.ScopeConsent:
+				response.ErrorWithCode(c, http.StatusForbidden, ConsentRequiredCode, "Policies must be accepted")
+			default:
+				response.Error(c, http.StatusForbidden, "Forbidden")
+			}
+			c.Abort()
+			return
+		}
+
+		if claims.SessionID != "" {
+			live, err := sessions.Exists(c.Request.Context(), claims.UserID, claims.SessionID)
+			if err != nil {
+				logger.Error(c.Request.Context(), "jwt middleware session lookup failed", err, map[string]interface{}{"user_id": claims.UserID, "path": c.Request.URL.Path})
+				response.Error(c, http.StatusServiceUnavailable, "Service temporarily unavailable")
+				c.Abort()
+				return
+			}
+			if !live {
+				logger.Warn(c.Request.Context(), "jwt middleware session ended", map[string]interface{}{"user_id": claims.UserID, "path": c.Request.URL.Path})
+				response.Error(c, http.StatusUnauthorized, "Invalid Token")
+				c.Abort()
+				return
+			}
+		}
+
 		logger.Debug(c.Request.Context(), "jwt middleware authenticated request", map[string]interface{}{"user_id": claims.UserID, "path": c.Request.URL.Path})
 		c.Set(UserContextKey, claims.UserID)
 		c.Next()
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/SilentPlaces/basicauth.git/internal/adapters/inbound/http/gin/response"
+	"github.com/SilentPlaces/basicauth.git/internal/application/port"
+	appLogger "github.com/SilentPlaces/basicauth.git/internal/shared/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRoleMiddleware must run after JWTAuthMiddleware. It rejects users that do not hold the given role.
+func RequireRoleMiddleware(roleChecker port.RoleChecker, role string, logger appLogger.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetString(UserContextKey)
+		if userID == "" {
+			logger.Warn(c.Request.Context(), "role middleware missing context user id", map[string]interface{}{"path": c.Request.URL.Path})
+			response.Error(c, http.StatusUnauthorized, "Unauthorized request!")
+			c.Abort()
+			return
+		}
+
+		allowed, err := roleChecker.HasRole(userID, role)
+		if err != nil {
+			logger.Error(c.Request.Context(), "role middleware lookup failed", err, map[string]interface{}{"user_id": userID, "role": role})
+			response.Error(c, http.StatusInternalServerError, "Internal Server Error")
+			c.Abort()
+			return
+		}
+		if !allowed {
+			logger.Warn(c.Request.Context(), "role middleware access denied", map[string]interface{}{"user_id": userID, "role": role, "path": c.Request.URL.Path})
+			response.Error(c, http.StatusForbidden, "Forbidden")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
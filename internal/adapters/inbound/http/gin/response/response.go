@@ -21,3 +21,24 @@ func Error(c *gin.Context, statusCode int, message string) {
 		Message: message,
 	})
 }
+
+// ErrorWithCode is Error with a machine-readable errorCode clients can branch on.
+func ErrorWithCode(c *gin.Context, statusCode int, errorCode string, message string) {
+	c.JSON(statusCode, generaldto.Response{
+		Status:    "error",
+		Code:      statusCode,
+		Message:   message,
+		ErrorCode: errorCode,
+	})
+}
+
+// ErrorWithDetails is ErrorWithCode with structured details about the error.
+func ErrorWithDetails(c *gin.Context, statusCode int, errorCode string, message string, details interface{}) {
+	c.JSON(statusCode, generaldto.Response{
+		Status:    "error",
+		Code:      statusCode,
+		Message:   message,
+		ErrorCode: errorCode,
+		Details:   details,
+	})
+}
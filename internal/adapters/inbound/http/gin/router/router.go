@@ -4,6 +4,7 @@ import (
 	"github.com/SilentPlaces/basicauth.git/internal/adapters/inbound/http/gin/handlers"
 	"github.com/SilentPlaces/basicauth.git/internal/adapters/inbound/http/gin/middleware"
 	"github.com/SilentPlaces/basicauth.git/internal/application/port"
+	userrepo "github.com/SilentPlaces/basicauth.git/internal/repositories/user"
 	appLogger "github.com/SilentPlaces/basicauth.git/internal/shared/logger"
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -13,11 +14,26 @@ func NewRouter(
 	userHandler *handlers.UserHandler,
 	registrationHandler *handlers.RegistrationHandler,
 	healthHandler *handlers.HealthHandler,
+	auditHandler *handlers.AuditHandler,
+	webhookHandler *handlers.WebhookHandler,
+	mailQueueHandler *handlers.MailQueueHandler,
+	invitationHandler *handlers.InvitationHandler,
+	challengeHandler *handlers.ChallengeHandler,
+	policyHandler *handlers.PolicyHandler,
 	authService port.AuthTokenManager,
+	sessionChecker port.SessionChecker,
+	roleChecker port.RoleChecker,
+	trustedProxies []string,
 	logger appLogger.Logger,
-) *gin.Engine {
+) (*gin.Engine, error) {
 	engine := gin.Default()
+	// The client IP ends up in the audit log, sessions and consents, so X-Forwarded-For is only
+	// honoured from the configured proxies.
+	if err := engine.SetTrustedProxies(trustedProxies); err != nil {
+		return nil, err
+	}
 	engine.Use(middleware.CorrelationIDMiddleware(logger))
+	engine.Use(middleware.ClientIPMiddleware())
 	engine.Use(middleware.OTelReadyMiddleware(logger))
 	engine.Use(middleware.PrometheusMetricsMiddleware())
 
@@ -25,16 +41,50 @@ func NewRouter(
 	engine.GET("/health/live", healthHandler.Liveness)
 	engine.GET("/health/ready", healthHandler.Readiness)
 
+	engine.POST("/challenge", challengeHandler.NewChallenge)
+
 	engine.POST("/auth/login", userHandler.Login)
 	engine.POST("/auth/refresh-token", userHandler.RefreshToken)
 
+	engine.GET("/policies", policyHandler.CurrentPolicies)
+
 	engine.POST("/register/init", registrationHandler.SignUp)
 	engine.POST("/register/verify", registrationHandler.VerifyMail)
+	engine.POST("/register/verify-code", registrationHandler.VerifyCode)
 	engine.POST("/register/resend-verification", registrationHandler.ResendVerification)
 
+	// Also reachable with the token login issues when the password must be changed.
+	engine.PUT("/user/password", middleware.PasswordChangeAuthMiddleware(authService, sessionChecker, logger), userHandler.ChangePassword)
+	// Also reachable with the token login issues when policies must be accepted.
+	engine.POST("/user/consents", middleware.ConsentAuthMiddleware(authService, sessionChecker, logger), policyHandler.AcceptPolicies)
+
 	protected := engine.Group("/")
-	protected.Use(middleware.JWTAuthMiddleware(authService, logger))
+	protected.Use(middleware.JWTAuthMiddleware(authService, sessionChecker, logger))
 	protected.GET("/user", userHandler.GetUser)
+	protected.DELETE("/user", userHandler.DeleteAccount)
+	protected.GET("/user/export", userHandler.ExportAccount)
+	protected.GET("/user/consents", policyHandler.ListConsents)
+	protected.POST("/invitations", invitationHandler.CreateInvitation)
+	protected.GET("/invitations", invitationHandler.ListInvitations)
+	protected.DELETE("/invitations/:id", invitationHandler.RevokeInvitation)
+
+	admin := engine.Group("/admin")
+	admin.Use(middleware.JWTAuthMiddleware(authService, sessionChecker, logger))
+	admin.Use(middleware.RequireRoleMiddleware(roleChecker, userrepo.RoleAdmin, logger))
+	admin.GET("/audit-events", auditHandler.QueryEvents)
+	admin.POST("/webhooks", webhookHandler.CreateSubscription)
+	admin.GET("/webhooks", webhookHandler.ListSubscriptions)
+	admin.DELETE("/webhooks/:id", webhookHandler.DeleteSubscription)
+	admin.GET("/webhooks/:id/deliveries", webhookHandler.ListDeliveries)
+	admin.POST("/webhook-deliveries/:id/retry", webhookHandler.RetryDelivery)
+	admin.GET("/mail/dead-letters", mailQueueHandler.ListDeadLetters)
+	admin.POST("/mail/dead-letters/:id/replay", mailQueueHandler.ReplayDeadLetter)
+	admin.POST("/invitations", invitationHandler.AdminCreateInvitation)
+	admin.GET("/invitations", invitationHandler.AdminListInvitations)
+	admin.DELETE("/invitations/:id", invitationHandler.AdminRevokeInvitation)
+	admin.POST("/users/:id/require-password-change", userHandler.AdminRequirePasswordChange)
+	admin.POST("/policies", policyHandler.AdminPublishPolicy)
+	admin.GET("/policies", policyHandler.AdminListPolicies)
 
-	return engine
+	return engine, nil
 }
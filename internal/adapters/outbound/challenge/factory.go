@@ -0,0 +1,30 @@
+package challenge
+
+import (
+	"fmt"
+
+	"github.com/SilentPlaces/basicauth.git/internal/config"
+	challengerepo "github.com/SilentPlaces/basicauth.git/internal/repositories/challenge"
+	challengeservice "github.com/SilentPlaces/basicauth.git/internal/services/challenge"
+)
+
+// NewChallengeVerifier returns the verifier selected by cfg.Provider.
+func NewChallengeVerifier(cfg *config.ChallengeConfig, challengeRepository challengerepo.ChallengeRepository) (challengeservice.ChallengeVerifier, error) {
+	switch cfg.Provider {
+	case config.ChallengeProviderPoW:
+		return challengeservice.NewProofOfWork(challengeRepository, cfg), nil
+	case config.ChallengeProviderHCaptcha:
+		return NewHCaptchaVerifier(verifyURL(cfg, HCaptchaVerifyURL), cfg.Secret, cfg.SiteKey, cfg.Timeout), nil
+	case config.ChallengeProviderTurnstile:
+		return NewTurnstileVerifier(verifyURL(cfg, TurnstileVerifyURL), cfg.Secret, cfg.SiteKey, cfg.Timeout), nil
+	default:
+		return nil, fmt.Errorf("unknown challenge provider %q", cfg.Provider)
+	}
+}
+
+func verifyURL(cfg *config.ChallengeConfig, fallback string) string {
+	if cfg.VerifyURL != "" {
+		return cfg.VerifyURL
+	}
+	return fallback
+}
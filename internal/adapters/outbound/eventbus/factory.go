@@ -0,0 +1,34 @@
+package eventbus
+
+import (
+	"fmt"
+
+	"github.com/SilentPlaces/basicauth.git/internal/config"
+	outboxservice "github.com/SilentPlaces/basicauth.git/internal/services/outbox"
+	"github.com/redis/go-redis/v9"
+)
+
+// NewEventBus returns the bus adapter selected by cfg.Driver. It returns nil for
+// the "none" driver, in which case events stay in the outbox.
+func NewEventBus(cfg *config.EventBusConfig, redisClient *redis.Client) (outboxservice.EventBus, error) {
+	switch cfg.Driver {
+	case config.EventBusDriverNone:
+		return nil, nil
+	case config.EventBusDriverMemory:
+		return NewMemoryBus(), nil
+	case config.EventBusDriverRedis:
+		return NewRedisStreamBus(redisClient, cfg.Topic), nil
+	case config.EventBusDriverNATS:
+		if cfg.NATSURL == "" {
+			return nil, fmt.Errorf("event bus driver %q requires a NATS url", cfg.Driver)
+		}
+		return NewNATSBus(cfg.NATSURL, cfg.Topic)
+	case config.EventBusDriverKafka:
+		if cfg.KafkaRESTURL == "" {
+			return nil, fmt.Errorf("event bus driver %q requires a Kafka REST proxy url", cfg.Driver)
+		}
+		return NewKafkaRESTBus(cfg.KafkaRESTURL, cfg.Topic), nil
+	default:
+		return nil, fmt.Errorf("unknown event bus driver %q", cfg.Driver)
+	}
+}
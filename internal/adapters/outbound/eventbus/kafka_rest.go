@@ -0,0 +1,83 @@
+package eventbus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/SilentPlaces/basicauth.git/internal/models/models"
+	outboxservice "github.com/SilentPlaces/basicauth.git/internal/services/outbox"
+)
+
+const kafkaJSONContentType = "application/vnd.kafka.json.v2+json"
+
+// KafkaRESTBus produces events to a Kafka topic through a Kafka REST Proxy (v2 API).
+// The aggregate ID is used as record key, so events of one user land on the same partition in order.
+type KafkaRESTBus struct {
+	endpoint string
+	client   *http.Client
+}
+
+func NewKafkaRESTBus(baseURL string, topic string) *KafkaRESTBus {
+	return &KafkaRESTBus{
+		endpoint: strings.TrimRight(baseURL, "/") + "/topics/" + url.PathEscape(topic),
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (b *KafkaRESTBus) Publish(ctx context.Context, event models.OutboxEvent) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"records": []map[string]interface{}{
+			{"key": event.AggregateID, "value": outboxservice.EnvelopeOf(event)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode kafka record: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", kafkaJSONContentType)
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("kafka rest proxy request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("kafka rest proxy responded with %d: %s", resp.StatusCode, snippet)
+	}
+
+	// The proxy answers 200 even when a single record failed; the error is reported per offset.
+	var result struct {
+		Offsets []struct {
+			ErrorCode *int   `json:"error_code"`
+			Error     string `json:"error"`
+		} `json:"offsets"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to decode kafka rest proxy response: %w", err)
+	}
+	for _, offset := range result.Offsets {
+		if offset.ErrorCode != nil {
+			return fmt.Errorf("kafka rejected record (code %d): %s", *offset.ErrorCode, offset.Error)
+		}
+	}
+	return nil
+}
+
+func (b *KafkaRESTBus) Close() error {
+	b.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,49 @@
+package eventbus
+
+import (
+	"context"
+	"sync"
+
+	"github.com/SilentPlaces/basicauth.git/internal/models/models"
+)
+
+// MemoryBus keeps published events in process. It is meant for tests and local development.
+type MemoryBus struct {
+	mu       sync.RWMutex
+	events   []models.OutboxEvent
+	handlers []func(models.OutboxEvent)
+}
+
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{}
+}
+
+func (b *MemoryBus) Publish(_ context.Context, event models.OutboxEvent) error {
+	b.mu.Lock()
+	b.events = append(b.events, event)
+	handlers := append([]func(models.OutboxEvent){}, b.handlers...)
+	b.mu.Unlock()
+
+	for _, handler := range handlers {
+		handler(event)
+	}
+	return nil
+}
+
+// Subscribe registers a handler that is called synchronously for every published event.
+func (b *MemoryBus) Subscribe(handler func(models.OutboxEvent)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+// Events returns a copy of all events published so far.
+func (b *MemoryBus) Events() []models.OutboxEvent {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return append([]models.OutboxEvent{}, b.events...)
+}
+
+func (b *MemoryBus) Close() error {
+	return nil
+}
@@ -0,0 +1,153 @@
+package eventbus
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SilentPlaces/basicauth.git/internal/models/models"
+	outboxservice "github.com/SilentPlaces/basicauth.git/internal/services/outbox"
+)
+
+const natsTimeout = 5 * time.Second
+
+// NATSBus publishes events to core NATS subjects "<topic>.<event type>" using the
+// plain text client protocol. Every publish is followed by a PING so the call only
+// returns once the server has processed the message.
+type NATSBus struct {
+	address  string
+	user     string
+	password string
+	topic    string
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func NewNATSBus(rawURL string, topic string) (*NATSBus, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid NATS url %q", rawURL)
+	}
+	bus := &NATSBus{address: parsed.Host, topic: topic}
+	if parsed.User != nil {
+		bus.user = parsed.User.Username()
+		bus.password, _ = parsed.User.Password()
+	}
+	return bus, nil
+}
+
+func (b *NATSBus) Publish(ctx context.Context, event models.OutboxEvent) error {
+	payload, err := json.Marshal(outboxservice.EnvelopeOf(event))
+	if err != nil {
+		return fmt.Errorf("failed to encode nats message: %w", err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.ensureConnected(ctx); err != nil {
+		return err
+	}
+	if err := b.publish(b.topic+"."+event.EventType, payload); err != nil {
+		// Drop the connection so the next publish starts from a clean state.
+		b.closeLocked()
+		return err
+	}
+	return nil
+}
+
+func (b *NATSBus) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closeLocked()
+	return nil
+}
+
+func (b *NATSBus) ensureConnected(ctx context.Context) error {
+	if b.conn != nil {
+		return nil
+	}
+
+	dialer := net.Dialer{Timeout: natsTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", b.address)
+	if err != nil {
+		return fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+	b.conn = conn
+	b.reader = bufio.NewReader(conn)
+
+	_ = conn.SetDeadline(time.Now().Add(natsTimeout))
+	line, err := b.reader.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "INFO ") {
+		b.closeLocked()
+		return fmt.Errorf("unexpected NATS greeting: %q", strings.TrimSpace(line))
+	}
+
+	connect := map[string]interface{}{
+		"verbose":  false,
+		"pedantic": false,
+		"name":     "basicauth-outbox",
+		"lang":     "go",
+		"protocol": 0,
+	}
+	if b.user != "" {
+		connect["user"] = b.user
+		connect["pass"] = b.password
+	}
+	options, _ := json.Marshal(connect)
+	if _, err := fmt.Fprintf(conn, "CONNECT %s\r\nPING\r\n", options); err != nil {
+		b.closeLocked()
+		return fmt.Errorf("failed to send NATS CONNECT: %w", err)
+	}
+	if err := b.awaitPong(); err != nil {
+		b.closeLocked()
+		return err
+	}
+	return nil
+}
+
+func (b *NATSBus) publish(subject string, payload []byte) error {
+	_ = b.conn.SetDeadline(time.Now().Add(natsTimeout))
+	if _, err := fmt.Fprintf(b.conn, "PUB %s %d\r\n%s\r\nPING\r\n", subject, len(payload), payload); err != nil {
+		return fmt.Errorf("failed to publish to NATS: %w", err)
+	}
+	return b.awaitPong()
+}
+
+// awaitPong reads until the server acknowledges our PING, answering server PINGs on the way.
+func (b *NATSBus) awaitPong() error {
+	for {
+		line, err := b.reader.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("failed to read from NATS: %w", err)
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err := b.conn.Write([]byte("PONG\r\n")); err != nil {
+				return fmt.Errorf("failed to answer NATS ping: %w", err)
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return errors.New("NATS error: " + strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+}
+
+func (b *NATSBus) closeLocked() {
+	if b.conn != nil {
+		_ = b.conn.Close()
+	}
+	b.conn = nil
+	b.reader = nil
+}
@@ -0,0 +1,46 @@
+package eventbus
+
+import (
+	"context"
+	"time"
+
+	"github.com/SilentPlaces/basicauth.git/internal/models/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// streamMaxLen caps the stream so it does not grow without bound. Trimming is approximate.
+const streamMaxLen = 100000
+
+// RedisStreamBus appends events to a single Redis stream. Consumers read it with consumer groups.
+type RedisStreamBus struct {
+	client *redis.Client
+	stream string
+}
+
+func NewRedisStreamBus(client *redis.Client, stream string) *RedisStreamBus {
+	return &RedisStreamBus{client: client, stream: stream}
+}
+
+func (b *RedisStreamBus) Publish(ctx context.Context, event models.OutboxEvent) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	return b.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: b.stream,
+		MaxLen: streamMaxLen,
+		Approx: true,
+		Values: map[string]interface{}{
+			"id":             event.EventID,
+			"type":           event.EventType,
+			"aggregate_type": event.AggregateType,
+			"aggregate_id":   event.AggregateID,
+			"occurred_at":    event.OccurredAt.Format(time.RFC3339Nano),
+			"data":           event.Payload,
+		},
+	}).Err()
+}
+
+// Close is a no-op: the Redis client is shared with the rest of the application.
+func (b *RedisStreamBus) Close() error {
+	return nil
+}
@@ -0,0 +1,42 @@
+package mailtransport
+
+import (
+	"fmt"
+
+	"github.com/SilentPlaces/basicauth.git/internal/config"
+	mailservice "github.com/SilentPlaces/basicauth.git/internal/services/mail"
+)
+
+const defaultMailPath = "./mail"
+
+// NewMailTransport returns the transport selected by cfg.Driver.
+func NewMailTransport(cfg *config.MailTransportConfig) (mailservice.MailTransport, error) {
+	switch cfg.Driver {
+	case config.MailTransportSMTP:
+		if cfg.SMTP == nil {
+			return nil, fmt.Errorf("mail transport %q requires smtp settings", cfg.Driver)
+		}
+		return NewSMTPTransport(cfg.SMTP)
+	case config.MailTransportFile:
+		path := cfg.FilePath
+		if path == "" {
+			path = defaultMailPath
+		}
+		return NewFileTransport(path)
+	case config.MailTransportMbox:
+		path := cfg.FilePath
+		if path == "" {
+			path = defaultMailPath + "/mail.mbox"
+		}
+		return NewMboxTransport(path)
+	case config.MailTransportMemory:
+		return NewMemoryTransport(), nil
+	case config.MailTransportHTTP:
+		if cfg.HTTPURL == "" {
+			return nil, fmt.Errorf("mail transport %q requires an http url", cfg.Driver)
+		}
+		return NewHTTPTransport(cfg.HTTPURL, cfg.HTTPToken, cfg.HTTPTimeout), nil
+	default:
+		return nil, fmt.Errorf("unknown mail transport %q", cfg.Driver)
+	}
+}
@@ -0,0 +1,96 @@
+package mailtransport
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	mailservice "github.com/SilentPlaces/basicauth.git/internal/services/mail"
+	"github.com/google/uuid"
+)
+
+// FileTransport writes every message as a separate .eml file into a directory. It is meant for
+// local development; the files open in any mail client.
+type FileTransport struct {
+	dir string
+}
+
+func NewFileTransport(dir string) (*FileTransport, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create mail directory: %w", err)
+	}
+	return &FileTransport{dir: dir}, nil
+}
+
+func (t *FileTransport) Send(message *mailservice.Message) error {
+	raw, err := message.Bytes()
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000"), uuid.NewString())
+	return os.WriteFile(filepath.Join(t.dir, name), raw, 0o640)
+}
+
+func (t *FileTransport) Close() error {
+	return nil
+}
+
+// MboxTransport appends messages to a single mbox file (mboxrd flavour).
+type MboxTransport struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewMboxTransport(path string) (*MboxTransport, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create mbox directory: %w", err)
+	}
+	return &MboxTransport{path: path}, nil
+}
+
+func (t *MboxTransport) Send(message *mailservice.Message) error {
+	raw, err := message.Bytes()
+	if err != nil {
+		return err
+	}
+	sender, err := message.Sender()
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From %s %s\n", sender, message.Date.UTC().Format(time.ANSIC))
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(raw)+1)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		// mboxrd: quote "From " lines, including already quoted ones, so readers can undo it.
+		if strings.HasPrefix(strings.TrimLeft(line, ">"), "From ") {
+			buf.WriteByte('>')
+		}
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	buf.WriteByte('\n')
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	f, err := os.OpenFile(t.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o640)
+	if err != nil {
+		return fmt.Errorf("failed to open mbox: %w", err)
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to append to mbox: %w", err)
+	}
+	return f.Close()
+}
+
+func (t *MboxTransport) Close() error {
+	return nil
+}
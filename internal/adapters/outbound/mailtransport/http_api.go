@@ -0,0 +1,88 @@
+package mailtransport
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	mailservice "github.com/SilentPlaces/basicauth.git/internal/services/mail"
+)
+
+// HTTPTransport posts messages as JSON to a mail provider API or a small relay in front of one.
+// The body is:
+//
+//	{"message_id": "...", "from": "...", "to": ["..."], "subject": "...", "text": "...", "html": "..."}
+//
+// Any 2xx response counts as accepted.
+type HTTPTransport struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+type httpMailRequest struct {
+	MessageID string   `json:"message_id"`
+	From      string   `json:"from"`
+	To        []string `json:"to"`
+	Subject   string   `json:"subject"`
+	Text      string   `json:"text"`
+	HTML      string   `json:"html,omitempty"`
+}
+
+func NewHTTPTransport(url string, token string, timeout time.Duration) *HTTPTransport {
+	return &HTTPTransport{
+		url:    url,
+		token:  token,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (t *HTTPTransport) Send(message *mailservice.Message) error {
+	// Bytes validates the addresses and assigns the Message-ID.
+	if _, err := message.Bytes(); err != nil {
+		return err
+	}
+	body, err := json.Marshal(httpMailRequest{
+		MessageID: message.MessageID,
+		From:      message.From,
+		To:        message.To,
+		Subject:   message.Subject,
+		Text:      message.TextBody,
+		HTML:      message.HTMLBody,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode mail request: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, t.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// Providers that deduplicate can use the Message-ID as idempotency key.
+	req.Header.Set("Idempotency-Key", message.MessageID)
+	if t.token != "" {
+		req.Header.Set("Authorization", "Bearer "+t.token)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("mail api request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("mail api responded with %d: %s", resp.StatusCode, snippet)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+func (t *HTTPTransport) Close() error {
+	t.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,46 @@
+package mailtransport
+
+import (
+	"sync"
+
+	mailservice "github.com/SilentPlaces/basicauth.git/internal/services/mail"
+)
+
+// MemoryTransport captures messages instead of sending them. It is meant for tests.
+type MemoryTransport struct {
+	mu       sync.RWMutex
+	messages []mailservice.Message
+}
+
+func NewMemoryTransport() *MemoryTransport {
+	return &MemoryTransport{}
+}
+
+func (t *MemoryTransport) Send(message *mailservice.Message) error {
+	// Render once so a test sees the same Date and Message-ID a real transport would send.
+	if _, err := message.Bytes(); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.messages = append(t.messages, *message)
+	return nil
+}
+
+// Messages returns a copy of all captured messages.
+func (t *MemoryTransport) Messages() []mailservice.Message {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return append([]mailservice.Message{}, t.messages...)
+}
+
+// Reset drops all captured messages.
+func (t *MemoryTransport) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.messages = nil
+}
+
+func (t *MemoryTransport) Close() error {
+	return nil
+}
@@ -0,0 +1,235 @@
+package mailtransport
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/SilentPlaces/basicauth.git/internal/config"
+	mailservice "github.com/SilentPlaces/basicauth.git/internal/services/mail"
+)
+
+const (
+	smtpDialTimeout = 10 * time.Second
+	// smtpSendTimeout bounds one complete MAIL/RCPT/DATA exchange on a connection.
+	smtpSendTimeout = 30 * time.Second
+)
+
+var errSTARTTLSUnsupported = errors.New("smtp server does not offer STARTTLS")
+
+// SMTPTransport delivers mail over SMTP and keeps up to PoolSize authenticated connections open
+// between messages.
+type SMTPTransport struct {
+	cfg       *config.SMTPConfig
+	addr      string
+	tlsConfig *tls.Config
+
+	mu     sync.Mutex
+	idle   []*smtpConn
+	closed bool
+}
+
+type smtpConn struct {
+	conn     net.Conn
+	client   *smtp.Client
+	lastUsed time.Time
+}
+
+func NewSMTPTransport(cfg *config.SMTPConfig) (*SMTPTransport, error) {
+	switch cfg.TLSMode {
+	case config.SMTPTLSNone, config.SMTPTLSOpportunistic, config.SMTPTLSRequired, config.SMTPTLSImplicit:
+	default:
+		return nil, fmt.Errorf("unknown smtp tls mode %q", cfg.TLSMode)
+	}
+
+	serverName := cfg.ServerName
+	if serverName == "" {
+		serverName = cfg.Host
+	}
+	tlsConfig := &tls.Config{ServerName: serverName, MinVersion: tls.VersionTLS12}
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read smtp ca file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("smtp ca file %s contains no certificates", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return &SMTPTransport{
+		cfg:       cfg,
+		addr:      net.JoinHostPort(cfg.Host, cfg.Port),
+		tlsConfig: tlsConfig,
+	}, nil
+}
+
+func (t *SMTPTransport) Send(message *mailservice.Message) error {
+	raw, err := message.Bytes()
+	if err != nil {
+		return err
+	}
+	sender, err := message.Sender()
+	if err != nil {
+		return err
+	}
+	recipients, err := message.Recipients()
+	if err != nil {
+		return err
+	}
+
+	c, err := t.acquire()
+	if err != nil {
+		return err
+	}
+	if err := t.deliver(c, sender, recipients, raw); err != nil {
+		// The connection state is unknown after a failure; do not reuse it.
+		c.client.Close()
+		return err
+	}
+	t.release(c)
+	return nil
+}
+
+func (t *SMTPTransport) deliver(c *smtpConn, sender string, recipients []string, raw []byte) error {
+	if err := c.conn.SetDeadline(time.Now().Add(smtpSendTimeout)); err != nil {
+		return err
+	}
+	if err := c.client.Mail(sender); err != nil {
+		return fmt.Errorf("smtp MAIL FROM rejected: %w", err)
+	}
+	for _, recipient := range recipients {
+		if err := c.client.Rcpt(recipient); err != nil {
+			return fmt.Errorf("smtp RCPT TO %s rejected: %w", recipient, err)
+		}
+	}
+	w, err := c.client.Data()
+	if err != nil {
+		return fmt.Errorf("smtp DATA rejected: %w", err)
+	}
+	if _, err := w.Write(raw); err != nil {
+		return fmt.Errorf("smtp DATA write failed: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("smtp message rejected: %w", err)
+	}
+	return nil
+}
+
+// acquire returns a pooled connection that still answers RSET, or dials a new one.
+func (t *SMTPTransport) acquire() (*smtpConn, error) {
+	for {
+		t.mu.Lock()
+		if t.closed {
+			t.mu.Unlock()
+			return nil, errors.New("smtp transport is closed")
+		}
+		if len(t.idle) == 0 {
+			t.mu.Unlock()
+			return t.dial()
+		}
+		c := t.idle[len(t.idle)-1]
+		t.idle = t.idle[:len(t.idle)-1]
+		t.mu.Unlock()
+
+		if time.Since(c.lastUsed) > t.cfg.IdleTimeout {
+			t.quit(c)
+			continue
+		}
+		if err := c.conn.SetDeadline(time.Now().Add(smtpDialTimeout)); err == nil {
+			if err := c.client.Reset(); err == nil {
+				return c, nil
+			}
+		}
+		c.client.Close()
+	}
+}
+
+func (t *SMTPTransport) release(c *smtpConn) {
+	c.lastUsed = time.Now()
+
+	t.mu.Lock()
+	if !t.closed && len(t.idle) < t.cfg.PoolSize {
+		t.idle = append(t.idle, c)
+		t.mu.Unlock()
+		return
+	}
+	t.mu.Unlock()
+	t.quit(c)
+}
+
+func (t *SMTPTransport) dial() (*smtpConn, error) {
+	dialer := &net.Dialer{Timeout: smtpDialTimeout}
+
+	var conn net.Conn
+	var err error
+	if t.cfg.TLSMode == config.SMTPTLSImplicit {
+		conn, err = tls.DialWithDialer(dialer, "tcp", t.addr, t.tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", t.addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to smtp server %s: %w", t.addr, err)
+	}
+	if err := conn.SetDeadline(time.Now().Add(smtpSendTimeout)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	client, err := smtp.NewClient(conn, t.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("smtp handshake failed: %w", err)
+	}
+	c := &smtpConn{conn: conn, client: client}
+
+	if t.cfg.TLSMode == config.SMTPTLSOpportunistic || t.cfg.TLSMode == config.SMTPTLSRequired {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(t.tlsConfig); err != nil {
+				client.Close()
+				return nil, fmt.Errorf("smtp STARTTLS failed: %w", err)
+			}
+		} else if t.cfg.TLSMode == config.SMTPTLSRequired {
+			client.Close()
+			return nil, errSTARTTLSUnsupported
+		}
+	}
+
+	if t.cfg.Username != "" {
+		// PlainAuth itself refuses to send credentials over an unencrypted connection to a remote host.
+		if err := client.Auth(smtp.PlainAuth("", t.cfg.Username, t.cfg.Password, t.cfg.Host)); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("smtp authentication failed: %w", err)
+		}
+	}
+	return c, nil
+}
+
+func (t *SMTPTransport) quit(c *smtpConn) {
+	_ = c.conn.SetDeadline(time.Now().Add(smtpDialTimeout))
+	if err := c.client.Quit(); err != nil {
+		c.client.Close()
+	}
+}
+
+// Close quits all pooled connections. Later sends fail.
+func (t *SMTPTransport) Close() error {
+	t.mu.Lock()
+	idle := t.idle
+	t.idle = nil
+	t.closed = true
+	t.mu.Unlock()
+
+	for _, c := range idle {
+		t.quit(c)
+	}
+	return nil
+}
@@ -1,8 +1,13 @@
 package port
 
 import (
+	"context"
+
 	"github.com/SilentPlaces/basicauth.git/internal/dto/user"
+	"github.com/SilentPlaces/basicauth.git/internal/models/models"
+	auditservice "github.com/SilentPlaces/basicauth.git/internal/services/audit"
 	authservice "github.com/SilentPlaces/basicauth.git/internal/services/auth"
+	passwordservice "github.com/SilentPlaces/basicauth.git/internal/services/password"
 )
 
 type UserReader interface {
@@ -11,8 +16,69 @@ type UserReader interface {
 }
 
 type AuthTokenManager interface {
-	GenerateToken(userID string) (*authservice.Tokens, error)
+	GenerateToken(userID string, sessionID string) (*authservice.Tokens, error)
+	GeneratePasswordChangeToken(userID string) (string, error)
+	GenerateConsentToken(userID string) (string, error)
 	RefreshToken(token string) (*authservice.Tokens, error)
 	ValidateToken(token string) error
 	ExtractClaims(token string) (*authservice.Claims, error)
 }
+
+type PasswordStatusChecker interface {
+	Status(ctx context.Context, userID string) (*passwordservice.PasswordStatus, error)
+}
+
+type PolicyConsentChecker interface {
+	Pending(userID string) ([]models.PolicyDocument, error)
+}
+
+type SessionTracker interface {
+	Start(ctx context.Context, userID string) (string, error)
+	Touch(ctx context.Context, userID string, sessionID string) (bool, error)
+}
+
+type SessionChecker interface {
+	Exists(ctx context.Context, userID string, sessionID string) (bool, error)
+}
+
+type AccountDeletionCanceller interface {
+	CancelDeletion(ctx context.Context, userID string) (bool, error)
+}
+
+type AuditRecorder interface {
+	Record(ctx context.Context, entry auditservice.Entry) error
+}
+
+type AuditReader interface {
+	Query(filter models.AuditEventFilter) ([]models.AuditEvent, error)
+	Verify() (*auditservice.VerificationReport, error)
+}
+
+type RoleChecker interface {
+	HasRole(userID string, role string) (bool, error)
+}
+
+type WebhookPublisher interface {
+	Publish(ctx context.Context, eventType string, data map[string]interface{}) error
+}
+
+type WebhookManager interface {
+	CreateSubscription(rawURL string, eventTypes []string, secret string) (*models.WebhookSubscription, error)
+	ListSubscriptions() ([]models.WebhookSubscription, error)
+	DeleteSubscription(id string) error
+	ListDeliveries(subscriptionID string, status string, limit int, offset int) ([]models.WebhookDelivery, error)
+	RetryDelivery(id string) error
+}
+
+type MailEnqueuer interface {
+	Enqueue(job models.MailJob) (bool, error)
+}
+
+type MailQueueManager interface {
+	ListDeadLetters(limit int) ([]models.MailJob, error)
+	Replay(id string) (*models.MailJob, error)
+}
+
+type DomainEventRecorder interface {
+	Record(ctx context.Context, eventType string, aggregateID string, payload map[string]interface{}) error
+}
@@ -0,0 +1,56 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/SilentPlaces/basicauth.git/internal/application/port"
+	"github.com/SilentPlaces/basicauth.git/internal/models/models"
+	auditservice "github.com/SilentPlaces/basicauth.git/internal/services/audit"
+	appLogger "github.com/SilentPlaces/basicauth.git/internal/shared/logger"
+)
+
+type AuditUseCase struct {
+	auditReader   port.AuditReader
+	auditRecorder port.AuditRecorder
+	logger        appLogger.Logger
+}
+
+func NewAuditUseCase(auditReader port.AuditReader, auditRecorder port.AuditRecorder, logger appLogger.Logger) *AuditUseCase {
+	return &AuditUseCase{
+		auditReader:   auditReader,
+		auditRecorder: auditRecorder,
+		logger:        logger,
+	}
+}
+
+// QueryEvents returns audit events for an administrator. The query itself is audited.
+func (u *AuditUseCase) QueryEvents(ctx context.Context, adminID string, filter models.AuditEventFilter) ([]models.AuditEvent, error) {
+	u.logger.Info(ctx, "audit query requested", map[string]interface{}{"user_id": adminID})
+	events, err := u.auditReader.Query(filter)
+	if err != nil {
+		u.logger.Error(ctx, "audit query failed", err, map[string]interface{}{"user_id": adminID})
+		recordAudit(ctx, u.auditRecorder, u.logger, auditservice.Entry{
+			Actor:   adminID,
+			Action:  models.AuditActionAuditQuery,
+			Outcome: models.AuditOutcomeFailure,
+		})
+		return nil, err
+	}
+
+	recordAudit(ctx, u.auditRecorder, u.logger, auditservice.Entry{
+		Actor:   adminID,
+		Action:  models.AuditActionAuditQuery,
+		Outcome: models.AuditOutcomeSuccess,
+	})
+	return events, nil
+}
+
+// recordAudit writes an audit event. A failing audit store is logged but never fails the caller's flow.
+func recordAudit(ctx context.Context, recorder port.AuditRecorder, logger appLogger.Logger, entry auditservice.Entry) {
+	if err := recorder.Record(ctx, entry); err != nil {
+		logger.Error(ctx, "audit event could not be recorded", err, map[string]interface{}{
+			"action":  entry.Action,
+			"outcome": entry.Outcome,
+		})
+	}
+}
@@ -7,7 +7,14 @@ import (
 	"github.com/SilentPlaces/basicauth.git/internal/application/port"
 	logindto "github.com/SilentPlaces/basicauth.git/internal/dto/auth/login"
 	refreshtokendto "github.com/SilentPlaces/basicauth.git/internal/dto/auth/refresh_token"
+	dto "github.com/SilentPlaces/basicauth.git/internal/dto/user"
+	policymapper "github.com/SilentPlaces/basicauth.git/internal/mappers/policy"
 	mapper "github.com/SilentPlaces/basicauth.git/internal/mappers/users"
+	"github.com/SilentPlaces/basicauth.git/internal/models/models"
+	accountservice "github.com/SilentPlaces/basicauth.git/internal/services/account"
+	auditservice "github.com/SilentPlaces/basicauth.git/internal/services/audit"
+	authservice "github.com/SilentPlaces/basicauth.git/internal/services/auth"
+	passwordservice "github.com/SilentPlaces/basicauth.git/internal/services/password"
 	appLogger "github.com/SilentPlaces/basicauth.git/internal/shared/logger"
 	validation "github.com/SilentPlaces/basicauth.git/internal/validation/user"
 )
@@ -16,58 +23,290 @@ var (
 	ErrBadRequest      = errors.New("bad request")
 	ErrWrongCredential = errors.New("wrong email or password")
 	ErrUnauthorized    = errors.New("unauthorized")
+	ErrNotFound        = errors.New("not found")
+	// ErrTooManyAttempts means a per-user attempt limit was reached.
+	ErrTooManyAttempts = errors.New("too many attempts")
+	// ErrServiceUnavailable means a dependency is down and the request may be retried later.
+	ErrServiceUnavailable = errors.New("service unavailable")
+	// ErrChallengeRequired means the route requires a challenge response and none was sent.
+	ErrChallengeRequired = errors.New("challenge required")
+	// ErrChallengeFailed means the challenge response was not accepted.
+	ErrChallengeFailed = errors.New("challenge failed")
+	// ErrPasswordChangeRequired means the user must set a new password before getting a session.
+	ErrPasswordChangeRequired = errors.New("password change required")
+	// ErrConsentRequired means the user must accept the policies in force before getting a session.
+	ErrConsentRequired = errors.New("consent required")
 )
 
 type AuthUseCase struct {
-	userService port.UserReader
-	authService port.AuthTokenManager
-	logger      appLogger.Logger
+	userService     port.UserReader
+	authService     port.AuthTokenManager
+	passwords       port.PasswordStatusChecker
+	policies        port.PolicyConsentChecker
+	sessions        port.SessionTracker
+	accounts        port.AccountDeletionCanceller
+	auditRecorder   port.AuditRecorder
+	webhooks        port.WebhookPublisher
+	events          port.DomainEventRecorder
+	emailNormalizer *validation.EmailNormalizer
+	logger          appLogger.Logger
 }
 
-func NewAuthUseCase(userService port.UserReader, authService port.AuthTokenManager, logger appLogger.Logger) *AuthUseCase {
+func NewAuthUseCase(
+	userService port.UserReader,
+	authService port.AuthTokenManager,
+	passwords port.PasswordStatusChecker,
+	policies port.PolicyConsentChecker,
+	sessions port.SessionTracker,
+	accounts port.AccountDeletionCanceller,
+	auditRecorder port.AuditRecorder,
+	webhooks port.WebhookPublisher,
+	events port.DomainEventRecorder,
+	emailNormalizer *validation.EmailNormalizer,
+	logger appLogger.Logger,
+) *AuthUseCase {
 	return &AuthUseCase{
-		userService: userService,
-		authService: authService,
-		logger:      logger,
+		userService:     userService,
+		authService:     authService,
+		passwords:       passwords,
+		policies:        policies,
+		sessions:        sessions,
+		accounts:        accounts,
+		auditRecorder:   auditRecorder,
+		webhooks:        webhooks,
+		events:          events,
+		emailNormalizer: emailNormalizer,
+		logger:          logger,
 	}
 }
 
 func (u *AuthUseCase) Login(ctx context.Context, email, password string) (*logindto.LoginResponseDTO, error) {
 	u.logger.Info(ctx, "auth login requested", map[string]interface{}{"email": email})
-	if err := validation.ValidateEmail(email); err != nil {
+	_, canonicalEmail, err := u.emailNormalizer.Normalize(email)
+	if err != nil {
 		u.logger.Warn(ctx, "auth login validation failed", map[string]interface{}{"email": email})
+		u.recordLogin(ctx, email, email, models.AuditOutcomeFailure, "invalid_email")
 		return nil, ErrBadRequest
 	}
 
-	userData, err := u.userService.VerifyLogin(email, password)
+	userData, err := u.userService.VerifyLogin(canonicalEmail, password)
 	if err != nil {
 		u.logger.Warn(ctx, "auth login credentials rejected", map[string]interface{}{"email": email})
+		u.recordLogin(ctx, email, email, models.AuditOutcomeFailure, "wrong_credentials")
+		return nil, ErrWrongCredential
+	}
+
+	// Logging in during the grace period of a deletion request restores the account.
+	deletionCancelled, err := u.accounts.CancelDeletion(ctx, userData.ID)
+	if errors.Is(err, accountservice.ErrAccountDeleted) {
+		u.logger.Warn(ctx, "auth login to a deleted account rejected", map[string]interface{}{"user_id": userData.ID})
+		u.recordLogin(ctx, userData.ID, userData.ID, models.AuditOutcomeFailure, "account_deleted")
 		return nil, ErrWrongCredential
 	}
+	if err != nil {
+		u.logger.Error(ctx, "auth login deletion check failed", err, map[string]interface{}{"user_id": userData.ID})
+		u.recordLogin(ctx, userData.ID, userData.ID, models.AuditOutcomeFailure, "deletion_check_failed")
+		return nil, err
+	}
+	if deletionCancelled {
+		u.logger.Info(ctx, "auth login cancelled account deletion", map[string]interface{}{"user_id": userData.ID})
+		recordAudit(ctx, u.auditRecorder, u.logger, auditservice.Entry{
+			Actor:   userData.ID,
+			Subject: userData.ID,
+			Action:  models.AuditActionDeletionCancel,
+			Outcome: models.AuditOutcomeSuccess,
+		})
+	}
+
+	status, err := u.passwords.Status(ctx, userData.ID)
+	if err != nil {
+		u.logger.Error(ctx, "auth login password status lookup failed", err, map[string]interface{}{"user_id": userData.ID})
+		u.recordLogin(ctx, userData.ID, userData.ID, models.AuditOutcomeFailure, "password_status_failed")
+		return nil, err
+	}
+	if status.ChangeRequired() {
+		data, err := u.passwordChangeLogin(ctx, userData, status)
+		if data != nil {
+			data.AccountDeletionCancelled = deletionCancelled
+		}
+		return data, err
+	}
+
+	pending, err := u.policies.Pending(userData.ID)
+	if err != nil {
+		u.logger.Error(ctx, "auth login policy consent lookup failed", err, map[string]interface{}{"user_id": userData.ID})
+		u.recordLogin(ctx, userData.ID, userData.ID, models.AuditOutcomeFailure, "consent_check_failed")
+		return nil, err
+	}
+	if len(pending) > 0 {
+		data, err := u.consentLogin(ctx, userData, pending)
+		if data != nil {
+			data.AccountDeletionCancelled = deletionCancelled
+		}
+		return data, err
+	}
 
-	token, err := u.authService.GenerateToken(userData.ID)
+	sessionID, err := u.sessions.Start(ctx, userData.ID)
 	if err != nil {
-		u.logger.Error(ctx, "auth login token generation failed", err, map[string]interface{}{"user_id": userData.ID})
+		u.logger.Error(ctx, "auth login session could not be started", err, map[string]interface{}{"user_id": userData.ID})
+		u.recordLogin(ctx, userData.ID, userData.ID, models.AuditOutcomeFailure, "session_start_failed")
 		return nil, err
 	}
+	token, err := u.authService.GenerateToken(userData.ID, sessionID)
+	if err != nil {
+		return nil, u.tokenGenerationFailed(ctx, userData.ID, err)
+	}
 
 	u.logger.Info(ctx, "auth login succeeded", map[string]interface{}{"user_id": userData.ID})
+	u.recordLogin(ctx, userData.ID, userData.ID, models.AuditOutcomeSuccess, "")
+	publishWebhook(ctx, u.webhooks, u.logger, models.WebhookEventUserLoggedIn, map[string]interface{}{"user_id": userData.ID})
+	if err := u.events.Record(ctx, models.EventUserLoggedIn, userData.ID, map[string]interface{}{"user_id": userData.ID}); err != nil {
+		u.logger.Error(ctx, "auth login domain event could not be recorded", err, map[string]interface{}{"user_id": userData.ID})
+	}
+	data := &logindto.LoginResponseDTO{
+		User:                     userData,
+		Token:                    token.AccessToken,
+		RefreshToken:             token.RefreshToken,
+		AccountDeletionCancelled: deletionCancelled,
+	}
+	if status.ExpiresSoon {
+		data.PasswordExpiresAt = &status.ExpiresAt
+	}
+	return data, nil
+}
+
+// passwordChangeLogin answers a login whose password must be changed with a token that only
+// allows the change, instead of a session.
+func (u *AuthUseCase) passwordChangeLogin(ctx context.Context, userData *dto.UserResponseDTO, status *passwordservice.PasswordStatus) (*logindto.LoginResponseDTO, error) {
+	token, err := u.authService.GeneratePasswordChangeToken(userData.ID)
+	if err != nil {
+		return nil, u.tokenGenerationFailed(ctx, userData.ID, err)
+	}
+
+	u.logger.Warn(ctx, "auth login requires a password change", map[string]interface{}{"user_id": userData.ID, "reason": status.ChangeReason})
+	u.recordLogin(ctx, userData.ID, userData.ID, models.AuditOutcomeFailure, status.ChangeReason)
 	return &logindto.LoginResponseDTO{
-		User:         userData,
-		Token:        token.AccessToken,
-		RefreshToken: token.RefreshToken,
+		User:                   userData,
+		PasswordChangeToken:    token,
+		PasswordChangeRequired: true,
+		PasswordChangeReason:   status.ChangeReason,
 	}, nil
 }
 
+// consentLogin answers a login of a user who has not accepted a mandatory policy version with a
+// token that only allows accepting it, instead of a session.
+func (u *AuthUseCase) consentLogin(ctx context.Context, userData *dto.UserResponseDTO, pending []models.PolicyDocument) (*logindto.LoginResponseDTO, error) {
+	token, err := u.authService.GenerateConsentToken(userData.ID)
+	if err != nil {
+		return nil, u.tokenGenerationFailed(ctx, userData.ID, err)
+	}
+
+	u.logger.Warn(ctx, "auth login requires policy consent", map[string]interface{}{"user_id": userData.ID})
+	u.recordLogin(ctx, userData.ID, userData.ID, models.AuditOutcomeFailure, "consent_required")
+	return &logindto.LoginResponseDTO{
+		User:            userData,
+		ConsentToken:    token,
+		ConsentRequired: true,
+		PendingPolicies: policymapper.MapPolicyDocumentsToResDTO(pending),
+	}, nil
+}
+
+func (u *AuthUseCase) tokenGenerationFailed(ctx context.Context, userID string, err error) error {
+	u.logger.Error(ctx, "auth login token generation failed", err, map[string]interface{}{"user_id": userID})
+	u.recordLogin(ctx, userID, userID, models.AuditOutcomeFailure, "token_generation_failed")
+	if errors.Is(err, authservice.ErrSigningUnavailable) {
+		return ErrServiceUnavailable
+	}
+	return err
+}
+
 func (u *AuthUseCase) RefreshToken(ctx context.Context, refreshToken string) (*refreshtokendto.RefreshTokenResDTO, error) {
 	u.logger.Info(ctx, "auth refresh token requested", nil)
 	tokens, err := u.authService.RefreshToken(refreshToken)
+	if errors.Is(err, authservice.ErrSigningUnavailable) {
+		u.logger.Error(ctx, "auth refresh token signing failed", err, nil)
+		return nil, ErrServiceUnavailable
+	}
 	if err != nil {
 		u.logger.Warn(ctx, "auth refresh token failed", map[string]interface{}{"reason": "invalid_or_expired_refresh_token"})
+		u.recordRefresh(ctx, "", models.AuditOutcomeFailure, "invalid_or_expired_refresh_token")
 		return nil, err
 	}
 
+	userID, sessionID := "", ""
+	if claims, err := u.authService.ExtractClaims(tokens.AccessToken); err == nil {
+		userID, sessionID = claims.UserID, claims.SessionID
+	}
+	// Refresh tokens issued before sessions were tracked carry no session ID. They are refused, as
+	// the tokens they would mint could not be revoked either; the user logs in again.
+	if sessionID == "" {
+		u.logger.Warn(ctx, "auth refresh token has no session", map[string]interface{}{"user_id": userID})
+		u.recordRefresh(ctx, userID, models.AuditOutcomeFailure, "no_session")
+		return nil, ErrUnauthorized
+	}
+	live, err := u.sessions.Touch(ctx, userID, sessionID)
+	if err != nil {
+		u.logger.Error(ctx, "auth refresh token session lookup failed", err, map[string]interface{}{"user_id": userID})
+		return nil, err
+	}
+	if !live {
+		u.logger.Warn(ctx, "auth refresh token session ended", map[string]interface{}{"user_id": userID})
+		u.recordRefresh(ctx, userID, models.AuditOutcomeFailure, "session_ended")
+		return nil, ErrUnauthorized
+	}
+	// A refresh token issued before the password expired or a reset was required does not extend
+	// the session.
+	if userID != "" {
+		status, err := u.passwords.Status(ctx, userID)
+		if err != nil {
+			u.logger.Error(ctx, "auth refresh token password status lookup failed", err, map[string]interface{}{"user_id": userID})
+			return nil, err
+		}
+		if status.ChangeRequired() {
+			u.logger.Warn(ctx, "auth refresh token requires a password change", map[string]interface{}{"user_id": userID, "reason": status.ChangeReason})
+			u.recordRefresh(ctx, userID, models.AuditOutcomeFailure, status.ChangeReason)
+			return nil, ErrPasswordChangeRequired
+		}
+		pending, err := u.policies.Pending(userID)
+		if err != nil {
+			u.logger.Error(ctx, "auth refresh token policy consent lookup failed", err, map[string]interface{}{"user_id": userID})
+			return nil, err
+		}
+		if len(pending) > 0 {
+			u.logger.Warn(ctx, "auth refresh token requires policy consent", map[string]interface{}{"user_id": userID})
+			u.recordRefresh(ctx, userID, models.AuditOutcomeFailure, "consent_required")
+			return nil, ErrConsentRequired
+		}
+	}
+	u.recordRefresh(ctx, userID, models.AuditOutcomeSuccess, "")
+
 	mapped := mapper.MapTokenToRefreshTokenResDTO(tokens)
 	u.logger.Info(ctx, "auth refresh token succeeded", nil)
 	return mapped, nil
 }
+
+func (u *AuthUseCase) recordRefresh(ctx context.Context, userID, outcome, reason string) {
+	entry := auditservice.Entry{
+		Actor:   userID,
+		Subject: userID,
+		Action:  models.AuditActionRefreshToken,
+		Outcome: outcome,
+	}
+	if reason != "" {
+		entry.Metadata = map[string]string{"reason": reason}
+	}
+	recordAudit(ctx, u.auditRecorder, u.logger, entry)
+}
+
+func (u *AuthUseCase) recordLogin(ctx context.Context, actor, subject, outcome, reason string) {
+	entry := auditservice.Entry{
+		Actor:   actor,
+		Subject: subject,
+		Action:  models.AuditActionLogin,
+		Outcome: outcome,
+	}
+	if reason != "" {
+		entry.Metadata = map[string]string{"reason": reason}
+	}
+	recordAudit(ctx, u.auditRecorder, u.logger, entry)
+}
@@ -0,0 +1,72 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/SilentPlaces/basicauth.git/internal/config"
+	challengeservice "github.com/SilentPlaces/basicauth.git/internal/services/challenge"
+	appLogger "github.com/SilentPlaces/basicauth.git/internal/shared/logger"
+	"github.com/SilentPlaces/basicauth.git/internal/shared/observability"
+)
+
+type ChallengeUseCase struct {
+	verifier     challengeservice.ChallengeVerifier
+	routesConfig *config.Value[config.ChallengeRoutesConfig]
+	logger       appLogger.Logger
+}
+
+func NewChallengeUseCase(
+	verifier challengeservice.ChallengeVerifier,
+	routesConfig *config.Value[config.ChallengeRoutesConfig],
+	logger appLogger.Logger,
+) *ChallengeUseCase {
+	return &ChallengeUseCase{
+		verifier:     verifier,
+		routesConfig: routesConfig,
+		logger:       logger,
+	}
+}
+
+// NewChallenge returns what a client needs to answer the challenge of the configured provider.
+func (u *ChallengeUseCase) NewChallenge(ctx context.Context) (*challengeservice.Challenge, error) {
+	challenge, err := u.verifier.NewChallenge(ctx)
+	if errors.Is(err, challengeservice.ErrTooManyChallenges) {
+		u.logger.Warn(ctx, "challenge creation rate limited", map[string]interface{}{"provider": u.verifier.Provider(), "ip": observability.ClientIPFromContext(ctx)})
+		return nil, ErrTooManyAttempts
+	}
+	if err != nil {
+		u.logger.Error(ctx, "challenge creation failed", err, map[string]interface{}{"provider": u.verifier.Provider()})
+		return nil, ErrServiceUnavailable
+	}
+	return challenge, nil
+}
+
+// Verify checks the challenge response sent for route. It returns nil without looking at the
+// response while the route does not require a challenge.
+func (u *ChallengeUseCase) Verify(ctx context.Context, route string, response string) error {
+	if !u.routesConfig.Load().Enabled(route) {
+		return nil
+	}
+	fields := map[string]interface{}{"route": route, "provider": u.verifier.Provider()}
+	if response == "" {
+		u.logger.Warn(ctx, "challenge response missing", fields)
+		return ErrChallengeRequired
+	}
+
+	err := u.verifier.Verify(ctx, response, observability.ClientIPFromContext(ctx))
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, challengeservice.ErrChallengeFailed):
+		fields["error"] = err.Error()
+		u.logger.Warn(ctx, "challenge response rejected", fields)
+		return ErrChallengeFailed
+	default:
+		// Fail closed: letting requests through while the provider is down would switch the
+		// protection off exactly when an attacker can make that happen.
+		u.logger.Error(ctx, "challenge verification failed", err, fields)
+		return fmt.Errorf("%w: %w", ErrServiceUnavailable, err)
+	}
+}
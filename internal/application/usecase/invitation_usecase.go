@@ -0,0 +1,189 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/SilentPlaces/basicauth.git/internal/application/port"
+	"github.com/SilentPlaces/basicauth.git/internal/config"
+	"github.com/SilentPlaces/basicauth.git/internal/models/models"
+	invitationrepo "github.com/SilentPlaces/basicauth.git/internal/repositories/invitation"
+	auditservice "github.com/SilentPlaces/basicauth.git/internal/services/audit"
+	invitationservice "github.com/SilentPlaces/basicauth.git/internal/services/invitation"
+	mailservice "github.com/SilentPlaces/basicauth.git/internal/services/mail"
+	appLogger "github.com/SilentPlaces/basicauth.git/internal/shared/logger"
+	validation "github.com/SilentPlaces/basicauth.git/internal/validation/user"
+)
+
+const invitationLink = "https://%s/registration/signup?invite=%s&%s=%s"
+
+type InvitationUseCase struct {
+	invitationService  invitationservice.InvitationService
+	mailService        mailservice.MailService
+	mailQueue          port.MailEnqueuer
+	registrationConfig *config.Value[config.RegistrationConfig]
+	generalConfig      *config.GeneralConfig
+	emailNormalizer    *validation.EmailNormalizer
+	auditRecorder      port.AuditRecorder
+	logger             appLogger.Logger
+}
+
+// CreateInvitationInput describes an invitation requested over the API. Email, when set, binds the
+// invitation to it and receives the invitation mail.
+type CreateInvitationInput struct {
+	Email          string
+	Roles          []string
+	MaxUses        int
+	ExpiresInHours int
+	Locale         string
+	AcceptLanguage string
+}
+
+func NewInvitationUseCase(
+	invitationService invitationservice.InvitationService,
+	mailService mailservice.MailService,
+	mailQueue port.MailEnqueuer,
+	registrationConfig *config.Value[config.RegistrationConfig],
+	generalConfig *config.GeneralConfig,
+	emailNormalizer *validation.EmailNormalizer,
+	auditRecorder port.AuditRecorder,
+	logger appLogger.Logger,
+) *InvitationUseCase {
+	return &InvitationUseCase{
+		invitationService:  invitationService,
+		mailService:        mailService,
+		mailQueue:          mailQueue,
+		registrationConfig: registrationConfig,
+		generalConfig:      generalConfig,
+		emailNormalizer:    emailNormalizer,
+		auditRecorder:      auditRecorder,
+		logger:             logger,
+	}
+}
+
+// CreateInvitation creates an invitation and returns it with its code. Only admins may set roles,
+// uses and expiry; other users are limited by the quota. An invitation bound to an email is mailed
+// to it; the invitation is kept even when queueing the mail fails, as the code can be passed on.
+func (u *InvitationUseCase) CreateInvitation(ctx context.Context, inviterID string, admin bool, input CreateInvitationInput) (*models.Invitation, string, error) {
+	u.logger.Info(ctx, "invitation create requested", map[string]interface{}{"user_id": inviterID, "admin": admin})
+	request := invitationservice.CreateRequest{
+		CreatedBy: inviterID,
+		Admin:     admin,
+		Roles:     input.Roles,
+		MaxUses:   input.MaxUses,
+		ExpiresIn: time.Duration(input.ExpiresInHours) * time.Hour,
+	}
+	address := ""
+	if input.Email != "" {
+		var err error
+		address, request.Email, err = u.emailNormalizer.Normalize(input.Email)
+		if err != nil {
+			u.logger.Warn(ctx, "invitation create invalid email", map[string]interface{}{"user_id": inviterID})
+			u.recordAudit(ctx, inviterID, "", models.AuditOutcomeFailure, "invalid_email")
+			return nil, "", fmt.Errorf("%w: invalid email", ErrBadRequest)
+		}
+	}
+
+	invitation, code, err := u.invitationService.Create(request)
+	if err != nil {
+		switch {
+		case errors.Is(err, invitationservice.ErrInvitationNotAllowed):
+			u.logger.Warn(ctx, "invitation create not allowed", map[string]interface{}{"user_id": inviterID})
+			u.recordAudit(ctx, inviterID, "", models.AuditOutcomeFailure, "not_allowed")
+			return nil, "", fmt.Errorf("%w: %v", ErrUnauthorized, err)
+		case errors.Is(err, invitationservice.ErrInvitationQuotaExceeded):
+			u.logger.Warn(ctx, "invitation create quota exceeded", map[string]interface{}{"user_id": inviterID})
+			u.recordAudit(ctx, inviterID, "", models.AuditOutcomeFailure, "quota_exceeded")
+			return nil, "", fmt.Errorf("%w: %v", ErrTooManyAttempts, err)
+		case errors.Is(err, invitationservice.ErrInvalidInvitation):
+			u.logger.Warn(ctx, "invitation create rejected", map[string]interface{}{"user_id": inviterID, "reason": err.Error()})
+			u.recordAudit(ctx, inviterID, "", models.AuditOutcomeFailure, "invalid_invitation")
+			return nil, "", fmt.Errorf("%w: %v", ErrBadRequest, err)
+		default:
+			u.logger.Error(ctx, "invitation create failed", err, map[string]interface{}{"user_id": inviterID})
+			u.recordAudit(ctx, inviterID, "", models.AuditOutcomeFailure, "create_failed")
+			return nil, "", err
+		}
+	}
+
+	reason := ""
+	if address != "" {
+		if err := u.queueInvitationEmail(address, code, u.mailService.MatchLocale(input.Locale, input.AcceptLanguage), invitation); err != nil {
+			u.logger.Error(ctx, "invitation email enqueue failed", err, map[string]interface{}{"invitation_id": invitation.ID})
+			reason = "mail_enqueue_failed"
+		}
+	}
+	u.logger.Info(ctx, "invitation created", map[string]interface{}{"user_id": inviterID, "invitation_id": invitation.ID})
+	u.recordAudit(ctx, inviterID, invitation.ID, models.AuditOutcomeSuccess, reason)
+	return invitation, code, nil
+}
+
+// ListInvitations returns the caller's invitations, or everyone's for admins.
+func (u *InvitationUseCase) ListInvitations(ctx context.Context, userID string, admin bool, limit int, offset int) ([]models.Invitation, error) {
+	u.logger.Info(ctx, "invitation list requested", map[string]interface{}{"user_id": userID, "admin": admin})
+	createdBy := userID
+	if admin {
+		createdBy = ""
+	}
+	return u.invitationService.List(createdBy, limit, offset)
+}
+
+// RevokeInvitation revokes one of the caller's invitations, or any invitation for admins.
+func (u *InvitationUseCase) RevokeInvitation(ctx context.Context, userID string, admin bool, id string) error {
+	u.logger.Info(ctx, "invitation revoke requested", map[string]interface{}{"user_id": userID, "invitation_id": id})
+	createdBy := userID
+	if admin {
+		createdBy = ""
+	}
+	if err := u.invitationService.Revoke(id, createdBy); err != nil {
+		if errors.Is(err, invitationrepo.ErrInvitationNotFound) {
+			return ErrNotFound
+		}
+		u.logger.Error(ctx, "invitation revoke failed", err, map[string]interface{}{"invitation_id": id})
+		return err
+	}
+
+	recordAudit(ctx, u.auditRecorder, u.logger, auditservice.Entry{
+		Actor:   userID,
+		Subject: id,
+		Action:  models.AuditActionInvitationRevoke,
+		Outcome: models.AuditOutcomeSuccess,
+	})
+	return nil
+}
+
+// queueInvitationEmail hands the invitation mail to the queue. The invitation ID is the idempotency
+// key, so a retried request does not send a second mail.
+func (u *InvitationUseCase) queueInvitationEmail(email, code, locale string, invitation *models.Invitation) error {
+	_, err := u.mailQueue.Enqueue(models.MailJob{
+		IdempotencyKey: mailservice.TemplateInvitation + ":" + invitation.ID,
+		From:           u.registrationConfig.Load().HostVerificationMailAddress,
+		To:             email,
+		Locale:         locale,
+		Template:       mailservice.TemplateInvitation,
+		Data: map[string]interface{}{
+			"Domain":         u.generalConfig.Domain,
+			"InvitationURL":  fmt.Sprintf(invitationLink, u.generalConfig.Domain, code, queryParamMailKey, url.QueryEscape(email)),
+			"InvitationCode": code,
+			"ExpiresAt":      invitation.ExpiresAt.UTC().Format("2006-01-02 15:04 UTC"),
+		},
+		Sensitive: true,
+	})
+	return err
+}
+
+func (u *InvitationUseCase) recordAudit(ctx context.Context, inviterID, invitationID, outcome, reason string) {
+	entry := auditservice.Entry{
+		Actor:   inviterID,
+		Subject: invitationID,
+		Action:  models.AuditActionInvitationCreate,
+		Outcome: outcome,
+	}
+	if reason != "" {
+		entry.Metadata = map[string]string{"reason": reason}
+	}
+	recordAudit(ctx, u.auditRecorder, u.logger, entry)
+}
@@ -0,0 +1,50 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/SilentPlaces/basicauth.git/internal/application/port"
+	"github.com/SilentPlaces/basicauth.git/internal/models/models"
+	mailqueuerepo "github.com/SilentPlaces/basicauth.git/internal/repositories/mailqueue"
+	appLogger "github.com/SilentPlaces/basicauth.git/internal/shared/logger"
+)
+
+type MailQueueUseCase struct {
+	mailQueueManager port.MailQueueManager
+	logger           appLogger.Logger
+}
+
+func NewMailQueueUseCase(mailQueueManager port.MailQueueManager, logger appLogger.Logger) *MailQueueUseCase {
+	return &MailQueueUseCase{mailQueueManager: mailQueueManager, logger: logger}
+}
+
+func (u *MailQueueUseCase) ListDeadLetters(ctx context.Context, limit int) ([]models.MailJob, error) {
+	u.logger.Info(ctx, "mail dead letters requested", nil)
+	jobs, err := u.mailQueueManager.ListDeadLetters(limit)
+	if err != nil {
+		u.logger.Error(ctx, "mail dead letter list failed", err, nil)
+		return nil, err
+	}
+	return jobs, nil
+}
+
+func (u *MailQueueUseCase) Replay(ctx context.Context, id string) (*models.MailJob, error) {
+	u.logger.Info(ctx, "mail dead letter replay requested", map[string]interface{}{"job_id": id})
+	job, err := u.mailQueueManager.Replay(id)
+	if err != nil {
+		if errors.Is(err, mailqueuerepo.ErrMailJobNotFound) {
+			return nil, ErrNotFound
+		}
+		if errors.Is(err, mailqueuerepo.ErrMailJobNotReplayable) {
+			u.logger.Warn(ctx, "mail dead letter not replayable", map[string]interface{}{"job_id": id})
+			return nil, fmt.Errorf("%w: %v", ErrBadRequest, err)
+		}
+		u.logger.Error(ctx, "mail dead letter replay failed", err, map[string]interface{}{"job_id": id})
+		return nil, err
+	}
+
+	u.logger.Info(ctx, "mail dead letter replayed", map[string]interface{}{"job_id": id, "new_job_id": job.ID})
+	return job, nil
+}
@@ -0,0 +1,141 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/SilentPlaces/basicauth.git/internal/application/port"
+	"github.com/SilentPlaces/basicauth.git/internal/models/models"
+	auditservice "github.com/SilentPlaces/basicauth.git/internal/services/audit"
+	policyservice "github.com/SilentPlaces/basicauth.git/internal/services/policy"
+	appLogger "github.com/SilentPlaces/basicauth.git/internal/shared/logger"
+)
+
+type PolicyUseCase struct {
+	policyService policyservice.PolicyService
+	auditRecorder port.AuditRecorder
+	logger        appLogger.Logger
+}
+
+// PublishPolicyInput describes a policy document version published over the API. A nil PublishAt
+// publishes it right away.
+type PublishPolicyInput struct {
+	Kind      string
+	Version   string
+	URL       string
+	Mandatory bool
+	PublishAt *time.Time
+}
+
+func NewPolicyUseCase(policyService policyservice.PolicyService, auditRecorder port.AuditRecorder, logger appLogger.Logger) *PolicyUseCase {
+	return &PolicyUseCase{
+		policyService: policyService,
+		auditRecorder: auditRecorder,
+		logger:        logger,
+	}
+}
+
+// PublishPolicy stores a new policy document version. actorID is the admin publishing it.
+func (u *PolicyUseCase) PublishPolicy(ctx context.Context, actorID string, input PublishPolicyInput) (*models.PolicyDocument, error) {
+	u.logger.Info(ctx, "policy publish requested", map[string]interface{}{"user_id": actorID, "kind": input.Kind, "version": input.Version})
+	request := policyservice.PublishRequest{
+		Kind:      input.Kind,
+		Version:   input.Version,
+		URL:       input.URL,
+		Mandatory: input.Mandatory,
+		CreatedBy: actorID,
+	}
+	if input.PublishAt != nil {
+		request.PublishAt = *input.PublishAt
+	}
+
+	document, err := u.policyService.Publish(request)
+	switch {
+	case errors.Is(err, policyservice.ErrInvalidDocument), errors.Is(err, policyservice.ErrDocumentExists):
+		u.logger.Warn(ctx, "policy publish rejected", map[string]interface{}{"user_id": actorID, "reason": err.Error()})
+		u.recordPublish(ctx, actorID, "", models.AuditOutcomeFailure, "invalid_document")
+		return nil, fmt.Errorf("%w: %v", ErrBadRequest, err)
+	case err != nil:
+		u.logger.Error(ctx, "policy publish failed", err, map[string]interface{}{"user_id": actorID})
+		u.recordPublish(ctx, actorID, "", models.AuditOutcomeFailure, "publish_failed")
+		return nil, err
+	}
+
+	u.logger.Info(ctx, "policy published", map[string]interface{}{"user_id": actorID, "document_id": document.ID, "mandatory": document.Mandatory})
+	u.recordPublish(ctx, actorID, document.ID, models.AuditOutcomeSuccess, "")
+	return document, nil
+}
+
+// ListPolicies returns all policy document versions, scheduled ones included.
+func (u *PolicyUseCase) ListPolicies(ctx context.Context, limit int, offset int) ([]models.PolicyDocument, error) {
+	u.logger.Info(ctx, "policy list requested", nil)
+	return u.policyService.List(limit, offset)
+}
+
+// CurrentPolicies returns the document in force per kind, which signup must accept.
+func (u *PolicyUseCase) CurrentPolicies(ctx context.Context) ([]models.PolicyDocument, error) {
+	return u.policyService.Current()
+}
+
+// AcceptPolicies records the user's consent to the versions in accepted, keyed by kind. A missing
+// pending kind or an outdated version returns ErrBadRequest wrapping
+// policyservice.ErrPoliciesNotAccepted.
+func (u *PolicyUseCase) AcceptPolicies(ctx context.Context, userID string, accepted map[string]string) ([]models.UserConsent, error) {
+	u.logger.Info(ctx, "policy consent requested", map[string]interface{}{"user_id": userID})
+	if len(accepted) == 0 {
+		return nil, fmt.Errorf("%w: %w: no policies given", ErrBadRequest, policyservice.ErrPoliciesNotAccepted)
+	}
+
+	consents, err := u.policyService.Consent(ctx, userID, accepted)
+	switch {
+	case errors.Is(err, policyservice.ErrPoliciesNotAccepted):
+		u.logger.Warn(ctx, "policy consent rejected", map[string]interface{}{"user_id": userID, "reason": err.Error()})
+		u.recordConsent(ctx, userID, models.AuditOutcomeFailure, map[string]string{"reason": "policies_not_accepted"})
+		return nil, fmt.Errorf("%w: %w", ErrBadRequest, err)
+	case err != nil:
+		u.logger.Error(ctx, "policy consent failed", err, map[string]interface{}{"user_id": userID})
+		u.recordConsent(ctx, userID, models.AuditOutcomeFailure, map[string]string{"reason": "consent_failed"})
+		return nil, err
+	}
+
+	metadata := make(map[string]string, len(consents))
+	for _, consent := range consents {
+		metadata[consent.Kind] = consent.Version
+	}
+	u.logger.Info(ctx, "policy consent recorded", map[string]interface{}{"user_id": userID})
+	u.recordConsent(ctx, userID, models.AuditOutcomeSuccess, metadata)
+	return consents, nil
+}
+
+// ListConsents returns the user's consents, latest first.
+func (u *PolicyUseCase) ListConsents(ctx context.Context, userID string) ([]models.UserConsent, error) {
+	u.logger.Info(ctx, "policy consent list requested", map[string]interface{}{"user_id": userID})
+	return u.policyService.Consents(userID)
+}
+
+func (u *PolicyUseCase) recordPublish(ctx context.Context, actorID, documentID, outcome, reason string) {
+	entry := auditservice.Entry{
+		Actor:   actorID,
+		Subject: documentID,
+		Action:  models.AuditActionPolicyPublish,
+		Outcome: outcome,
+	}
+	if reason != "" {
+		entry.Metadata = map[string]string{"reason": reason}
+	}
+	recordAudit(ctx, u.auditRecorder, u.logger, entry)
+}
+
+// recordConsent audits a consent; metadata holds the accepted version per kind, or the reason of
+// a failure.
+func (u *PolicyUseCase) recordConsent(ctx context.Context, userID, outcome string, metadata map[string]string) {
+	recordAudit(ctx, u.auditRecorder, u.logger, auditservice.Entry{
+		Actor:    userID,
+		Subject:  userID,
+		Action:   models.AuditActionConsent,
+		Outcome:  outcome,
+		Metadata: metadata,
+	})
+}
@@ -2,14 +2,23 @@ package usecase
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"net/url"
 
+	"github.com/SilentPlaces/basicauth.git/internal/application/port"
 	"github.com/SilentPlaces/basicauth.git/internal/config"
 	customerror "github.com/SilentPlaces/basicauth.git/internal/errors"
+	"github.com/SilentPlaces/basicauth.git/internal/models/models"
+	auditservice "github.com/SilentPlaces/basicauth.git/internal/services/audit"
+	invitationservice "github.com/SilentPlaces/basicauth.git/internal/services/invitation"
 	mailservice "github.com/SilentPlaces/basicauth.git/internal/services/mail"
+	passwordservice "github.com/SilentPlaces/basicauth.git/internal/services/password"
+	policyservice "github.com/SilentPlaces/basicauth.git/internal/services/policy"
 	registrationservice "github.com/SilentPlaces/basicauth.git/internal/services/registration"
+	signuppolicyservice "github.com/SilentPlaces/basicauth.git/internal/services/signuppolicy"
 	appLogger "github.com/SilentPlaces/basicauth.git/internal/shared/logger"
 	validation "github.com/SilentPlaces/basicauth.git/internal/validation/user"
 )
@@ -22,124 +31,321 @@ const (
 
 type RegistrationUseCase struct {
 	mailService         mailservice.MailService
+	mailQueue           port.MailEnqueuer
 	registrationService registrationservice.RegistrationService
-	registrationConfig  *config.RegistrationConfig
-	passwordConfig      *config.RegistrationPasswordConfig
+	registrationConfig  *config.Value[config.RegistrationConfig]
+	passwordService     passwordservice.PasswordService
 	generalConfig       *config.GeneralConfig
+	emailNormalizer     *validation.EmailNormalizer
+	signupPolicy        signuppolicyservice.SignupPolicyService
+	invitationService   invitationservice.InvitationService
+	policyService       policyservice.PolicyService
+	auditRecorder       port.AuditRecorder
+	webhooks            port.WebhookPublisher
 	logger              appLogger.Logger
 }
 
 func NewRegistrationUseCase(
 	mailService mailservice.MailService,
+	mailQueue port.MailEnqueuer,
 	registrationService registrationservice.RegistrationService,
-	registrationConfig *config.RegistrationConfig,
-	passwordConfig *config.RegistrationPasswordConfig,
+	registrationConfig *config.Value[config.RegistrationConfig],
+	passwordService passwordservice.PasswordService,
 	generalConfig *config.GeneralConfig,
+	emailNormalizer *validation.EmailNormalizer,
+	signupPolicy signuppolicyservice.SignupPolicyService,
+	invitationService invitationservice.InvitationService,
+	policyService policyservice.PolicyService,
+	auditRecorder port.AuditRecorder,
+	webhooks port.WebhookPublisher,
 	logger appLogger.Logger,
 ) *RegistrationUseCase {
 	return &RegistrationUseCase{
 		mailService:         mailService,
+		mailQueue:           mailQueue,
 		registrationService: registrationService,
 		registrationConfig:  registrationConfig,
-		passwordConfig:      passwordConfig,
+		passwordService:     passwordService,
 		generalConfig:       generalConfig,
+		emailNormalizer:     emailNormalizer,
+		signupPolicy:        signupPolicy,
+		invitationService:   invitationService,
+		policyService:       policyService,
+		auditRecorder:       auditRecorder,
+		webhooks:            webhooks,
 		logger:              logger,
 	}
 }
 
-func (u *RegistrationUseCase) SignUp(ctx context.Context, email, name, password string) error {
+// SignUp registers a user and queues the verification mail. The mail locale comes from the explicit
+// locale, or else from the Accept-Language header; it is stored as the user's preference.
+// Once the user row exists signup succeeds even if queueing fails, as the user can ask for a resend.
+// A signup the registration mode, the invitation or the signup policy rejects returns ErrBadRequest
+// wrapping a *customerror.SignupPolicyError; a rejected password wraps a
+// *customerror.PasswordPolicyError instead. acceptedPolicies holds the accepted version per policy
+// kind; every policy in force must be accepted in its current version.
+func (u *RegistrationUseCase) SignUp(ctx context.Context, email, name, password, locale, acceptLanguage, inviteCode string, acceptedPolicies map[string]string) error {
 	u.logger.Info(ctx, "registration signup requested", map[string]interface{}{"email": email})
-	if err := validation.ValidateEmail(email); err != nil {
-		u.logger.Warn(ctx, "registration signup invalid email", map[string]interface{}{"email": email})
-		return fmt.Errorf("%w: invalid email", ErrBadRequest)
+	email, canonicalEmail, err := u.normalizeEmail(ctx, models.AuditActionSignup, email)
+	if err != nil {
+		return err
+	}
+	invitation, err := u.admitSignup(ctx, email, canonicalEmail, inviteCode)
+	if err != nil {
+		return err
+	}
+	// An invitation bound to the email vouches for it, so the domain rules do not apply.
+	if invitation == nil || invitation.Email == "" {
+		if err := u.signupPolicy.Check(ctx, canonicalEmail); err != nil {
+			return u.rejectSignup(ctx, email, err)
+		}
+	}
+	if err := u.passwordService.CheckPolicy(ctx, password, name, email); err != nil {
+		return u.rejectSignup(ctx, email, err)
 	}
-	if err := validation.ValidatePassword(password, u.passwordConfig); err != nil {
-		u.logger.Warn(ctx, "registration signup invalid password", map[string]interface{}{"email": email})
-		return fmt.Errorf("%w: invalid password", ErrBadRequest)
+	consents, err := u.policyService.Accept(ctx, acceptedPolicies)
+	if errors.Is(err, policyservice.ErrPoliciesNotAccepted) {
+		return u.rejectSignup(ctx, email, customerror.NewSignupPolicyError(customerror.SignupPolicyPoliciesNotAccepted, err.Error()))
+	}
+	if err != nil {
+		return u.rejectSignup(ctx, email, err)
 	}
 
-	token, err := u.registrationService.Signup(email, name, password)
+	mailLocale := u.mailService.MatchLocale(locale, acceptLanguage)
+	verification, err := u.registrationService.Signup(email, canonicalEmail, name, password, mailLocale, invitation, consents)
+	if errors.Is(err, registrationservice.ErrInvitationUsedUp) {
+		return u.rejectSignup(ctx, email, customerror.NewSignupPolicyError(customerror.SignupPolicyInvitationInvalid, invitationservice.ErrInvitationInvalid.Error()))
+	}
+	if errors.Is(err, registrationservice.ErrEmailInUse) {
+		u.logger.Warn(ctx, "registration signup email in use", map[string]interface{}{"email": email})
+		u.recordAudit(ctx, models.AuditActionSignup, email, models.AuditOutcomeFailure, "email_in_use")
+		return fmt.Errorf("%w: email already in use", ErrBadRequest)
+	}
 	if err != nil {
 		u.logger.Error(ctx, "registration signup service failure", err, map[string]interface{}{"email": email})
+		u.recordAudit(ctx, models.AuditActionSignup, email, models.AuditOutcomeFailure, "signup_failed")
 		return err
 	}
 
-	verificationURL := fmt.Sprintf(
-		verificationLink,
-		u.generalConfig.Domain,
-		queryParamTokenKey,
-		url.QueryEscape(token),
-		queryParamMailKey,
-		url.QueryEscape(email),
-	)
-	emailBody := fmt.Sprintf(u.registrationConfig.VerificationMailText, verificationURL)
-	emailSubject := fmt.Sprintf("Registration Verification Email at %s", u.generalConfig.Domain)
-
-	if err := u.mailService.SendVerificationEmail(
-		u.registrationConfig.HostVerificationMailAddress,
-		email,
-		emailSubject,
-		emailBody,
-	); err != nil {
-		u.logger.Error(ctx, "registration signup email send failed", err, map[string]interface{}{"email": email})
-		return err
+	if invitation != nil {
+		u.logger.Info(ctx, "registration signup redeemed invitation", map[string]interface{}{"email": email, "invitation_id": invitation.ID})
 	}
 
-	u.logger.Info(ctx, "registration signup email sent", map[string]interface{}{"email": email})
+	if err := u.queueVerificationEmail(email, name, mailLocale, verification); err != nil {
+		u.logger.Error(ctx, "registration signup email enqueue failed", err, map[string]interface{}{"email": email})
+		u.recordAudit(ctx, models.AuditActionSignup, email, models.AuditOutcomeSuccess, "mail_enqueue_failed")
+	} else {
+		u.logger.Info(ctx, "registration signup email queued", map[string]interface{}{"email": email})
+		u.recordAudit(ctx, models.AuditActionSignup, email, models.AuditOutcomeSuccess, "")
+	}
+	publishWebhook(ctx, u.webhooks, u.logger, models.WebhookEventUserSignedUp, map[string]interface{}{"email": email, "name": name})
 	return nil
 }
 
+// admitSignup applies the registration mode and returns the invitation the signup redeems, or nil.
+// A code is checked whenever one is given, also in open mode, so its roles are not silently lost.
+func (u *RegistrationUseCase) admitSignup(ctx context.Context, email, canonicalEmail, inviteCode string) (*models.Invitation, error) {
+	mode := u.registrationConfig.Load().Mode
+	switch {
+	case mode == config.RegistrationModeClosed:
+		return nil, u.rejectSignup(ctx, email, customerror.NewSignupPolicyError(customerror.SignupPolicyRegistrationClosed, "registration is closed"))
+	case inviteCode == "" && mode == config.RegistrationModeInviteOnly:
+		return nil, u.rejectSignup(ctx, email, customerror.NewSignupPolicyError(customerror.SignupPolicyInvitationRequired, "an invitation is required to sign up"))
+	case inviteCode == "":
+		return nil, nil
+	}
+
+	invitation, err := u.invitationService.Resolve(inviteCode, canonicalEmail)
+	switch {
+	case errors.Is(err, invitationservice.ErrInvitationInvalid):
+		return nil, u.rejectSignup(ctx, email, customerror.NewSignupPolicyError(customerror.SignupPolicyInvitationInvalid, err.Error()))
+	case errors.Is(err, invitationservice.ErrInvitationEmailMismatch):
+		return nil, u.rejectSignup(ctx, email, customerror.NewSignupPolicyError(customerror.SignupPolicyInvitationEmailMismatch, err.Error()))
+	case err != nil:
+		u.logger.Error(ctx, "registration invitation lookup failed", err, map[string]interface{}{"email": email})
+		u.recordAudit(ctx, models.AuditActionSignup, email, models.AuditOutcomeFailure, "invitation_lookup_failed")
+		return nil, err
+	}
+	return invitation, nil
+}
+
+// rejectSignup audits a signup rejected with a *customerror.SignupPolicyError or
+// *customerror.PasswordPolicyError, whose code is the audit reason, and returns it wrapped in
+// ErrBadRequest. Other errors are returned as they are.
+func (u *RegistrationUseCase) rejectSignup(ctx context.Context, email string, err error) error {
+	var policyErr *customerror.SignupPolicyError
+	var passwordErr *customerror.PasswordPolicyError
+	var code string
+	switch {
+	case errors.As(err, &policyErr):
+		code = policyErr.Code
+	case errors.As(err, &passwordErr):
+		code = passwordErr.Code
+	default:
+		u.logger.Error(ctx, "registration signup policy check failed", err, map[string]interface{}{"email": email})
+		u.recordAudit(ctx, models.AuditActionSignup, email, models.AuditOutcomeFailure, "policy_check_failed")
+		return err
+	}
+	u.logger.Warn(ctx, "registration signup rejected by policy", map[string]interface{}{"email": email, "code": code})
+	u.recordAudit(ctx, models.AuditActionSignup, email, models.AuditOutcomeFailure, code)
+	return fmt.Errorf("%w: %w", ErrBadRequest, err)
+}
+
 func (u *RegistrationUseCase) VerifyEmail(ctx context.Context, email, token string) error {
 	decodedMail, _ := url.QueryUnescape(email)
-	decodedToken, _ := url.QueryUnescape(token)
 	u.logger.Info(ctx, "registration email verification requested", map[string]interface{}{"email": decodedMail})
+	decodedMail, canonicalEmail, err := u.normalizeEmail(ctx, models.AuditActionVerifyEmail, decodedMail)
+	if err != nil {
+		return err
+	}
 
-	if err := u.registrationService.VerifyToken(decodedMail, decodedToken); err != nil {
-		u.logger.Warn(ctx, "registration email token verification failed", map[string]interface{}{"email": decodedMail})
+	// Tokens are URL-safe, so they are used as given.
+	if err := u.registrationService.VerifyToken(canonicalEmail, token); err != nil {
+		reason := "invalid_token"
+		if errors.Is(err, registrationservice.ErrTokenBurned) {
+			reason = "token_burned"
+		}
+		u.logger.Warn(ctx, "registration email token verification failed", map[string]interface{}{"email": decodedMail, "reason": reason})
+		u.recordAudit(ctx, models.AuditActionVerifyEmail, decodedMail, models.AuditOutcomeFailure, reason)
 		return err
 	}
-	if err := u.registrationService.SetUserVerified(decodedMail); err != nil {
+	if err := u.registrationService.SetUserVerified(canonicalEmail); err != nil {
 		u.logger.Error(ctx, "registration set verified failed", err, map[string]interface{}{"email": decodedMail})
+		u.recordAudit(ctx, models.AuditActionVerifyEmail, decodedMail, models.AuditOutcomeFailure, "set_verified_failed")
 		return err
 	}
 
 	u.logger.Info(ctx, "registration email verified", map[string]interface{}{"email": decodedMail})
+	u.recordAudit(ctx, models.AuditActionVerifyEmail, decodedMail, models.AuditOutcomeSuccess, "")
+	publishWebhook(ctx, u.webhooks, u.logger, models.WebhookEventUserVerified, map[string]interface{}{"email": decodedMail})
 	return nil
 }
 
-func (u *RegistrationUseCase) ResendVerification(ctx context.Context, email string) error {
+// VerifyCode verifies the email with the numeric code from the verification mail. A wrong or
+// expired code is a bad request; the guess that uses up the attempts returns ErrTooManyAttempts.
+func (u *RegistrationUseCase) VerifyCode(ctx context.Context, email, code string) error {
+	u.logger.Info(ctx, "registration code verification requested", map[string]interface{}{"email": email})
+	email, canonicalEmail, err := u.normalizeEmail(ctx, models.AuditActionVerifyEmail, email)
+	if err != nil {
+		return err
+	}
+
+	if err := u.registrationService.VerifyCode(canonicalEmail, code); err != nil {
+		switch {
+		case errors.Is(err, registrationservice.ErrTokenBurned):
+			u.logger.Warn(ctx, "registration code verification attempts exhausted", map[string]interface{}{"email": email})
+			u.recordAudit(ctx, models.AuditActionVerifyEmail, email, models.AuditOutcomeFailure, "code_burned")
+			return fmt.Errorf("%w: %v", ErrTooManyAttempts, err)
+		case errors.Is(err, registrationservice.ErrTokenNotFound), errors.Is(err, registrationservice.ErrTokenMismatch):
+			u.logger.Warn(ctx, "registration code verification failed", map[string]interface{}{"email": email})
+			u.recordAudit(ctx, models.AuditActionVerifyEmail, email, models.AuditOutcomeFailure, "invalid_code")
+			return fmt.Errorf("%w: invalid verification code", ErrBadRequest)
+		default:
+			u.logger.Error(ctx, "registration code verification error", err, map[string]interface{}{"email": email})
+			u.recordAudit(ctx, models.AuditActionVerifyEmail, email, models.AuditOutcomeFailure, "verification_error")
+			return err
+		}
+	}
+	if err := u.registrationService.SetUserVerified(canonicalEmail); err != nil {
+		u.logger.Error(ctx, "registration set verified failed", err, map[string]interface{}{"email": email})
+		u.recordAudit(ctx, models.AuditActionVerifyEmail, email, models.AuditOutcomeFailure, "set_verified_failed")
+		return err
+	}
+
+	u.logger.Info(ctx, "registration email verified by code", map[string]interface{}{"email": email})
+	u.recordAudit(ctx, models.AuditActionVerifyEmail, email, models.AuditOutcomeSuccess, "")
+	publishWebhook(ctx, u.webhooks, u.logger, models.WebhookEventUserVerified, map[string]interface{}{"email": email})
+	return nil
+}
+
+// ResendVerification queues a new verification mail in the user's stored locale, falling back to Accept-Language.
+func (u *RegistrationUseCase) ResendVerification(ctx context.Context, email, acceptLanguage string) error {
 	u.logger.Info(ctx, "registration resend verification requested", map[string]interface{}{"email": email})
-	token, err := u.registrationService.ReloadToken(email)
+	email, canonicalEmail, err := u.normalizeEmail(ctx, models.AuditActionResendVerification, email)
+	if err != nil {
+		return err
+	}
+	verification, err := u.registrationService.ReloadToken(canonicalEmail)
 	if err != nil {
 		if errors.Is(err, &customerror.TokenGenerationCountError{}) {
 			u.logger.Warn(ctx, "registration resend verification limited", map[string]interface{}{"email": email})
+			u.recordAudit(ctx, models.AuditActionResendVerification, email, models.AuditOutcomeFailure, "rate_limited")
 			return err
 		}
 		u.logger.Error(ctx, "registration resend verification failed", err, map[string]interface{}{"email": email})
+		u.recordAudit(ctx, models.AuditActionResendVerification, email, models.AuditOutcomeFailure, "token_reload_failed")
 		return err
 	}
 
-	verificationURL := fmt.Sprintf(
-		verificationLink,
-		u.generalConfig.Domain,
-		queryParamTokenKey,
-		url.QueryEscape(token),
-		queryParamMailKey,
-		url.QueryEscape(email),
-	)
-	emailBody := fmt.Sprintf(u.registrationConfig.VerificationMailText, verificationURL)
-	emailSubject := fmt.Sprintf("Registration Verification Email at %s", u.generalConfig.Domain)
-
-	if err := u.mailService.SendVerificationEmail(
-		u.registrationConfig.HostVerificationMailAddress,
-		email,
-		emailSubject,
-		emailBody,
-	); err != nil {
-		u.logger.Error(ctx, "registration resend email send failed", err, map[string]interface{}{"email": email})
+	mailLocale := u.mailService.MatchLocale(u.registrationService.GetPreferredLocale(canonicalEmail), acceptLanguage)
+	if err := u.queueVerificationEmail(email, "", mailLocale, verification); err != nil {
+		u.logger.Error(ctx, "registration resend email enqueue failed", err, map[string]interface{}{"email": email})
+		u.recordAudit(ctx, models.AuditActionResendVerification, email, models.AuditOutcomeFailure, "mail_enqueue_failed")
 		return err
 	}
 
-	u.logger.Info(ctx, "registration resend email sent", map[string]interface{}{"email": email})
+	u.logger.Info(ctx, "registration resend email queued", map[string]interface{}{"email": email})
+	u.recordAudit(ctx, models.AuditActionResendVerification, email, models.AuditOutcomeSuccess, "")
 	return nil
 }
+
+// queueVerificationEmail hands the mail to the queue. The template gets VerificationURL and
+// VerificationCode for whichever the verification carries. The idempotency key is derived from the
+// token and code, so a retried request for the same verification does not send a second mail.
+func (u *RegistrationUseCase) queueVerificationEmail(email, name, locale string, verification *registrationservice.Verification) error {
+	data := map[string]interface{}{
+		"Name":   name,
+		"Domain": u.generalConfig.Domain,
+	}
+	if verification.Token != "" {
+		data["VerificationURL"] = fmt.Sprintf(
+			verificationLink,
+			u.generalConfig.Domain,
+			queryParamTokenKey,
+			verification.Token,
+			queryParamMailKey,
+			url.QueryEscape(email),
+		)
+	}
+	if verification.Code != "" {
+		data["VerificationCode"] = verification.Code
+	}
+
+	digest := sha256.Sum256([]byte(verification.Token + ":" + verification.Code))
+	_, err := u.mailQueue.Enqueue(models.MailJob{
+		IdempotencyKey: mailservice.TemplateVerification + ":" + hex.EncodeToString(digest[:]),
+		From:           u.registrationConfig.Load().HostVerificationMailAddress,
+		To:             email,
+		Locale:         locale,
+		Template:       mailservice.TemplateVerification,
+		Data:           data,
+		Sensitive:      true,
+	})
+	return err
+}
+
+// normalizeEmail returns the normalized address, used for mail, logs and audit, and the canonical
+// email the account is keyed by. An invalid email is audited and returned as ErrBadRequest.
+func (u *RegistrationUseCase) normalizeEmail(ctx context.Context, action, email string) (string, string, error) {
+	address, canonicalEmail, err := u.emailNormalizer.Normalize(email)
+	if err != nil {
+		u.logger.Warn(ctx, "registration invalid email", map[string]interface{}{"email": email, "action": action})
+		u.recordAudit(ctx, action, email, models.AuditOutcomeFailure, "invalid_email")
+		return "", "", fmt.Errorf("%w: invalid email", ErrBadRequest)
+	}
+	return address, canonicalEmail, nil
+}
+
+// recordAudit audits a registration step. The email is both actor and subject as the user is not logged in yet.
+func (u *RegistrationUseCase) recordAudit(ctx context.Context, action, email, outcome, reason string) {
+	entry := auditservice.Entry{
+		Actor:   email,
+		Subject: email,
+		Action:  action,
+		Outcome: outcome,
+	}
+	if reason != "" {
+		entry.Metadata = map[string]string{"reason": reason}
+	}
+	recordAudit(ctx, u.auditRecorder, u.logger, entry)
+}
@@ -2,22 +2,181 @@ package usecase
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"time"
 
 	"github.com/SilentPlaces/basicauth.git/internal/application/port"
 	"github.com/SilentPlaces/basicauth.git/internal/dto/user"
+	customerror "github.com/SilentPlaces/basicauth.git/internal/errors"
+	mapper "github.com/SilentPlaces/basicauth.git/internal/mappers/users"
+	"github.com/SilentPlaces/basicauth.git/internal/models/models"
+	accountservice "github.com/SilentPlaces/basicauth.git/internal/services/account"
+	auditservice "github.com/SilentPlaces/basicauth.git/internal/services/audit"
+	passwordservice "github.com/SilentPlaces/basicauth.git/internal/services/password"
 	appLogger "github.com/SilentPlaces/basicauth.git/internal/shared/logger"
 )
 
 type UserUseCase struct {
-	userService port.UserReader
-	logger      appLogger.Logger
+	userService     port.UserReader
+	passwordService passwordservice.PasswordService
+	accountService  accountservice.AccountService
+	auditRecorder   port.AuditRecorder
+	logger          appLogger.Logger
 }
 
-func NewUserUseCase(userService port.UserReader, logger appLogger.Logger) *UserUseCase {
-	return &UserUseCase{userService: userService, logger: logger}
+func NewUserUseCase(
+	userService port.UserReader,
+	passwordService passwordservice.PasswordService,
+	accountService accountservice.AccountService,
+	auditRecorder port.AuditRecorder,
+	logger appLogger.Logger,
+) *UserUseCase {
+	return &UserUseCase{
+		userService:     userService,
+		passwordService: passwordService,
+		accountService:  accountService,
+		auditRecorder:   auditRecorder,
+		logger:          logger,
+	}
 }
 
 func (u *UserUseCase) GetUserByID(ctx context.Context, userID string) (*dto.UserResponseDTO, error) {
 	u.logger.Info(ctx, "user fetch requested", map[string]interface{}{"user_id": userID})
 	return u.userService.GetUser(userID)
 }
+
+// ChangePassword replaces the user's password. A wrong current password returns
+// ErrWrongCredential; a new password the policy rejects returns ErrBadRequest wrapping a
+// *customerror.PasswordPolicyError.
+func (u *UserUseCase) ChangePassword(ctx context.Context, userID, currentPassword, newPassword string) error {
+	u.logger.Info(ctx, "user password change requested", map[string]interface{}{"user_id": userID})
+	err := u.passwordService.ChangePassword(ctx, userID, currentPassword, newPassword)
+
+	var passwordErr *customerror.PasswordPolicyError
+	switch {
+	case err == nil:
+		u.logger.Info(ctx, "user password changed", map[string]interface{}{"user_id": userID})
+		u.recordPasswordChange(ctx, userID, models.AuditOutcomeSuccess, "")
+		return nil
+	case errors.Is(err, passwordservice.ErrWrongPassword):
+		u.logger.Warn(ctx, "user password change with wrong current password", map[string]interface{}{"user_id": userID})
+		u.recordPasswordChange(ctx, userID, models.AuditOutcomeFailure, "wrong_credentials")
+		return ErrWrongCredential
+	case errors.As(err, &passwordErr):
+		u.logger.Warn(ctx, "user password change rejected by policy", map[string]interface{}{"user_id": userID, "code": passwordErr.Code})
+		u.recordPasswordChange(ctx, userID, models.AuditOutcomeFailure, passwordErr.Code)
+		return fmt.Errorf("%w: %w", ErrBadRequest, err)
+	case errors.Is(err, passwordservice.ErrUserNotFound):
+		return ErrNotFound
+	default:
+		u.logger.Error(ctx, "user password change failed", err, map[string]interface{}{"user_id": userID})
+		u.recordPasswordChange(ctx, userID, models.AuditOutcomeFailure, "change_failed")
+		return err
+	}
+}
+
+// RequirePasswordChange makes login issue only a password change token to the user until a new
+// password is set. actorID is the admin asking for it.
+func (u *UserUseCase) RequirePasswordChange(ctx context.Context, actorID, userID string) error {
+	u.logger.Info(ctx, "user password change requirement requested", map[string]interface{}{"actor_id": actorID, "user_id": userID})
+	err := u.passwordService.RequireChange(ctx, userID)
+	if errors.Is(err, passwordservice.ErrUserNotFound) {
+		return ErrNotFound
+	}
+
+	entry := auditservice.Entry{
+		Actor:   actorID,
+		Subject: userID,
+		Action:  models.AuditActionRequirePasswordChange,
+		Outcome: models.AuditOutcomeSuccess,
+	}
+	if err != nil {
+		u.logger.Error(ctx, "user password change requirement failed", err, map[string]interface{}{"user_id": userID})
+		entry.Outcome = models.AuditOutcomeFailure
+	}
+	recordAudit(ctx, u.auditRecorder, u.logger, entry)
+	return err
+}
+
+// DeleteAccount schedules the deletion of the user's account once password confirms the request
+// and returns when it becomes final. A wrong password returns ErrWrongCredential.
+func (u *UserUseCase) DeleteAccount(ctx context.Context, userID, password string) (time.Time, error) {
+	u.logger.Info(ctx, "user account deletion requested", map[string]interface{}{"user_id": userID})
+	err := u.passwordService.VerifyPassword(ctx, userID, password)
+	switch {
+	case errors.Is(err, passwordservice.ErrWrongPassword):
+		u.logger.Warn(ctx, "user account deletion with wrong password", map[string]interface{}{"user_id": userID})
+		u.recordDeletionRequest(ctx, userID, models.AuditOutcomeFailure, "wrong_credentials")
+		return time.Time{}, ErrWrongCredential
+	case errors.Is(err, passwordservice.ErrUserNotFound):
+		return time.Time{}, ErrNotFound
+	case err != nil:
+		u.logger.Error(ctx, "user account deletion password check failed", err, map[string]interface{}{"user_id": userID})
+		return time.Time{}, err
+	}
+
+	deleteAt, err := u.accountService.ScheduleDeletion(ctx, userID)
+	if errors.Is(err, accountservice.ErrUserNotFound) {
+		return time.Time{}, ErrNotFound
+	}
+	if err != nil {
+		u.logger.Error(ctx, "user account deletion scheduling failed", err, map[string]interface{}{"user_id": userID})
+		u.recordDeletionRequest(ctx, userID, models.AuditOutcomeFailure, "schedule_failed")
+		return time.Time{}, err
+	}
+
+	u.logger.Info(ctx, "user account deletion scheduled", map[string]interface{}{"user_id": userID, "delete_at": deleteAt})
+	u.recordDeletionRequest(ctx, userID, models.AuditOutcomeSuccess, "")
+	return deleteAt, nil
+}
+
+// ExportAccount returns everything stored about the user.
+func (u *UserUseCase) ExportAccount(ctx context.Context, userID string) (*dto.AccountExportDTO, error) {
+	u.logger.Info(ctx, "user account export requested", map[string]interface{}{"user_id": userID})
+	export, err := u.accountService.Export(ctx, userID)
+	if errors.Is(err, accountservice.ErrUserNotFound) {
+		return nil, ErrNotFound
+	}
+
+	entry := auditservice.Entry{
+		Actor:   userID,
+		Subject: userID,
+		Action:  models.AuditActionExport,
+		Outcome: models.AuditOutcomeSuccess,
+	}
+	if err != nil {
+		u.logger.Error(ctx, "user account export failed", err, map[string]interface{}{"user_id": userID})
+		entry.Outcome = models.AuditOutcomeFailure
+		recordAudit(ctx, u.auditRecorder, u.logger, entry)
+		return nil, err
+	}
+	recordAudit(ctx, u.auditRecorder, u.logger, entry)
+	return mapper.MapAccountExportToDTO(export), nil
+}
+
+func (u *UserUseCase) recordDeletionRequest(ctx context.Context, userID, outcome, reason string) {
+	entry := auditservice.Entry{
+		Actor:   userID,
+		Subject: userID,
+		Action:  models.AuditActionDeletionRequest,
+		Outcome: outcome,
+	}
+	if reason != "" {
+		entry.Metadata = map[string]string{"reason": reason}
+	}
+	recordAudit(ctx, u.auditRecorder, u.logger, entry)
+}
+
+func (u *UserUseCase) recordPasswordChange(ctx context.Context, userID, outcome, reason string) {
+	entry := auditservice.Entry{
+		Actor:   userID,
+		Subject: userID,
+		Action:  models.AuditActionChangePassword,
+		Outcome: outcome,
+	}
+	if reason != "" {
+		entry.Metadata = map[string]string{"reason": reason}
+	}
+	recordAudit(ctx, u.auditRecorder, u.logger, entry)
+}
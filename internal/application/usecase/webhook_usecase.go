@@ -0,0 +1,80 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/SilentPlaces/basicauth.git/internal/application/port"
+	"github.com/SilentPlaces/basicauth.git/internal/models/models"
+	webhookrepo "github.com/SilentPlaces/basicauth.git/internal/repositories/webhook"
+	webhookservice "github.com/SilentPlaces/basicauth.git/internal/services/webhook"
+	appLogger "github.com/SilentPlaces/basicauth.git/internal/shared/logger"
+)
+
+type WebhookUseCase struct {
+	webhookManager port.WebhookManager
+	logger         appLogger.Logger
+}
+
+func NewWebhookUseCase(webhookManager port.WebhookManager, logger appLogger.Logger) *WebhookUseCase {
+	return &WebhookUseCase{webhookManager: webhookManager, logger: logger}
+}
+
+func (u *WebhookUseCase) CreateSubscription(ctx context.Context, rawURL string, eventTypes []string, secret string) (*models.WebhookSubscription, error) {
+	u.logger.Info(ctx, "webhook subscription create requested", map[string]interface{}{"url": rawURL})
+	subscription, err := u.webhookManager.CreateSubscription(rawURL, eventTypes, secret)
+	if err != nil {
+		if errors.Is(err, webhookservice.ErrInvalidWebhookURL) || errors.Is(err, webhookservice.ErrInvalidWebhookEventType) {
+			u.logger.Warn(ctx, "webhook subscription rejected", map[string]interface{}{"url": rawURL, "reason": err.Error()})
+			return nil, fmt.Errorf("%w: %s", ErrBadRequest, err.Error())
+		}
+		u.logger.Error(ctx, "webhook subscription create failed", err, map[string]interface{}{"url": rawURL})
+		return nil, err
+	}
+
+	u.logger.Info(ctx, "webhook subscription created", map[string]interface{}{"subscription_id": subscription.ID})
+	return subscription, nil
+}
+
+func (u *WebhookUseCase) ListSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	u.logger.Info(ctx, "webhook subscription list requested", nil)
+	return u.webhookManager.ListSubscriptions()
+}
+
+func (u *WebhookUseCase) DeleteSubscription(ctx context.Context, id string) error {
+	u.logger.Info(ctx, "webhook subscription delete requested", map[string]interface{}{"subscription_id": id})
+	if err := u.webhookManager.DeleteSubscription(id); err != nil {
+		if errors.Is(err, webhookrepo.ErrWebhookNotFound) {
+			return ErrNotFound
+		}
+		u.logger.Error(ctx, "webhook subscription delete failed", err, map[string]interface{}{"subscription_id": id})
+		return err
+	}
+	return nil
+}
+
+func (u *WebhookUseCase) ListDeliveries(ctx context.Context, subscriptionID string, status string, limit int, offset int) ([]models.WebhookDelivery, error) {
+	u.logger.Info(ctx, "webhook delivery log requested", map[string]interface{}{"subscription_id": subscriptionID})
+	return u.webhookManager.ListDeliveries(subscriptionID, status, limit, offset)
+}
+
+func (u *WebhookUseCase) RetryDelivery(ctx context.Context, id string) error {
+	u.logger.Info(ctx, "webhook delivery retry requested", map[string]interface{}{"delivery_id": id})
+	if err := u.webhookManager.RetryDelivery(id); err != nil {
+		if errors.Is(err, webhookrepo.ErrWebhookNotFound) {
+			return ErrNotFound
+		}
+		u.logger.Error(ctx, "webhook delivery retry failed", err, map[string]interface{}{"delivery_id": id})
+		return err
+	}
+	return nil
+}
+
+// publishWebhook enqueues a webhook event. Subscribers are informed on a best-effort basis,
+// so a failure is logged and never fails the user's request.
+func publishWebhook(ctx context.Context, publisher port.WebhookPublisher, logger appLogger.Logger, eventType string, data map[string]interface{}) {
+	if err := publisher.Publish(ctx, eventType, data); err != nil {
+		logger.Error(ctx, "webhook event could not be enqueued", err, map[string]interface{}{"event_type": eventType})
+	}
+}
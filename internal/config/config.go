@@ -1,9 +1,12 @@
 package config
 
 import (
+	"errors"
 	"github.com/SilentPlaces/basicauth.git/pkg/constants"
+	"io/fs"
 	"log"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -11,15 +14,26 @@ import (
 	"github.com/joho/godotenv"
 )
 
-// AppConfig. it only holds consule connection info. other configs are placed in Consul
+// AppConfig holds bootstrap settings from the environment: where the rest of the
+// configuration comes from, and logging.
 type AppConfig struct {
 	ConsulAddress string
 	ConsulScheme  string
 	Environment   string
 	LogLevel      string
 	LogFormat     string
+	// ConfigSources lists the config providers in order of precedence.
+	ConfigSources []string
+	ConfigFile    string
 }
 
+// Config sources that can be named in CONFIG_SOURCES.
+const (
+	ConfigSourceEnv    = "env"
+	ConfigSourceFile   = "file"
+	ConfigSourceConsul = "consul"
+)
+
 // Configuration Structs
 type MySQLConfig struct {
 	Host               string
@@ -27,11 +41,25 @@ type MySQLConfig struct {
 	User               string
 	Password           string
 	DB                 string
-	MaxLifetimeSeconds string
-	MaxOpenConnections string
-	IdleConnections    string
+	MaxLifetime        time.Duration
+	MaxOpenConnections int
+	IdleConnections    int
+	// CredentialSource is one of the MySQLCredentials constants. With vault, User and Password
+	// are unused and credentials come from VaultMount/creds/VaultRole.
+	CredentialSource string
+	VaultMount       string
+	VaultRole        string
+	// RotateBefore is how long before the lease ends new credentials are fetched. Pooled
+	// connections are recycled within this window as well.
+	RotateBefore time.Duration
 }
 
+// MySQL credential sources.
+const (
+	MySQLCredentialsStatic = "static"
+	MySQLCredentialsVault  = "vault"
+)
+
 type RedisConfig struct {
 	Host     string
 	Port     string
@@ -43,26 +71,287 @@ type SMTPConfig struct {
 	Port     string
 	Username string
 	Password string
+	// TLSMode is one of the SMTPTLSMode constants.
+	TLSMode    string
+	CAFile     string
+	ServerName string
+	PoolSize   int
+	// IdleTimeout closes pooled connections that were unused for longer.
+	IdleTimeout time.Duration
+}
+
+// SMTP TLS modes.
+const (
+	// SMTPTLSNone never encrypts; only for local catchers such as MailHog.
+	SMTPTLSNone = "none"
+	// SMTPTLSOpportunistic upgrades with STARTTLS when the server offers it.
+	SMTPTLSOpportunistic = "starttls"
+	// SMTPTLSRequired fails unless the server accepts STARTTLS.
+	SMTPTLSRequired = "starttls-required"
+	// SMTPTLSImplicit speaks TLS from the first byte (SMTPS, usually port 465).
+	SMTPTLSImplicit = "implicit"
+)
+
+// Mail transports that can deliver outgoing mail.
+const (
+	MailTransportSMTP   = "smtp"
+	MailTransportFile   = "file"
+	MailTransportMbox   = "mbox"
+	MailTransportMemory = "memory"
+	MailTransportHTTP   = "http"
+)
+
+type MailTransportConfig struct {
+	Driver string
+	SMTP   *SMTPConfig
+	// FilePath is a directory for the file driver and a file for the mbox driver.
+	FilePath    string
+	HTTPURL     string
+	HTTPToken   string
+	HTTPTimeout time.Duration
 }
 
 type GeneralConfig struct {
 	Domain           string
 	HTTPListenerPort string
+	// TrustedProxies are the IPs and CIDRs of the reverse proxies allowed to set the client IP
+	// through X-Forwarded-For. When empty, the client IP is the peer address.
+	TrustedProxies []string
 }
 
 type RegistrationPasswordConfig struct {
 	MinLength      int
+	MaxLength      int
 	RequireUpper   bool
 	RequireLower   bool
 	RequireNumber  bool
 	RequireSpecial bool
+	// MinScore is the strength score from 0 to 4 a new password needs, see
+	// validation.EstimatePasswordStrength.
+	MinScore int
+	// HistorySize is the number of recent passwords, the current one included, a new password
+	// must differ from.
+	HistorySize int
+	// MaxAge is how long a password may be used before it must be changed, 0 for no limit.
+	MaxAge time.Duration
+	// ExpiryWarning is how long before expiry logins report the upcoming expiry.
+	ExpiryWarning time.Duration
+}
+
+// Sources of breached password hashes.
+const (
+	BreachedPasswordSourceNone = "none"
+	// BreachedPasswordSourceFilter is a Bloom filter file built with the breach-filter command.
+	BreachedPasswordSourceFilter = "filter"
+	// BreachedPasswordSourceCorpus is a directory of HIBP range files named <PREFIX>.txt.
+	BreachedPasswordSourceCorpus = "corpus"
+)
+
+type BreachedPasswordConfig struct {
+	Source string
+	Path   string
+	// MinCount ignores hashes seen fewer times in breaches. Only corpus lookups know the counts; a
+	// filter applies the count it was built with.
+	MinCount int
 }
 
 type RegistrationConfig struct {
 	MailVerificationTimeInSeconds        time.Duration
 	HostVerificationMailAddress          string
-	VerificationMailText                 string
 	MaxVerificationMailGenerationInHours int64
+	// MaxVerificationAttempts is the number of wrong guesses after which a token is deleted.
+	MaxVerificationAttempts int
+	// VerificationMode selects what the verification mail carries: a link, a code or both.
+	VerificationMode string
+	CodeLength       int
+	// MaxCodeAttempts is kept low, as a short code is far easier to guess than a token.
+	MaxCodeAttempts int
+	// Mode is one of the RegistrationMode constants.
+	Mode string
+}
+
+// Registration modes.
+const (
+	RegistrationModeOpen = "open"
+	// RegistrationModeInviteOnly requires an invitation code to sign up.
+	RegistrationModeInviteOnly = "invite-only"
+	RegistrationModeClosed     = "closed"
+)
+
+// Verification modes.
+const (
+	VerificationModeLink = "link"
+	VerificationModeCode = "code"
+	VerificationModeBoth = "both"
+)
+
+// UsesLink reports whether verification mails carry a link.
+func (c *RegistrationConfig) UsesLink() bool {
+	return c.VerificationMode != VerificationModeCode
+}
+
+// UsesCode reports whether verification mails carry a numeric code.
+func (c *RegistrationConfig) UsesCode() bool {
+	return c.VerificationMode == VerificationModeCode || c.VerificationMode == VerificationModeBoth
+}
+
+// SignupPolicyConfig decides which email domains may sign up. Domains are lower case ASCII
+// (punycode) and also match their subdomains.
+type SignupPolicyConfig struct {
+	// AllowedDomains, when not empty, are the only domains that may sign up.
+	AllowedDomains []string
+	DeniedDomains  []string
+	// BlockDisposable rejects throwaway mail providers.
+	BlockDisposable bool
+	// DisposableDomains are added to the disposable domain list.
+	DisposableDomains []string
+	// DisposableDomainsFile replaces the bundled disposable domain list. It is re-read when it changes.
+	DisposableDomainsFile string
+	// RequireMX rejects domains that cannot receive mail.
+	RequireMX bool
+	MXTimeout time.Duration
+}
+
+// Challenge providers.
+const (
+	// ChallengeProviderPoW is the self-hosted proof-of-work puzzle.
+	ChallengeProviderPoW       = "pow"
+	ChallengeProviderHCaptcha  = "hcaptcha"
+	ChallengeProviderTurnstile = "turnstile"
+)
+
+// Routes a challenge can be required on.
+const (
+	ChallengeRouteSignup = "signup"
+	ChallengeRouteLogin  = "login"
+	ChallengeRouteResend = "resend_verification"
+)
+
+type ChallengeConfig struct {
+	Provider  string
+	Secret    string
+	SiteKey   string
+	VerifyURL string
+	Timeout   time.Duration
+	// PoWDifficulty is the number of leading zero bits a proof-of-work hash needs.
+	PoWDifficulty int
+	PoWTTL        time.Duration
+	// PoWMaxPerMinute is how many puzzles one client IP may request per minute.
+	PoWMaxPerMinute int
+}
+
+// ChallengeRoutesConfig selects the routes that require a solved challenge.
+type ChallengeRoutesConfig struct {
+	Signup             bool
+	Login              bool
+	ResendVerification bool
+}
+
+// Enabled reports whether route requires a challenge.
+func (c *ChallengeRoutesConfig) Enabled(route string) bool {
+	switch route {
+	case ChallengeRouteSignup:
+		return c.Signup
+	case ChallengeRouteLogin:
+		return c.Login
+	case ChallengeRouteResend:
+		return c.ResendVerification
+	}
+	return false
+}
+
+// RuntimeConfig groups the settings that are reloaded from Consul while the service runs.
+type RuntimeConfig struct {
+	Registration         RegistrationConfig
+	RegistrationPassword RegistrationPasswordConfig
+	SignupPolicy         SignupPolicyConfig
+	ChallengeRoutes      ChallengeRoutesConfig
+}
+
+type WebhookConfig struct {
+	MaxAttempts    int
+	RequestTimeout time.Duration
+	PollInterval   time.Duration
+	BatchSize      int
+}
+
+type MailQueueConfig struct {
+	Workers        int
+	MaxAttempts    int
+	BatchSize      int
+	ClaimIdle      time.Duration
+	IdempotencyTTL time.Duration
+}
+
+// Event bus drivers supported by the outbox relay.
+const (
+	EventBusDriverNone   = "none"
+	EventBusDriverMemory = "memory"
+	EventBusDriverRedis  = "redis"
+	EventBusDriverNATS   = "nats"
+	EventBusDriverKafka  = "kafka"
+)
+
+type EventBusConfig struct {
+	Driver       string
+	Topic        string
+	NATSURL      string
+	KafkaRESTURL string
+	PollInterval time.Duration
+	BatchSize    int
+	MaxAttempts  int
+	Retention    time.Duration
+}
+
+// Token signers.
+const (
+	// TokenSignerHMAC signs with the HS256 secrets stored in Vault KV.
+	TokenSignerHMAC = "hmac"
+	// TokenSignerTransit signs in Vault's Transit engine; private keys never leave Vault.
+	TokenSignerTransit = "transit"
+)
+
+type TokenSignerConfig struct {
+	Signer            string
+	TransitMount      string
+	TransitAccessKey  string
+	TransitRefreshKey string
+	// KeyRefreshInterval is how often Transit public keys are re-read to pick up rotations.
+	KeyRefreshInterval time.Duration
+}
+
+type InvitationConfig struct {
+	// DefaultExpiry applies when the creator does not choose one, and always to user invitations.
+	DefaultExpiry time.Duration
+	MaxExpiry     time.Duration
+	// UserQuota is how many invitations a user without the admin role may create within
+	// UserQuotaWindow, revoked ones included.
+	UserQuota       int
+	UserQuotaWindow time.Duration
+}
+
+// EmailNormalizationConfig lists the providers whose addresses have aliases. Domains are lower
+// case ASCII (punycode).
+type EmailNormalizationConfig struct {
+	// PlusTagDomains ignore a "+tag" suffix of the local part. "*" applies it to every domain.
+	PlusTagDomains []string
+	// DotInsensitiveDomains ignore dots in the local part.
+	DotInsensitiveDomains []string
+}
+
+type ReaperConfig struct {
+	Enabled bool
+	// MaxAge is how long an account may stay unverified before it is deleted.
+	MaxAge    time.Duration
+	Interval  time.Duration
+	BatchSize int
+}
+
+type AccountDeletionConfig struct {
+	// GracePeriod is how long a deleted account can still be restored by logging in.
+	GracePeriod time.Duration
+	Interval    time.Duration
+	BatchSize   int
 }
 
 var (
@@ -70,10 +359,14 @@ var (
 	once      sync.Once
 )
 
-// LoadConsulConfig loads consul configuration from the specified .env file. It is singleton.
-func LoadConsulConfig() *AppConfig {
+// LoadAppConfig loads the bootstrap configuration from the environment, reading the .env file
+// first when it exists. It is singleton.
+//
+// Without CONFIG_SOURCES the sources are: environment variables, then CONFIG_FILE if set, then
+// Consul if CONSUL_ADDRESS is set.
+func LoadAppConfig() *AppConfig {
 	once.Do(func() {
-		if err := godotenv.Load(constants.EnvFile); err != nil {
+		if err := godotenv.Load(constants.EnvFile); err != nil && !errors.Is(err, fs.ErrNotExist) {
 			log.Panic("Error loading .env file:", err)
 		}
 		appConfig = &AppConfig{
@@ -82,6 +375,24 @@ func LoadConsulConfig() *AppConfig {
 			Environment:   os.Getenv(constants.EnvKeyAppEnvironment),
 			LogLevel:      os.Getenv(constants.EnvKeyLogLevel),
 			LogFormat:     os.Getenv(constants.EnvKeyLogFormat),
+			ConfigFile:    os.Getenv(constants.EnvKeyConfigFile),
+		}
+
+		if sources := os.Getenv(constants.EnvKeyConfigSources); sources != "" {
+			for _, source := range strings.Split(sources, ",") {
+				appConfig.ConfigSources = append(appConfig.ConfigSources, strings.TrimSpace(source))
+			}
+		} else {
+			appConfig.ConfigSources = []string{ConfigSourceEnv}
+			if appConfig.ConfigFile != "" {
+				appConfig.ConfigSources = append(appConfig.ConfigSources, ConfigSourceFile)
+			}
+			if appConfig.ConsulAddress != "" {
+				appConfig.ConfigSources = append(appConfig.ConfigSources, ConfigSourceConsul)
+			}
+		}
+		if appConfig.ConsulScheme == "" {
+			appConfig.ConsulScheme = "http"
 		}
 
 		if appConfig.LogLevel == "" {
@@ -95,12 +406,17 @@ func LoadConsulConfig() *AppConfig {
 			}
 		}
 
-		if appConfig.ConsulAddress == "" || appConfig.ConsulScheme == "" {
-			log.Panic("Enter Consul Configuration in .env file to continue")
+		for _, source := range appConfig.ConfigSources {
+			if source == ConfigSourceConsul && appConfig.ConsulAddress == "" {
+				log.Panic("CONFIG_SOURCES includes consul but CONSUL_ADDRESS is not set")
+			}
+			if source == ConfigSourceFile && appConfig.ConfigFile == "" {
+				log.Panic("CONFIG_SOURCES includes file but CONFIG_FILE is not set")
+			}
 		}
 	})
 	return appConfig
 }
 
 // Dependency Injection
-var ProviderSet = wire.NewSet(LoadConsulConfig)
+var ProviderSet = wire.NewSet(LoadAppConfig)
@@ -0,0 +1,72 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	consulapi "github.com/hashicorp/consul/api"
+)
+
+// consulWaitTime is how long one blocking query may wait for a change.
+const consulWaitTime = 5 * time.Minute
+
+type consulProvider struct {
+	client *consulapi.Client
+}
+
+func NewConsulProvider(address, scheme string) (ConfigProvider, error) {
+	consulConfig := consulapi.DefaultConfig()
+	consulConfig.Address = address
+	consulConfig.Scheme = scheme
+
+	client, err := consulapi.NewClient(consulConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to Consul server: %w", err)
+	}
+	return &consulProvider{client: client}, nil
+}
+
+func (p *consulProvider) Name() string {
+	return "consul"
+}
+
+func (p *consulProvider) Get(key string) (string, bool, error) {
+	pair, _, err := p.client.KV().Get(key, nil)
+	if err != nil {
+		return "", false, fmt.Errorf("error retrieving key %s: %v", key, err)
+	}
+	if pair == nil {
+		return "", false, nil
+	}
+	return string(pair.Value), true, nil
+}
+
+func (p *consulProvider) List(prefix string) (map[string]string, error) {
+	pairs, _, err := p.client.KV().List(prefix, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error listing keys under %s: %v", prefix, err)
+	}
+	values := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		values[pair.Key] = string(pair.Value)
+	}
+	return values, nil
+}
+
+// Watch runs a Consul blocking query on prefix.
+func (p *consulProvider) Watch(ctx context.Context, prefix string, waitIndex uint64) (uint64, error) {
+	opts := (&consulapi.QueryOptions{WaitIndex: waitIndex, WaitTime: consulWaitTime}).WithContext(ctx)
+	_, meta, err := p.client.KV().Keys(prefix, "", opts)
+	if err != nil {
+		return 0, fmt.Errorf("error watching keys under %s: %v", prefix, err)
+	}
+	return meta.LastIndex, nil
+}
+
+func (p *consulProvider) Put(key, value string) error {
+	if _, err := p.client.KV().Put(&consulapi.KVPair{Key: key, Value: []byte(value)}, nil); err != nil {
+		return fmt.Errorf("error storing key %s: %v", key, err)
+	}
+	return nil
+}
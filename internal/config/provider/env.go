@@ -0,0 +1,37 @@
+package provider
+
+import (
+	"os"
+	"strings"
+)
+
+var envKeyReplacer = strings.NewReplacer("/", "_", "-", "_", ".", "_")
+
+// envProvider reads keys from environment variables. The variable name is the key in upper case
+// with separators turned into underscores: config/mysql/connection/maxLifeTime is read from
+// CONFIG_MYSQL_CONNECTION_MAXLIFETIME.
+type envProvider struct{}
+
+func NewEnvProvider() ConfigProvider {
+	return envProvider{}
+}
+
+// EnvName returns the environment variable that holds key.
+func EnvName(key string) string {
+	return strings.ToUpper(envKeyReplacer.Replace(key))
+}
+
+func (envProvider) Name() string {
+	return "env"
+}
+
+func (envProvider) Get(key string) (string, bool, error) {
+	value, ok := os.LookupEnv(EnvName(key))
+	return value, ok, nil
+}
+
+// List returns nothing: variable names cannot be mapped back to keys, since the case of the
+// original key is lost. Listing is only used for optional trees such as mail templates.
+func (envProvider) List(string) (map[string]string, error) {
+	return map[string]string{}, nil
+}
@@ -0,0 +1,143 @@
+package provider
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// fileProvider reads a YAML or TOML document whose nesting mirrors the key layout:
+//
+//	config:
+//	  mysql:
+//	    connection:
+//	      host: localhost
+//
+// The file is read again when its modification time changes.
+type fileProvider struct {
+	path string
+
+	mu      sync.Mutex
+	values  map[string]string
+	modTime time.Time
+}
+
+func NewFileProvider(path string) (ConfigProvider, error) {
+	if path == "" {
+		return nil, fmt.Errorf("config file path is empty")
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".toml":
+	default:
+		return nil, fmt.Errorf("config file %s must end in .yaml, .yml or .toml", path)
+	}
+
+	p := &fileProvider{path: path}
+	if _, err := p.load(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *fileProvider) Name() string {
+	return "file:" + p.path
+}
+
+func (p *fileProvider) Get(key string) (string, bool, error) {
+	values, err := p.load()
+	if err != nil {
+		return "", false, err
+	}
+	value, ok := values[key]
+	return value, ok, nil
+}
+
+func (p *fileProvider) List(prefix string) (map[string]string, error) {
+	values, err := p.load()
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]string)
+	for key, value := range values {
+		if strings.HasPrefix(key, prefix) {
+			result[key] = value
+		}
+	}
+	return result, nil
+}
+
+// load returns the parsed file, reading it again if it changed on disk.
+func (p *fileProvider) load() (map[string]string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat config file: %w", err)
+	}
+	if p.values != nil && info.ModTime().Equal(p.modTime) {
+		return p.values, nil
+	}
+
+	raw, err := os.ReadFile(p.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	var document map[string]interface{}
+	if strings.ToLower(filepath.Ext(p.path)) == ".toml" {
+		err = toml.Unmarshal(raw, &document)
+	} else {
+		err = yaml.Unmarshal(raw, &document)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", p.path, err)
+	}
+
+	values := make(map[string]string)
+	if err := flatten("", document, values); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", p.path, err)
+	}
+	p.values = values
+	p.modTime = info.ModTime()
+	return values, nil
+}
+
+// flatten turns nested maps into slash separated keys with string values.
+func flatten(prefix string, node map[string]interface{}, out map[string]string) error {
+	for name, value := range node {
+		key := name
+		if prefix != "" {
+			key = prefix + "/" + name
+		}
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if err := flatten(key, v, out); err != nil {
+				return err
+			}
+		case nil:
+			out[key] = ""
+		case string:
+			out[key] = v
+		case bool:
+			out[key] = strconv.FormatBool(v)
+		case int:
+			out[key] = strconv.Itoa(v)
+		case int64:
+			out[key] = strconv.FormatInt(v, 10)
+		case uint64:
+			out[key] = strconv.FormatUint(v, 10)
+		case float64:
+			out[key] = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			return fmt.Errorf("%s: unsupported value of type %T", key, value)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// layeredPollInterval is how often Watch checks the providers that cannot watch.
+const layeredPollInterval = 15 * time.Second
+
+// layeredProvider asks its providers in order; the first one that has a key wins.
+type layeredProvider struct {
+	providers []ConfigProvider
+
+	// Watch hands out its own change index, which moves when the watching provider or one of the
+	// polled ones changed.
+	mu         sync.Mutex
+	index      uint64
+	watchIndex uint64
+	pollState  string
+}
+
+func NewLayeredProvider(providers ...ConfigProvider) ConfigProvider {
+	return &layeredProvider{providers: providers}
+}
+
+func (l *layeredProvider) Name() string {
+	names := make([]string, 0, len(l.providers))
+	for _, p := range l.providers {
+		names = append(names, p.Name())
+	}
+	return strings.Join(names, ",")
+}
+
+func (l *layeredProvider) Get(key string) (string, bool, error) {
+	for _, p := range l.providers {
+		value, ok, err := p.Get(key)
+		if err != nil {
+			return "", false, err
+		}
+		if ok {
+			return value, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// List merges the keys of all providers. Values are resolved with Get, so providers that cannot
+// list (the environment) still override the values they hold.
+func (l *layeredProvider) List(prefix string) (map[string]string, error) {
+	result := make(map[string]string)
+	for _, p := range l.providers {
+		values, err := p.List(prefix)
+		if err != nil {
+			return nil, err
+		}
+		for key := range values {
+			if _, seen := result[key]; seen {
+				continue
+			}
+			value, _, err := l.Get(key)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = value
+		}
+	}
+	return result, nil
+}
+
+// Watch runs the watch of the first provider that can watch, and polls the other providers every
+// layeredPollInterval while it waits, so a changed file is picked up without a change in Consul.
+func (l *layeredProvider) Watch(ctx context.Context, prefix string, waitIndex uint64) (uint64, error) {
+	var watcher Watcher
+	var polled []ConfigProvider
+	for _, p := range l.providers {
+		if w, ok := p.(Watcher); ok && watcher == nil {
+			watcher = w
+			continue
+		}
+		polled = append(polled, p)
+	}
+	if watcher == nil {
+		return 0, ErrWatchUnsupported
+	}
+
+	for {
+		state, err := snapshot(polled, prefix)
+		if err != nil {
+			return 0, err
+		}
+
+		l.mu.Lock()
+		if l.index == 0 || state != l.pollState {
+			l.index++
+			l.pollState = state
+		}
+		index, watchIndex := l.index, l.watchIndex
+		l.mu.Unlock()
+
+		if index != waitIndex {
+			return index, nil
+		}
+
+		pollCtx, cancel := context.WithTimeout(ctx, layeredPollInterval)
+		next, err := watcher.Watch(pollCtx, prefix, watchIndex)
+		cancel()
+		if err != nil {
+			if ctx.Err() != nil {
+				return 0, ctx.Err()
+			}
+			if errors.Is(pollCtx.Err(), context.DeadlineExceeded) {
+				continue
+			}
+			return 0, err
+		}
+
+		l.mu.Lock()
+		if next != l.watchIndex {
+			l.watchIndex = next
+			l.index++
+		}
+		l.mu.Unlock()
+	}
+}
+
+// snapshot renders the values of providers below prefix, for comparing them between polls.
+func snapshot(providers []ConfigProvider, prefix string) (string, error) {
+	var state strings.Builder
+	for _, p := range providers {
+		values, err := p.List(prefix)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&state, "%s=%v;", p.Name(), values)
+	}
+	return state.String(), nil
+}
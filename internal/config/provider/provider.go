@@ -0,0 +1,65 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/SilentPlaces/basicauth.git/internal/config"
+)
+
+// ErrWatchUnsupported is returned by Watch when the provider cannot block on changes.
+// Callers fall back to polling List.
+var ErrWatchUnsupported = errors.New("config provider does not support watching")
+
+// ConfigProvider is a source of raw configuration values. Keys use the Consul layout,
+// e.g. "config/mysql/connection/host".
+type ConfigProvider interface {
+	// Get returns the value of key and whether the provider has it.
+	Get(key string) (string, bool, error)
+	// List returns all keys below prefix with their values.
+	List(prefix string) (map[string]string, error)
+	Name() string
+}
+
+// Watcher is implemented by providers that can block until keys below prefix change.
+type Watcher interface {
+	// Watch returns once the change index moves past waitIndex, or after a provider specific
+	// timeout with the same index.
+	Watch(ctx context.Context, prefix string, waitIndex uint64) (uint64, error)
+}
+
+// New builds the provider chain named in cfg.ConfigSources. Earlier sources take precedence.
+func New(cfg *config.AppConfig) (ConfigProvider, error) {
+	var providers []ConfigProvider
+	for _, source := range cfg.ConfigSources {
+		switch source {
+		case config.ConfigSourceEnv:
+			providers = append(providers, NewEnvProvider())
+		case config.ConfigSourceFile:
+			p, err := NewFileProvider(cfg.ConfigFile)
+			if err != nil {
+				return nil, err
+			}
+			providers = append(providers, p)
+		case config.ConfigSourceConsul:
+			p, err := NewConsulProvider(cfg.ConsulAddress, cfg.ConsulScheme)
+			if err != nil {
+				return nil, err
+			}
+			providers = append(providers, p)
+		default:
+			return nil, fmt.Errorf("unknown config source %q", source)
+		}
+	}
+
+	if len(providers) == 1 {
+		return providers[0], nil
+	}
+	return NewLayeredProvider(providers...), nil
+}
+
+// Writer is implemented by providers that can store values, used to seed defaults.
+type Writer interface {
+	Put(key, value string) error
+}
@@ -0,0 +1,306 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/SilentPlaces/basicauth.git/pkg/constants"
+)
+
+// KeyType is the format a config value must have.
+type KeyType int
+
+const (
+	KeyString KeyType = iota
+	KeyInt
+	KeyBool
+	KeyPort
+	KeyHost
+	KeyEmail
+	KeyURL
+	KeyEnum
+)
+
+// KeySpec describes one config key. An empty value counts as missing, since Consul seeds often
+// store an empty string for "not set".
+type KeySpec struct {
+	Key      string
+	Type     KeyType
+	Required bool
+	// Default is used when the key is missing. It is also what `config seed` writes.
+	Default string
+	// Min and Max bound KeyInt values. Max 0 means unbounded.
+	Min, Max int
+	// Values lists the allowed KeyEnum values.
+	Values []string
+	// Secret values are redacted when the config is printed.
+	Secret bool
+}
+
+// Check reports whether raw is a valid value for the key.
+func (s KeySpec) Check(raw string) error {
+	switch s.Type {
+	case KeyInt:
+		v, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("%s must be an integer, got %q", s.Key, raw)
+		}
+		if v < s.Min || (s.Max > 0 && v > s.Max) {
+			if s.Max > 0 {
+				return fmt.Errorf("%s must be between %d and %d, got %d", s.Key, s.Min, s.Max, v)
+			}
+			return fmt.Errorf("%s must be at least %d, got %d", s.Key, s.Min, v)
+		}
+	case KeyBool:
+		if _, err := strconv.ParseBool(raw); err != nil {
+			return fmt.Errorf("%s must be true or false, got %q", s.Key, raw)
+		}
+	case KeyPort:
+		if v, err := strconv.Atoi(raw); err != nil || v < 1 || v > 65535 {
+			return fmt.Errorf("%s must be a port between 1 and 65535, got %q", s.Key, raw)
+		}
+	case KeyHost:
+		if net.ParseIP(raw) == nil && strings.ContainsAny(raw, " \t/:") {
+			return fmt.Errorf("%s must be a host name or address, got %q", s.Key, raw)
+		}
+	case KeyEmail:
+		if _, err := mail.ParseAddress(raw); err != nil {
+			return fmt.Errorf("%s must be an email address, got %q", s.Key, raw)
+		}
+	case KeyURL:
+		u, err := url.Parse(raw)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("%s must be an absolute url, got %q", s.Key, raw)
+		}
+	case KeyEnum:
+		if !slices.Contains(s.Values, raw) {
+			return fmt.Errorf("%s must be one of %s, got %q", s.Key, strings.Join(s.Values, ", "), raw)
+		}
+	}
+	return nil
+}
+
+// Redact returns value, or a placeholder for secrets that are set.
+func (s KeySpec) Redact(value string) string {
+	if s.Secret && value != "" {
+		return "******"
+	}
+	return value
+}
+
+// Schema lists every config key the service reads, except the mail template tree.
+var Schema = []KeySpec{
+	{Key: constants.MySQLHostKey, Type: KeyHost, Required: true},
+	{Key: constants.MySQLPortKey, Type: KeyPort, Default: "3306"},
+	// User and password are required for static credentials only.
+	{Key: constants.MySQLUserKey, Type: KeyString},
+	{Key: constants.MySQLPasswordKey, Type: KeyString, Secret: true},
+	{Key: constants.MySQLDBKey, Type: KeyString, Required: true},
+	{Key: constants.MySQLMaxLifetimeSecondsKey, Type: KeyInt, Default: "180", Min: 1},
+	{Key: constants.MySQLMaxOpenConnectionsKey, Type: KeyInt, Default: "10", Min: 1, Max: 1000},
+	{Key: constants.MySQLIdleConnectionsKey, Type: KeyInt, Default: "10", Min: 0, Max: 1000},
+	{Key: constants.MySQLCredentialSourceKey, Type: KeyEnum, Default: MySQLCredentialsStatic,
+		Values: []string{MySQLCredentialsStatic, MySQLCredentialsVault}},
+	{Key: constants.MySQLVaultMountKey, Type: KeyString, Default: "database"},
+	{Key: constants.MySQLVaultRoleKey, Type: KeyString},
+	{Key: constants.MySQLRotateBeforeSecondsKey, Type: KeyInt, Default: "300", Min: 10},
+
+	{Key: constants.RedisHostKey, Type: KeyHost, Required: true},
+	{Key: constants.RedisPortKey, Type: KeyPort, Default: "6379"},
+	{Key: constants.RedisPasswordKey, Type: KeyString, Secret: true},
+
+	{Key: constants.SMTPHostKey, Type: KeyHost, Required: true},
+	{Key: constants.SMTPPortKey, Type: KeyPort, Default: "587"},
+	{Key: constants.SMTPUsernameKey, Type: KeyString},
+	{Key: constants.SMTPPasswordKey, Type: KeyString, Secret: true},
+	{Key: constants.SMTPTLSModeKey, Type: KeyEnum, Default: SMTPTLSOpportunistic,
+		Values: []string{SMTPTLSNone, SMTPTLSOpportunistic, SMTPTLSRequired, SMTPTLSImplicit}},
+	{Key: constants.SMTPCAFileKey, Type: KeyString},
+	{Key: constants.SMTPServerNameKey, Type: KeyHost},
+	{Key: constants.SMTPPoolSizeKey, Type: KeyInt, Default: "2", Min: 1, Max: 100},
+	{Key: constants.SMTPIdleTimeoutSecondsKey, Type: KeyInt, Default: "30", Min: 1},
+
+	{Key: constants.MailTransportDriverKey, Type: KeyEnum, Default: MailTransportSMTP,
+		Values: []string{MailTransportSMTP, MailTransportFile, MailTransportMbox, MailTransportMemory, MailTransportHTTP}},
+	{Key: constants.MailTransportFilePathKey, Type: KeyString},
+	{Key: constants.MailTransportHTTPURLKey, Type: KeyURL},
+	{Key: constants.MailTransportHTTPTokenKey, Type: KeyString, Secret: true},
+	{Key: constants.MailTransportHTTPTimeoutSecKey, Type: KeyInt, Default: "10", Min: 1},
+	{Key: constants.MailDefaultLocaleKey, Type: KeyString, Default: "en"},
+
+	// The domain may carry a port, e.g. localhost:8080.
+	{Key: constants.GeneralDomainKey, Type: KeyString, Required: true},
+	{Key: constants.GeneralHTTPListenerPortKey, Type: KeyPort, Default: "8080"},
+	// Comma-separated IPs or CIDRs of reverse proxies whose X-Forwarded-For is trusted; none by default.
+	{Key: constants.GeneralTrustedProxiesKey, Type: KeyString},
+	{Key: constants.GeneralRegisterMailVerificationTimeInSecondsKey, Type: KeyInt, Default: "600", Min: 1},
+	{Key: constants.GeneralRegisterHostVerificationMailAddressKey, Type: KeyEmail, Required: true},
+	{Key: constants.GeneralMaxVerificationMailCountInDay, Type: KeyInt, Default: "5", Min: 1},
+	{Key: constants.GeneralMaxVerificationAttemptsKey, Type: KeyInt, Default: "5", Min: 1, Max: 100},
+	{Key: constants.GeneralRegisterVerificationModeKey, Type: KeyEnum, Default: VerificationModeLink,
+		Values: []string{VerificationModeLink, VerificationModeCode, VerificationModeBoth}},
+	{Key: constants.GeneralRegisterCodeLengthKey, Type: KeyInt, Default: "6", Min: 4, Max: 10},
+	{Key: constants.GeneralRegisterMaxCodeAttemptsKey, Type: KeyInt, Default: "3", Min: 1, Max: 20},
+
+	{Key: constants.RegistrationModeKey, Type: KeyEnum, Default: RegistrationModeOpen,
+		Values: []string{RegistrationModeOpen, RegistrationModeInviteOnly, RegistrationModeClosed}},
+	{Key: constants.InvitationDefaultExpiryHoursKey, Type: KeyInt, Default: "168", Min: 1},
+	{Key: constants.InvitationMaxExpiryHoursKey, Type: KeyInt, Default: "2160", Min: 1},
+	// 0 lets only admins create invitations.
+	{Key: constants.InvitationUserQuotaKey, Type: KeyInt, Default: "5", Min: 0, Max: 1000},
+	{Key: constants.InvitationUserQuotaWindowKey, Type: KeyInt, Default: "24", Min: 1, Max: 8760},
+
+	{Key: constants.KeyRegistrationPasswordMinLength, Type: KeyInt, Default: "8", Min: 1, Max: 128},
+	{Key: constants.KeyRegistrationPasswordRequireUpper, Type: KeyBool, Default: "false"},
+	{Key: constants.KeyRegistrationPasswordRequireLower, Type: KeyBool, Default: "false"},
+	{Key: constants.KeyRegistrationPasswordRequireNumber, Type: KeyBool, Default: "false"},
+	{Key: constants.KeyRegistrationPasswordRequireSpecial, Type: KeyBool, Default: "false"},
+	{Key: constants.KeyRegistrationPasswordMaxLength, Type: KeyInt, Default: "128", Min: 16, Max: 1024},
+	// 0 turns the strength estimate off.
+	{Key: constants.KeyRegistrationPasswordMinScore, Type: KeyInt, Default: "2", Min: 0, Max: 4},
+	// 0 allows reusing the current password.
+	{Key: constants.KeyRegistrationPasswordHistorySize, Type: KeyInt, Default: "5", Min: 0, Max: 24},
+	// 0 lets passwords never expire.
+	{Key: constants.KeyRegistrationPasswordMaxAgeDays, Type: KeyInt, Default: "0", Min: 0, Max: 3650},
+	{Key: constants.KeyRegistrationPasswordWarningDays, Type: KeyInt, Default: "14", Min: 0, Max: 365},
+	{Key: constants.BreachedPasswordSourceKey, Type: KeyEnum, Default: BreachedPasswordSourceNone,
+		Values: []string{BreachedPasswordSourceNone, BreachedPasswordSourceFilter, BreachedPasswordSourceCorpus}},
+	// The filter file or corpus directory; required unless the source is none.
+	{Key: constants.BreachedPasswordPathKey, Type: KeyString},
+	{Key: constants.BreachedPasswordMinCountKey, Type: KeyInt, Default: "1", Min: 1},
+
+	{Key: constants.WebhookMaxAttemptsKey, Type: KeyInt, Default: "8", Min: 1, Max: 100},
+	{Key: constants.WebhookRequestTimeoutSecKey, Type: KeyInt, Default: "10", Min: 1},
+	{Key: constants.WebhookPollIntervalSecondsKey, Type: KeyInt, Default: "5", Min: 1},
+	{Key: constants.WebhookBatchSizeKey, Type: KeyInt, Default: "20", Min: 1, Max: 1000},
+
+	{Key: constants.MailQueueWorkersKey, Type: KeyInt, Default: "4", Min: 1, Max: 64},
+	{Key: constants.MailQueueMaxAttemptsKey, Type: KeyInt, Default: "6", Min: 1, Max: 100},
+	{Key: constants.MailQueueBatchSizeKey, Type: KeyInt, Default: "10", Min: 1, Max: 1000},
+	{Key: constants.MailQueueClaimIdleSecondsKey, Type: KeyInt, Default: "120", Min: 1},
+	{Key: constants.MailQueueIdempotencyHoursKey, Type: KeyInt, Default: "24", Min: 1},
+
+	{Key: constants.AuthSignerKey, Type: KeyEnum, Default: TokenSignerHMAC,
+		Values: []string{TokenSignerHMAC, TokenSignerTransit}},
+	{Key: constants.AuthTransitMountKey, Type: KeyString, Default: "transit"},
+	{Key: constants.AuthTransitAccessKeyKey, Type: KeyString, Default: "basicauth-access"},
+	{Key: constants.AuthTransitRefreshKeyKey, Type: KeyString, Default: "basicauth-refresh"},
+	{Key: constants.AuthTransitKeyRefreshSecondsKey, Type: KeyInt, Default: "300", Min: 10},
+
+	// Comma-separated domain lists.
+	{Key: constants.EmailPlusTagDomainsKey, Type: KeyString,
+		Default: "gmail.com,googlemail.com,outlook.com,hotmail.com,live.com,icloud.com,me.com,fastmail.com,proton.me,protonmail.com"},
+	{Key: constants.EmailDotInsensitiveDomainsKey, Type: KeyString, Default: "gmail.com,googlemail.com"},
+
+	// Comma-separated domain lists. An entry also matches its subdomains.
+	{Key: constants.SignupPolicyAllowedDomainsKey, Type: KeyString},
+	{Key: constants.SignupPolicyDeniedDomainsKey, Type: KeyString},
+	{Key: constants.SignupPolicyBlockDisposableKey, Type: KeyBool, Default: "true"},
+	{Key: constants.SignupPolicyDisposableDomainsKey, Type: KeyString},
+	{Key: constants.SignupPolicyDisposableDomainsFileKey, Type: KeyString},
+	{Key: constants.SignupPolicyRequireMXKey, Type: KeyBool, Default: "false"},
+	{Key: constants.SignupPolicyMXTimeoutMillisKey, Type: KeyInt, Default: "2000", Min: 100, Max: 30000},
+
+	{Key: constants.ChallengeProviderKey, Type: KeyEnum, Default: ChallengeProviderPoW,
+		Values: []string{ChallengeProviderPoW, ChallengeProviderHCaptcha, ChallengeProviderTurnstile}},
+	{Key: constants.ChallengeSignupEnabledKey, Type: KeyBool, Default: "false"},
+	{Key: constants.ChallengeLoginEnabledKey, Type: KeyBool, Default: "false"},
+	{Key: constants.ChallengeResendEnabledKey, Type: KeyBool, Default: "false"},
+	// The secret and site key are required for hcaptcha and turnstile.
+	{Key: constants.ChallengeSecretKey, Type: KeyString, Secret: true},
+	{Key: constants.ChallengeSiteKeyKey, Type: KeyString},
+	// Overrides the provider's verify endpoint, e.g. with a local stub.
+	{Key: constants.ChallengeVerifyURLKey, Type: KeyURL},
+	{Key: constants.ChallengeTimeoutSecondsKey, Type: KeyInt, Default: "5", Min: 1, Max: 60},
+	{Key: constants.ChallengePoWDifficultyKey, Type: KeyInt, Default: "20", Min: 8, Max: 32},
+	{Key: constants.ChallengePoWTTLSecondsKey, Type: KeyInt, Default: "300", Min: 10, Max: 3600},
+	{Key: constants.ChallengePoWMaxPerMinuteKey, Type: KeyInt, Default: "30", Min: 1, Max: 10000},
+
+	{Key: constants.ReaperEnabledKey, Type: KeyBool, Default: "true"},
+	{Key: constants.ReaperMaxAgeHoursKey, Type: KeyInt, Default: "72", Min: 1},
+	{Key: constants.ReaperIntervalMinutesKey, Type: KeyInt, Default: "60", Min: 1},
+	{Key: constants.ReaperBatchSizeKey, Type: KeyInt, Default: "500", Min: 1, Max: 10000},
+
+	// 0 deletes accounts on the next run.
+	{Key: constants.AccountDeletionGraceHoursKey, Type: KeyInt, Default: "720", Min: 0, Max: 8760},
+	{Key: constants.AccountDeletionIntervalMinutesKey, Type: KeyInt, Default: "15", Min: 1},
+	{Key: constants.AccountDeletionBatchSizeKey, Type: KeyInt, Default: "100", Min: 1, Max: 10000},
+
+	{Key: constants.EventBusDriverKey, Type: KeyEnum, Default: EventBusDriverRedis,
+		Values: []string{EventBusDriverNone, EventBusDriverMemory, EventBusDriverRedis, EventBusDriverNATS, EventBusDriverKafka}},
+	{Key: constants.EventBusTopicKey, Type: KeyString, Default: "basicauth.events"},
+	{Key: constants.EventBusNATSURLKey, Type: KeyURL},
+	{Key: constants.EventBusKafkaRESTURLKey, Type: KeyURL},
+	{Key: constants.EventBusPollIntervalSecondsKey, Type: KeyInt, Default: "2", Min: 1},
+	{Key: constants.EventBusBatchSizeKey, Type: KeyInt, Default: "100", Min: 1, Max: 10000},
+	{Key: constants.EventBusMaxAttemptsKey, Type: KeyInt, Default: "10", Min: 1, Max: 100},
+	{Key: constants.EventBusRetentionHoursKey, Type: KeyInt, Default: "168", Min: 1},
+}
+
+// LookupKeySpec returns the schema entry for key.
+func LookupKeySpec(key string) (KeySpec, bool) {
+	for _, spec := range Schema {
+		if spec.Key == key {
+			return spec, true
+		}
+	}
+	return KeySpec{}, false
+}
+
+// Values holds resolved, validated config values. The typed getters do not fail: every value has
+// been checked against its KeySpec.
+type Values map[string]string
+
+func (v Values) String(key string) string {
+	return v[key]
+}
+
+func (v Values) Int(key string) int {
+	i, _ := strconv.Atoi(v[key])
+	return i
+}
+
+func (v Values) Bool(key string) bool {
+	b, _ := strconv.ParseBool(v[key])
+	return b
+}
+
+// Resolve reads keys through lookup, fills in defaults and checks every value against the schema.
+// All problems are returned together. Invalid values are replaced by their default in the returned
+// Values, so callers can go on and add cross-field problems of their own.
+func Resolve(keys []string, lookup func(key string) (string, bool, error)) (Values, error) {
+	values := make(Values, len(keys))
+	var problems []error
+	for _, key := range keys {
+		spec, ok := LookupKeySpec(key)
+		if !ok {
+			problems = append(problems, fmt.Errorf("%s is not in the config schema", key))
+			continue
+		}
+		raw, _, err := lookup(key)
+		if err != nil {
+			// The source is unreachable; every further lookup would fail the same way.
+			return nil, err
+		}
+		if raw == "" {
+			if spec.Required {
+				problems = append(problems, fmt.Errorf("%s is required", key))
+			}
+			values[key] = spec.Default
+			continue
+		}
+		if err := spec.Check(raw); err != nil {
+			problems = append(problems, err)
+			values[key] = spec.Default
+			continue
+		}
+		values[key] = raw
+	}
+	return values, errors.Join(problems...)
+}
@@ -0,0 +1,23 @@
+package config
+
+import "sync/atomic"
+
+// Value holds a config struct that can be replaced at runtime. Readers always see a complete
+// snapshot; a stored struct must not be modified afterwards.
+type Value[T any] struct {
+	current atomic.Pointer[T]
+}
+
+func NewValue[T any](initial *T) *Value[T] {
+	v := &Value[T]{}
+	v.current.Store(initial)
+	return v
+}
+
+func (v *Value[T]) Load() *T {
+	return v.current.Load()
+}
+
+func (v *Value[T]) Store(next *T) {
+	v.current.Store(next)
+}
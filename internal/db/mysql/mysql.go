@@ -1,54 +1,73 @@
 package mysql
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"time"
+	"net"
 
 	consul "github.com/SilentPlaces/basicauth.git/internal/services/consul"
-	helpers "github.com/SilentPlaces/basicauth.git/pkg/helper/convertor"
 
-	_ "github.com/go-sql-driver/mysql"
+	mysqldriver "github.com/go-sql-driver/mysql"
 	"github.com/google/wire"
 )
 
-func NewMySQLDb(consulService consul.ConsulService) (*sql.DB, error) {
+// CredentialSource supplies short-lived credentials, e.g. from Vault's database secrets engine.
+type CredentialSource interface {
+	Credentials() (username, password string)
+	// OnRotate registers fn to run after the credentials changed.
+	OnRotate(fn func())
+}
+
+// NewMySQLDb opens the connection pool. With a nil credentials source the static user and password
+// from the config are used. Otherwise every new connection asks the source, so rotated credentials
+// take effect without replacing the *sql.DB that repositories hold.
+func NewMySQLDb(consulService consul.ConsulService, credentials CredentialSource) (*sql.DB, error) {
 	cfg, err := consulService.GetMySQLConfig()
 	if err != nil {
 		return nil, fmt.Errorf("cannot access config: %w", err)
 	}
 
-	user := cfg.User
-	password := cfg.Password
-	host := cfg.Host
-	port := cfg.Port
-	dbName := cfg.DB
+	driverCfg := mysqldriver.NewConfig()
+	driverCfg.Net = "tcp"
+	driverCfg.Addr = net.JoinHostPort(cfg.Host, cfg.Port)
+	driverCfg.DBName = cfg.DB
+	driverCfg.ParseTime = true
+	driverCfg.User = cfg.User
+	driverCfg.Passwd = cfg.Password
 
-	lifeTimeInt, err := helpers.ParseInt("max lifetime", cfg.MaxLifetimeSeconds)
-	if err != nil {
-		return nil, err
+	maxLifetime := cfg.MaxLifetime
+	if credentials != nil {
+		err := driverCfg.Apply(mysqldriver.BeforeConnect(func(_ context.Context, c *mysqldriver.Config) error {
+			c.User, c.Passwd = credentials.Credentials()
+			return nil
+		}))
+		if err != nil {
+			return nil, err
+		}
+		// Connections opened with old credentials must close before their lease ends.
+		maxLifetime = min(maxLifetime, cfg.RotateBefore/2)
 	}
-	maxOpenConn, err := helpers.ParseInt("max open connections", cfg.MaxOpenConnections)
-	if err != nil {
-		return nil, err
-	}
-	maxIdleConn, err := helpers.ParseInt("max idle connections", cfg.IdleConnections)
+
+	connector, err := mysqldriver.NewConnector(driverCfg)
 	if err != nil {
 		return nil, err
 	}
+	db := sql.OpenDB(connector)
 
-	// Datasource connection string: user:password@tcp(host:port)/dbname?parseTime=true
-	dataSourceName := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true", user, password, host, port, dbName)
+	db.SetConnMaxLifetime(maxLifetime)
+	db.SetMaxOpenConns(cfg.MaxOpenConnections)
+	db.SetMaxIdleConns(cfg.IdleConnections)
 
-	db, dbErr := sql.Open("mysql", dataSourceName)
-	if dbErr != nil {
-		return nil, dbErr
+	if credentials != nil {
+		credentials.OnRotate(func() {
+			// Close idle connections that still use the old credentials. Connections in use finish
+			// their work and are closed by the max lifetime.
+			db.SetMaxIdleConns(0)
+			db.SetMaxIdleConns(cfg.IdleConnections)
+		})
 	}
 
-	db.SetConnMaxLifetime(time.Duration(lifeTimeInt) * time.Second)
-	db.SetMaxOpenConns(maxOpenConn)
-	db.SetMaxIdleConns(maxIdleConn)
-
 	if pingErr := db.Ping(); pingErr != nil {
 		return nil, pingErr
 	}
@@ -0,0 +1,31 @@
+package audit
+
+import "time"
+
+// AuditQueryReqDTO holds the query string filters of the audit events endpoint.
+// From and To are RFC 3339 timestamps.
+type AuditQueryReqDTO struct {
+	Actor         string `form:"actor"`
+	Subject       string `form:"subject"`
+	Action        string `form:"action"`
+	Outcome       string `form:"outcome"`
+	CorrelationID string `form:"correlation_id"`
+	From          string `form:"from"`
+	To            string `form:"to"`
+	Limit         int    `form:"limit"`
+	Offset        int    `form:"offset"`
+}
+
+type AuditEventResDTO struct {
+	ID            int64             `json:"id"`
+	OccurredAt    time.Time         `json:"occurred_at"`
+	Actor         string            `json:"actor"`
+	Subject       string            `json:"subject"`
+	Action        string            `json:"action"`
+	Outcome       string            `json:"outcome"`
+	IP            string            `json:"ip"`
+	CorrelationID string            `json:"correlation_id"`
+	Metadata      map[string]string `json:"metadata,omitempty"`
+	Hash          string            `json:"hash"`
+	PrevHash      string            `json:"prev_hash"`
+}
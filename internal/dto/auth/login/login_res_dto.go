@@ -1,10 +1,31 @@
 package login
 
-import dto "github.com/SilentPlaces/basicauth.git/internal/dto/user"
+import (
+	"time"
 
-// LoginResponseDTO represents the response for the auth endpoint.
+	policydto "github.com/SilentPlaces/basicauth.git/internal/dto/policy"
+	dto "github.com/SilentPlaces/basicauth.git/internal/dto/user"
+)
+
+// LoginResponseDTO represents the response for the auth endpoint. When the password must be
+// changed first, it carries a PasswordChangeToken instead of Token and RefreshToken; when policies
+// must be accepted first, a ConsentToken.
 type LoginResponseDTO struct {
 	User         *dto.UserResponseDTO `json:"user"`
-	Token        string               `json:"token"`
-	RefreshToken string               `json:"refreshToken"`
+	Token        string               `json:"token,omitempty"`
+	RefreshToken string               `json:"refreshToken,omitempty"`
+	// PasswordChangeToken only grants PUT /user/password.
+	PasswordChangeToken    string `json:"passwordChangeToken,omitempty"`
+	PasswordChangeRequired bool   `json:"passwordChangeRequired,omitempty"`
+	// PasswordChangeReason is password_expired or password_reset_required.
+	PasswordChangeReason string `json:"passwordChangeReason,omitempty"`
+	// PasswordExpiresAt is set when the password expires within the warning window.
+	PasswordExpiresAt *time.Time `json:"passwordExpiresAt,omitempty"`
+	// ConsentToken only grants POST /user/consents.
+	ConsentToken    string `json:"consentToken,omitempty"`
+	ConsentRequired bool   `json:"consentRequired,omitempty"`
+	// PendingPolicies are the versions to accept.
+	PendingPolicies []*policydto.PolicyDocumentResDTO `json:"pendingPolicies,omitempty"`
+	// AccountDeletionCancelled is set when the login restored an account pending deletion.
+	AccountDeletionCancelled bool `json:"accountDeletionCancelled,omitempty"`
 }
@@ -0,0 +1,13 @@
+package challenge
+
+import "time"
+
+type ChallengeResDTO struct {
+	Provider string `json:"provider"`
+	// SiteKey is set for CAPTCHA providers and configures their widget.
+	SiteKey string `json:"site_key,omitempty"`
+	// Puzzle, Difficulty and ExpiresAt are set for proof-of-work.
+	Puzzle     string     `json:"puzzle,omitempty"`
+	Difficulty int        `json:"difficulty,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+}
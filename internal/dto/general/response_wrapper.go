@@ -6,5 +6,9 @@ type (
 		Data    interface{} `json:"data,omitempty"`
 		Message string      `json:"message,omitempty"`
 		Code    int         `json:"code,omitempty"`
+		// ErrorCode tells errors with the same status apart, e.g. why a signup was rejected.
+		ErrorCode string `json:"errorCode,omitempty"`
+		// Details carries structured information about an error, e.g. password feedback.
+		Details interface{} `json:"details,omitempty"`
 	}
 )
@@ -0,0 +1,32 @@
+package invitation
+
+import "time"
+
+type CreateInvitationReqDTO struct {
+	// Email binds the invitation to one address and sends the invitation mail to it.
+	Email string   `json:"email"`
+	Roles []string `json:"roles"`
+	// MaxUses and ExpiresInHours default to one use and the configured expiry.
+	MaxUses        int    `json:"max_uses"`
+	ExpiresInHours int    `json:"expires_in_hours"`
+	Locale         string `json:"locale"`
+}
+
+type InvitationQueryReqDTO struct {
+	Limit  int `form:"limit"`
+	Offset int `form:"offset"`
+}
+
+type InvitationResDTO struct {
+	ID        string     `json:"id"`
+	Email     string     `json:"email,omitempty"`
+	Roles     []string   `json:"roles"`
+	MaxUses   int        `json:"max_uses"`
+	Uses      int        `json:"uses"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	CreatedBy string     `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	// Code is only returned once, when the invitation is created.
+	Code string `json:"code,omitempty"`
+}
@@ -0,0 +1,19 @@
+package mail
+
+import "time"
+
+type DeadLetterQueryReqDTO struct {
+	Limit int `form:"limit"`
+}
+
+type MailJobResDTO struct {
+	ID             string     `json:"id"`
+	IdempotencyKey string     `json:"idempotency_key"`
+	To             string     `json:"to"`
+	Template       string     `json:"template"`
+	Locale         string     `json:"locale"`
+	Attempts       int        `json:"attempts"`
+	LastError      string     `json:"last_error,omitempty"`
+	EnqueuedAt     time.Time  `json:"enqueued_at"`
+	FailedAt       *time.Time `json:"failed_at,omitempty"`
+}
@@ -0,0 +1,40 @@
+package policy
+
+import "time"
+
+type PublishPolicyReqDTO struct {
+	// Kind is tos or privacy.
+	Kind    string `json:"kind"`
+	Version string `json:"version"`
+	URL     string `json:"url"`
+	// Mandatory versions must be accepted by existing users before they get tokens again.
+	Mandatory bool `json:"mandatory"`
+	// PublishAt schedules the version, RFC 3339; it takes effect right away when empty.
+	PublishAt *time.Time `json:"publish_at"`
+}
+
+type PolicyQueryReqDTO struct {
+	Limit  int `form:"limit"`
+	Offset int `form:"offset"`
+}
+
+// AcceptPoliciesReqDTO carries the accepted version per kind, e.g. {"tos": "2025-03"}.
+type AcceptPoliciesReqDTO struct {
+	AcceptedPolicies map[string]string `json:"accepted_policies"`
+}
+
+type PolicyDocumentResDTO struct {
+	ID          string    `json:"id"`
+	Kind        string    `json:"kind"`
+	Version     string    `json:"version"`
+	URL         string    `json:"url"`
+	Mandatory   bool      `json:"mandatory"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+type ConsentResDTO struct {
+	Kind       string    `json:"kind"`
+	Version    string    `json:"version"`
+	AcceptedAt time.Time `json:"accepted_at"`
+	IP         string    `json:"ip"`
+}
@@ -5,4 +5,11 @@ type RegistrationRequestDTO struct {
 	Name     string `json:"name"`
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	// Locale optionally overrides the Accept-Language header for emails, e.g. "de".
+	Locale string `json:"locale"`
+	// InviteCode is required while registration is invite-only and optional otherwise.
+	InviteCode string `json:"invite_code"`
+	// AcceptedPolicies maps each policy kind in force to the accepted version, e.g.
+	// {"tos": "2025-03", "privacy": "2025-03"}; see GET /policies.
+	AcceptedPolicies map[string]string `json:"accepted_policies"`
 }
@@ -0,0 +1,6 @@
+package verify_mail_req_dto
+
+type VerifyCodeReqDTO struct {
+	Code string `json:"code"`
+	Mail string `json:"email"`
+}
@@ -0,0 +1,60 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+
+	auditdto "github.com/SilentPlaces/basicauth.git/internal/dto/audit"
+	invitationdto "github.com/SilentPlaces/basicauth.git/internal/dto/invitation"
+	maildto "github.com/SilentPlaces/basicauth.git/internal/dto/mail"
+	policydto "github.com/SilentPlaces/basicauth.git/internal/dto/policy"
+	"github.com/SilentPlaces/basicauth.git/internal/models/models"
+)
+
+// DeleteAccountReqDTO confirms an account deletion with the current password.
+type DeleteAccountReqDTO struct {
+	Password string `json:"password"`
+}
+
+type DeleteAccountResDTO struct {
+	// DeletionScheduledAt is when the deletion becomes final; logging in before then cancels it.
+	DeletionScheduledAt time.Time `json:"deletion_scheduled_at"`
+}
+
+// AccountExportDTO is the archive returned by the data export endpoint.
+type AccountExportDTO struct {
+	ExportedAt        time.Time                         `json:"exported_at"`
+	Profile           AccountProfileDTO                 `json:"profile"`
+	Roles             []string                          `json:"roles"`
+	Sessions          []models.Session                  `json:"sessions"`
+	Consents          []policydto.ConsentResDTO         `json:"consents"`
+	LoginHistory      []auditdto.AuditEventResDTO       `json:"login_history"`
+	Activity          []auditdto.AuditEventResDTO       `json:"activity"`
+	Invitations       []*invitationdto.InvitationResDTO `json:"invitations"`
+	WebhookDeliveries []AccountWebhookDeliveryDTO       `json:"webhook_deliveries"`
+	DeadLetterMails   []*maildto.MailJobResDTO          `json:"dead_letter_mails"`
+}
+
+// AccountWebhookDeliveryDTO is a webhook event about the user, with the subscription it was sent to.
+type AccountWebhookDeliveryDTO struct {
+	SubscriptionID string          `json:"subscription_id"`
+	EventID        string          `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+}
+
+type AccountProfileDTO struct {
+	ID                     string     `json:"id"`
+	Name                   string     `json:"name"`
+	Email                  string     `json:"email"`
+	IsVerified             bool       `json:"is_verified"`
+	VerifiedAt             *time.Time `json:"verified_at,omitempty"`
+	CreatedAt              time.Time  `json:"created_at"`
+	Locale                 string     `json:"locale,omitempty"`
+	PasswordChangedAt      time.Time  `json:"password_changed_at"`
+	PasswordChangeRequired bool       `json:"password_change_required"`
+	DeletionScheduledAt    *time.Time `json:"deletion_scheduled_at,omitempty"`
+}
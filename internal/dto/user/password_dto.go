@@ -0,0 +1,14 @@
+package dto
+
+type ChangePasswordReqDTO struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// PasswordFeedbackDTO explains why a password is too easy to guess.
+type PasswordFeedbackDTO struct {
+	// Score is the strength estimate from 0 to 4.
+	Score       int      `json:"score"`
+	Warning     string   `json:"warning,omitempty"`
+	Suggestions []string `json:"suggestions,omitempty"`
+}
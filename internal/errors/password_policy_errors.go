@@ -0,0 +1,32 @@
+package custom_error
+
+// Codes of PasswordPolicyError, returned to clients as errorCode.
+const (
+	PasswordPolicyTooShort       = "password_too_short"
+	PasswordPolicyTooLong        = "password_too_long"
+	PasswordPolicyCharacterClass = "password_character_class"
+	PasswordPolicyTooWeak        = "password_too_weak"
+	PasswordPolicyBreached       = "password_breached"
+	PasswordPolicyReused         = "password_reused"
+)
+
+// PasswordPolicyError represents a password the password policy does not accept. Weak passwords
+// carry the strength estimate to explain what to change.
+type PasswordPolicyError struct {
+	Code        string
+	Message     string
+	Score       int
+	Warning     string
+	Suggestions []string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return e.Message
+}
+
+func NewPasswordPolicyError(code, message string) error {
+	return &PasswordPolicyError{
+		Code:    code,
+		Message: message,
+	}
+}
@@ -0,0 +1,34 @@
+package custom_error
+
+// Codes of SignupPolicyError, returned to clients as errorCode.
+const (
+	SignupPolicyDomainNotAllowed = "domain_not_allowed"
+	SignupPolicyDomainDenied     = "domain_denied"
+	SignupPolicyDisposableEmail  = "disposable_email"
+	SignupPolicyNoMailServer     = "no_mail_server"
+
+	SignupPolicyRegistrationClosed      = "registration_closed"
+	SignupPolicyInvitationRequired      = "invitation_required"
+	SignupPolicyInvitationInvalid       = "invitation_invalid"
+	SignupPolicyInvitationEmailMismatch = "invitation_email_mismatch"
+
+	// SignupPolicyPoliciesNotAccepted is also returned when accepting policies after login.
+	SignupPolicyPoliciesNotAccepted = "policies_not_accepted"
+)
+
+// SignupPolicyError represents an email the signup policy does not accept
+type SignupPolicyError struct {
+	Code    string
+	Message string
+}
+
+func (e *SignupPolicyError) Error() string {
+	return e.Message
+}
+
+func NewSignupPolicyError(code, message string) error {
+	return &SignupPolicyError{
+		Code:    code,
+		Message: message,
+	}
+}
@@ -2,38 +2,82 @@ package di
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"net"
 
 	"github.com/SilentPlaces/basicauth.git/internal/adapters/inbound/http/gin/handlers"
 	ginrouter "github.com/SilentPlaces/basicauth.git/internal/adapters/inbound/http/gin/router"
+	"github.com/SilentPlaces/basicauth.git/internal/adapters/outbound/challenge"
+	"github.com/SilentPlaces/basicauth.git/internal/adapters/outbound/eventbus"
+	"github.com/SilentPlaces/basicauth.git/internal/adapters/outbound/mailtransport"
 	"github.com/SilentPlaces/basicauth.git/internal/application/usecase"
 	"github.com/SilentPlaces/basicauth.git/internal/config"
 	"github.com/SilentPlaces/basicauth.git/internal/db/mysql"
 	redisprovider "github.com/SilentPlaces/basicauth.git/internal/db/redis"
 	healthinfra "github.com/SilentPlaces/basicauth.git/internal/infrastructure/health"
 	"github.com/SilentPlaces/basicauth.git/internal/infrastructure/logging"
+	auditrepo "github.com/SilentPlaces/basicauth.git/internal/repositories/audit"
+	challengerepo "github.com/SilentPlaces/basicauth.git/internal/repositories/challenge"
+	invitationrepo "github.com/SilentPlaces/basicauth.git/internal/repositories/invitation"
+	lockrepo "github.com/SilentPlaces/basicauth.git/internal/repositories/lock"
+	mailqueuerepo "github.com/SilentPlaces/basicauth.git/internal/repositories/mailqueue"
+	outboxrepo "github.com/SilentPlaces/basicauth.git/internal/repositories/outbox"
+	passwordhistoryrepo "github.com/SilentPlaces/basicauth.git/internal/repositories/passwordhistory"
+	policyrepo "github.com/SilentPlaces/basicauth.git/internal/repositories/policy"
 	registrationrepo "github.com/SilentPlaces/basicauth.git/internal/repositories/registration"
+	sessionrepo "github.com/SilentPlaces/basicauth.git/internal/repositories/session"
 	userrepo "github.com/SilentPlaces/basicauth.git/internal/repositories/user"
+	webhookrepo "github.com/SilentPlaces/basicauth.git/internal/repositories/webhook"
+	accountservice "github.com/SilentPlaces/basicauth.git/internal/services/account"
+	auditservice "github.com/SilentPlaces/basicauth.git/internal/services/audit"
 	authservice "github.com/SilentPlaces/basicauth.git/internal/services/auth"
+	breachedpasswordservice "github.com/SilentPlaces/basicauth.git/internal/services/breachedpassword"
 	consulservice "github.com/SilentPlaces/basicauth.git/internal/services/consul"
+	invitationservice "github.com/SilentPlaces/basicauth.git/internal/services/invitation"
 	mailservice "github.com/SilentPlaces/basicauth.git/internal/services/mail"
+	mailqueueservice "github.com/SilentPlaces/basicauth.git/internal/services/mailqueue"
+	outboxservice "github.com/SilentPlaces/basicauth.git/internal/services/outbox"
+	passwordservice "github.com/SilentPlaces/basicauth.git/internal/services/password"
+	policyservice "github.com/SilentPlaces/basicauth.git/internal/services/policy"
 	registrationservice "github.com/SilentPlaces/basicauth.git/internal/services/registration"
+	sessionservice "github.com/SilentPlaces/basicauth.git/internal/services/session"
+	signuppolicyservice "github.com/SilentPlaces/basicauth.git/internal/services/signuppolicy"
 	userservice "github.com/SilentPlaces/basicauth.git/internal/services/users"
 	vaultservice "github.com/SilentPlaces/basicauth.git/internal/services/vault"
+	webhookservice "github.com/SilentPlaces/basicauth.git/internal/services/webhook"
+	appLogger "github.com/SilentPlaces/basicauth.git/internal/shared/logger"
+	validation "github.com/SilentPlaces/basicauth.git/internal/validation/user"
 	"github.com/gin-gonic/gin"
 )
 
+// BackgroundJob is a long running worker started next to the HTTP server. Run returns when ctx is cancelled.
+type BackgroundJob interface {
+	Run(ctx context.Context)
+}
+
 type Container struct {
-	Router *gin.Engine
+	Router         *gin.Engine
+	BackgroundJobs []BackgroundJob
+}
+
+// AuditContainer holds what the audit tooling needs without starting the HTTP stack.
+type AuditContainer struct {
+	AuditService auditservice.AuditService
 }
 
 func BuildContainer() (*Container, error) {
-	appCfg := config.LoadConsulConfig()
+	appCfg := config.LoadAppConfig()
 	logger := logging.NewZeroLogger(appCfg)
 	logger.Info(context.Background(), "building dependency container", nil)
 
 	consul := consulservice.NewConsulService(appCfg)
+	if err := consul.Validate(); err != nil {
+		logger.Error(context.Background(), "configuration is invalid", err, map[string]interface{}{"source": consul.ProviderName()})
+		return nil, err
+	}
 
-	mysqlDB, err := mysql.NewMySQLDb(consul)
+	mysqlDB, credentialRotator, err := newMySQL(consul, logger)
 	if err != nil {
 		logger.Error(context.Background(), "mysql initialization failed", err, nil)
 		return nil, err
@@ -45,40 +89,360 @@ func BuildContainer() (*Container, error) {
 		return nil, err
 	}
 
-	userRepository := userrepo.NewUserRepository(mysqlDB, consul)
-	registrationRepository := registrationrepo.NewRegistrationRepository(redisClient, consul)
+	userRepository, err := userrepo.NewUserRepository(mysqlDB, consul)
+	if err != nil {
+		logger.Error(context.Background(), "user repository initialization failed", err, nil)
+		return nil, err
+	}
+	roleRepository := userrepo.NewRoleRepository(mysqlDB)
+	auditRepository := auditrepo.NewAuditRepository(mysqlDB)
+	auditService := auditservice.NewAuditService(auditRepository)
+	webhookRepository := webhookrepo.NewWebhookRepository(mysqlDB)
+	webhookService := webhookservice.NewWebhookService(webhookRepository)
+	outboxRepository := outboxrepo.NewOutboxRepository(mysqlDB)
+	outboxService := outboxservice.NewOutboxService(outboxRepository)
+	configWatcher, err := consulservice.NewConfigWatcher(consul, logger)
+	if err != nil {
+		logger.Error(context.Background(), "runtime config validation failed", err, nil)
+		return nil, err
+	}
+	registrationCfg := config.NewValue(&configWatcher.Current().Registration)
+	passwordCfg := config.NewValue(&configWatcher.Current().RegistrationPassword)
+	signupPolicyCfg := config.NewValue(&configWatcher.Current().SignupPolicy)
+	challengeRoutesCfg := config.NewValue(&configWatcher.Current().ChallengeRoutes)
+	configWatcher.Subscribe(func(runtimeCfg *config.RuntimeConfig) {
+		registrationCfg.Store(&runtimeCfg.Registration)
+		passwordCfg.Store(&runtimeCfg.RegistrationPassword)
+		signupPolicyCfg.Store(&runtimeCfg.SignupPolicy)
+		challengeRoutesCfg.Store(&runtimeCfg.ChallengeRoutes)
+	})
+
+	registrationRepository := registrationrepo.NewRegistrationRepository(redisClient, registrationCfg)
+	invitationRepository := invitationrepo.NewInvitationRepository(mysqlDB)
+	policyRepository := policyrepo.NewPolicyRepository(mysqlDB)
+	policyService := policyservice.NewPolicyService(policyRepository)
 	userService := userservice.NewUserService(userRepository)
-	registrationService := registrationservice.NewUserRegistrationService(registrationRepository, userRepository)
-	authService := authservice.NewAuthService(vaultservice.NewSecureVaultService())
+	registrationService := registrationservice.NewUserRegistrationService(
+		registrationRepository,
+		userRepository,
+		roleRepository,
+		invitationRepository,
+		outboxRepository,
+		policyRepository,
+		mysql.NewTransactor(mysqlDB),
+		registrationCfg,
+	)
+	vaultService, err := vaultservice.NewSecureVaultService(logger)
+	if err != nil {
+		logger.Error(context.Background(), "vault initialization failed", err, nil)
+		return nil, err
+	}
+	signerCfg, err := consul.GetTokenSignerConfig()
+	if err != nil {
+		logger.Error(context.Background(), "token signer config retrieval failed", err, nil)
+		return nil, err
+	}
+	var tokenSigner authservice.TokenSigner
+	var transitSigner *authservice.TransitSigner
+	if signerCfg.Signer == config.TokenSignerTransit {
+		transitSigner = authservice.NewTransitSigner(vaultService, signerCfg, logger)
+		tokenSigner = transitSigner
+	} else {
+		tokenSigner, err = authservice.NewHMACSignerFromVault(vaultService)
+		if err != nil {
+			logger.Error(context.Background(), "jwt secret retrieval failed", err, nil)
+			return nil, err
+		}
+	}
+	authService := authservice.NewAuthService(tokenSigner)
+
+	mailTransportCfg, err := consul.GetMailTransportConfig()
+	if err != nil {
+		logger.Error(context.Background(), "mail transport config retrieval failed", err, nil)
+		return nil, err
+	}
+	mailTransport, err := mailtransport.NewMailTransport(mailTransportCfg)
+	if err != nil {
+		logger.Error(context.Background(), "mail transport initialization failed", err, nil)
+		return nil, err
+	}
+
+	var dkimSigner *mailservice.DKIMSigner
+	dkimCfg, err := vaultService.GetDKIMConfig()
+	switch {
+	case errors.Is(err, vaultservice.ErrDKIMNotConfigured):
+		logger.Warn(context.Background(), "dkim secret not found, outgoing mail is not signed", nil)
+	case err != nil:
+		logger.Error(context.Background(), "dkim config retrieval failed", err, nil)
+		return nil, err
+	default:
+		dkimSigner, err = mailservice.NewDKIMSigner(dkimCfg.Domain, dkimCfg.Selector, dkimCfg.PrivateKey)
+		if err != nil {
+			logger.Error(context.Background(), "dkim signer initialization failed", err, nil)
+			return nil, err
+		}
+	}
 
-	mailSvc, err := mailservice.NewMailService(consul, appCfg)
+	mailSvc, err := mailservice.NewMailService(consul, mailTransport, dkimSigner)
 	if err != nil {
 		logger.Error(context.Background(), "mail service initialization failed", err, nil)
 		return nil, err
 	}
 
+	mailQueueCfg, err := consul.GetMailQueueConfig()
+	if err != nil {
+		logger.Error(context.Background(), "mail queue config retrieval failed", err, nil)
+		return nil, err
+	}
+	mailQueueRepository := mailqueuerepo.NewMailQueueRepository(redisClient)
+	mailQueueService := mailqueueservice.NewMailQueueService(mailQueueRepository, mailQueueCfg)
+
 	generalCfg, err := consul.GetGeneralConfig()
 	if err != nil {
 		logger.Error(context.Background(), "general config retrieval failed", err, nil)
 		return nil, err
 	}
 
+	webhookCfg, err := consul.GetWebhookConfig()
+	if err != nil {
+		logger.Error(context.Background(), "webhook config retrieval failed", err, nil)
+		return nil, err
+	}
+
+	emailNormalizationCfg, err := consul.GetEmailNormalizationConfig()
+	if err != nil {
+		logger.Error(context.Background(), "email normalization config retrieval failed", err, nil)
+		return nil, err
+	}
+	emailNormalizer := validation.NewEmailNormalizer(emailNormalizationCfg)
+
+	signupPolicyService, err := signuppolicyservice.NewSignupPolicyService(signupPolicyCfg, net.DefaultResolver, logger)
+	if err != nil {
+		logger.Error(context.Background(), "signup policy initialization failed", err, nil)
+		return nil, err
+	}
+
+	breachedPasswordCfg, err := consul.GetBreachedPasswordConfig()
+	if err != nil {
+		logger.Error(context.Background(), "breached password config retrieval failed", err, nil)
+		return nil, err
+	}
+	breachedPasswordService, err := breachedpasswordservice.NewBreachedPasswordService(breachedPasswordCfg, logger)
+	if err != nil {
+		logger.Error(context.Background(), "breached password screening initialization failed", err, nil)
+		return nil, err
+	}
+	sessionService := sessionservice.NewSessionService(sessionrepo.NewSessionRepository(redisClient, authservice.RefreshTokenExpireTime))
+	passwordService := passwordservice.NewPasswordService(
+		userRepository,
+		passwordhistoryrepo.NewPasswordHistoryRepository(mysqlDB),
+		breachedPasswordService,
+		outboxRepository,
+		mysql.NewTransactor(mysqlDB),
+		sessionService,
+		passwordCfg,
+	)
+
+	invitationCfg, err := consul.GetInvitationConfig()
+	if err != nil {
+		logger.Error(context.Background(), "invitation config retrieval failed", err, nil)
+		return nil, err
+	}
+	invitationService := invitationservice.NewInvitationService(invitationRepository, mysql.NewTransactor(mysqlDB), invitationCfg)
+
+	challengeCfg, err := consul.GetChallengeConfig()
+	if err != nil {
+		logger.Error(context.Background(), "challenge config retrieval failed", err, nil)
+		return nil, err
+	}
+	challengeVerifier, err := challenge.NewChallengeVerifier(challengeCfg, challengerepo.NewChallengeRepository(redisClient))
+	if err != nil {
+		logger.Error(context.Background(), "challenge verifier initialization failed", err, nil)
+		return nil, err
+	}
+
+	accountDeletionCfg, err := consul.GetAccountDeletionConfig()
+	if err != nil {
+		logger.Error(context.Background(), "account deletion config retrieval failed", err, nil)
+		return nil, err
+	}
+	accountService := accountservice.NewAccountService(
+		userRepository,
+		roleRepository,
+		auditRepository,
+		invitationRepository,
+		policyRepository,
+		webhookRepository,
+		mailQueueRepository,
+		sessionService,
+		accountDeletionCfg,
+	)
+
+	reaperCfg, err := consul.GetReaperConfig()
+	if err != nil {
+		logger.Error(context.Background(), "reaper config retrieval failed", err, nil)
+		return nil, err
+	}
+
+	eventBusCfg, err := consul.GetEventBusConfig()
+	if err != nil {
+		logger.Error(context.Background(), "event bus config retrieval failed", err, nil)
+		return nil, err
+	}
+	eventBus, err := eventbus.NewEventBus(eventBusCfg, redisClient)
+	if err != nil {
+		logger.Error(context.Background(), "event bus initialization failed", err, nil)
+		return nil, err
+	}
+
 	registrationUseCase := usecase.NewRegistrationUseCase(
 		mailSvc,
+		mailQueueService,
 		registrationService,
-		consul.GetRegistrationConfig(),
-		consul.GetRegistrationPasswordConfig(),
+		registrationCfg,
+		passwordService,
 		generalCfg,
+		emailNormalizer,
+		signupPolicyService,
+		invitationService,
+		policyService,
+		auditService,
+		webhookService,
+		logger,
+	)
+	userUseCase := usecase.NewUserUseCase(userService, passwordService, accountService, auditService, logger)
+	authUseCase := usecase.NewAuthUseCase(userService, authService, passwordService, policyService, sessionService, accountService, auditService, webhookService, outboxService, emailNormalizer, logger)
+	auditUseCase := usecase.NewAuditUseCase(auditService, auditService, logger)
+	webhookUseCase := usecase.NewWebhookUseCase(webhookService, logger)
+	mailQueueUseCase := usecase.NewMailQueueUseCase(mailQueueService, logger)
+	policyUseCase := usecase.NewPolicyUseCase(policyService, auditService, logger)
+	challengeUseCase := usecase.NewChallengeUseCase(challengeVerifier, challengeRoutesCfg, logger)
+	invitationUseCase := usecase.NewInvitationUseCase(
+		invitationService,
+		mailSvc,
+		mailQueueService,
+		registrationCfg,
+		generalCfg,
+		emailNormalizer,
+		auditService,
 		logger,
 	)
-	userUseCase := usecase.NewUserUseCase(userService, logger)
-	authUseCase := usecase.NewAuthUseCase(userService, authService, logger)
 
-	userHandler := handlers.NewUserHandler(userUseCase, authUseCase, logger)
-	registrationHandler := handlers.NewRegistrationHandler(registrationUseCase, logger)
-	healthHandler := handlers.NewHealthHandler(healthinfra.NewChecker(mysqlDB, redisClient), logger)
+	userHandler := handlers.NewUserHandler(userUseCase, authUseCase, challengeUseCase, logger)
+	registrationHandler := handlers.NewRegistrationHandler(registrationUseCase, challengeUseCase, logger)
+	healthHandler := handlers.NewHealthHandler(healthinfra.NewChecker(mysqlDB, redisClient, vaultService), logger)
+	auditHandler := handlers.NewAuditHandler(auditUseCase, logger)
+	webhookHandler := handlers.NewWebhookHandler(webhookUseCase, logger)
+	mailQueueHandler := handlers.NewMailQueueHandler(mailQueueUseCase, logger)
+	invitationHandler := handlers.NewInvitationHandler(invitationUseCase, logger)
+	challengeHandler := handlers.NewChallengeHandler(challengeUseCase, logger)
+	policyHandler := handlers.NewPolicyHandler(policyUseCase, logger)
+
+	router, err := ginrouter.NewRouter(
+		userHandler,
+		registrationHandler,
+		healthHandler,
+		auditHandler,
+		webhookHandler,
+		mailQueueHandler,
+		invitationHandler,
+		challengeHandler,
+		policyHandler,
+		authService,
+		sessionService,
+		roleRepository,
+		generalCfg.TrustedProxies,
+		logger,
+	)
+	if err != nil {
+		logger.Error(context.Background(), "router initialization failed", err, nil)
+		return nil, err
+	}
+
+	backgroundJobs := []BackgroundJob{
+		configWatcher,
+		vaultService,
+		webhookservice.NewDispatcher(webhookRepository, webhookCfg, logger),
+		mailqueueservice.NewWorker(mailQueueRepository, mailSvc, mailQueueCfg, logger),
+	}
+	if transitSigner != nil {
+		backgroundJobs = append(backgroundJobs, transitSigner)
+	}
+	if credentialRotator != nil {
+		backgroundJobs = append(backgroundJobs, credentialRotator)
+	}
+	if eventBus != nil {
+		backgroundJobs = append(backgroundJobs, outboxservice.NewRelay(outboxRepository, eventBus, eventBusCfg, logger))
+	}
+	backgroundJobs = append(backgroundJobs, accountservice.NewDeletionReaper(
+		userRepository,
+		invitationRepository,
+		outboxRepository,
+		registrationRepository,
+		webhookRepository,
+		mailQueueRepository,
+		lockrepo.NewLockRepository(redisClient),
+		mysql.NewTransactor(mysqlDB),
+		sessionService,
+		auditService,
+		webhookService,
+		accountDeletionCfg,
+		logger,
+	))
+	if reaperCfg.Enabled {
+		backgroundJobs = append(backgroundJobs, registrationservice.NewUnverifiedReaper(
+			userRepository,
+			registrationRepository,
+			lockrepo.NewLockRepository(redisClient),
+			reaperCfg,
+			logger,
+		))
+	}
 
-	router := ginrouter.NewRouter(userHandler, registrationHandler, healthHandler, authService, logger)
 	logger.Info(context.Background(), "dependency container built", nil)
-	return &Container{Router: router}, nil
+	return &Container{Router: router, BackgroundJobs: backgroundJobs}, nil
+}
+
+// newMySQL opens the MySQL pool with static credentials, or with dynamic ones from Vault when
+// configured. The returned rotator keeps dynamic credentials fresh and is nil for static ones.
+func newMySQL(consul consulservice.ConsulService, logger appLogger.Logger) (*sql.DB, BackgroundJob, error) {
+	mysqlCfg, err := consul.GetMySQLConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	if mysqlCfg.CredentialSource != config.MySQLCredentialsVault {
+		db, err := mysql.NewMySQLDb(consul, nil)
+		return db, nil, err
+	}
+
+	vaultService, err := vaultservice.NewSecureVaultService(logger)
+	if err != nil {
+		return nil, nil, err
+	}
+	rotator, err := vaultservice.NewDatabaseCredentialRotator(vaultService, mysqlCfg.VaultMount, mysqlCfg.VaultRole, mysqlCfg.RotateBefore, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+	db, err := mysql.NewMySQLDb(consul, rotator)
+	if err != nil {
+		return nil, nil, err
+	}
+	return db, rotator, nil
+}
+
+// BuildAuditContainer wires only Consul, MySQL and the audit service.
+func BuildAuditContainer() (*AuditContainer, error) {
+	appCfg := config.LoadAppConfig()
+	consul := consulservice.NewConsulService(appCfg)
+
+	// Credentials from Vault stay valid for at least the rotation window, long enough for a
+	// verification run, so the rotator is not started.
+	mysqlDB, _, err := newMySQL(consul, logging.NewZeroLogger(appCfg))
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuditContainer{
+		AuditService: auditservice.NewAuditService(auditrepo.NewAuditRepository(mysqlDB)),
+	}, nil
 }
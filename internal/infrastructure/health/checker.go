@@ -13,13 +13,19 @@ type Checker interface {
 	Readiness(ctx context.Context) (map[string]string, bool)
 }
 
+// StatusReporter is a dependency that tracks its own health, such as the Vault token renewer.
+type StatusReporter interface {
+	Health() error
+}
+
 type checker struct {
 	mysql *sql.DB
 	redis *redis.Client
+	vault StatusReporter
 }
 
-func NewChecker(mysql *sql.DB, redis *redis.Client) Checker {
-	return &checker{mysql: mysql, redis: redis}
+func NewChecker(mysql *sql.DB, redis *redis.Client, vault StatusReporter) Checker {
+	return &checker{mysql: mysql, redis: redis, vault: vault}
 }
 
 func (c *checker) Liveness() map[string]string {
@@ -31,6 +37,7 @@ func (c *checker) Readiness(ctx context.Context) (map[string]string, bool) {
 		"status": "ok",
 		"mysql":  "ok",
 		"redis":  "ok",
+		"vault":  "ok",
 	}
 
 	mysqlCtx, mysqlCancel := context.WithTimeout(ctx, 2*time.Second)
@@ -47,5 +54,10 @@ func (c *checker) Readiness(ctx context.Context) (map[string]string, bool) {
 		status["redis"] = "down"
 	}
 
+	if err := c.vault.Health(); err != nil {
+		status["status"] = "degraded"
+		status["vault"] = "down"
+	}
+
 	return status, status["status"] == "ok"
 }
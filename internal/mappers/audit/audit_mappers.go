@@ -0,0 +1,32 @@
+package mapper
+
+import (
+	"encoding/json"
+
+	auditdto "github.com/SilentPlaces/basicauth.git/internal/dto/audit"
+	"github.com/SilentPlaces/basicauth.git/internal/models/models"
+)
+
+func MapAuditEventsToResDTO(events []models.AuditEvent) []auditdto.AuditEventResDTO {
+	result := make([]auditdto.AuditEventResDTO, 0, len(events))
+	for _, e := range events {
+		var metadata map[string]string
+		if e.Metadata != "" {
+			_ = json.Unmarshal([]byte(e.Metadata), &metadata)
+		}
+		result = append(result, auditdto.AuditEventResDTO{
+			ID:            e.ID,
+			OccurredAt:    e.OccurredAt,
+			Actor:         e.Actor,
+			Subject:       e.Subject,
+			Action:        e.Action,
+			Outcome:       e.Outcome,
+			IP:            e.IP,
+			CorrelationID: e.CorrelationID,
+			Metadata:      metadata,
+			Hash:          e.Hash,
+			PrevHash:      e.PrevHash,
+		})
+	}
+	return result
+}
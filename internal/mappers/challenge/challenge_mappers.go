@@ -0,0 +1,20 @@
+package mapper
+
+import (
+	challengedto "github.com/SilentPlaces/basicauth.git/internal/dto/challenge"
+	challengeservice "github.com/SilentPlaces/basicauth.git/internal/services/challenge"
+)
+
+func MapChallengeToResDTO(c *challengeservice.Challenge) *challengedto.ChallengeResDTO {
+	res := &challengedto.ChallengeResDTO{
+		Provider:   c.Provider,
+		SiteKey:    c.SiteKey,
+		Puzzle:     c.Puzzle,
+		Difficulty: c.Difficulty,
+	}
+	if !c.ExpiresAt.IsZero() {
+		expiresAt := c.ExpiresAt
+		res.ExpiresAt = &expiresAt
+	}
+	return res
+}
@@ -0,0 +1,36 @@
+package mapper
+
+import (
+	invitationdto "github.com/SilentPlaces/basicauth.git/internal/dto/invitation"
+	"github.com/SilentPlaces/basicauth.git/internal/models/models"
+)
+
+func MapInvitationToResDTO(i *models.Invitation, code string) *invitationdto.InvitationResDTO {
+	res := &invitationdto.InvitationResDTO{
+		ID:        i.ID,
+		Email:     i.Email,
+		Roles:     i.Roles,
+		MaxUses:   i.MaxUses,
+		Uses:      i.Uses,
+		ExpiresAt: i.ExpiresAt,
+		CreatedBy: i.CreatedBy,
+		CreatedAt: i.CreatedAt,
+		Code:      code,
+	}
+	if res.Roles == nil {
+		res.Roles = []string{}
+	}
+	if i.RevokedAt.Valid {
+		revokedAt := i.RevokedAt.Time
+		res.RevokedAt = &revokedAt
+	}
+	return res
+}
+
+func MapInvitationsToResDTO(invitations []models.Invitation) []*invitationdto.InvitationResDTO {
+	result := make([]*invitationdto.InvitationResDTO, 0, len(invitations))
+	for i := range invitations {
+		result = append(result, MapInvitationToResDTO(&invitations[i], ""))
+	}
+	return result
+}
@@ -0,0 +1,29 @@
+package mapper
+
+import (
+	maildto "github.com/SilentPlaces/basicauth.git/internal/dto/mail"
+	"github.com/SilentPlaces/basicauth.git/internal/models/models"
+)
+
+// MapMailJobToResDTO leaves out the template data, which holds verification links.
+func MapMailJobToResDTO(job *models.MailJob) *maildto.MailJobResDTO {
+	return &maildto.MailJobResDTO{
+		ID:             job.ID,
+		IdempotencyKey: job.IdempotencyKey,
+		To:             job.To,
+		Template:       job.Template,
+		Locale:         job.Locale,
+		Attempts:       job.Attempts,
+		LastError:      job.LastError,
+		EnqueuedAt:     job.EnqueuedAt,
+		FailedAt:       job.FailedAt,
+	}
+}
+
+func MapMailJobsToResDTO(jobs []models.MailJob) []*maildto.MailJobResDTO {
+	result := make([]*maildto.MailJobResDTO, 0, len(jobs))
+	for i := range jobs {
+		result = append(result, MapMailJobToResDTO(&jobs[i]))
+	}
+	return result
+}
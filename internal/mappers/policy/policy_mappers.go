@@ -0,0 +1,38 @@
+package mapper
+
+import (
+	policydto "github.com/SilentPlaces/basicauth.git/internal/dto/policy"
+	"github.com/SilentPlaces/basicauth.git/internal/models/models"
+)
+
+func MapPolicyDocumentToResDTO(d *models.PolicyDocument) *policydto.PolicyDocumentResDTO {
+	return &policydto.PolicyDocumentResDTO{
+		ID:          d.ID,
+		Kind:        d.Kind,
+		Version:     d.Version,
+		URL:         d.URL,
+		Mandatory:   d.Mandatory,
+		PublishedAt: d.PublishedAt,
+	}
+}
+
+func MapPolicyDocumentsToResDTO(documents []models.PolicyDocument) []*policydto.PolicyDocumentResDTO {
+	result := make([]*policydto.PolicyDocumentResDTO, 0, len(documents))
+	for i := range documents {
+		result = append(result, MapPolicyDocumentToResDTO(&documents[i]))
+	}
+	return result
+}
+
+func MapConsentsToResDTO(consents []models.UserConsent) []policydto.ConsentResDTO {
+	result := make([]policydto.ConsentResDTO, 0, len(consents))
+	for _, c := range consents {
+		result = append(result, policydto.ConsentResDTO{
+			Kind:       c.Kind,
+			Version:    c.Version,
+			AcceptedAt: c.AcceptedAt,
+			IP:         c.IP,
+		})
+	}
+	return result
+}
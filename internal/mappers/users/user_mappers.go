@@ -1,8 +1,17 @@
 package mapper
 
 import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
 	"github.com/SilentPlaces/basicauth.git/internal/dto/auth/refresh_token"
 	dto "github.com/SilentPlaces/basicauth.git/internal/dto/user"
+	custom_error "github.com/SilentPlaces/basicauth.git/internal/errors"
+	auditmapper "github.com/SilentPlaces/basicauth.git/internal/mappers/audit"
+	invitationmapper "github.com/SilentPlaces/basicauth.git/internal/mappers/invitation"
+	mailmapper "github.com/SilentPlaces/basicauth.git/internal/mappers/mail"
+	policymapper "github.com/SilentPlaces/basicauth.git/internal/mappers/policy"
 	"github.com/SilentPlaces/basicauth.git/internal/models/models"
 	service "github.com/SilentPlaces/basicauth.git/internal/services/auth"
 )
@@ -14,9 +23,71 @@ func MapUserToUserResponse(u *models.User) *dto.UserResponseDTO {
 	}
 }
 
+// MapPasswordPolicyErrorToFeedbackDTO returns the strength feedback of err, or nil when err is
+// not about a weak password.
+func MapPasswordPolicyErrorToFeedbackDTO(err *custom_error.PasswordPolicyError) *dto.PasswordFeedbackDTO {
+	if err.Code != custom_error.PasswordPolicyTooWeak {
+		return nil
+	}
+	return &dto.PasswordFeedbackDTO{
+		Score:       err.Score,
+		Warning:     err.Warning,
+		Suggestions: err.Suggestions,
+	}
+}
+
 func MapTokenToRefreshTokenResDTO(token *service.Tokens) *refresh_token.RefreshTokenResDTO {
 	return &refresh_token.RefreshTokenResDTO{
 		RefreshToken: token.RefreshToken,
 		Token:        token.AccessToken,
 	}
 }
+
+func MapAccountExportToDTO(e *models.AccountExport) *dto.AccountExportDTO {
+	return &dto.AccountExportDTO{
+		ExportedAt: e.ExportedAt,
+		Profile: dto.AccountProfileDTO{
+			ID:                     e.User.ID,
+			Name:                   e.User.Name,
+			Email:                  e.User.Email,
+			IsVerified:             e.User.IsVerified,
+			VerifiedAt:             nullTimePtr(e.User.VerifiedAt),
+			CreatedAt:              e.User.CreatedAt,
+			Locale:                 e.User.Locale,
+			PasswordChangedAt:      e.User.PasswordChangedAt,
+			PasswordChangeRequired: e.User.PasswordChangeRequired,
+			DeletionScheduledAt:    nullTimePtr(e.User.DeletionScheduledAt),
+		},
+		Roles:             e.Roles,
+		Sessions:          e.Sessions,
+		Consents:          policymapper.MapConsentsToResDTO(e.Consents),
+		LoginHistory:      auditmapper.MapAuditEventsToResDTO(e.LoginHistory),
+		Activity:          auditmapper.MapAuditEventsToResDTO(e.Activity),
+		Invitations:       invitationmapper.MapInvitationsToResDTO(e.Invitations),
+		WebhookDeliveries: mapAccountWebhookDeliveries(e.WebhookDeliveries),
+		DeadLetterMails:   mailmapper.MapMailJobsToResDTO(e.DeadLetterMails),
+	}
+}
+
+func mapAccountWebhookDeliveries(deliveries []models.WebhookDelivery) []dto.AccountWebhookDeliveryDTO {
+	result := make([]dto.AccountWebhookDeliveryDTO, 0, len(deliveries))
+	for _, d := range deliveries {
+		result = append(result, dto.AccountWebhookDeliveryDTO{
+			SubscriptionID: d.SubscriptionID,
+			EventID:        d.EventID,
+			EventType:      d.EventType,
+			Payload:        json.RawMessage(d.Payload),
+			Status:         d.Status,
+			CreatedAt:      d.CreatedAt,
+			DeliveredAt:    nullTimePtr(d.DeliveredAt),
+		})
+	}
+	return result
+}
+
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
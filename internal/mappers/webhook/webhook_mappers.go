@@ -0,0 +1,54 @@
+package mapper
+
+import (
+	webhookdto "github.com/SilentPlaces/basicauth.git/internal/dto/webhook"
+	"github.com/SilentPlaces/basicauth.git/internal/models/models"
+)
+
+func MapSubscriptionToResDTO(s *models.WebhookSubscription, includeSecret bool) *webhookdto.SubscriptionResDTO {
+	res := &webhookdto.SubscriptionResDTO{
+		ID:         s.ID,
+		URL:        s.URL,
+		EventTypes: s.EventTypes,
+		Active:     s.Active,
+		CreatedAt:  s.CreatedAt,
+	}
+	if includeSecret {
+		res.Secret = s.Secret
+	}
+	return res
+}
+
+func MapSubscriptionsToResDTO(subscriptions []models.WebhookSubscription) []*webhookdto.SubscriptionResDTO {
+	result := make([]*webhookdto.SubscriptionResDTO, 0, len(subscriptions))
+	for i := range subscriptions {
+		result = append(result, MapSubscriptionToResDTO(&subscriptions[i], false))
+	}
+	return result
+}
+
+func MapDeliveriesToResDTO(deliveries []models.WebhookDelivery) []webhookdto.DeliveryResDTO {
+	result := make([]webhookdto.DeliveryResDTO, 0, len(deliveries))
+	for _, d := range deliveries {
+		res := webhookdto.DeliveryResDTO{
+			ID:            d.ID,
+			EventID:       d.EventID,
+			EventType:     d.EventType,
+			Status:        d.Status,
+			Attempts:      d.Attempts,
+			NextAttemptAt: d.NextAttemptAt,
+			LastError:     d.LastError.String,
+			CreatedAt:     d.CreatedAt,
+		}
+		if d.LastStatusCode.Valid {
+			code := d.LastStatusCode.Int64
+			res.LastStatusCode = &code
+		}
+		if d.DeliveredAt.Valid {
+			deliveredAt := d.DeliveredAt.Time
+			res.DeliveredAt = &deliveredAt
+		}
+		result = append(result, res)
+	}
+	return result
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+type (
+	// AccountExport is everything stored about a user, as handed out on a data export request.
+	AccountExport struct {
+		ExportedAt time.Time
+		User       User
+		Roles      []string
+		Sessions   []Session
+		// Consents holds the accepted policy versions, newest first.
+		Consents []UserConsent
+		// LoginHistory holds the user's login events, newest first.
+		LoginHistory []AuditEvent
+		// Activity holds the other audit events the user is the actor or subject of, newest first.
+		Activity    []AuditEvent
+		Invitations []Invitation
+		// WebhookDeliveries holds the webhook events about the user, newest first.
+		WebhookDeliveries []WebhookDelivery
+		// DeadLetterMails holds the mails to the user that could not be sent, oldest first.
+		DeadLetterMails []MailJob
+	}
+)
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Audit actions recorded by the application.
+const (
+	AuditActionSignup             = "user.signup"
+	AuditActionVerifyEmail        = "user.verify_email"
+	AuditActionResendVerification = "user.resend_verification"
+	AuditActionChangePassword     = "user.change_password"
+	// AuditActionRequirePasswordChange is an admin forcing a user to set a new password.
+	AuditActionRequirePasswordChange = "user.require_password_change"
+	AuditActionDeletionRequest       = "user.deletion_request"
+	AuditActionDeletionCancel        = "user.deletion_cancel"
+	AuditActionDelete                = "user.delete"
+	AuditActionExport                = "user.export"
+	AuditActionConsent               = "user.consent"
+	AuditActionLogin                 = "auth.login"
+	AuditActionRefreshToken          = "auth.refresh_token"
+	AuditActionAuditQuery            = "audit.query"
+	AuditActionInvitationCreate      = "invitation.create"
+	AuditActionInvitationRevoke      = "invitation.revoke"
+	AuditActionPolicyPublish         = "policy.publish"
+)
+
+// Audit outcomes.
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+)
+
+// AuditGenesisHash is the previous hash of the first event in the chain.
+const AuditGenesisHash = "0000000000000000000000000000000000000000000000000000000000000000"
+
+type (
+	// AuditEvent is a single row of the append-only audit log.
+	AuditEvent struct {
+		ID            int64
+		OccurredAt    time.Time
+		Actor         string
+		Subject       string
+		Action        string
+		Outcome       string
+		IP            string
+		CorrelationID string
+		Metadata      string
+		PayloadHash   string
+		PrevHash      string
+		Hash          string
+		// RedactedAt is set once the personal data of the event was removed. Its payload no
+		// longer matches PayloadHash.
+		RedactedAt sql.NullTime
+	}
+
+	// AuditEventFilter narrows down audit log queries. Empty fields are ignored.
+	AuditEventFilter struct {
+		Actor         string
+		Subject       string
+		Action        string
+		Outcome       string
+		CorrelationID string
+		From          *time.Time
+		To            *time.Time
+		Limit         int
+		Offset        int
+	}
+)
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Invitation lets people sign up while registration is invite-only. Only the hash of its code is
+// stored.
+type Invitation struct {
+	ID       string
+	CodeHash string
+	// Email is the canonical email the invitation is bound to, empty for an open invitation.
+	Email string
+	// Roles are assigned to the users who sign up with the invitation.
+	Roles     []string
+	MaxUses   int
+	Uses      int
+	ExpiresAt time.Time
+	CreatedBy string
+	CreatedAt time.Time
+	RevokedAt sql.NullTime
+}
+
+// Usable reports whether the invitation can still be redeemed at now.
+func (i Invitation) Usable(now time.Time) bool {
+	return !i.RevokedAt.Valid && i.Uses < i.MaxUses && now.Before(i.ExpiresAt)
+}
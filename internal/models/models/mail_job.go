@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// MailJob is a queued templated email. ID is the Redis stream entry ID and changes whenever the
+// job is re-added to a stream (retry, replay); IdempotencyKey stays the same.
+type MailJob struct {
+	ID             string                 `json:"-"`
+	IdempotencyKey string                 `json:"idempotency_key"`
+	From           string                 `json:"from"`
+	To             string                 `json:"to"`
+	Locale         string                 `json:"locale"`
+	Template       string                 `json:"template"`
+	Data           map[string]interface{} `json:"data"`
+	Attempts       int                    `json:"attempts"`
+	LastError      string                 `json:"last_error,omitempty"`
+	EnqueuedAt     time.Time              `json:"enqueued_at"`
+	FailedAt       *time.Time             `json:"failed_at,omitempty"`
+	// Sensitive jobs carry secrets in Data, such as verification links. Their Data is dropped when
+	// they are dead-lettered.
+	Sensitive bool `json:"sensitive,omitempty"`
+}
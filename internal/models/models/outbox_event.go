@@ -0,0 +1,34 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Domain event types written to the outbox.
+const (
+	EventUserRegistered  = "UserRegistered"
+	EventEmailVerified   = "EmailVerified"
+	EventUserLoggedIn    = "UserLoggedIn"
+	EventPasswordChanged = "PasswordChanged"
+	EventUserDeleted     = "UserDeleted"
+)
+
+// AggregateUser is the aggregate type of all user related events.
+const AggregateUser = "user"
+
+type (
+	// OutboxEvent is a domain event stored in the same transaction as the state change
+	// that produced it. The relay publishes it to the event bus afterwards.
+	OutboxEvent struct {
+		ID            int64
+		EventID       string
+		AggregateType string
+		AggregateID   string
+		EventType     string
+		Payload       string
+		OccurredAt    time.Time
+		PublishedAt   sql.NullTime
+		Attempts      int
+	}
+)
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// Kinds of PolicyDocument.
+const (
+	PolicyKindTerms   = "tos"
+	PolicyKindPrivacy = "privacy"
+)
+
+// PolicyKinds lists the policy document kinds users accept.
+var PolicyKinds = []string{PolicyKindTerms, PolicyKindPrivacy}
+
+type (
+	// PolicyDocument is one version of the terms of service or the privacy policy. The version in
+	// force for a kind is the one published last.
+	PolicyDocument struct {
+		ID      string
+		Kind    string
+		Version string
+		URL     string
+		// Mandatory versions must be accepted before existing users get a session again.
+		Mandatory   bool
+		PublishedAt time.Time
+		CreatedBy   string
+		CreatedAt   time.Time
+	}
+
+	// UserConsent records that a user accepted a policy document version.
+	UserConsent struct {
+		UserID     string
+		DocumentID string
+		Kind       string
+		Version    string
+		AcceptedAt time.Time
+		// IP is the client address the document was accepted from. It is only as reliable as the
+		// trusted proxy configuration and does not prove who accepted.
+		IP string
+	}
+)
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+type (
+	// Session is one login and the refresh tokens issued from it. Access tokens are not checked
+	// against it.
+	Session struct {
+		ID         string    `json:"id"`
+		IP         string    `json:"ip"`
+		CreatedAt  time.Time `json:"created_at"`
+		LastUsedAt time.Time `json:"last_used_at"`
+	}
+)
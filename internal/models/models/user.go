@@ -8,12 +8,23 @@ import (
 // User is structure for a single user in database
 type (
 	User struct {
-		ID         string
-		Name       string
-		Email      string
-		Password   string
-		IsVerified bool
-		VerifiedAt sql.NullTime
-		CreatedAt  time.Time
+		ID    string
+		Name  string
+		Email string
+		// CanonicalEmail identifies the account, see validation.EmailNormalizer.
+		CanonicalEmail string
+		Password       string
+		// PasswordChangedAt is when the password was last set; it starts the password's max age.
+		PasswordChangedAt time.Time
+		// PasswordChangeRequired is set by an admin to force a new password on the next login.
+		PasswordChangeRequired bool
+		IsVerified             bool
+		VerifiedAt             sql.NullTime
+		CreatedAt              time.Time
+		// Locale is the preferred language for emails, empty when unknown.
+		Locale string
+		// DeletionScheduledAt is when a requested deletion becomes final, NULL when the account
+		// is not being deleted.
+		DeletionScheduledAt sql.NullTime
 	}
 )
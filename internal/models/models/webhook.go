@@ -0,0 +1,67 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Webhook event types sent to subscribers.
+const (
+	WebhookEventUserSignedUp = "user.signed_up"
+	WebhookEventUserVerified = "user.verified"
+	WebhookEventUserLoggedIn = "user.logged_in"
+	WebhookEventUserDeleted  = "user.deleted"
+)
+
+// WebhookEventTypes lists every event type a subscription may ask for.
+var WebhookEventTypes = []string{
+	WebhookEventUserSignedUp,
+	WebhookEventUserVerified,
+	WebhookEventUserLoggedIn,
+	WebhookEventUserDeleted,
+}
+
+// Webhook delivery states.
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryDead      = "dead"
+)
+
+type (
+	WebhookSubscription struct {
+		ID         string
+		URL        string
+		EventTypes []string
+		Secret     string
+		Active     bool
+		CreatedAt  time.Time
+	}
+
+	WebhookDelivery struct {
+		ID             string
+		SubscriptionID string
+		EventID        string
+		EventType      string
+		// Subject is the user ID or email the event is about, empty for other events.
+		Subject        string
+		Payload        string
+		Status         string
+		Attempts       int
+		NextAttemptAt  time.Time
+		LastStatusCode sql.NullInt64
+		LastError      sql.NullString
+		CreatedAt      time.Time
+		DeliveredAt    sql.NullTime
+	}
+)
+
+// Accepts reports whether the subscription wants events of the given type.
+func (s WebhookSubscription) Accepts(eventType string) bool {
+	for _, t := range s.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
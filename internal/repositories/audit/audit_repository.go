@@ -0,0 +1,227 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/SilentPlaces/basicauth.git/internal/models/models"
+	"github.com/google/wire"
+)
+
+const (
+	defaultQueryLimit = 50
+	maxQueryLimit     = 500
+)
+
+type (
+	// SealFunc fills in the chain fields (ID, PrevHash, Hash) of an event
+	// given the sequence number and hash of the current chain head.
+	SealFunc func(event *models.AuditEvent, lastSeq int64, lastHash string)
+
+	AuditRepository interface {
+		Append(event models.AuditEvent, seal SealFunc) (*models.AuditEvent, error)
+		Query(filter models.AuditEventFilter) ([]models.AuditEvent, error)
+		ListAfter(afterID int64, limit int) ([]models.AuditEvent, error)
+		GetHead() (int64, string, error)
+		// Redact replaces identifiers in the actor and subject of every event with placeholder,
+		// clears the IP of events the identifiers are the actor of and the metadata of all of
+		// them. It returns the number of redacted events.
+		Redact(identifiers []string, placeholder string) (int64, error)
+	}
+
+	auditRepository struct {
+		db *sql.DB
+	}
+)
+
+func NewAuditRepository(db *sql.DB) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+// Helper function to create a context with timeout
+func (ar *auditRepository) newContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), 5*time.Second)
+}
+
+// Append locks the chain head, seals the event against it and stores both in one transaction.
+func (ar *auditRepository) Append(event models.AuditEvent, seal SealFunc) (*models.AuditEvent, error) {
+	ctx, cancel := ar.newContext()
+	defer cancel()
+
+	tx, err := ar.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin audit transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var lastSeq int64
+	var lastHash string
+	err = tx.QueryRowContext(ctx, "SELECT last_seq, last_hash FROM audit_chain_head WHERE id=1 FOR UPDATE").Scan(&lastSeq, &lastHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock audit chain head: %w", err)
+	}
+
+	seal(&event, lastSeq, lastHash)
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO audit_events (id, occurred_at, actor, subject, action, outcome, ip, correlation_id, metadata, payload_hash, prev_hash, hash)
+		 VALUES (?,?,?,?,?,?,?,?,?,?,?,?)`,
+		event.ID, event.OccurredAt, event.Actor, event.Subject, event.Action, event.Outcome, event.IP,
+		event.CorrelationID, event.Metadata, event.PayloadHash, event.PrevHash, event.Hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert audit event: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE audit_chain_head SET last_seq=?, last_hash=? WHERE id=1", event.ID, event.Hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to move audit chain head: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit audit event: %w", err)
+	}
+	return &event, nil
+}
+
+// Query returns audit events matching the filter, newest first.
+func (ar *auditRepository) Query(filter models.AuditEventFilter) ([]models.AuditEvent, error) {
+	var conditions []string
+	var args []interface{}
+
+	addCondition := func(column, value string) {
+		if value != "" {
+			conditions = append(conditions, column+"=?")
+			args = append(args, value)
+		}
+	}
+	addCondition("actor", filter.Actor)
+	addCondition("subject", filter.Subject)
+	addCondition("action", filter.Action)
+	addCondition("outcome", filter.Outcome)
+	addCondition("correlation_id", filter.CorrelationID)
+	if filter.From != nil {
+		conditions = append(conditions, "occurred_at>=?")
+		args = append(args, *filter.From)
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "occurred_at<=?")
+		args = append(args, *filter.To)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultQueryLimit
+	}
+	if limit > maxQueryLimit {
+		limit = maxQueryLimit
+	}
+	offset := filter.Offset
+	if offset < 0 {
+		offset = 0
+	}
+
+	query := "SELECT " + auditColumns + " FROM audit_events"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY id DESC LIMIT ? OFFSET ?"
+	args = append(args, limit, offset)
+
+	return ar.list(query, args...)
+}
+
+// ListAfter returns up to limit events with an ID greater than afterID, in chain order.
+func (ar *auditRepository) ListAfter(afterID int64, limit int) ([]models.AuditEvent, error) {
+	query := "SELECT " + auditColumns + " FROM audit_events WHERE id>? ORDER BY id ASC LIMIT ?"
+	return ar.list(query, afterID, limit)
+}
+
+// GetHead returns the sequence number and hash of the last sealed event.
+func (ar *auditRepository) GetHead() (int64, string, error) {
+	ctx, cancel := ar.newContext()
+	defer cancel()
+
+	var lastSeq int64
+	var lastHash string
+	err := ar.db.QueryRowContext(ctx, "SELECT last_seq, last_hash FROM audit_chain_head WHERE id=1").Scan(&lastSeq, &lastHash)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to read audit chain head: %w", err)
+	}
+	return lastSeq, lastHash, nil
+}
+
+func (ar *auditRepository) Redact(identifiers []string, placeholder string) (int64, error) {
+	if len(identifiers) == 0 {
+		return 0, nil
+	}
+	ctx, cancel := ar.newContext()
+	defer cancel()
+
+	in := "(" + strings.TrimSuffix(strings.Repeat("?,", len(identifiers)), ",") + ")"
+	// Columns are assigned from left to right, so ip still sees the original actor.
+	query := "UPDATE audit_events SET " +
+		"ip=IF(actor IN " + in + ", '', ip), " +
+		"metadata=NULL, " +
+		"actor=IF(actor IN " + in + ", ?, actor), " +
+		"subject=IF(subject IN " + in + ", ?, subject), " +
+		"redacted_at=CURRENT_TIMESTAMP(6) " +
+		"WHERE actor IN " + in + " OR subject IN " + in
+
+	var args []interface{}
+	addIdentifiers := func() {
+		for _, identifier := range identifiers {
+			args = append(args, identifier)
+		}
+	}
+	addIdentifiers()
+	addIdentifiers()
+	args = append(args, placeholder)
+	addIdentifiers()
+	args = append(args, placeholder)
+	addIdentifiers()
+	addIdentifiers()
+
+	result, err := ar.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to redact audit events: %w", err)
+	}
+	redacted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to check affected rows: %w", err)
+	}
+	return redacted, nil
+}
+
+func (ar *auditRepository) list(query string, args ...interface{}) ([]models.AuditEvent, error) {
+	ctx, cancel := ar.newContext()
+	defer cancel()
+
+	rows, err := ar.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying audit events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []models.AuditEvent
+	for rows.Next() {
+		var e models.AuditEvent
+		var metadata sql.NullString
+		if err := rows.Scan(&e.ID, &e.OccurredAt, &e.Actor, &e.Subject, &e.Action, &e.Outcome, &e.IP,
+			&e.CorrelationID, &metadata, &e.PayloadHash, &e.PrevHash, &e.Hash, &e.RedactedAt); err != nil {
+			return nil, fmt.Errorf("error scanning audit event: %w", err)
+		}
+		e.Metadata = metadata.String
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating audit events: %w", err)
+	}
+	return events, nil
+}
+
+const auditColumns = "id, occurred_at, actor, subject, action, outcome, ip, correlation_id, metadata, payload_hash, prev_hash, hash, redacted_at"
+
+var AuditRepositoryProviderSet = wire.NewSet(NewAuditRepository)
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/wire"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	prefixPuzzleKey = "challenge-pow-"
+	// prefixIssuedKey counts the puzzles issued to a client IP in the current window.
+	prefixIssuedKey = "challenge-pow-issued-"
+)
+
+type (
+	// ChallengeRepository keeps issued proof-of-work puzzles until they are solved or expire.
+	ChallengeRepository interface {
+		StorePuzzle(id string, difficulty int, ttl time.Duration) error
+		// ConsumePuzzle deletes the puzzle and returns its difficulty. It reports false when the
+		// puzzle does not exist, e.g. because it expired or was already used.
+		ConsumePuzzle(id string) (int, bool, error)
+		// CountIssued counts a puzzle issued to ip and returns the count within the current window.
+		CountIssued(ip string, window time.Duration) (int64, error)
+	}
+
+	challengeRepository struct {
+		redisClient *redis.Client
+	}
+)
+
+func NewChallengeRepository(redisClient *redis.Client) ChallengeRepository {
+	return &challengeRepository{redisClient: redisClient}
+}
+
+func (r *challengeRepository) StorePuzzle(id string, difficulty int, ttl time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := r.redisClient.Set(ctx, prefixPuzzleKey+id, difficulty, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to store puzzle: %w", err)
+	}
+	return nil
+}
+
+func (r *challengeRepository) ConsumePuzzle(id string) (int, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	// GETDEL makes a puzzle single-use even when the same solution is sent twice at once.
+	value, err := r.redisClient.GetDel(ctx, prefixPuzzleKey+id).Result()
+	if errors.Is(err, redis.Nil) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to consume puzzle: %w", err)
+	}
+	difficulty, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, false, fmt.Errorf("stored puzzle has invalid difficulty %q", value)
+	}
+	return difficulty, true, nil
+}
+
+func (r *challengeRepository) CountIssued(ip string, window time.Duration) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var incr *redis.IntCmd
+	_, err := r.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		// The counter starts with the window's expiry, which later requests do not extend.
+		pipe.SetNX(ctx, prefixIssuedKey+ip, 0, window)
+		incr = pipe.Incr(ctx, prefixIssuedKey+ip)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count issued puzzles: %w", err)
+	}
+	return incr.Val(), nil
+}
+
+var ChallengeRepositoryProviderSet = wire.NewSet(NewChallengeRepository)
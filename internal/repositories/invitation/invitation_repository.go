@@ -0,0 +1,216 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/SilentPlaces/basicauth.git/internal/db/mysql"
+	"github.com/SilentPlaces/basicauth.git/internal/models/models"
+	"github.com/google/wire"
+)
+
+var ErrInvitationNotFound = errors.New("invitation not found")
+
+type (
+	InvitationRepository interface {
+		InsertInvitation(invitation models.Invitation) error
+		GetInvitationByCodeHash(codeHash string) (*models.Invitation, error)
+		// ListInvitations returns the newest invitations first. An empty createdBy lists everyone's.
+		ListInvitations(createdBy string, limit int, offset int) ([]models.Invitation, error)
+		// CountInvitationsSince counts the invitations createdBy has issued since the given time,
+		// revoked and used up ones included.
+		CountInvitationsSince(createdBy string, since time.Time) (int, error)
+		// LockCreator locks the user row of createdBy until the transaction ends, so quota checks
+		// of one creator run one at a time. It only has an effect inside WithTx.
+		LockCreator(createdBy string) error
+		// RevokeInvitation revokes an invitation that is not revoked yet. A non-empty createdBy
+		// restricts it to that creator's invitations.
+		RevokeInvitation(id string, createdBy string) error
+		// RedeemInvitation uses up one use while the invitation is usable and reports whether it did.
+		RedeemInvitation(id string) (bool, error)
+		// DeleteUserInvitations deletes the invitations createdBy issued and those bound to email.
+		DeleteUserInvitations(createdBy string, email string) error
+		WithTx(tx *sql.Tx) InvitationRepository
+	}
+
+	invitationRepository struct {
+		db mysql.Executor
+	}
+)
+
+// invitationColumns are the columns scanned into models.Invitation, in scan order.
+const invitationColumns = "id, code_hash, COALESCE(email, ''), roles, max_uses, uses, expires_at, created_by, created_at, revoked_at"
+
+func NewInvitationRepository(db *sql.DB) InvitationRepository {
+	return &invitationRepository{db: db}
+}
+
+// WithTx returns a repository that runs its queries inside the given transaction.
+func (ir *invitationRepository) WithTx(tx *sql.Tx) InvitationRepository {
+	return &invitationRepository{db: tx}
+}
+
+// Helper function to create a context with timeout
+func (ir *invitationRepository) newContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), 5*time.Second)
+}
+
+func (ir *invitationRepository) InsertInvitation(invitation models.Invitation) error {
+	ctx, cancel := ir.newContext()
+	defer cancel()
+
+	_, err := ir.db.ExecContext(ctx,
+		"INSERT INTO invitations (id, code_hash, email, roles, max_uses, expires_at, created_by) VALUES (?,?,NULLIF(?, ''),?,?,?,?)",
+		invitation.ID, invitation.CodeHash, invitation.Email, strings.Join(invitation.Roles, ","),
+		invitation.MaxUses, invitation.ExpiresAt, invitation.CreatedBy)
+	if err != nil {
+		return fmt.Errorf("failed to insert invitation: %w", err)
+	}
+	return nil
+}
+
+func (ir *invitationRepository) GetInvitationByCodeHash(codeHash string) (*models.Invitation, error) {
+	ctx, cancel := ir.newContext()
+	defer cancel()
+
+	row := ir.db.QueryRowContext(ctx, "SELECT "+invitationColumns+" FROM invitations WHERE code_hash=?", codeHash)
+	invitation, err := scanInvitation(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvitationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error querying invitation: %w", err)
+	}
+	return invitation, nil
+}
+
+func (ir *invitationRepository) ListInvitations(createdBy string, limit int, offset int) ([]models.Invitation, error) {
+	ctx, cancel := ir.newContext()
+	defer cancel()
+
+	query := "SELECT " + invitationColumns + " FROM invitations"
+	var args []interface{}
+	if createdBy != "" {
+		query += " WHERE created_by=?"
+		args = append(args, createdBy)
+	}
+	query += " ORDER BY created_at DESC, id LIMIT ? OFFSET ?"
+	args = append(args, limit, offset)
+
+	rows, err := ir.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying invitations: %w", err)
+	}
+	defer rows.Close()
+
+	var invitations []models.Invitation
+	for rows.Next() {
+		invitation, err := scanInvitation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning invitation: %w", err)
+		}
+		invitations = append(invitations, *invitation)
+	}
+	return invitations, rows.Err()
+}
+
+func (ir *invitationRepository) CountInvitationsSince(createdBy string, since time.Time) (int, error) {
+	ctx, cancel := ir.newContext()
+	defer cancel()
+
+	var count int
+	err := ir.db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM invitations WHERE created_by=? AND created_at > ?",
+		createdBy, since).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("error counting invitations: %w", err)
+	}
+	return count, nil
+}
+
+func (ir *invitationRepository) LockCreator(createdBy string) error {
+	ctx, cancel := ir.newContext()
+	defer cancel()
+
+	var id string
+	err := ir.db.QueryRowContext(ctx, "SELECT id FROM users WHERE id=? FOR UPDATE", createdBy).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("invitation creator %s not found", createdBy)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock invitation creator: %w", err)
+	}
+	return nil
+}
+
+func (ir *invitationRepository) RevokeInvitation(id string, createdBy string) error {
+	ctx, cancel := ir.newContext()
+	defer cancel()
+
+	query := "UPDATE invitations SET revoked_at=? WHERE id=? AND revoked_at IS NULL"
+	args := []interface{}{time.Now(), id}
+	if createdBy != "" {
+		query += " AND created_by=?"
+		args = append(args, createdBy)
+	}
+	result, err := ir.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to revoke invitation: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrInvitationNotFound
+	}
+	return nil
+}
+
+func (ir *invitationRepository) RedeemInvitation(id string) (bool, error) {
+	ctx, cancel := ir.newContext()
+	defer cancel()
+
+	// The conditions are checked in the update itself, so concurrent signups cannot exceed max_uses.
+	result, err := ir.db.ExecContext(ctx,
+		"UPDATE invitations SET uses=uses+1 WHERE id=? AND revoked_at IS NULL AND uses < max_uses AND expires_at > ?",
+		id, time.Now())
+	if err != nil {
+		return false, fmt.Errorf("failed to redeem invitation: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to determine rows affected: %w", err)
+	}
+	return affected == 1, nil
+}
+
+func (ir *invitationRepository) DeleteUserInvitations(createdBy string, email string) error {
+	ctx, cancel := ir.newContext()
+	defer cancel()
+
+	if _, err := ir.db.ExecContext(ctx, "DELETE FROM invitations WHERE created_by=? OR email=?", createdBy, email); err != nil {
+		return fmt.Errorf("failed to delete user invitations: %w", err)
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanInvitation(row rowScanner) (*models.Invitation, error) {
+	var invitation models.Invitation
+	var roles string
+	err := row.Scan(&invitation.ID, &invitation.CodeHash, &invitation.Email, &roles, &invitation.MaxUses,
+		&invitation.Uses, &invitation.ExpiresAt, &invitation.CreatedBy, &invitation.CreatedAt, &invitation.RevokedAt)
+	if err != nil {
+		return nil, err
+	}
+	if roles != "" {
+		invitation.Roles = strings.Split(roles, ",")
+	}
+	return &invitation, nil
+}
+
+var InvitationRepositoryProviderSet = wire.NewSet(NewInvitationRepository)
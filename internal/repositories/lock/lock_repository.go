@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/google/wire"
+	"github.com/redis/go-redis/v9"
+)
+
+const prefixLockKey = "lock-"
+
+// releaseLockScript deletes the lock only while it still holds the owner's token, so a holder whose
+// lock expired cannot release the lock of the next holder.
+var releaseLockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+type (
+	// LockRepository provides named locks shared by all replicas. A lock expires after its ttl, so
+	// a crashed holder does not block the others for good.
+	LockRepository interface {
+		// Acquire takes the lock if it is free. It returns the token needed to release it, or
+		// false when another holder has it.
+		Acquire(name string, ttl time.Duration) (string, bool, error)
+		Release(name string, token string) error
+	}
+
+	lockRepository struct {
+		redisClient *redis.Client
+	}
+)
+
+func NewLockRepository(redisClient *redis.Client) LockRepository {
+	return &lockRepository{redisClient: redisClient}
+}
+
+func (r *lockRepository) Acquire(name string, ttl time.Duration) (string, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	token := uuid.NewString()
+	err := r.redisClient.SetArgs(ctx, prefixLockKey+name, token, redis.SetArgs{Mode: "NX", TTL: ttl}).Err()
+	if errors.Is(err, redis.Nil) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to acquire lock %s: %w", name, err)
+	}
+	return token, true, nil
+}
+
+func (r *lockRepository) Release(name string, token string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := releaseLockScript.Run(ctx, r.redisClient, []string{prefixLockKey + name}, token).Err(); err != nil {
+		return fmt.Errorf("failed to release lock %s: %w", name, err)
+	}
+	return nil
+}
+
+var LockRepositoryProviderSet = wire.NewSet(NewLockRepository)
@@ -0,0 +1,421 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/SilentPlaces/basicauth.git/internal/models/models"
+	"github.com/google/wire"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	queueStream    = "mail:queue"
+	deadStream     = "mail:dead"
+	retrySet       = "mail:retry"
+	consumerGroup  = "mail-workers"
+	idempotencyKey = "mail:idempotency:"
+	jobField       = "job"
+
+	// deadStreamMaxLen keeps the dead-letter stream from growing without bound.
+	deadStreamMaxLen = 10000
+	// deadScanPageSize is how many dead letters are read at a time when searching by recipient.
+	deadScanPageSize = 500
+
+	idempotencyQueued = "queued"
+	idempotencySent   = "sent"
+)
+
+var (
+	ErrMailJobNotFound = errors.New("mail job not found")
+	// ErrMailJobNotReplayable is returned for sensitive dead letters, whose data was dropped.
+	ErrMailJobNotReplayable = errors.New("mail job data was dropped and cannot be replayed")
+)
+
+// promoteDueScript moves retries whose time has come from the retry set back onto the queue
+// stream in one step, so a crash cannot lose or duplicate them.
+var promoteDueScript = redis.NewScript(`
+local due = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+for _, job in ipairs(due) do
+	redis.call('ZREM', KEYS[1], job)
+	redis.call('XADD', KEYS[2], '*', ARGV[3], job)
+end
+return #due
+`)
+
+type (
+	MailQueueRepository interface {
+		EnsureGroup() error
+		// Reserve claims an idempotency key. It returns false when the key is already taken.
+		Reserve(key string, ttl time.Duration) (bool, error)
+		Release(key string) error
+		MarkSent(key string) error
+		IsSent(key string) (bool, error)
+		Add(job models.MailJob) (string, error)
+		Read(consumer string, count int, block time.Duration) ([]models.MailJob, error)
+		ClaimStale(consumer string, minIdle time.Duration, count int) ([]models.MailJob, error)
+		Complete(id string) error
+		Retry(job models.MailJob, at time.Time) error
+		Bury(job models.MailJob) error
+		PromoteDue(now time.Time, limit int) (int, error)
+		ListDead(limit int) ([]models.MailJob, error)
+		// ListDeadTo returns the dead letters addressed to any of addresses, compared without
+		// case, oldest first.
+		ListDeadTo(addresses []string) ([]models.MailJob, error)
+		// DeleteDeadTo deletes the dead letters addressed to any of addresses and returns how many
+		// it deleted.
+		DeleteDeadTo(addresses []string) (int, error)
+		Replay(id string) (*models.MailJob, error)
+	}
+
+	mailQueueRepository struct {
+		redisClient *redis.Client
+	}
+)
+
+func NewMailQueueRepository(redisClient *redis.Client) MailQueueRepository {
+	return &mailQueueRepository{redisClient: redisClient}
+}
+
+// Helper function to create a context with timeout
+func (mqr *mailQueueRepository) newContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), 5*time.Second)
+}
+
+// EnsureGroup creates the queue stream and its consumer group if they do not exist yet.
+func (mqr *mailQueueRepository) EnsureGroup() error {
+	ctx, cancel := mqr.newContext()
+	defer cancel()
+
+	err := mqr.redisClient.XGroupCreateMkStream(ctx, queueStream, consumerGroup, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("failed to create mail consumer group: %w", err)
+	}
+	return nil
+}
+
+func (mqr *mailQueueRepository) Reserve(key string, ttl time.Duration) (bool, error) {
+	ctx, cancel := mqr.newContext()
+	defer cancel()
+
+	ok, err := mqr.redisClient.SetNX(ctx, idempotencyKey+key, idempotencyQueued, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to reserve mail idempotency key: %w", err)
+	}
+	return ok, nil
+}
+
+func (mqr *mailQueueRepository) Release(key string) error {
+	ctx, cancel := mqr.newContext()
+	defer cancel()
+
+	return mqr.redisClient.Del(ctx, idempotencyKey+key).Err()
+}
+
+// MarkSent records that the job behind key was delivered, so a redelivered copy is skipped.
+func (mqr *mailQueueRepository) MarkSent(key string) error {
+	ctx, cancel := mqr.newContext()
+	defer cancel()
+
+	err := mqr.redisClient.SetArgs(ctx, idempotencyKey+key, idempotencySent, redis.SetArgs{Mode: "XX", KeepTTL: true}).Err()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return fmt.Errorf("failed to mark mail as sent: %w", err)
+	}
+	return nil
+}
+
+func (mqr *mailQueueRepository) IsSent(key string) (bool, error) {
+	ctx, cancel := mqr.newContext()
+	defer cancel()
+
+	state, err := mqr.redisClient.Get(ctx, idempotencyKey+key).Result()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read mail idempotency key: %w", err)
+	}
+	return state == idempotencySent, nil
+}
+
+func (mqr *mailQueueRepository) Add(job models.MailJob) (string, error) {
+	ctx, cancel := mqr.newContext()
+	defer cancel()
+
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode mail job: %w", err)
+	}
+	id, err := mqr.redisClient.XAdd(ctx, &redis.XAddArgs{
+		Stream: queueStream,
+		Values: map[string]interface{}{jobField: payload},
+	}).Result()
+	if err != nil {
+		return "", fmt.Errorf("failed to enqueue mail job: %w", err)
+	}
+	return id, nil
+}
+
+// Read returns new jobs for this consumer, waiting up to block when the queue is empty.
+func (mqr *mailQueueRepository) Read(consumer string, count int, block time.Duration) ([]models.MailJob, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), block+5*time.Second)
+	defer cancel()
+
+	streams, err := mqr.redisClient.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    consumerGroup,
+		Consumer: consumer,
+		Streams:  []string{queueStream, ">"},
+		Count:    int64(count),
+		Block:    block,
+	}).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mail queue: %w", err)
+	}
+
+	var jobs []models.MailJob
+	for _, stream := range streams {
+		for _, message := range stream.Messages {
+			jobs = append(jobs, decodeJob(message))
+		}
+	}
+	return jobs, nil
+}
+
+// ClaimStale takes over jobs that another consumer read but did not finish within minIdle,
+// e.g. because its process died mid-send.
+func (mqr *mailQueueRepository) ClaimStale(consumer string, minIdle time.Duration, count int) ([]models.MailJob, error) {
+	ctx, cancel := mqr.newContext()
+	defer cancel()
+
+	messages, _, err := mqr.redisClient.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+		Stream:   queueStream,
+		Group:    consumerGroup,
+		Consumer: consumer,
+		MinIdle:  minIdle,
+		Start:    "0-0",
+		Count:    int64(count),
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim stale mail jobs: %w", err)
+	}
+
+	jobs := make([]models.MailJob, 0, len(messages))
+	for _, message := range messages {
+		jobs = append(jobs, decodeJob(message))
+	}
+	return jobs, nil
+}
+
+// Complete acknowledges and removes a finished job.
+func (mqr *mailQueueRepository) Complete(id string) error {
+	ctx, cancel := mqr.newContext()
+	defer cancel()
+
+	_, err := mqr.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.XAck(ctx, queueStream, consumerGroup, id)
+		pipe.XDel(ctx, queueStream, id)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to complete mail job: %w", err)
+	}
+	return nil
+}
+
+// Retry acknowledges the job and schedules a copy for at.
+func (mqr *mailQueueRepository) Retry(job models.MailJob, at time.Time) error {
+	ctx, cancel := mqr.newContext()
+	defer cancel()
+
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to encode mail job: %w", err)
+	}
+	_, err = mqr.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(ctx, retrySet, redis.Z{Score: float64(at.Unix()), Member: payload})
+		pipe.XAck(ctx, queueStream, consumerGroup, job.ID)
+		pipe.XDel(ctx, queueStream, job.ID)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to schedule mail retry: %w", err)
+	}
+	return nil
+}
+
+// Bury acknowledges the job and moves it to the dead-letter stream.
+func (mqr *mailQueueRepository) Bury(job models.MailJob) error {
+	ctx, cancel := mqr.newContext()
+	defer cancel()
+
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to encode mail job: %w", err)
+	}
+	_, err = mqr.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.XAdd(ctx, &redis.XAddArgs{
+			Stream: deadStream,
+			MaxLen: deadStreamMaxLen,
+			Approx: true,
+			Values: map[string]interface{}{jobField: payload},
+		})
+		pipe.XAck(ctx, queueStream, consumerGroup, job.ID)
+		pipe.XDel(ctx, queueStream, job.ID)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to dead-letter mail job: %w", err)
+	}
+	return nil
+}
+
+func (mqr *mailQueueRepository) PromoteDue(now time.Time, limit int) (int, error) {
+	ctx, cancel := mqr.newContext()
+	defer cancel()
+
+	moved, err := promoteDueScript.Run(ctx, mqr.redisClient, []string{retrySet, queueStream}, now.Unix(), limit, jobField).Int()
+	if err != nil {
+		return 0, fmt.Errorf("failed to promote mail retries: %w", err)
+	}
+	return moved, nil
+}
+
+// ListDead returns the newest dead-lettered jobs first.
+func (mqr *mailQueueRepository) ListDead(limit int) ([]models.MailJob, error) {
+	ctx, cancel := mqr.newContext()
+	defer cancel()
+
+	messages, err := mqr.redisClient.XRevRangeN(ctx, deadStream, "+", "-", int64(limit)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead mail jobs: %w", err)
+	}
+
+	jobs := make([]models.MailJob, 0, len(messages))
+	for _, message := range messages {
+		jobs = append(jobs, decodeJob(message))
+	}
+	return jobs, nil
+}
+
+func (mqr *mailQueueRepository) ListDeadTo(addresses []string) ([]models.MailJob, error) {
+	var jobs []models.MailJob
+	err := mqr.scanDead(func(job models.MailJob) error {
+		if addressedTo(job, addresses) {
+			jobs = append(jobs, job)
+		}
+		return nil
+	})
+	return jobs, err
+}
+
+func (mqr *mailQueueRepository) DeleteDeadTo(addresses []string) (int, error) {
+	deleted := 0
+	err := mqr.scanDead(func(job models.MailJob) error {
+		if !addressedTo(job, addresses) {
+			return nil
+		}
+		ctx, cancel := mqr.newContext()
+		defer cancel()
+
+		if err := mqr.redisClient.XDel(ctx, deadStream, job.ID).Err(); err != nil {
+			return fmt.Errorf("failed to delete dead mail job: %w", err)
+		}
+		deleted++
+		return nil
+	})
+	return deleted, err
+}
+
+// scanDead calls fn for every dead letter, oldest first. The stream is capped, so a full scan
+// stays bounded.
+func (mqr *mailQueueRepository) scanDead(fn func(job models.MailJob) error) error {
+	start := "-"
+	for {
+		ctx, cancel := mqr.newContext()
+		messages, err := mqr.redisClient.XRangeN(ctx, deadStream, start, "+", deadScanPageSize).Result()
+		cancel()
+		if err != nil {
+			return fmt.Errorf("failed to read dead mail jobs: %w", err)
+		}
+		for _, message := range messages {
+			if err := fn(decodeJob(message)); err != nil {
+				return err
+			}
+		}
+		if len(messages) < deadScanPageSize {
+			return nil
+		}
+		start = "(" + messages[len(messages)-1].ID
+	}
+}
+
+func addressedTo(job models.MailJob, addresses []string) bool {
+	for _, address := range addresses {
+		if strings.EqualFold(job.To, address) {
+			return true
+		}
+	}
+	return false
+}
+
+// Replay moves a dead-lettered job back onto the queue with a fresh attempt budget.
+func (mqr *mailQueueRepository) Replay(id string) (*models.MailJob, error) {
+	ctx, cancel := mqr.newContext()
+	defer cancel()
+
+	messages, err := mqr.redisClient.XRange(ctx, deadStream, id, id).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dead mail job: %w", err)
+	}
+	if len(messages) == 0 {
+		return nil, ErrMailJobNotFound
+	}
+
+	job := decodeJob(messages[0])
+	if job.Sensitive && job.Data == nil {
+		return nil, ErrMailJobNotReplayable
+	}
+	job.Attempts = 0
+	job.LastError = ""
+	job.FailedAt = nil
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode mail job: %w", err)
+	}
+
+	var add *redis.StringCmd
+	_, err = mqr.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		add = pipe.XAdd(ctx, &redis.XAddArgs{
+			Stream: queueStream,
+			Values: map[string]interface{}{jobField: payload},
+		})
+		pipe.XDel(ctx, deadStream, id)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to replay mail job: %w", err)
+	}
+	job.ID = add.Val()
+	return &job, nil
+}
+
+// decodeJob parses a stream entry. Entries that cannot be parsed come back without a template,
+// which the worker treats as a permanent failure.
+func decodeJob(message redis.XMessage) models.MailJob {
+	var job models.MailJob
+	raw, _ := message.Values[jobField].(string)
+	if err := json.Unmarshal([]byte(raw), &job); err != nil {
+		job = models.MailJob{LastError: fmt.Sprintf("malformed mail job: %v", err)}
+	}
+	job.ID = message.ID
+	return job
+}
+
+var MailQueueRepositoryProviderSet = wire.NewSet(NewMailQueueRepository)
@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/SilentPlaces/basicauth.git/internal/db/mysql"
+	"github.com/SilentPlaces/basicauth.git/internal/models/models"
+	"github.com/google/wire"
+)
+
+// relayTimeout bounds one relay batch, including the time spent publishing to the bus.
+const relayTimeout = 30 * time.Second
+
+type (
+	OutboxRepository interface {
+		Add(event models.OutboxEvent) error
+		WithTx(tx *sql.Tx) OutboxRepository
+		ProcessBatch(limit int, maxAttempts int, handle func(event models.OutboxEvent) error) (int, error)
+		DeleteSettledBefore(before time.Time) (int64, error)
+		// DeleteAggregateEvents removes the events of one aggregate, published or not.
+		DeleteAggregateEvents(aggregateType string, aggregateID string) (int64, error)
+	}
+
+	outboxRepository struct {
+		db       *sql.DB
+		executor mysql.Executor
+	}
+)
+
+func NewOutboxRepository(db *sql.DB) OutboxRepository {
+	return &outboxRepository{db: db, executor: db}
+}
+
+// Helper function to create a context with timeout
+func (obr *outboxRepository) newContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), 5*time.Second)
+}
+
+// WithTx returns a repository that writes through the given transaction.
+func (obr *outboxRepository) WithTx(tx *sql.Tx) OutboxRepository {
+	return &outboxRepository{db: obr.db, executor: tx}
+}
+
+func (obr *outboxRepository) Add(event models.OutboxEvent) error {
+	ctx, cancel := obr.newContext()
+	defer cancel()
+
+	_, err := obr.executor.ExecContext(ctx,
+		"INSERT INTO outbox_events (event_id, aggregate_type, aggregate_id, event_type, payload, occurred_at) VALUES (?,?,?,?,?,?)",
+		event.EventID, event.AggregateType, event.AggregateID, event.EventType, event.Payload, event.OccurredAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert outbox event: %w", err)
+	}
+	return nil
+}
+
+// ProcessBatch locks up to limit pending events in insertion order and hands each to handle.
+// Events that were handled successfully are marked as published; failures are counted and retried
+// in a later batch, until the maxAttempts-th failure marks the event as dead. Rows locked by another
+// relay are skipped.
+func (obr *outboxRepository) ProcessBatch(limit int, maxAttempts int, handle func(event models.OutboxEvent) error) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), relayTimeout)
+	defer cancel()
+
+	tx, err := obr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin outbox transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx,
+		`SELECT id, event_id, aggregate_type, aggregate_id, event_type, payload, occurred_at, published_at, attempts
+		 FROM outbox_events WHERE published_at IS NULL AND dead_at IS NULL ORDER BY id LIMIT ? FOR UPDATE SKIP LOCKED`, limit)
+	if err != nil {
+		return 0, fmt.Errorf("error querying outbox events: %w", err)
+	}
+
+	var events []models.OutboxEvent
+	for rows.Next() {
+		var e models.OutboxEvent
+		if err := rows.Scan(&e.ID, &e.EventID, &e.AggregateType, &e.AggregateID, &e.EventType, &e.Payload,
+			&e.OccurredAt, &e.PublishedAt, &e.Attempts); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("error scanning outbox event: %w", err)
+		}
+		events = append(events, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating outbox events: %w", err)
+	}
+
+	// Within a batch, the events of an aggregate that failed are held back so they are not published
+	// ahead of it. This is best effort: another relay may still publish a later event of the same
+	// aggregate, and the hold is lifted once the failing event is dead.
+	published := 0
+	failed := make(map[string]bool)
+	for _, e := range events {
+		aggregate := e.AggregateType + "/" + e.AggregateID
+		if failed[aggregate] {
+			continue
+		}
+		if handleErr := handle(e); handleErr != nil {
+			failed[aggregate] = true
+			var deadAt sql.NullTime
+			if e.Attempts+1 >= maxAttempts {
+				deadAt = sql.NullTime{Time: time.Now().UTC(), Valid: true}
+			}
+			_, err = tx.ExecContext(ctx, "UPDATE outbox_events SET attempts=attempts+1, last_error=?, dead_at=? WHERE id=?", handleErr.Error(), deadAt, e.ID)
+			if err != nil {
+				return 0, fmt.Errorf("failed to record outbox failure: %w", err)
+			}
+			continue
+		}
+		_, err = tx.ExecContext(ctx, "UPDATE outbox_events SET published_at=?, attempts=attempts+1, last_error=NULL WHERE id=?", time.Now().UTC(), e.ID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to mark outbox event as published: %w", err)
+		}
+		published++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit outbox batch: %w", err)
+	}
+	return published, nil
+}
+
+// DeleteSettledBefore removes events that were published or marked as dead before the given time.
+func (obr *outboxRepository) DeleteSettledBefore(before time.Time) (int64, error) {
+	ctx, cancel := obr.newContext()
+	defer cancel()
+
+	result, err := obr.db.ExecContext(ctx, "DELETE FROM outbox_events WHERE published_at<? OR dead_at<?", before.UTC(), before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune outbox events: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+func (obr *outboxRepository) DeleteAggregateEvents(aggregateType string, aggregateID string) (int64, error) {
+	ctx, cancel := obr.newContext()
+	defer cancel()
+
+	result, err := obr.executor.ExecContext(ctx, "DELETE FROM outbox_events WHERE aggregate_type=? AND aggregate_id=?", aggregateType, aggregateID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete outbox events: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+var OutboxRepositoryProviderSet = wire.NewSet(NewOutboxRepository)
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/SilentPlaces/basicauth.git/internal/db/mysql"
+	"github.com/google/wire"
+)
+
+type (
+	// PasswordHistoryRepository keeps the hashes of passwords users had before, so they are not
+	// reused.
+	PasswordHistoryRepository interface {
+		AddPasswordHash(userID string, passwordHash string) error
+		// RecentPasswordHashes returns up to limit hashes, newest first.
+		RecentPasswordHashes(userID string, limit int) ([]string, error)
+		// PrunePasswordHistory deletes all but the newest keep hashes of the user.
+		PrunePasswordHistory(userID string, keep int) error
+		WithTx(tx *sql.Tx) PasswordHistoryRepository
+	}
+
+	passwordHistoryRepository struct {
+		db mysql.Executor
+	}
+)
+
+func NewPasswordHistoryRepository(db *sql.DB) PasswordHistoryRepository {
+	return &passwordHistoryRepository{db: db}
+}
+
+// WithTx returns a repository that runs its queries inside the given transaction.
+func (pr *passwordHistoryRepository) WithTx(tx *sql.Tx) PasswordHistoryRepository {
+	return &passwordHistoryRepository{db: tx}
+}
+
+// Helper function to create a context with timeout
+func (pr *passwordHistoryRepository) newContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), 5*time.Second)
+}
+
+func (pr *passwordHistoryRepository) AddPasswordHash(userID string, passwordHash string) error {
+	ctx, cancel := pr.newContext()
+	defer cancel()
+
+	_, err := pr.db.ExecContext(ctx, "INSERT INTO password_history (user_id, password) VALUES (?,?)", userID, passwordHash)
+	if err != nil {
+		return fmt.Errorf("failed to insert password history: %w", err)
+	}
+	return nil
+}
+
+func (pr *passwordHistoryRepository) RecentPasswordHashes(userID string, limit int) ([]string, error) {
+	ctx, cancel := pr.newContext()
+	defer cancel()
+
+	rows, err := pr.db.QueryContext(ctx,
+		"SELECT password FROM password_history WHERE user_id=? ORDER BY id DESC LIMIT ?", userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error querying password history: %w", err)
+	}
+	defer rows.Close()
+
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, fmt.Errorf("error scanning password history: %w", err)
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, rows.Err()
+}
+
+func (pr *passwordHistoryRepository) PrunePasswordHistory(userID string, keep int) error {
+	ctx, cancel := pr.newContext()
+	defer cancel()
+
+	// MySQL does not allow LIMIT in an IN subquery, so the kept ids go through a derived table.
+	_, err := pr.db.ExecContext(ctx,
+		"DELETE FROM password_history WHERE user_id=? AND id NOT IN "+
+			"(SELECT id FROM (SELECT id FROM password_history WHERE user_id=? ORDER BY id DESC LIMIT ?) AS kept)",
+		userID, userID, keep)
+	if err != nil {
+		return fmt.Errorf("failed to prune password history: %w", err)
+	}
+	return nil
+}
+
+var PasswordHistoryRepositoryProviderSet = wire.NewSet(NewPasswordHistoryRepository)
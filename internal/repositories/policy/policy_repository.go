@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/SilentPlaces/basicauth.git/internal/db/mysql"
+	"github.com/SilentPlaces/basicauth.git/internal/models/models"
+	"github.com/google/wire"
+)
+
+var ErrDocumentNotFound = errors.New("policy document not found")
+
+type (
+	PolicyRepository interface {
+		InsertDocument(document models.PolicyDocument) error
+		GetDocument(kind string, version string) (*models.PolicyDocument, error)
+		// ListDocuments returns the latest published documents first, scheduled ones included.
+		ListDocuments(limit int, offset int) ([]models.PolicyDocument, error)
+		// ListPublishedDocuments returns the documents published by at, latest first.
+		ListPublishedDocuments(at time.Time) ([]models.PolicyDocument, error)
+		// AddConsents stores the consents of userID. A document the user accepted before keeps its
+		// first acceptance.
+		AddConsents(userID string, consents []models.UserConsent) error
+		// ListConsents returns the consents of userID, latest first.
+		ListConsents(userID string) ([]models.UserConsent, error)
+		WithTx(tx *sql.Tx) PolicyRepository
+	}
+
+	policyRepository struct {
+		db mysql.Executor
+	}
+)
+
+// documentColumns are the columns scanned into models.PolicyDocument, in scan order.
+const documentColumns = "id, kind, version, url, mandatory, published_at, created_by, created_at"
+
+func NewPolicyRepository(db *sql.DB) PolicyRepository {
+	return &policyRepository{db: db}
+}
+
+// WithTx returns a repository that runs its queries inside the given transaction.
+func (pr *policyRepository) WithTx(tx *sql.Tx) PolicyRepository {
+	return &policyRepository{db: tx}
+}
+
+// Helper function to create a context with timeout
+func (pr *policyRepository) newContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), 5*time.Second)
+}
+
+func (pr *policyRepository) InsertDocument(document models.PolicyDocument) error {
+	ctx, cancel := pr.newContext()
+	defer cancel()
+
+	_, err := pr.db.ExecContext(ctx,
+		"INSERT INTO policy_documents (id, kind, version, url, mandatory, published_at, created_by) VALUES (?,?,?,?,?,?,?)",
+		document.ID, document.Kind, document.Version, document.URL, document.Mandatory, document.PublishedAt, document.CreatedBy)
+	if err != nil {
+		return fmt.Errorf("failed to insert policy document: %w", err)
+	}
+	return nil
+}
+
+func (pr *policyRepository) GetDocument(kind string, version string) (*models.PolicyDocument, error) {
+	ctx, cancel := pr.newContext()
+	defer cancel()
+
+	row := pr.db.QueryRowContext(ctx, "SELECT "+documentColumns+" FROM policy_documents WHERE kind=? AND version=?", kind, version)
+	document, err := scanDocument(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrDocumentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error querying policy document: %w", err)
+	}
+	return document, nil
+}
+
+func (pr *policyRepository) ListDocuments(limit int, offset int) ([]models.PolicyDocument, error) {
+	return pr.queryDocuments("SELECT "+documentColumns+" FROM policy_documents ORDER BY published_at DESC, created_at DESC LIMIT ? OFFSET ?", limit, offset)
+}
+
+func (pr *policyRepository) ListPublishedDocuments(at time.Time) ([]models.PolicyDocument, error) {
+	return pr.queryDocuments("SELECT "+documentColumns+" FROM policy_documents WHERE published_at <= ? ORDER BY published_at DESC, created_at DESC", at)
+}
+
+func (pr *policyRepository) queryDocuments(query string, args ...interface{}) ([]models.PolicyDocument, error) {
+	ctx, cancel := pr.newContext()
+	defer cancel()
+
+	rows, err := pr.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying policy documents: %w", err)
+	}
+	defer rows.Close()
+
+	var documents []models.PolicyDocument
+	for rows.Next() {
+		document, err := scanDocument(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning policy document: %w", err)
+		}
+		documents = append(documents, *document)
+	}
+	return documents, rows.Err()
+}
+
+func (pr *policyRepository) AddConsents(userID string, consents []models.UserConsent) error {
+	if len(consents) == 0 {
+		return nil
+	}
+	ctx, cancel := pr.newContext()
+	defer cancel()
+
+	placeholders := make([]string, 0, len(consents))
+	args := make([]interface{}, 0, 4*len(consents))
+	for _, consent := range consents {
+		placeholders = append(placeholders, "(?,?,?,?)")
+		args = append(args, userID, consent.DocumentID, consent.AcceptedAt, consent.IP)
+	}
+	_, err := pr.db.ExecContext(ctx,
+		"INSERT INTO user_consents (user_id, document_id, accepted_at, ip) VALUES "+strings.Join(placeholders, ",")+
+			" ON DUPLICATE KEY UPDATE id=id", args...)
+	if err != nil {
+		return fmt.Errorf("failed to insert user consents: %w", err)
+	}
+	return nil
+}
+
+func (pr *policyRepository) ListConsents(userID string) ([]models.UserConsent, error) {
+	ctx, cancel := pr.newContext()
+	defer cancel()
+
+	rows, err := pr.db.QueryContext(ctx,
+		"SELECT c.user_id, c.document_id, d.kind, d.version, c.accepted_at, c.ip FROM user_consents c "+
+			"JOIN policy_documents d ON d.id = c.document_id WHERE c.user_id=? ORDER BY c.accepted_at DESC, c.id DESC", userID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying user consents: %w", err)
+	}
+	defer rows.Close()
+
+	var consents []models.UserConsent
+	for rows.Next() {
+		var consent models.UserConsent
+		if err := rows.Scan(&consent.UserID, &consent.DocumentID, &consent.Kind, &consent.Version, &consent.AcceptedAt, &consent.IP); err != nil {
+			return nil, fmt.Errorf("error scanning user consent: %w", err)
+		}
+		consents = append(consents, consent)
+	}
+	return consents, rows.Err()
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanDocument(row rowScanner) (*models.PolicyDocument, error) {
+	var document models.PolicyDocument
+	err := row.Scan(&document.ID, &document.Kind, &document.Version, &document.URL, &document.Mandatory,
+		&document.PublishedAt, &document.CreatedBy, &document.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &document, nil
+}
+
+var PolicyRepositoryProviderSet = wire.NewSet(NewPolicyRepository)
@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"github.com/SilentPlaces/basicauth.git/internal/config"
-	consul "github.com/SilentPlaces/basicauth.git/internal/services/consul"
 	"github.com/google/wire"
 	"github.com/redis/go-redis/v9"
 	"log"
@@ -13,9 +12,24 @@ import (
 
 type (
 	RegistrationRepository interface {
-		SetVerifyToken(mail string, token string) error
+		// SetVerifyToken stores the hash of a new token, replacing any earlier token and its attempts.
+		SetVerifyToken(mail string, tokenHash string) error
 		GetVerifyToken(mail string) (string, error)
+		// RecordFailedAttempt counts a wrong guess and deletes the token once the configured number
+		// of attempts is reached. It reports whether the token was deleted.
+		RecordFailedAttempt(mail string) (bool, error)
+		// ConsumeToken deletes the token if its hash is still tokenHash. It reports false when the
+		// token was already used or replaced.
+		ConsumeToken(mail string, tokenHash string) (bool, error)
 		DeleteToken(email string) error
+		// The code methods work like their token counterparts, with their own attempt limit.
+		SetVerifyCode(mail string, codeHash string) error
+		GetVerifyCode(mail string) (string, error)
+		RecordFailedCodeAttempt(mail string) (bool, error)
+		ConsumeCode(mail string, codeHash string) (bool, error)
+		DeleteCode(mail string) error
+		// HasPendingVerification reports whether a token or code is still stored for mail.
+		HasPendingVerification(mail string) (bool, error)
 		TrackTokenGeneration(email string) error
 		CanGenerateToken(email string) (bool, error)
 		DeleteVerificationCount(mail string) error
@@ -23,57 +37,131 @@ type (
 
 	registrationRepository struct {
 		redisClient        *redis.Client
-		registrationConfig *config.RegistrationConfig
+		registrationConfig *config.Value[config.RegistrationConfig]
 	}
 )
 
-func NewRegistrationRepository(redisClient *redis.Client, consul consul.ConsulService) RegistrationRepository {
-	cfg := consul.GetRegistrationConfig()
-
+// NewRegistrationRepository reads token TTL and generation limits from registrationConfig on every
+// call, so reloaded settings apply without a restart.
+func NewRegistrationRepository(redisClient *redis.Client, registrationConfig *config.Value[config.RegistrationConfig]) RegistrationRepository {
 	return &registrationRepository{
 		redisClient:        redisClient,
-		registrationConfig: cfg,
+		registrationConfig: registrationConfig,
 	}
 }
 
-func (rp *registrationRepository) SetVerifyToken(mail string, token string) error {
+func (rp *registrationRepository) SetVerifyToken(mail string, tokenHash string) error {
+	return rp.setSecret(prefixTokenKey, mail, tokenHash)
+}
+
+// GetVerifyToken returns the stored token hash, or redis.Nil when there is no token.
+func (rp *registrationRepository) GetVerifyToken(mail string) (string, error) {
+	return rp.getSecret(prefixTokenKey, mail)
+}
+
+func (rp *registrationRepository) RecordFailedAttempt(mail string) (bool, error) {
+	return rp.recordFailedAttempt(prefixTokenKey, mail, rp.registrationConfig.Load().MaxVerificationAttempts)
+}
+
+func (rp *registrationRepository) ConsumeToken(mail string, tokenHash string) (bool, error) {
+	return rp.consumeSecret(prefixTokenKey, mail, tokenHash)
+}
+
+func (rp *registrationRepository) DeleteToken(mail string) error {
+	return rp.deleteSecret(prefixTokenKey, mail)
+}
+
+func (rp *registrationRepository) SetVerifyCode(mail string, codeHash string) error {
+	return rp.setSecret(prefixCodeKey, mail, codeHash)
+}
+
+func (rp *registrationRepository) GetVerifyCode(mail string) (string, error) {
+	return rp.getSecret(prefixCodeKey, mail)
+}
+
+func (rp *registrationRepository) RecordFailedCodeAttempt(mail string) (bool, error) {
+	return rp.recordFailedAttempt(prefixCodeKey, mail, rp.registrationConfig.Load().MaxCodeAttempts)
+}
+
+func (rp *registrationRepository) ConsumeCode(mail string, codeHash string) (bool, error) {
+	return rp.consumeSecret(prefixCodeKey, mail, codeHash)
+}
+
+func (rp *registrationRepository) DeleteCode(mail string) error {
+	return rp.deleteSecret(prefixCodeKey, mail)
+}
+
+func (rp *registrationRepository) HasPendingVerification(mail string) (bool, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	key := prefixTokenKey + mail
-	fmt.Printf("SetVerifyToken key=%s, %s \n", key, rp.registrationConfig.MailVerificationTimeInSeconds)
-	err := rp.redisClient.Set(ctx, key, token, rp.registrationConfig.MailVerificationTimeInSeconds).Err()
+	count, err := rp.redisClient.Exists(ctx, prefixTokenKey+mail, prefixCodeKey+mail).Result()
 	if err != nil {
-		log.Printf("Failed to set registration token for mail '%s': %v", mail, err)
+		return false, fmt.Errorf("failed to check pending verification: %w", err)
+	}
+	return count > 0, nil
+}
+
+func (rp *registrationRepository) setSecret(prefix, mail, secretHash string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	key := prefix + mail
+	ttl := rp.registrationConfig.Load().MailVerificationTimeInSeconds
+	_, err := rp.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, key)
+		pipe.HSet(ctx, key, secretHashField, secretHash, secretAttemptsField, 0)
+		pipe.Expire(ctx, key, ttl)
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to set registration secret %s for mail '%s': %v", prefix, mail, err)
 		return err
 	}
 	return nil
 }
 
-func (rp *registrationRepository) GetVerifyToken(mail string) (string, error) {
+func (rp *registrationRepository) getSecret(prefix, mail string) (string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	token, err := rp.redisClient.Get(ctx, prefixTokenKey+mail).Result()
+	secretHash, err := rp.redisClient.HGet(ctx, prefix+mail, secretHashField).Result()
 	if err != nil {
-		log.Printf("Failed to get registration token for mail '%s': %v", mail, err)
+		log.Printf("Failed to get registration secret %s for mail '%s': %v", prefix, mail, err)
 		return "", err
 	}
-	return token, nil
+	return secretHash, nil
 }
 
-func (rp *registrationRepository) DeleteToken(mail string) error {
+func (rp *registrationRepository) recordFailedAttempt(prefix, mail string, maxAttempts int) (bool, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	err := rp.redisClient.Del(ctx, prefixTokenKey+mail).Err()
+	attempts, err := recordFailedAttemptScript.Run(ctx, rp.redisClient, []string{prefix + mail},
+		secretAttemptsField, maxAttempts).Int()
 	if err != nil {
-		return err
+		return false, fmt.Errorf("failed to record verification attempt: %w", err)
 	}
-	return nil
+	return attempts >= maxAttempts, nil
+}
+
+func (rp *registrationRepository) consumeSecret(prefix, mail, secretHash string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	deleted, err := consumeSecretScript.Run(ctx, rp.redisClient, []string{prefix + mail},
+		secretHashField, secretHash).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to consume verification secret: %w", err)
+	}
+	return deleted == 1, nil
+}
+
+func (rp *registrationRepository) deleteSecret(prefix, mail string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return rp.redisClient.Del(ctx, prefix+mail).Err()
 }
 
 func (rp *registrationRepository) DeleteVerificationCount(mail string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	err := rp.redisClient.Del(ctx, prefixTokenKey+mail).Err()
+	err := rp.redisClient.Del(ctx, prefixVerifyCountKey+mail).Err()
 	if err != nil {
 		return err
 	}
@@ -114,15 +202,43 @@ func (rp *registrationRepository) CanGenerateToken(email string) (bool, error) {
 	}
 
 	// Allow generation only if there are fewer than registrationConfig.MaxVerificationMailGenerationInHours token generations in the last 24 hours.
-	if count >= rp.registrationConfig.MaxVerificationMailGenerationInHours {
+	if count >= rp.registrationConfig.Load().MaxVerificationMailGenerationInHours {
 		return false, nil
 	}
 	return true, nil
 }
 
 const (
-	prefixTokenKey       = "token-"
+	// Tokens were stored in plain text under "token-"; the new prefix leaves those unreadable, so
+	// pending users ask for a new mail instead of failing on the old format.
+	prefixTokenKey       = "verify-token-"
+	prefixCodeKey        = "verify-code-"
 	prefixVerifyCountKey = "resend_verification-count-"
+
+	secretHashField     = "hash"
+	secretAttemptsField = "attempts"
 )
 
+// recordFailedAttemptScript increments the attempt counter of an existing token or code and
+// deletes it once ARGV[2] attempts are reached. It returns 0 when there is nothing stored.
+var recordFailedAttemptScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+local attempts = redis.call('HINCRBY', KEYS[1], ARGV[1], 1)
+if attempts >= tonumber(ARGV[2]) then
+	redis.call('DEL', KEYS[1])
+end
+return attempts
+`)
+
+// consumeSecretScript deletes a token or code only if it still has the hash that was checked, so
+// two concurrent verifications cannot both succeed and a secret issued in between is kept.
+var consumeSecretScript = redis.NewScript(`
+if redis.call('HGET', KEYS[1], ARGV[1]) == ARGV[2] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
 var RegistrationRepositoryProviderSet = wire.NewSet(NewRegistrationRepository)
@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/SilentPlaces/basicauth.git/internal/models/models"
+	"github.com/google/wire"
+	"github.com/redis/go-redis/v9"
+)
+
+// prefixSessionsKey names the hash of a user's sessions, keyed by session ID.
+const prefixSessionsKey = "sessions-"
+
+// touchScript writes the updated session only while it still exists, so a refresh racing with a
+// revocation cannot bring the revoked session back.
+var touchScript = redis.NewScript(`
+if redis.call('HEXISTS', KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+redis.call('PEXPIRE', KEYS[1], ARGV[3])
+return 1
+`)
+
+type (
+	// SessionRepository keeps the sessions of each user. A session lives for ttl after its last
+	// use; the hash of a user expires with the most recent one.
+	SessionRepository interface {
+		Add(userID string, session models.Session) error
+		// Touch records a use of the session. It reports false when the session does not exist or
+		// expired.
+		Touch(userID string, sessionID string) (bool, error)
+		// Exists reports whether the session exists and has not expired, without recording a use.
+		Exists(userID string, sessionID string) (bool, error)
+		// List returns the live sessions of the user, newest first.
+		List(userID string) ([]models.Session, error)
+		DeleteAll(userID string) error
+	}
+
+	sessionRepository struct {
+		redisClient *redis.Client
+		ttl         time.Duration
+	}
+)
+
+func NewSessionRepository(redisClient *redis.Client, ttl time.Duration) SessionRepository {
+	return &sessionRepository{redisClient: redisClient, ttl: ttl}
+}
+
+func (r *sessionRepository) Add(userID string, session models.Session) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := r.store(ctx, userID, session); err != nil {
+		return fmt.Errorf("failed to store session: %w", err)
+	}
+	return nil
+}
+
+func (r *sessionRepository) Touch(userID string, sessionID string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	session, err := r.get(ctx, userID, sessionID)
+	if err != nil || session == nil {
+		return false, err
+	}
+
+	session.LastUsedAt = time.Now().UTC()
+	raw, err := json.Marshal(session)
+	if err != nil {
+		return false, err
+	}
+	touched, err := touchScript.Run(ctx, r.redisClient, []string{prefixSessionsKey + userID},
+		sessionID, raw, r.ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to update session: %w", err)
+	}
+	return touched == 1, nil
+}
+
+func (r *sessionRepository) Exists(userID string, sessionID string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	session, err := r.get(ctx, userID, sessionID)
+	return session != nil, err
+}
+
+func (r *sessionRepository) List(userID string) ([]models.Session, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	raw, err := r.redisClient.HGetAll(ctx, prefixSessionsKey+userID).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	now := time.Now()
+	sessions := make([]models.Session, 0, len(raw))
+	var expired []string
+	for id, value := range raw {
+		session, err := decodeSession(value)
+		if err != nil {
+			return nil, err
+		}
+		if r.expired(session, now) {
+			expired = append(expired, id)
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+	// Expired sessions are only dropped here, as the hash outlives all but its newest session.
+	if len(expired) > 0 {
+		if err := r.redisClient.HDel(ctx, prefixSessionsKey+userID, expired...).Err(); err != nil {
+			return nil, fmt.Errorf("failed to prune sessions: %w", err)
+		}
+	}
+
+	slices.SortFunc(sessions, func(a, b models.Session) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+	return sessions, nil
+}
+
+func (r *sessionRepository) DeleteAll(userID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := r.redisClient.Del(ctx, prefixSessionsKey+userID).Err(); err != nil {
+		return fmt.Errorf("failed to delete sessions: %w", err)
+	}
+	return nil
+}
+
+// get returns the live session, or nil when it does not exist or expired.
+func (r *sessionRepository) get(ctx context.Context, userID string, sessionID string) (*models.Session, error) {
+	raw, err := r.redisClient.HGet(ctx, prefixSessionsKey+userID, sessionID).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session: %w", err)
+	}
+	session, err := decodeSession(raw)
+	if err != nil {
+		return nil, err
+	}
+	if r.expired(session, time.Now()) {
+		return nil, nil
+	}
+	return &session, nil
+}
+
+func (r *sessionRepository) store(ctx context.Context, userID string, session models.Session) error {
+	raw, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	_, err = r.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, prefixSessionsKey+userID, session.ID, raw)
+		pipe.Expire(ctx, prefixSessionsKey+userID, r.ttl)
+		return nil
+	})
+	return err
+}
+
+func (r *sessionRepository) expired(session models.Session, now time.Time) bool {
+	return !now.Before(session.LastUsedAt.Add(r.ttl))
+}
+
+func decodeSession(raw string) (models.Session, error) {
+	var session models.Session
+	if err := json.Unmarshal([]byte(raw), &session); err != nil {
+		return models.Session{}, fmt.Errorf("malformed session: %w", err)
+	}
+	return session, nil
+}
+
+var SessionRepositoryProviderSet = wire.NewSet(NewSessionRepository)
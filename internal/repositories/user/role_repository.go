@@ -0,0 +1,85 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/SilentPlaces/basicauth.git/internal/db/mysql"
+	"github.com/google/wire"
+)
+
+// RoleAdmin grants access to the administrative endpoints.
+const RoleAdmin = "admin"
+
+type RoleRepository interface {
+	HasRole(userID string, role string) (bool, error)
+	GetRoles(userID string) ([]string, error)
+	AssignRole(userID string, role string) error
+	WithTx(tx *sql.Tx) RoleRepository
+}
+
+type roleRepository struct {
+	db mysql.Executor
+}
+
+func NewRoleRepository(dbConnection *sql.DB) RoleRepository {
+	return &roleRepository{db: dbConnection}
+}
+
+// WithTx returns a repository that runs its queries inside the given transaction.
+func (rr *roleRepository) WithTx(tx *sql.Tx) RoleRepository {
+	return &roleRepository{db: tx}
+}
+
+// Helper function to create a context with timeout
+func (rr *roleRepository) newContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), 5*time.Second)
+}
+
+func (rr *roleRepository) HasRole(userID string, role string) (bool, error) {
+	ctx, cancel := rr.newContext()
+	defer cancel()
+
+	var count int
+	err := rr.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM user_roles WHERE user_id=? AND role=?", userID, role).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("error querying user role: %w", err)
+	}
+	return count > 0, nil
+}
+
+func (rr *roleRepository) GetRoles(userID string) ([]string, error) {
+	ctx, cancel := rr.newContext()
+	defer cancel()
+
+	rows, err := rr.db.QueryContext(ctx, "SELECT role FROM user_roles WHERE user_id=? ORDER BY role", userID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying user roles: %w", err)
+	}
+	defer rows.Close()
+
+	var roles []string
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			return nil, fmt.Errorf("error scanning user role: %w", err)
+		}
+		roles = append(roles, role)
+	}
+	return roles, rows.Err()
+}
+
+func (rr *roleRepository) AssignRole(userID string, role string) error {
+	ctx, cancel := rr.newContext()
+	defer cancel()
+
+	_, err := rr.db.ExecContext(ctx, "INSERT IGNORE INTO user_roles (user_id, role) VALUES (?,?)", userID, role)
+	if err != nil {
+		return fmt.Errorf("failed to assign role: %w", err)
+	}
+	return nil
+}
+
+var RoleRepositoryProviderSet = wire.NewSet(NewRoleRepository)
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"github.com/SilentPlaces/basicauth.git/internal/db/mysql"
 	"github.com/SilentPlaces/basicauth.git/internal/models/models"
 	consulService "github.com/SilentPlaces/basicauth.git/internal/services/consul"
 	helpers "github.com/SilentPlaces/basicauth.git/pkg/helper/hash"
@@ -15,21 +16,56 @@ import (
 
 type UserRepository interface {
 	GetUserByID(id string) (*models.User, error)
-	GetUserByMail(mail string) (*models.User, error)
+	// GetUserByMail looks the user up by canonical email, see validation.EmailNormalizer.
+	GetUserByMail(canonicalEmail string) (*models.User, error)
 	InsertUser(user models.User) (*models.User, error)
 	UpdateUser(user *models.User) (*models.User, error)
+	// UpdatePassword hashes password and stores it as the user's password. It restarts the
+	// password's age and clears PasswordChangeRequired.
+	UpdatePassword(id string, password string) error
+	// SetPasswordChangeRequired sets or clears the flag that forces a password change on login.
+	SetPasswordChangeRequired(id string, required bool) error
 	DeleteUserByID(id string) error
+	// ListUnverifiedBefore returns up to limit unverified users created before cutoff, ordered by
+	// id and starting after afterID, so callers can page through rows they decide to keep.
+	ListUnverifiedBefore(cutoff time.Time, afterID string, limit int) ([]models.User, error)
+	// DeleteUnverifiedUser deletes the user only while it is still unverified and created before
+	// cutoff. It reports whether a row was deleted.
+	DeleteUnverifiedUser(id string, cutoff time.Time) (bool, error)
+	// ScheduleDeletion marks the user for deletion at the given time.
+	ScheduleDeletion(id string, at time.Time) error
+	// CancelDeletion unmarks the user while the scheduled time has not passed. It reports whether
+	// a deletion was cancelled.
+	CancelDeletion(id string) (bool, error)
+	// ListDeletionsDueBefore returns up to limit users whose deletion is scheduled before cutoff,
+	// ordered by id and starting after afterID.
+	ListDeletionsDueBefore(cutoff time.Time, afterID string, limit int) ([]models.User, error)
+	// DeleteScheduledUser deletes the user only while its deletion is scheduled before cutoff. It
+	// reports whether a row was deleted.
+	DeleteScheduledUser(id string, cutoff time.Time) (bool, error)
+	WithTx(tx *sql.Tx) UserRepository
 }
 
+// userColumns are the columns scanned into models.User, in scan order.
+const userColumns = "id, name, email, COALESCE(canonical_email, ''), password, password_changed_at, password_change_required, is_verified, verified_at, created_at, locale, deletion_scheduled_at"
+
 type userRepository struct {
-	db      *sql.DB
+	db      mysql.Executor
 	timeout time.Duration
 }
 
-func NewUserRepository(dbConnection *sql.DB, consul consulService.ConsulService) UserRepository {
-	cfg := consul.GetRegistrationConfig()
+func NewUserRepository(dbConnection *sql.DB, consul consulService.ConsulService) (UserRepository, error) {
+	cfg, err := consul.GetRegistrationConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return &userRepository{db: dbConnection, timeout: cfg.MailVerificationTimeInSeconds}, nil
+}
 
-	return &userRepository{db: dbConnection, timeout: cfg.MailVerificationTimeInSeconds}
+// WithTx returns a repository that runs its queries inside the given transaction.
+func (ur *userRepository) WithTx(tx *sql.Tx) UserRepository {
+	return &userRepository{db: tx, timeout: ur.timeout}
 }
 
 // Helper function to create a context with timeout
@@ -37,13 +73,23 @@ func (ur *userRepository) newContext() (context.Context, context.CancelFunc) {
 	return context.WithTimeout(context.Background(), 5*time.Second)
 }
 
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanUser reads a row selected with userColumns into u.
+func scanUser(row rowScanner, u *models.User) error {
+	return row.Scan(&u.ID, &u.Name, &u.Email, &u.CanonicalEmail, &u.Password, &u.PasswordChangedAt, &u.PasswordChangeRequired,
+		&u.IsVerified, &u.VerifiedAt, &u.CreatedAt, &u.Locale, &u.DeletionScheduledAt)
+}
+
 // Common function to query a user by a given condition (ID or Mail)
 func (ur *userRepository) getUserByCondition(query string, args ...interface{}) (*models.User, error) {
 	var u models.User
 	ctx, cancel := ur.newContext()
 	defer cancel()
 
-	err := ur.db.QueryRowContext(ctx, query, args...).Scan(&u.ID, &u.Name, &u.Email, &u.Password, &u.IsVerified, &u.VerifiedAt, &u.CreatedAt)
+	err := scanUser(ur.db.QueryRowContext(ctx, query, args...), &u)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -54,13 +100,13 @@ func (ur *userRepository) getUserByCondition(query string, args ...interface{})
 }
 
 func (ur *userRepository) GetUserByID(id string) (*models.User, error) {
-	query := "SELECT id, name, email, password, is_verified, verified_at, created_at FROM users WHERE id=?"
+	query := "SELECT " + userColumns + " FROM users WHERE id=?"
 	return ur.getUserByCondition(query, id)
 }
 
-func (ur *userRepository) GetUserByMail(mail string) (*models.User, error) {
-	query := "SELECT id, name, email, password, is_verified, verified_at, created_at FROM users WHERE email=?"
-	return ur.getUserByCondition(query, mail)
+func (ur *userRepository) GetUserByMail(canonicalEmail string) (*models.User, error) {
+	query := "SELECT " + userColumns + " FROM users WHERE canonical_email=?"
+	return ur.getUserByCondition(query, canonicalEmail)
 }
 
 func (ur *userRepository) InsertUser(user models.User) (*models.User, error) {
@@ -77,8 +123,8 @@ func (ur *userRepository) InsertUser(user models.User) (*models.User, error) {
 	ctx, cancel := ur.newContext()
 	defer cancel()
 	result, err := ur.db.ExecContext(ctx,
-		"INSERT INTO users (id, name, email, password) VALUES (?,?,?,?)",
-		user.ID, user.Name, user.Email, user.Password)
+		"INSERT INTO users (id, name, email, canonical_email, password, locale) VALUES (?,?,?,?,?,?)",
+		user.ID, user.Name, user.Email, user.CanonicalEmail, user.Password, user.Locale)
 	if err != nil {
 		return nil, fmt.Errorf("failed to insert user: %w", err)
 	}
@@ -104,11 +150,54 @@ func (ur *userRepository) DeleteUserByID(id string) error {
 	return nil
 }
 
+func (ur *userRepository) ListUnverifiedBefore(cutoff time.Time, afterID string, limit int) ([]models.User, error) {
+	ctx, cancel := ur.newContext()
+	defer cancel()
+
+	rows, err := ur.db.QueryContext(ctx,
+		"SELECT "+userColumns+" FROM users "+
+			"WHERE is_verified = FALSE AND created_at < ? AND id > ? ORDER BY id LIMIT ?",
+		cutoff, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error querying unverified users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		var u models.User
+		if err := scanUser(rows, &u); err != nil {
+			return nil, fmt.Errorf("error scanning unverified user: %w", err)
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+func (ur *userRepository) DeleteUnverifiedUser(id string, cutoff time.Time) (bool, error) {
+	ctx, cancel := ur.newContext()
+	defer cancel()
+
+	result, err := ur.db.ExecContext(ctx, "DELETE FROM users WHERE id=? AND is_verified = FALSE AND created_at < ?", id, cutoff)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete unverified user: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to check affected rows: %w", err)
+	}
+	return rowsAffected > 0, nil
+}
+
 func (ur *userRepository) UpdateUser(user *models.User) (*models.User, error) {
 	ctx, cancel := ur.newContext()
 	defer cancel()
-	query := "UPDATE users SET name=?, email=?, password=?, is_verified=?, verified_at=? WHERE id=?"
-	result, err := ur.db.ExecContext(ctx, query, user.Name, user.Email, user.Password, user.IsVerified, user.VerifiedAt, user.ID)
+	// MySQL assigns from left to right, so password_changed_at still sees the old password.
+	query := "UPDATE users SET name=?, email=?, canonical_email=NULLIF(?, ''), " +
+		"password_changed_at=IF(password=?, password_changed_at, CURRENT_TIMESTAMP), password=?, " +
+		"password_change_required=?, is_verified=?, verified_at=?, locale=? WHERE id=?"
+	result, err := ur.db.ExecContext(ctx, query, user.Name, user.Email, user.CanonicalEmail, user.Password, user.Password,
+		user.PasswordChangeRequired, user.IsVerified, user.VerifiedAt, user.Locale, user.ID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update user: %w", err)
 	}
@@ -117,7 +206,7 @@ func (ur *userRepository) UpdateUser(user *models.User) (*models.User, error) {
 		return nil, fmt.Errorf("failed to check affected rows: %w", err)
 	}
 	if rowsAffected == 0 {
-		return nil, fmt.Errorf("no user found with id: %d", user.ID)
+		return nil, fmt.Errorf("no user found with id: %s", user.ID)
 	}
 	updatedUser, err := ur.GetUserByID(user.ID)
 	if err != nil {
@@ -126,4 +215,91 @@ func (ur *userRepository) UpdateUser(user *models.User) (*models.User, error) {
 	return updatedUser, nil
 }
 
+func (ur *userRepository) UpdatePassword(id string, password string) error {
+	ctx, cancel := ur.newContext()
+	defer cancel()
+
+	// MySQL reports an update that keeps the value as zero rows, so the count is not checked.
+	query := "UPDATE users SET password=?, password_changed_at=CURRENT_TIMESTAMP, password_change_required=FALSE WHERE id=?"
+	if _, err := ur.db.ExecContext(ctx, query, helpers.TextToSHA1(password), id); err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+	return nil
+}
+
+func (ur *userRepository) SetPasswordChangeRequired(id string, required bool) error {
+	ctx, cancel := ur.newContext()
+	defer cancel()
+
+	if _, err := ur.db.ExecContext(ctx, "UPDATE users SET password_change_required=? WHERE id=?", required, id); err != nil {
+		return fmt.Errorf("failed to update password change flag: %w", err)
+	}
+	return nil
+}
+
+func (ur *userRepository) ScheduleDeletion(id string, at time.Time) error {
+	ctx, cancel := ur.newContext()
+	defer cancel()
+
+	if _, err := ur.db.ExecContext(ctx, "UPDATE users SET deletion_scheduled_at=? WHERE id=?", at, id); err != nil {
+		return fmt.Errorf("failed to schedule user deletion: %w", err)
+	}
+	return nil
+}
+
+func (ur *userRepository) CancelDeletion(id string) (bool, error) {
+	ctx, cancel := ur.newContext()
+	defer cancel()
+
+	result, err := ur.db.ExecContext(ctx,
+		"UPDATE users SET deletion_scheduled_at=NULL WHERE id=? AND deletion_scheduled_at > CURRENT_TIMESTAMP", id)
+	if err != nil {
+		return false, fmt.Errorf("failed to cancel user deletion: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to check affected rows: %w", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+func (ur *userRepository) ListDeletionsDueBefore(cutoff time.Time, afterID string, limit int) ([]models.User, error) {
+	ctx, cancel := ur.newContext()
+	defer cancel()
+
+	rows, err := ur.db.QueryContext(ctx,
+		"SELECT "+userColumns+" FROM users "+
+			"WHERE deletion_scheduled_at <= ? AND id > ? ORDER BY id LIMIT ?",
+		cutoff, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error querying users due for deletion: %w", err)
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		var u models.User
+		if err := scanUser(rows, &u); err != nil {
+			return nil, fmt.Errorf("error scanning user due for deletion: %w", err)
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+func (ur *userRepository) DeleteScheduledUser(id string, cutoff time.Time) (bool, error) {
+	ctx, cancel := ur.newContext()
+	defer cancel()
+
+	result, err := ur.db.ExecContext(ctx, "DELETE FROM users WHERE id=? AND deletion_scheduled_at <= ?", id, cutoff)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete scheduled user: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to check affected rows: %w", err)
+	}
+	return rowsAffected > 0, nil
+}
+
 var UserRepositoryProviderSet = wire.NewSet(NewUserRepository)
@@ -0,0 +1,344 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/SilentPlaces/basicauth.git/internal/models/models"
+	"github.com/google/wire"
+)
+
+type (
+	WebhookRepository interface {
+		InsertSubscription(subscription models.WebhookSubscription) error
+		GetSubscription(id string) (*models.WebhookSubscription, error)
+		ListSubscriptions() ([]models.WebhookSubscription, error)
+		DeleteSubscription(id string) error
+
+		InsertDeliveries(deliveries []models.WebhookDelivery) error
+		ClaimDueDeliveries(limit int, lease time.Duration) ([]models.WebhookDelivery, error)
+		MarkDelivered(id string, statusCode int) error
+		MarkFailed(id string, status string, nextAttemptAt time.Time, statusCode int, lastError string) error
+		ListDeliveries(subscriptionID string, status string, limit int, offset int) ([]models.WebhookDelivery, error)
+		// ListDeliveriesBySubject returns the deliveries about any of subjects, newest first.
+		ListDeliveriesBySubject(subjects []string, limit int, offset int) ([]models.WebhookDelivery, error)
+		// RedactDeliveries replaces the payload of the deliveries about any of subjects with one
+		// that only names the event, and stops those still pending. It returns the number of
+		// deliveries changed.
+		RedactDeliveries(subjects []string) (int64, error)
+		Requeue(id string) error
+	}
+
+	webhookRepository struct {
+		db *sql.DB
+	}
+)
+
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+// redactedSubject replaces the subject of redacted deliveries, which are never sent again.
+const redactedSubject = "redacted"
+
+func NewWebhookRepository(db *sql.DB) WebhookRepository {
+	return &webhookRepository{db: db}
+}
+
+// Helper function to create a context with timeout
+func (wr *webhookRepository) newContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), 5*time.Second)
+}
+
+func (wr *webhookRepository) InsertSubscription(subscription models.WebhookSubscription) error {
+	ctx, cancel := wr.newContext()
+	defer cancel()
+
+	_, err := wr.db.ExecContext(ctx,
+		"INSERT INTO webhook_subscriptions (id, url, event_types, secret, active) VALUES (?,?,?,?,?)",
+		subscription.ID, subscription.URL, strings.Join(subscription.EventTypes, ","), subscription.Secret, subscription.Active)
+	if err != nil {
+		return fmt.Errorf("failed to insert webhook subscription: %w", err)
+	}
+	return nil
+}
+
+func (wr *webhookRepository) GetSubscription(id string) (*models.WebhookSubscription, error) {
+	ctx, cancel := wr.newContext()
+	defer cancel()
+
+	row := wr.db.QueryRowContext(ctx, "SELECT "+subscriptionColumns+" FROM webhook_subscriptions WHERE id=?", id)
+	s, err := scanSubscription(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error querying webhook subscription: %w", err)
+	}
+	return s, nil
+}
+
+func (wr *webhookRepository) ListSubscriptions() ([]models.WebhookSubscription, error) {
+	ctx, cancel := wr.newContext()
+	defer cancel()
+
+	rows, err := wr.db.QueryContext(ctx, "SELECT "+subscriptionColumns+" FROM webhook_subscriptions ORDER BY created_at")
+	if err != nil {
+		return nil, fmt.Errorf("error querying webhook subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	var subscriptions []models.WebhookSubscription
+	for rows.Next() {
+		s, err := scanSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning webhook subscription: %w", err)
+		}
+		subscriptions = append(subscriptions, *s)
+	}
+	return subscriptions, rows.Err()
+}
+
+func (wr *webhookRepository) DeleteSubscription(id string) error {
+	ctx, cancel := wr.newContext()
+	defer cancel()
+
+	result, err := wr.db.ExecContext(ctx, "DELETE FROM webhook_subscriptions WHERE id=?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook subscription: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+func (wr *webhookRepository) InsertDeliveries(deliveries []models.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	ctx, cancel := wr.newContext()
+	defer cancel()
+
+	placeholders := make([]string, 0, len(deliveries))
+	args := make([]interface{}, 0, len(deliveries)*7)
+	for _, d := range deliveries {
+		placeholders = append(placeholders, "(?,?,?,?,NULLIF(?, ''),?,?)")
+		args = append(args, d.ID, d.SubscriptionID, d.EventID, d.EventType, d.Subject, d.Payload, models.WebhookDeliveryPending)
+	}
+	_, err := wr.db.ExecContext(ctx,
+		"INSERT INTO webhook_deliveries (id, subscription_id, event_id, event_type, subject, payload, status) VALUES "+strings.Join(placeholders, ","),
+		args...)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue webhook deliveries: %w", err)
+	}
+	return nil
+}
+
+// ClaimDueDeliveries picks pending deliveries whose retry time has come and pushes their
+// next attempt out by lease, so other replicas skip them while this one is sending.
+func (wr *webhookRepository) ClaimDueDeliveries(limit int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	ctx, cancel := wr.newContext()
+	defer cancel()
+
+	tx, err := wr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin webhook claim transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx,
+		"SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE status=? AND next_attempt_at<=? ORDER BY next_attempt_at LIMIT ? FOR UPDATE SKIP LOCKED",
+		models.WebhookDeliveryPending, time.Now().UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("error querying due webhook deliveries: %w", err)
+	}
+	deliveries, err := scanDeliveries(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(deliveries) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]interface{}, 0, len(deliveries)+1)
+	ids = append(ids, time.Now().UTC().Add(lease))
+	for _, d := range deliveries {
+		ids = append(ids, d.ID)
+	}
+	_, err = tx.ExecContext(ctx,
+		"UPDATE webhook_deliveries SET next_attempt_at=? WHERE id IN (?"+strings.Repeat(",?", len(deliveries)-1)+")",
+		ids...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lease webhook deliveries: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit webhook claim: %w", err)
+	}
+	return deliveries, nil
+}
+
+func (wr *webhookRepository) MarkDelivered(id string, statusCode int) error {
+	ctx, cancel := wr.newContext()
+	defer cancel()
+
+	_, err := wr.db.ExecContext(ctx,
+		"UPDATE webhook_deliveries SET status=?, attempts=attempts+1, last_status_code=?, last_error=NULL, delivered_at=? WHERE id=?",
+		models.WebhookDeliveryDelivered, statusCode, time.Now().UTC(), id)
+	if err != nil {
+		return fmt.Errorf("failed to mark webhook delivery as delivered: %w", err)
+	}
+	return nil
+}
+
+// MarkFailed records a failed attempt. status is pending to retry at nextAttemptAt, or dead to stop retrying.
+func (wr *webhookRepository) MarkFailed(id string, status string, nextAttemptAt time.Time, statusCode int, lastError string) error {
+	ctx, cancel := wr.newContext()
+	defer cancel()
+
+	code := sql.NullInt64{Int64: int64(statusCode), Valid: statusCode != 0}
+	_, err := wr.db.ExecContext(ctx,
+		"UPDATE webhook_deliveries SET status=?, attempts=attempts+1, next_attempt_at=?, last_status_code=?, last_error=? WHERE id=?",
+		status, nextAttemptAt.UTC(), code, lastError, id)
+	if err != nil {
+		return fmt.Errorf("failed to record webhook delivery failure: %w", err)
+	}
+	return nil
+}
+
+func (wr *webhookRepository) ListDeliveries(subscriptionID string, status string, limit int, offset int) ([]models.WebhookDelivery, error) {
+	ctx, cancel := wr.newContext()
+	defer cancel()
+
+	query := "SELECT " + deliveryColumns + " FROM webhook_deliveries WHERE subscription_id=?"
+	args := []interface{}{subscriptionID}
+	if status != "" {
+		query += " AND status=?"
+		args = append(args, status)
+	}
+	query += " ORDER BY created_at DESC LIMIT ? OFFSET ?"
+	args = append(args, limit, offset)
+
+	rows, err := wr.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying webhook deliveries: %w", err)
+	}
+	return scanDeliveries(rows)
+}
+
+func (wr *webhookRepository) ListDeliveriesBySubject(subjects []string, limit int, offset int) ([]models.WebhookDelivery, error) {
+	if len(subjects) == 0 {
+		return nil, nil
+	}
+	ctx, cancel := wr.newContext()
+	defer cancel()
+
+	args := make([]interface{}, 0, len(subjects)+2)
+	for _, subject := range subjects {
+		args = append(args, subject)
+	}
+	args = append(args, limit, offset)
+	rows, err := wr.db.QueryContext(ctx,
+		"SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE subject IN (?"+strings.Repeat(",?", len(subjects)-1)+") ORDER BY created_at DESC, id LIMIT ? OFFSET ?",
+		args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying webhook deliveries: %w", err)
+	}
+	return scanDeliveries(rows)
+}
+
+func (wr *webhookRepository) RedactDeliveries(subjects []string) (int64, error) {
+	if len(subjects) == 0 {
+		return 0, nil
+	}
+	ctx, cancel := wr.newContext()
+	defer cancel()
+
+	// Assignments are applied left to right, so last_error still sees the old status.
+	args := []interface{}{
+		models.WebhookDeliveryPending, "payload redacted after account deletion",
+		models.WebhookDeliveryPending, models.WebhookDeliveryDead,
+		redactedSubject,
+	}
+	for _, subject := range subjects {
+		args = append(args, subject)
+	}
+	result, err := wr.db.ExecContext(ctx,
+		`UPDATE webhook_deliveries
+		 SET payload=JSON_OBJECT('id', event_id, 'type', event_type, 'redacted', TRUE),
+		     last_error=IF(status=?, ?, last_error),
+		     status=IF(status=?, ?, status),
+		     subject=?
+		 WHERE subject IN (?`+strings.Repeat(",?", len(subjects)-1)+")",
+		args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to redact webhook deliveries: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to determine rows affected: %w", err)
+	}
+	return affected, nil
+}
+
+// Requeue puts a delivery back into the queue with a fresh attempt budget. Redacted deliveries
+// cannot be requeued.
+func (wr *webhookRepository) Requeue(id string) error {
+	ctx, cancel := wr.newContext()
+	defer cancel()
+
+	result, err := wr.db.ExecContext(ctx,
+		"UPDATE webhook_deliveries SET status=?, attempts=0, next_attempt_at=? WHERE id=? AND status<>? AND (subject IS NULL OR subject<>?)",
+		models.WebhookDeliveryPending, time.Now().UTC(), id, models.WebhookDeliveryDelivered, redactedSubject)
+	if err != nil {
+		return fmt.Errorf("failed to requeue webhook delivery: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSubscription(row rowScanner) (*models.WebhookSubscription, error) {
+	var s models.WebhookSubscription
+	var eventTypes string
+	if err := row.Scan(&s.ID, &s.URL, &eventTypes, &s.Secret, &s.Active, &s.CreatedAt); err != nil {
+		return nil, err
+	}
+	if eventTypes != "" {
+		s.EventTypes = strings.Split(eventTypes, ",")
+	}
+	return &s, nil
+}
+
+func scanDeliveries(rows *sql.Rows) ([]models.WebhookDelivery, error) {
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		var d models.WebhookDelivery
+		if err := rows.Scan(&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &d.Subject, &d.Payload, &d.Status, &d.Attempts,
+			&d.NextAttemptAt, &d.LastStatusCode, &d.LastError, &d.CreatedAt, &d.DeliveredAt); err != nil {
+			return nil, fmt.Errorf("error scanning webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+const (
+	subscriptionColumns = "id, url, event_types, secret, active, created_at"
+	deliveryColumns     = "id, subscription_id, event_id, event_type, COALESCE(subject, ''), payload, status, attempts, next_attempt_at, last_status_code, last_error, created_at, delivered_at"
+)
+
+var WebhookRepositoryProviderSet = wire.NewSet(NewWebhookRepository)
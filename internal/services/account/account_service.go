@@ -0,0 +1,236 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/SilentPlaces/basicauth.git/internal/config"
+	"github.com/SilentPlaces/basicauth.git/internal/models/models"
+	auditRepo "github.com/SilentPlaces/basicauth.git/internal/repositories/audit"
+	invitationRepo "github.com/SilentPlaces/basicauth.git/internal/repositories/invitation"
+	mailQueueRepo "github.com/SilentPlaces/basicauth.git/internal/repositories/mailqueue"
+	policyRepo "github.com/SilentPlaces/basicauth.git/internal/repositories/policy"
+	userRepo "github.com/SilentPlaces/basicauth.git/internal/repositories/user"
+	webhookRepo "github.com/SilentPlaces/basicauth.git/internal/repositories/webhook"
+	sessionService "github.com/SilentPlaces/basicauth.git/internal/services/session"
+	"github.com/google/wire"
+)
+
+// exportPageSize is the page size used to collect audit events, invitations and webhook deliveries
+// for an export.
+const exportPageSize = 500
+
+var (
+	ErrUserNotFound = errors.New("user not found")
+	// ErrAccountDeleted is returned for an account whose deletion is final but not carried out yet.
+	ErrAccountDeleted = errors.New("account is deleted")
+)
+
+type (
+	AccountService interface {
+		// ScheduleDeletion ends the user's sessions and schedules the deletion of the account after
+		// the grace period. It returns when the deletion becomes final; asking again keeps the
+		// earlier date.
+		ScheduleDeletion(ctx context.Context, userID string) (time.Time, error)
+		// CancelDeletion cancels a scheduled deletion and reports whether there was one. Once the
+		// deletion is final it returns ErrAccountDeleted.
+		CancelDeletion(ctx context.Context, userID string) (bool, error)
+		// Export collects everything stored about the user.
+		Export(ctx context.Context, userID string) (*models.AccountExport, error)
+	}
+
+	accountService struct {
+		userRepository       userRepo.UserRepository
+		roleRepository       userRepo.RoleRepository
+		auditRepository      auditRepo.AuditRepository
+		invitationRepository invitationRepo.InvitationRepository
+		policyRepository     policyRepo.PolicyRepository
+		webhookRepository    webhookRepo.WebhookRepository
+		mailQueueRepository  mailQueueRepo.MailQueueRepository
+		sessions             sessionService.SessionService
+		cfg                  *config.AccountDeletionConfig
+	}
+)
+
+func NewAccountService(
+	userRepository userRepo.UserRepository,
+	roleRepository userRepo.RoleRepository,
+	auditRepository auditRepo.AuditRepository,
+	invitationRepository invitationRepo.InvitationRepository,
+	policyRepository policyRepo.PolicyRepository,
+	webhookRepository webhookRepo.WebhookRepository,
+	mailQueueRepository mailQueueRepo.MailQueueRepository,
+	sessions sessionService.SessionService,
+	cfg *config.AccountDeletionConfig,
+) AccountService {
+	return &accountService{
+		userRepository:       userRepository,
+		roleRepository:       roleRepository,
+		auditRepository:      auditRepository,
+		invitationRepository: invitationRepository,
+		policyRepository:     policyRepository,
+		webhookRepository:    webhookRepository,
+		mailQueueRepository:  mailQueueRepository,
+		sessions:             sessions,
+		cfg:                  cfg,
+	}
+}
+
+func (s *accountService) ScheduleDeletion(ctx context.Context, userID string) (time.Time, error) {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	deleteAt := time.Now().Add(s.cfg.GracePeriod).UTC().Truncate(time.Second)
+	if user.DeletionScheduledAt.Valid {
+		deleteAt = user.DeletionScheduledAt.Time
+	} else if err := s.userRepository.ScheduleDeletion(userID, deleteAt); err != nil {
+		return time.Time{}, err
+	}
+	if err := s.sessions.RevokeAll(ctx, userID); err != nil {
+		return time.Time{}, err
+	}
+	return deleteAt, nil
+}
+
+func (s *accountService) CancelDeletion(ctx context.Context, userID string) (bool, error) {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return false, err
+	}
+	if !user.DeletionScheduledAt.Valid {
+		return false, nil
+	}
+	cancelled, err := s.userRepository.CancelDeletion(userID)
+	if err != nil {
+		return false, err
+	}
+	if !cancelled {
+		return false, ErrAccountDeleted
+	}
+	return true, nil
+}
+
+func (s *accountService) Export(ctx context.Context, userID string) (*models.AccountExport, error) {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	export := &models.AccountExport{ExportedAt: time.Now().UTC(), User: *user}
+
+	if export.Roles, err = s.roleRepository.GetRoles(userID); err != nil {
+		return nil, err
+	}
+	if export.Sessions, err = s.sessions.List(ctx, userID); err != nil {
+		return nil, err
+	}
+	if export.Consents, err = s.policyRepository.ListConsents(userID); err != nil {
+		return nil, err
+	}
+
+	identifiers := accountIdentifiers(user)
+	events, err := s.auditEvents(identifiers)
+	if err != nil {
+		return nil, err
+	}
+	for _, event := range events {
+		if event.Action == models.AuditActionLogin {
+			export.LoginHistory = append(export.LoginHistory, event)
+		} else {
+			export.Activity = append(export.Activity, event)
+		}
+	}
+
+	for offset := 0; ; offset += exportPageSize {
+		invitations, err := s.invitationRepository.ListInvitations(userID, exportPageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		export.Invitations = append(export.Invitations, invitations...)
+		if len(invitations) < exportPageSize {
+			break
+		}
+	}
+
+	for offset := 0; ; offset += exportPageSize {
+		deliveries, err := s.webhookRepository.ListDeliveriesBySubject(identifiers, exportPageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		export.WebhookDeliveries = append(export.WebhookDeliveries, deliveries...)
+		if len(deliveries) < exportPageSize {
+			break
+		}
+	}
+
+	if export.DeadLetterMails, err = s.mailQueueRepository.ListDeadTo(identifiers); err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+// auditEvents returns the events any of identifiers is the actor or subject of, newest first.
+func (s *accountService) auditEvents(identifiers []string) ([]models.AuditEvent, error) {
+	seen := make(map[int64]bool)
+	var events []models.AuditEvent
+	collect := func(filter models.AuditEventFilter) error {
+		filter.Limit = exportPageSize
+		for {
+			page, err := s.auditRepository.Query(filter)
+			if err != nil {
+				return err
+			}
+			for _, event := range page {
+				if !seen[event.ID] {
+					seen[event.ID] = true
+					events = append(events, event)
+				}
+			}
+			if len(page) < exportPageSize {
+				return nil
+			}
+			filter.Offset += exportPageSize
+		}
+	}
+	for _, identifier := range identifiers {
+		if err := collect(models.AuditEventFilter{Actor: identifier}); err != nil {
+			return nil, err
+		}
+		if err := collect(models.AuditEventFilter{Subject: identifier}); err != nil {
+			return nil, err
+		}
+	}
+	slices.SortFunc(events, func(a, b models.AuditEvent) int {
+		return cmp.Compare(b.ID, a.ID)
+	})
+	return events, nil
+}
+
+func (s *accountService) getUser(userID string) (*models.User, error) {
+	user, err := s.userRepository.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// accountIdentifiers returns the values that identify the user in the audit log: the ID, and the
+// email as stored and in canonical form, which signup and login events are recorded under.
+func accountIdentifiers(user *models.User) []string {
+	var identifiers []string
+	for _, identifier := range []string{user.ID, user.Email, user.CanonicalEmail} {
+		if identifier != "" && !slices.Contains(identifiers, identifier) {
+			identifiers = append(identifiers, identifier)
+		}
+	}
+	return identifiers
+}
+
+var AccountServiceProviderSet = wire.NewSet(NewAccountService)
@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/SilentPlaces/basicauth.git/internal/config"
+	"github.com/SilentPlaces/basicauth.git/internal/db/mysql"
+	"github.com/SilentPlaces/basicauth.git/internal/models/models"
+	invitationRepo "github.com/SilentPlaces/basicauth.git/internal/repositories/invitation"
+	lockRepo "github.com/SilentPlaces/basicauth.git/internal/repositories/lock"
+	mailQueueRepo "github.com/SilentPlaces/basicauth.git/internal/repositories/mailqueue"
+	outboxRepo "github.com/SilentPlaces/basicauth.git/internal/repositories/outbox"
+	registrationRepo "github.com/SilentPlaces/basicauth.git/internal/repositories/registration"
+	userRepo "github.com/SilentPlaces/basicauth.git/internal/repositories/user"
+	webhookRepo "github.com/SilentPlaces/basicauth.git/internal/repositories/webhook"
+	auditService "github.com/SilentPlaces/basicauth.git/internal/services/audit"
+	outboxService "github.com/SilentPlaces/basicauth.git/internal/services/outbox"
+	sessionService "github.com/SilentPlaces/basicauth.git/internal/services/session"
+	webhookService "github.com/SilentPlaces/basicauth.git/internal/services/webhook"
+	appLogger "github.com/SilentPlaces/basicauth.git/internal/shared/logger"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const deletionReaperLockName = "account-deletion-reaper"
+
+var accountsDeletedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "accounts_deleted_total",
+	Help: "Accounts deleted after their deletion grace period.",
+})
+
+func init() {
+	prometheus.MustRegister(accountsDeletedTotal)
+}
+
+// DeletionReaper carries out account deletions once their grace period is over. It first removes
+// the user's personal data from the audit log, webhook deliveries, dead-lettered mails and Redis,
+// then deletes the user row with its roles, password history, invitations and outbox events. A
+// failed step is retried on the next run, as the row is only deleted last. A Redis lock makes sure
+// only one replica reaps at a time.
+type DeletionReaper struct {
+	userRepository         userRepo.UserRepository
+	invitationRepository   invitationRepo.InvitationRepository
+	outboxRepository       outboxRepo.OutboxRepository
+	registrationRepository registrationRepo.RegistrationRepository
+	webhookRepository      webhookRepo.WebhookRepository
+	mailQueueRepository    mailQueueRepo.MailQueueRepository
+	lockRepository         lockRepo.LockRepository
+	transactor             mysql.Transactor
+	sessions               sessionService.SessionService
+	audit                  auditService.AuditService
+	webhooks               webhookService.WebhookService
+	cfg                    *config.AccountDeletionConfig
+	logger                 appLogger.Logger
+}
+
+func NewDeletionReaper(
+	userRepository userRepo.UserRepository,
+	invitationRepository invitationRepo.InvitationRepository,
+	outboxRepository outboxRepo.OutboxRepository,
+	registrationRepository registrationRepo.RegistrationRepository,
+	webhookRepository webhookRepo.WebhookRepository,
+	mailQueueRepository mailQueueRepo.MailQueueRepository,
+	lockRepository lockRepo.LockRepository,
+	transactor mysql.Transactor,
+	sessions sessionService.SessionService,
+	audit auditService.AuditService,
+	webhooks webhookService.WebhookService,
+	cfg *config.AccountDeletionConfig,
+	logger appLogger.Logger,
+) *DeletionReaper {
+	return &DeletionReaper{
+		userRepository:         userRepository,
+		invitationRepository:   invitationRepository,
+		outboxRepository:       outboxRepository,
+		registrationRepository: registrationRepository,
+		webhookRepository:      webhookRepository,
+		mailQueueRepository:    mailQueueRepository,
+		lockRepository:         lockRepository,
+		transactor:             transactor,
+		sessions:               sessions,
+		audit:                  audit,
+		webhooks:               webhooks,
+		cfg:                    cfg,
+		logger:                 logger,
+	}
+}
+
+// Run reaps once at startup and then every interval until ctx is cancelled.
+func (r *DeletionReaper) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		r.reap(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *DeletionReaper) reap(ctx context.Context) {
+	token, acquired, err := r.lockRepository.Acquire(deletionReaperLockName, r.cfg.Interval)
+	if err != nil {
+		r.logger.Error(ctx, "account deletion reaper lock failed", err, nil)
+		return
+	}
+	if !acquired {
+		r.logger.Debug(ctx, "account deletion reaper running on another replica", nil)
+		return
+	}
+	defer func() {
+		if err := r.lockRepository.Release(deletionReaperLockName, token); err != nil {
+			r.logger.Warn(ctx, "account deletion reaper unlock failed", map[string]interface{}{"error": err.Error()})
+		}
+	}()
+
+	cutoff := time.Now()
+	deleted := 0
+	afterID := ""
+	for ctx.Err() == nil {
+		users, err := r.userRepository.ListDeletionsDueBefore(cutoff, afterID, r.cfg.BatchSize)
+		if err != nil {
+			r.logger.Error(ctx, "account deletion reaper listing failed", err, nil)
+			break
+		}
+		for _, user := range users {
+			afterID = user.ID
+			ok, err := r.deleteAccount(ctx, user, cutoff)
+			if err != nil {
+				r.logger.Error(ctx, "account deletion failed", err, map[string]interface{}{"user_id": user.ID})
+				continue
+			}
+			if ok {
+				deleted++
+			}
+		}
+		if len(users) < r.cfg.BatchSize {
+			break
+		}
+	}
+
+	if deleted > 0 {
+		r.logger.Info(ctx, "scheduled account deletions carried out", map[string]interface{}{"deleted": deleted})
+	}
+}
+
+// deleteAccount purges the user and reports whether the row was deleted.
+func (r *DeletionReaper) deleteAccount(ctx context.Context, user models.User, cutoff time.Time) (bool, error) {
+	identifiers := accountIdentifiers(&user)
+	if _, err := r.audit.Redact(identifiers); err != nil {
+		return false, err
+	}
+	if _, err := r.webhookRepository.RedactDeliveries(identifiers); err != nil {
+		return false, err
+	}
+	if _, err := r.mailQueueRepository.DeleteDeadTo(identifiers); err != nil {
+		return false, err
+	}
+	if user.CanonicalEmail != "" {
+		if err := r.registrationRepository.DeleteToken(user.CanonicalEmail); err != nil {
+			return false, err
+		}
+		if err := r.registrationRepository.DeleteCode(user.CanonicalEmail); err != nil {
+			return false, err
+		}
+		if err := r.registrationRepository.DeleteVerificationCount(user.CanonicalEmail); err != nil {
+			return false, err
+		}
+	}
+	if err := r.sessions.RevokeAll(ctx, user.ID); err != nil {
+		return false, err
+	}
+
+	deleted := false
+	err := r.transactor.WithinTransaction(func(tx *sql.Tx) error {
+		var err error
+		deleted, err = r.userRepository.WithTx(tx).DeleteScheduledUser(user.ID, cutoff)
+		if err != nil || !deleted {
+			return err
+		}
+		if err := r.invitationRepository.WithTx(tx).DeleteUserInvitations(user.ID, user.CanonicalEmail); err != nil {
+			return err
+		}
+		// Earlier events carry the email and name. Events not published yet are dropped too, the
+		// UserDeleted event below tells consumers to forget the user.
+		outbox := r.outboxRepository.WithTx(tx)
+		if _, err := outbox.DeleteAggregateEvents(models.AggregateUser, user.ID); err != nil {
+			return err
+		}
+		event, err := outboxService.NewUserEvent(models.EventUserDeleted, user.ID, map[string]interface{}{
+			"user_id": user.ID,
+		})
+		if err != nil {
+			return err
+		}
+		return outbox.Add(event)
+	})
+	if err != nil || !deleted {
+		return false, err
+	}
+	accountsDeletedTotal.Inc()
+
+	// The deletion itself is recorded under the bare user ID, which no longer leads to any data.
+	if err := r.audit.Record(ctx, auditService.Entry{
+		Subject: user.ID,
+		Action:  models.AuditActionDelete,
+		Outcome: models.AuditOutcomeSuccess,
+	}); err != nil {
+		r.logger.Error(ctx, "account deletion audit event could not be recorded", err, map[string]interface{}{"user_id": user.ID})
+	}
+	if err := r.webhooks.Publish(ctx, models.WebhookEventUserDeleted, map[string]interface{}{"user_id": user.ID}); err != nil {
+		r.logger.Error(ctx, "account deletion webhook could not be published", err, map[string]interface{}{"user_id": user.ID})
+	}
+	return true, nil
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SilentPlaces/basicauth.git/internal/models/models"
+	repository "github.com/SilentPlaces/basicauth.git/internal/repositories/audit"
+	"github.com/SilentPlaces/basicauth.git/internal/shared/observability"
+	"github.com/google/wire"
+)
+
+const verifyBatchSize = 500
+
+// RedactedPlaceholder replaces the actor or subject of redacted events.
+const RedactedPlaceholder = "redacted"
+
+// fieldSeparator keeps hashed fields unambiguous ("ab"+"c" vs "a"+"bc").
+const fieldSeparator = "\x1f"
+
+type (
+	AuditService interface {
+		Record(ctx context.Context, entry Entry) error
+		Query(filter models.AuditEventFilter) ([]models.AuditEvent, error)
+		Verify() (*VerificationReport, error)
+		// Redact removes identifiers from the audit log, see repository.AuditRepository.Redact.
+		Redact(identifiers []string) (int64, error)
+	}
+
+	auditService struct {
+		auditRepository repository.AuditRepository
+	}
+
+	// Entry is what callers know about an event. IP and correlation ID are taken from the context.
+	Entry struct {
+		Actor    string
+		Subject  string
+		Action   string
+		Outcome  string
+		Metadata map[string]string
+	}
+
+	// VerificationReport is the result of walking the whole hash chain.
+	VerificationReport struct {
+		Valid         bool  `json:"valid"`
+		CheckedEvents int64 `json:"checked_events"`
+		// RedactedEvents counts checked events whose payload was redacted and cannot be compared
+		// with its digest.
+		RedactedEvents int64  `json:"redacted_events,omitempty"`
+		BrokenAtID     int64  `json:"broken_at_id,omitempty"`
+		Reason         string `json:"reason,omitempty"`
+	}
+)
+
+func NewAuditService(auditRepository repository.AuditRepository) AuditService {
+	return &auditService{auditRepository: auditRepository}
+}
+
+// Record appends an event to the audit log and links it to the previous one.
+func (s *auditService) Record(ctx context.Context, entry Entry) error {
+	metadata := ""
+	if len(entry.Metadata) > 0 {
+		// encoding/json sorts map keys, so the serialized form is stable for hashing.
+		raw, err := json.Marshal(entry.Metadata)
+		if err != nil {
+			return fmt.Errorf("failed to encode audit metadata: %w", err)
+		}
+		metadata = string(raw)
+	}
+
+	event := models.AuditEvent{
+		OccurredAt:    time.Now().UTC().Truncate(time.Microsecond),
+		Actor:         entry.Actor,
+		Subject:       entry.Subject,
+		Action:        entry.Action,
+		Outcome:       entry.Outcome,
+		IP:            observability.ClientIPFromContext(ctx),
+		CorrelationID: observability.CorrelationIDFromContext(ctx),
+		Metadata:      metadata,
+	}
+
+	_, err := s.auditRepository.Append(event, seal)
+	return err
+}
+
+func (s *auditService) Query(filter models.AuditEventFilter) ([]models.AuditEvent, error) {
+	return s.auditRepository.Query(filter)
+}
+
+// Verify walks the chain from the first event and recomputes every hash. It stops at the first broken link.
+func (s *auditService) Verify() (*VerificationReport, error) {
+	report := &VerificationReport{Valid: true}
+	expectedSeq := int64(1)
+	prevHash := models.AuditGenesisHash
+
+	fail := func(id int64, reason string) (*VerificationReport, error) {
+		report.Valid = false
+		report.BrokenAtID = id
+		report.Reason = reason
+		return report, nil
+	}
+
+	var lastID int64
+	for {
+		events, err := s.auditRepository.ListAfter(lastID, verifyBatchSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range events {
+			if e.ID != expectedSeq {
+				return fail(expectedSeq, fmt.Sprintf("missing event, found id %d", e.ID))
+			}
+			if e.PrevHash != prevHash {
+				return fail(e.ID, "previous hash does not match the preceding event")
+			}
+			if e.RedactedAt.Valid {
+				if !redactedShape(e) {
+					return fail(e.ID, "redacted event holds values a redaction does not write")
+				}
+				report.RedactedEvents++
+			} else if payloadHash(e) != e.PayloadHash {
+				return fail(e.ID, "payload was modified")
+			}
+			if chainHash(e) != e.Hash {
+				return fail(e.ID, "event hash does not match its content")
+			}
+			prevHash = e.Hash
+			expectedSeq++
+			report.CheckedEvents++
+			lastID = e.ID
+		}
+		if len(events) < verifyBatchSize {
+			break
+		}
+	}
+
+	headSeq, headHash, err := s.auditRepository.GetHead()
+	if err != nil {
+		return nil, err
+	}
+	if headSeq != expectedSeq-1 || headHash != prevHash {
+		return fail(headSeq, "chain head does not match the last stored event, events were removed")
+	}
+	return report, nil
+}
+
+func (s *auditService) Redact(identifiers []string) (int64, error) {
+	return s.auditRepository.Redact(identifiers, RedactedPlaceholder)
+}
+
+// redactedShape reports whether e looks like the result of Redact: the actor or subject is the
+// placeholder, metadata is gone and the IP is cleared along with a redacted actor. The trigger on
+// audit_events keeps the other values as they were.
+func redactedShape(e models.AuditEvent) bool {
+	if e.Actor != RedactedPlaceholder && e.Subject != RedactedPlaceholder {
+		return false
+	}
+	if e.Metadata != "" {
+		return false
+	}
+	return e.Actor != RedactedPlaceholder || e.IP == ""
+}
+
+// seal assigns the next sequence number and computes the event hashes.
+func seal(event *models.AuditEvent, lastSeq int64, lastHash string) {
+	event.ID = lastSeq + 1
+	event.PrevHash = lastHash
+	event.PayloadHash = payloadHash(*event)
+	event.Hash = chainHash(*event)
+}
+
+// payloadHash covers the personal data of an event. The chain links this digest
+// rather than the raw values.
+func payloadHash(e models.AuditEvent) string {
+	return sha256Hex(e.Actor, e.Subject, e.IP, e.Metadata)
+}
+
+func chainHash(e models.AuditEvent) string {
+	return sha256Hex(
+		e.PrevHash,
+		strconv.FormatInt(e.ID, 10),
+		e.OccurredAt.UTC().Format(time.RFC3339Nano),
+		e.Action,
+		e.Outcome,
+		e.CorrelationID,
+		e.PayloadHash,
+	)
+}
+
+func sha256Hex(fields ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(fields, fieldSeparator)))
+	return hex.EncodeToString(sum[:])
+}
+
+var AuditServiceProviderSet = wire.NewSet(NewAuditService)
@@ -10,21 +10,39 @@ import (
 )
 
 const (
-	tokenExpireTime        = time.Hour * 72     // 72 hours
-	refreshTokenExpireTime = time.Hour * 24 * 7 // 7 days
+	tokenExpireTime = time.Hour * 72 // 72 hours
+	// RefreshTokenExpireTime also bounds how long an unused session is kept.
+	RefreshTokenExpireTime = time.Hour * 24 * 7 // 7 days
+	// scopedTokenExpireTime is short: scoped tokens only serve to set a new password or accept
+	// policies before logging in again.
+	scopedTokenExpireTime = time.Minute * 15
+)
+
+// Scopes of restricted access tokens. Full access tokens carry no scope.
+const (
+	// ScopePasswordChange marks an access token that only allows changing the password.
+	ScopePasswordChange = "password_change"
+	// ScopeConsent marks an access token that only allows accepting the policies in force.
+	ScopeConsent = "consent"
 )
 
 type (
 	AuthService interface {
-		GenerateToken(userId string) (*Tokens, error)
+		// GenerateToken issues a token pair for the session sessionID.
+		GenerateToken(userId string, sessionID string) (*Tokens, error)
+		// GeneratePasswordChangeToken issues an access token limited to ScopePasswordChange,
+		// without a refresh token.
+		GeneratePasswordChangeToken(userId string) (string, error)
+		// GenerateConsentToken issues an access token limited to ScopeConsent, without a refresh
+		// token.
+		GenerateConsentToken(userId string) (string, error)
 		ValidateToken(token string) error
 		RefreshToken(token string) (*Tokens, error)
 		ExtractClaims(token string) (*Claims, error)
 	}
 
 	authService struct {
-		jwtSecret        []byte
-		jwtRefreshSecret []byte
+		signer TokenSigner
 	}
 
 	Tokens struct {
@@ -34,52 +52,60 @@ type (
 
 	Claims struct {
 		UserID string `json:"user_id"`
+		// SessionID is empty for tokens that do not belong to a session, and for tokens issued
+		// before sessions were tracked.
+		SessionID string `json:"sid,omitempty"`
+		// Scope limits what the token may be used for, empty for full access.
+		Scope string `json:"scope,omitempty"`
 		jwt.RegisteredClaims
 	}
 
 	RefreshClaims struct {
-		UserID string `json:"user_id"`
+		UserID    string `json:"user_id"`
+		SessionID string `json:"sid,omitempty"`
 		jwt.RegisteredClaims
 	}
 )
 
-func NewAuthService(vault service.SecureVaultService) AuthService {
+func NewAuthService(signer TokenSigner) AuthService {
+	return &authService{signer: signer}
+}
+
+// NewHMACSignerFromVault builds the HS256 signer from the secrets in Vault KV.
+func NewHMACSignerFromVault(vault service.SecureVaultService) (TokenSigner, error) {
 	jwtConfig, err := vault.GetJWTConfig()
 	if err != nil {
-		log.Panic(err)
-	}
-	return &authService{
-		jwtSecret:        jwtConfig.JwtSecret,
-		jwtRefreshSecret: jwtConfig.JwtRefreshSecret,
+		return nil, err
 	}
+	return NewHMACSigner(jwtConfig.JwtSecret, jwtConfig.JwtRefreshSecret), nil
 }
 
-func (au *authService) GenerateToken(userId string) (*Tokens, error) {
+func (au *authService) GenerateToken(userId string, sessionID string) (*Tokens, error) {
 	// Create access token
 	expirationTime := time.Now().Add(tokenExpireTime)
 	claims := &Claims{
-		UserID: userId,
+		UserID:    userId,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(au.jwtSecret)
+	tokenString, err := au.signer.Sign(PurposeAccess, claims)
 	if err != nil {
 		log.Printf("Error generating access token: %v", err)
 		return nil, err
 	}
 
 	// Create refresh token
-	refreshExpirationTime := time.Now().Add(refreshTokenExpireTime)
+	refreshExpirationTime := time.Now().Add(RefreshTokenExpireTime)
 	rClaims := &RefreshClaims{
-		UserID: userId,
+		UserID:    userId,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(refreshExpirationTime),
 		},
 	}
-	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, rClaims)
-	refreshTokenString, err := refreshToken.SignedString(au.jwtRefreshSecret)
+	refreshTokenString, err := au.signer.Sign(PurposeRefresh, rClaims)
 	if err != nil {
 		log.Printf("Error generating refresh token: %v", err)
 		return nil, err
@@ -91,6 +117,30 @@ func (au *authService) GenerateToken(userId string) (*Tokens, error) {
 	}, nil
 }
 
+func (au *authService) GeneratePasswordChangeToken(userId string) (string, error) {
+	return au.generateScopedToken(userId, ScopePasswordChange)
+}
+
+func (au *authService) GenerateConsentToken(userId string) (string, error) {
+	return au.generateScopedToken(userId, ScopeConsent)
+}
+
+func (au *authService) generateScopedToken(userId string, scope string) (string, error) {
+	claims := &Claims{
+		UserID: userId,
+		Scope:  scope,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(scopedTokenExpireTime)),
+		},
+	}
+	tokenString, err := au.signer.Sign(PurposeAccess, claims)
+	if err != nil {
+		log.Printf("Error generating %s token: %v", scope, err)
+		return "", err
+	}
+	return tokenString, nil
+}
+
 func (au *authService) ValidateToken(token string) error {
 	if token == "" {
 		log.Print("ValidateToken: token is empty")
@@ -98,9 +148,7 @@ func (au *authService) ValidateToken(token string) error {
 	}
 
 	// Parse the token without needing to extract the user ID
-	cToken, err := jwt.ParseWithClaims(token, &Claims{}, func(t *jwt.Token) (interface{}, error) {
-		return au.jwtSecret, nil
-	})
+	cToken, err := jwt.ParseWithClaims(token, &Claims{}, au.signer.Keyfunc(PurposeAccess))
 	if err != nil {
 		log.Printf("ValidateToken: error parsing token: %v", err)
 		return err
@@ -119,9 +167,7 @@ func (au *authService) ExtractClaims(token string) (*Claims, error) {
 		return nil, errors.New("token is empty")
 	}
 
-	cToken, err := jwt.ParseWithClaims(token, &Claims{}, func(t *jwt.Token) (interface{}, error) {
-		return au.jwtSecret, nil
-	})
+	cToken, err := jwt.ParseWithClaims(token, &Claims{}, au.signer.Keyfunc(PurposeAccess))
 	if err != nil {
 		log.Printf("ExtractClaims: error parsing token: %v", err)
 		return nil, err
@@ -142,9 +188,7 @@ func (au *authService) RefreshToken(token string) (*Tokens, error) {
 		return nil, errors.New("token is empty")
 	}
 
-	cToken, err := jwt.ParseWithClaims(token, &RefreshClaims{}, func(t *jwt.Token) (interface{}, error) {
-		return au.jwtRefreshSecret, nil
-	})
+	cToken, err := jwt.ParseWithClaims(token, &RefreshClaims{}, au.signer.Keyfunc(PurposeRefresh))
 	if err != nil {
 		log.Printf("RefreshToken: error parsing refresh token: %v", err)
 		return nil, err
@@ -156,7 +200,7 @@ func (au *authService) RefreshToken(token string) (*Tokens, error) {
 		return nil, errors.New("invalid token")
 	}
 
-	return au.GenerateToken(refreshClaims.UserID)
+	return au.GenerateToken(refreshClaims.UserID, refreshClaims.SessionID)
 }
 
 var AuthServiceProviderSet = wire.NewSet(NewAuthService)
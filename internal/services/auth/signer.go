@@ -0,0 +1,54 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// TokenPurpose separates the keys of the different token kinds, so a refresh token never passes
+// as an access token.
+type TokenPurpose string
+
+const (
+	PurposeAccess  TokenPurpose = "access"
+	PurposeRefresh TokenPurpose = "refresh"
+)
+
+// ErrSigningUnavailable is returned when tokens cannot be signed right now, e.g. because Vault
+// is down. Verification keeps working from cached keys.
+var ErrSigningUnavailable = errors.New("token signing is unavailable")
+
+// TokenSigner signs and verifies the JWTs issued by the auth service.
+type TokenSigner interface {
+	Sign(purpose TokenPurpose, claims jwt.Claims) (string, error)
+	// Keyfunc returns the key lookup for verifying tokens of purpose. It rejects tokens whose
+	// algorithm does not match the key.
+	Keyfunc(purpose TokenPurpose) jwt.Keyfunc
+}
+
+// hmacSigner signs with HS256 secrets held in memory.
+type hmacSigner struct {
+	secrets map[TokenPurpose][]byte
+}
+
+func NewHMACSigner(accessSecret, refreshSecret []byte) TokenSigner {
+	return &hmacSigner{secrets: map[TokenPurpose][]byte{
+		PurposeAccess:  accessSecret,
+		PurposeRefresh: refreshSecret,
+	}}
+}
+
+func (s *hmacSigner) Sign(purpose TokenPurpose, claims jwt.Claims) (string, error) {
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.secrets[purpose])
+}
+
+func (s *hmacSigner) Keyfunc(purpose TokenPurpose) jwt.Keyfunc {
+	return func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %s", t.Method.Alg())
+		}
+		return s.secrets[purpose], nil
+	}
+}
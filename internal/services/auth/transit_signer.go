@@ -0,0 +1,257 @@
+package service
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SilentPlaces/basicauth.git/internal/config"
+	vaultservice "github.com/SilentPlaces/basicauth.git/internal/services/vault"
+	appLogger "github.com/SilentPlaces/basicauth.git/internal/shared/logger"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	transitTimeout = 5 * time.Second
+	// unknownKidRefreshGap limits how often a token with an unknown kid triggers a key refresh.
+	unknownKidRefreshGap = 30 * time.Second
+)
+
+// transitKey is the cached public state of one Transit key.
+type transitKey struct {
+	name       string
+	method     jwt.SigningMethod
+	request    vaultservice.TransitSignRequest
+	latest     int
+	publicKeys map[int]crypto.PublicKey
+	loadedAt   time.Time
+}
+
+// TransitSigner signs tokens with Vault's Transit engine, so private keys never enter the
+// process. The kid header is "<key>:v<version>". Public keys are cached: verification keeps
+// working while Vault is unreachable, signing then fails with ErrSigningUnavailable.
+type TransitSigner struct {
+	vault           vaultservice.SecureVaultService
+	mount           string
+	names           map[TokenPurpose]string
+	refreshInterval time.Duration
+	logger          appLogger.Logger
+
+	mu   sync.RWMutex
+	keys map[TokenPurpose]*transitKey
+}
+
+// NewTransitSigner loads the public keys. When Vault is unreachable it still returns a signer,
+// which loads the keys on first use.
+func NewTransitSigner(vault vaultservice.SecureVaultService, cfg *config.TokenSignerConfig, logger appLogger.Logger) *TransitSigner {
+	s := &TransitSigner{
+		vault: vault,
+		mount: cfg.TransitMount,
+		names: map[TokenPurpose]string{
+			PurposeAccess:  cfg.TransitAccessKey,
+			PurposeRefresh: cfg.TransitRefreshKey,
+		},
+		refreshInterval: cfg.KeyRefreshInterval,
+		logger:          logger,
+		keys:            make(map[TokenPurpose]*transitKey),
+	}
+	if err := s.refresh(context.Background()); err != nil {
+		logger.Warn(context.Background(), "transit keys could not be loaded, retrying on first use", map[string]interface{}{"error": err.Error()})
+	}
+	return s
+}
+
+func (s *TransitSigner) Sign(purpose TokenPurpose, claims jwt.Claims) (string, error) {
+	key, err := s.key(purpose)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrSigningUnavailable, err)
+	}
+
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = kid(key.name, key.latest)
+	signingString, err := token.SigningString()
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), transitTimeout)
+	defer cancel()
+	request := key.request
+	request.Version = key.latest
+	request.Input = []byte(signingString)
+	signature, err := s.vault.TransitSign(ctx, request)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrSigningUnavailable, err)
+	}
+	return signingString + "." + jwt.EncodeSegment(signature), nil
+}
+
+func (s *TransitSigner) Keyfunc(purpose TokenPurpose) jwt.Keyfunc {
+	return func(t *jwt.Token) (interface{}, error) {
+		rawKid, _ := t.Header["kid"].(string)
+		name, version, ok := parseKid(rawKid)
+		if !ok || name != s.names[purpose] {
+			return nil, fmt.Errorf("unexpected kid %q", rawKid)
+		}
+
+		publicKey, method := s.publicKey(purpose, version)
+		if publicKey == nil && s.refreshAllowed(purpose) {
+			// The key may have been rotated since the last refresh.
+			ctx, cancel := context.WithTimeout(context.Background(), transitTimeout)
+			defer cancel()
+			if err := s.load(ctx, purpose); err != nil {
+				s.logger.Warn(ctx, "transit key refresh failed", map[string]interface{}{"key": name, "error": err.Error()})
+			}
+			publicKey, method = s.publicKey(purpose, version)
+		}
+		if publicKey == nil {
+			return nil, fmt.Errorf("unknown kid %q", rawKid)
+		}
+		if t.Method.Alg() != method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %s", t.Method.Alg())
+		}
+		return publicKey, nil
+	}
+}
+
+// Run re-reads the public keys every refresh interval until ctx is cancelled.
+func (s *TransitSigner) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.refreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := s.refresh(ctx); err != nil && ctx.Err() == nil {
+			s.logger.Warn(ctx, "transit key refresh failed, using cached keys", map[string]interface{}{"error": err.Error()})
+		}
+	}
+}
+
+func (s *TransitSigner) refresh(ctx context.Context) error {
+	for purpose := range s.names {
+		if err := s.load(ctx, purpose); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *TransitSigner) key(purpose TokenPurpose) (*transitKey, error) {
+	s.mu.RLock()
+	key := s.keys[purpose]
+	s.mu.RUnlock()
+	if key != nil {
+		return key, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), transitTimeout)
+	defer cancel()
+	if err := s.load(ctx, purpose); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.keys[purpose], nil
+}
+
+func (s *TransitSigner) publicKey(purpose TokenPurpose, version int) (crypto.PublicKey, jwt.SigningMethod) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key := s.keys[purpose]
+	if key == nil {
+		return nil, nil
+	}
+	return key.publicKeys[version], key.method
+}
+
+func (s *TransitSigner) refreshAllowed(purpose TokenPurpose) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key := s.keys[purpose]
+	return key == nil || time.Since(key.loadedAt) > unknownKidRefreshGap
+}
+
+// load reads one Transit key. Old versions stay verifiable as long as Vault still lists them.
+func (s *TransitSigner) load(ctx context.Context, purpose TokenPurpose) error {
+	name := s.names[purpose]
+	transit, err := s.vault.GetTransitKey(ctx, s.mount, name)
+	if err != nil {
+		return err
+	}
+
+	key := &transitKey{
+		name:       name,
+		latest:     transit.LatestVersion,
+		publicKeys: make(map[int]crypto.PublicKey, len(transit.PublicKeys)),
+		loadedAt:   time.Now(),
+		request:    vaultservice.TransitSignRequest{Mount: s.mount, Key: name},
+	}
+	switch transit.Type {
+	case "rsa-2048", "rsa-3072", "rsa-4096":
+		key.method = jwt.SigningMethodRS256
+		key.request.HashAlgorithm = "sha2-256"
+		key.request.SignatureAlgorithm = "pkcs1v15"
+	case "ecdsa-p256":
+		key.method = jwt.SigningMethodES256
+		key.request.HashAlgorithm = "sha2-256"
+		key.request.MarshalingAlgorithm = "jws"
+	case "ecdsa-p384":
+		key.method = jwt.SigningMethodES384
+		key.request.HashAlgorithm = "sha2-384"
+		key.request.MarshalingAlgorithm = "jws"
+	case "ed25519":
+		key.method = jwt.SigningMethodEdDSA
+	default:
+		return fmt.Errorf("transit key %s has unsupported type %q", name, transit.Type)
+	}
+
+	for version, encoded := range transit.PublicKeys {
+		publicKey, err := parseTransitPublicKey(transit.Type, encoded)
+		if err != nil {
+			return fmt.Errorf("transit key %s version %d: %w", name, version, err)
+		}
+		key.publicKeys[version] = publicKey
+	}
+
+	s.mu.Lock()
+	s.keys[purpose] = key
+	s.mu.Unlock()
+	return nil
+}
+
+func parseTransitPublicKey(keyType, encoded string) (crypto.PublicKey, error) {
+	switch {
+	case strings.HasPrefix(keyType, "rsa-"):
+		return jwt.ParseRSAPublicKeyFromPEM([]byte(encoded))
+	case strings.HasPrefix(keyType, "ecdsa-"):
+		return jwt.ParseECPublicKeyFromPEM([]byte(encoded))
+	default:
+		raw, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(raw) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid ed25519 public key")
+		}
+		return ed25519.PublicKey(raw), nil
+	}
+}
+
+func kid(name string, version int) string {
+	return name + ":v" + strconv.Itoa(version)
+}
+
+func parseKid(value string) (string, int, bool) {
+	i := strings.LastIndex(value, ":v")
+	if i < 0 {
+		return "", 0, false
+	}
+	v, err := strconv.Atoi(value[i+2:])
+	return value[:i], v, err == nil
+}
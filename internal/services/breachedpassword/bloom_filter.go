@@ -0,0 +1,145 @@
+package service
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// bloomFilterMagic starts every filter file and versions its layout.
+const bloomFilterMagic = "BAPWBF01"
+
+// BloomFilter is a compact set of SHA-1 digests. It never misses a digest that was added, and
+// reports one that was not with about the false positive rate it was sized for. The digests are
+// uniformly distributed already, so the bit positions are derived from them directly by double
+// hashing instead of hashing again.
+//
+// File layout, big endian: the magic, k (uint32), the number of bits m (uint64), the number of
+// entries (uint64), then m/8 bytes of bits.
+type BloomFilter struct {
+	bits    []byte
+	m       uint64
+	k       uint32
+	entries uint64
+}
+
+// NewBloomFilter sizes an empty filter for n digests at the false positive rate fpRate.
+func NewBloomFilter(n uint64, fpRate float64) (*BloomFilter, error) {
+	if fpRate <= 0 || fpRate >= 1 {
+		return nil, fmt.Errorf("false positive rate must be between 0 and 1, got %v", fpRate)
+	}
+	if n == 0 {
+		n = 1
+	}
+	m := uint64(math.Ceil(-float64(n) * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	m = (m + 7) / 8 * 8
+	k := uint32(math.Round(float64(m) / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	return &BloomFilter{bits: make([]byte, m/8), m: m, k: k}, nil
+}
+
+// LoadBloomFilter reads a filter file written by WriteTo.
+func LoadBloomFilter(path string) (*BloomFilter, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password filter: %w", err)
+	}
+	defer file.Close()
+	filter, err := ReadBloomFilter(bufio.NewReader(file))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read breached password filter %s: %w", path, err)
+	}
+	return filter, nil
+}
+
+// ReadBloomFilter reads a filter in the layout written by WriteTo.
+func ReadBloomFilter(r io.Reader) (*BloomFilter, error) {
+	header := make([]byte, len(bloomFilterMagic)+4+8+8)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	if string(header[:len(bloomFilterMagic)]) != bloomFilterMagic {
+		return nil, errors.New("not a breached password filter")
+	}
+	rest := header[len(bloomFilterMagic):]
+	filter := &BloomFilter{
+		k:       binary.BigEndian.Uint32(rest[0:4]),
+		m:       binary.BigEndian.Uint64(rest[4:12]),
+		entries: binary.BigEndian.Uint64(rest[12:20]),
+	}
+	if filter.k == 0 || filter.k > 64 || filter.m == 0 || filter.m%8 != 0 {
+		return nil, fmt.Errorf("invalid filter parameters k=%d m=%d", filter.k, filter.m)
+	}
+	filter.bits = make([]byte, filter.m/8)
+	if _, err := io.ReadFull(r, filter.bits); err != nil {
+		return nil, fmt.Errorf("reading bits: %w", err)
+	}
+	return filter, nil
+}
+
+// WriteTo writes the filter in the layout ReadBloomFilter reads.
+func (f *BloomFilter) WriteTo(w io.Writer) (int64, error) {
+	header := make([]byte, 0, len(bloomFilterMagic)+4+8+8)
+	header = append(header, bloomFilterMagic...)
+	header = binary.BigEndian.AppendUint32(header, f.k)
+	header = binary.BigEndian.AppendUint64(header, f.m)
+	header = binary.BigEndian.AppendUint64(header, f.entries)
+	n, err := w.Write(header)
+	if err != nil {
+		return int64(n), err
+	}
+	m, err := w.Write(f.bits)
+	return int64(n + m), err
+}
+
+func (f *BloomFilter) Add(digest [sha1.Size]byte) {
+	h1, h2 := splitDigest(digest)
+	for i := uint64(0); i < uint64(f.k); i++ {
+		bit := (h1 + i*h2) % f.m
+		f.bits[bit/8] |= 1 << (bit % 8)
+	}
+	f.entries++
+}
+
+func (f *BloomFilter) Contains(digest [sha1.Size]byte) bool {
+	h1, h2 := splitDigest(digest)
+	for i := uint64(0); i < uint64(f.k); i++ {
+		bit := (h1 + i*h2) % f.m
+		if f.bits[bit/8]&(1<<(bit%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *BloomFilter) contains(digest [sha1.Size]byte) (bool, error) {
+	return f.Contains(digest), nil
+}
+
+// Entries returns the number of digests added.
+func (f *BloomFilter) Entries() uint64 {
+	return f.entries
+}
+
+// SizeBytes returns the size of the bit array.
+func (f *BloomFilter) SizeBytes() int {
+	return len(f.bits)
+}
+
+// HashFunctions returns the number of bits set per digest.
+func (f *BloomFilter) HashFunctions() uint32 {
+	return f.k
+}
+
+// splitDigest returns two independent 64 bit hashes of the digest. The second one is odd, so the
+// probe sequence does not collapse when m is even.
+func splitDigest(digest [sha1.Size]byte) (uint64, uint64) {
+	return binary.BigEndian.Uint64(digest[0:8]), binary.BigEndian.Uint64(digest[8:16]) | 1
+}
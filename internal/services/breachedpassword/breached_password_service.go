@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"crypto/sha1"
+	"fmt"
+
+	"github.com/SilentPlaces/basicauth.git/internal/config"
+	appLogger "github.com/SilentPlaces/basicauth.git/internal/shared/logger"
+	"github.com/google/wire"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var breachedPasswordChecksTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "breached_password_checks_total",
+		Help: "Passwords screened against the breached password hashes, by outcome.",
+	},
+	[]string{"outcome"},
+)
+
+func init() {
+	prometheus.MustRegister(breachedPasswordChecksTotal)
+}
+
+type (
+	// BreachedPasswordService screens passwords against SHA-1 hashes of passwords known from data
+	// breaches, as published by Have I Been Pwned. Everything is local; no password or hash
+	// prefix leaves the service.
+	BreachedPasswordService interface {
+		// IsBreached reports whether password appears in the breached hashes. A lookup that
+		// fails is logged and reported as not breached, so a broken corpus does not stop
+		// signups.
+		IsBreached(ctx context.Context, password string) bool
+	}
+
+	// hashSet answers whether a SHA-1 digest is among the breached hashes.
+	hashSet interface {
+		contains(digest [sha1.Size]byte) (bool, error)
+	}
+
+	breachedPasswordService struct {
+		hashes hashSet
+		logger appLogger.Logger
+	}
+)
+
+// NewBreachedPasswordService loads the source named in cfg. A filter is read into memory here, so
+// a missing or corrupt file fails startup. With source none it returns a service that accepts
+// every password.
+func NewBreachedPasswordService(cfg *config.BreachedPasswordConfig, logger appLogger.Logger) (BreachedPasswordService, error) {
+	var hashes hashSet
+	switch cfg.Source {
+	case config.BreachedPasswordSourceNone:
+		return disabledService{}, nil
+	case config.BreachedPasswordSourceFilter:
+		filter, err := LoadBloomFilter(cfg.Path)
+		if err != nil {
+			return nil, err
+		}
+		logger.Info(context.Background(), "breached password filter loaded", map[string]interface{}{
+			"path":    cfg.Path,
+			"entries": filter.Entries(),
+			"bytes":   filter.SizeBytes(),
+		})
+		hashes = filter
+	case config.BreachedPasswordSourceCorpus:
+		corpus, err := newCorpus(cfg.Path, cfg.MinCount)
+		if err != nil {
+			return nil, err
+		}
+		hashes = corpus
+	default:
+		return nil, fmt.Errorf("unknown breached password source %q", cfg.Source)
+	}
+	return &breachedPasswordService{hashes: hashes, logger: logger}, nil
+}
+
+func (s *breachedPasswordService) IsBreached(ctx context.Context, password string) bool {
+	breached, err := s.hashes.contains(sha1.Sum([]byte(password)))
+	switch {
+	case err != nil:
+		s.logger.Error(ctx, "breached password lookup failed", err, nil)
+		breachedPasswordChecksTotal.WithLabelValues("error").Inc()
+		return false
+	case breached:
+		breachedPasswordChecksTotal.WithLabelValues("breached").Inc()
+	default:
+		breachedPasswordChecksTotal.WithLabelValues("clean").Inc()
+	}
+	return breached
+}
+
+type disabledService struct{}
+
+func (disabledService) IsBreached(context.Context, string) bool {
+	return false
+}
+
+var BreachedPasswordServiceProviderSet = wire.NewSet(NewBreachedPasswordService)
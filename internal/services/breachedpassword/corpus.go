@@ -0,0 +1,152 @@
+package service
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// rangePrefixLength is the number of hex digits in the name of an HIBP range file.
+const rangePrefixLength = 5
+
+var rangeFileName = regexp.MustCompile(`^[0-9A-Fa-f]{5}\.txt$`)
+
+// corpus looks hashes up in a directory of HIBP range files as written by the Pwned Passwords
+// downloader: <PREFIX>.txt holds "SUFFIX:COUNT" lines for every hash starting with PREFIX. Only the
+// one file a hash falls into is read per lookup, so nothing is kept in memory.
+type corpus struct {
+	dir      string
+	minCount int
+}
+
+func newCorpus(dir string, minCount int) (*corpus, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password corpus: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("breached password corpus %s is not a directory", dir)
+	}
+	return &corpus{dir: dir, minCount: minCount}, nil
+}
+
+func (c *corpus) contains(digest [sha1.Size]byte) (bool, error) {
+	hash := strings.ToUpper(hex.EncodeToString(digest[:]))
+	file, err := os.Open(filepath.Join(c.dir, hash[:rangePrefixLength]+".txt"))
+	if errors.Is(err, fs.ErrNotExist) {
+		// A partial corpus simply does not know the hash.
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to open breached password range: %w", err)
+	}
+	defer file.Close()
+
+	suffix := hash[rangePrefixLength:]
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lineSuffix, count, err := parseHashLine(scanner.Text())
+		if err != nil || !strings.EqualFold(lineSuffix, suffix) {
+			continue
+		}
+		return count >= c.minCount, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("failed to read breached password range: %w", err)
+	}
+	return false, nil
+}
+
+// ScanHashes calls fn for every hash in path seen at least minCount times. path is either a
+// directory of HIBP range files, or a single file with one "HASH" or "HASH:COUNT" line per hash
+// as in the combined Pwned Passwords download. Hashes are hex SHA-1 in either case.
+func ScanHashes(path string, minCount int, fn func(digest [sha1.Size]byte) error) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return scanHashFile(path, "", minCount, fn)
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() && rangeFileName.MatchString(entry.Name()) {
+			names = append(names, entry.Name())
+		}
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("%s contains no range files", path)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		prefix := strings.TrimSuffix(name, ".txt")
+		if err := scanHashFile(filepath.Join(path, name), prefix, minCount, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scanHashFile reads one file of hash lines. prefix is prepended to every line, for range files.
+func scanHashFile(path, prefix string, minCount int, fn func(digest [sha1.Size]byte) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		hash, count, err := parseHashLine(text)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		if count < minCount {
+			continue
+		}
+		var digest [sha1.Size]byte
+		if n, err := hex.Decode(digest[:], []byte(prefix+hash)); err != nil || n != sha1.Size {
+			return fmt.Errorf("%s:%d: invalid SHA-1 hash %q", path, line, prefix+hash)
+		}
+		if err := fn(digest); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// parseHashLine splits "HASH:COUNT". A line without a count counts once.
+func parseHashLine(line string) (string, int, error) {
+	hash, rawCount, found := strings.Cut(strings.TrimSpace(line), ":")
+	if !found {
+		return hash, 1, nil
+	}
+	count, err := strconv.Atoi(rawCount)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid count %q", rawCount)
+	}
+	return hash, count, nil
+}
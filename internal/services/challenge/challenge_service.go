@@ -0,0 +1,40 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrChallengeFailed is returned for a response the provider does not accept.
+	ErrChallengeFailed = errors.New("challenge response is invalid or expired")
+	// ErrChallengeUnavailable is returned when the response could not be checked at all.
+	ErrChallengeUnavailable = errors.New("challenge verification is unavailable")
+	// ErrTooManyChallenges is returned when a client asks for challenges faster than allowed.
+	ErrTooManyChallenges = errors.New("too many challenges requested")
+)
+
+type (
+	// ChallengeVerifier checks a solved challenge sent by the client, such as a CAPTCHA token or a
+	// proof-of-work solution.
+	ChallengeVerifier interface {
+		// Provider returns the config.ChallengeProvider constant the verifier implements.
+		Provider() string
+		// NewChallenge returns what the client needs to solve a challenge.
+		NewChallenge(ctx context.Context) (*Challenge, error)
+		// Verify returns nil when response is a valid solution. remoteIP is passed on to providers
+		// that check it and may be empty.
+		Verify(ctx context.Context, response string, remoteIP string) error
+	}
+
+	// Challenge tells the client how to obtain a response. CAPTCHA providers set SiteKey for
+	// their widget; proof-of-work sets the puzzle fields.
+	Challenge struct {
+		Provider   string
+		SiteKey    string
+		Puzzle     string
+		Difficulty int
+		ExpiresAt  time.Time
+	}
+)
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"math/bits"
+	"strings"
+	"time"
+
+	"github.com/SilentPlaces/basicauth.git/internal/config"
+	repository "github.com/SilentPlaces/basicauth.git/internal/repositories/challenge"
+	"github.com/SilentPlaces/basicauth.git/internal/shared/observability"
+	helpers "github.com/SilentPlaces/basicauth.git/pkg/helper/strings"
+	"github.com/google/wire"
+)
+
+const (
+	puzzleBytes = 16
+	// maxNonceLength bounds the hashed input, which clients control.
+	maxNonceLength = 64
+	// issueWindow is the window PoWMaxPerMinute applies to.
+	issueWindow = time.Minute
+)
+
+// ProofOfWork is a self-hosted challenge. The client gets a random puzzle and has to find a nonce
+// for which SHA-256("<puzzle>:<nonce>") starts with the given number of zero bits, and then sends
+// "<puzzle>:<nonce>" as its response. Each puzzle is stored in Redis and can be used once, and
+// each client IP may only request PoWMaxPerMinute puzzles a minute, so requests cannot fill Redis.
+type ProofOfWork struct {
+	challengeRepository repository.ChallengeRepository
+	cfg                 *config.ChallengeConfig
+}
+
+func NewProofOfWork(challengeRepository repository.ChallengeRepository, cfg *config.ChallengeConfig) *ProofOfWork {
+	return &ProofOfWork{challengeRepository: challengeRepository, cfg: cfg}
+}
+
+func (p *ProofOfWork) Provider() string {
+	return config.ChallengeProviderPoW
+}
+
+func (p *ProofOfWork) NewChallenge(ctx context.Context) (*Challenge, error) {
+	issued, err := p.challengeRepository.CountIssued(observability.ClientIPFromContext(ctx), issueWindow)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrChallengeUnavailable, err)
+	}
+	if issued > int64(p.cfg.PoWMaxPerMinute) {
+		return nil, ErrTooManyChallenges
+	}
+
+	puzzle, err := helpers.GenerateRandomString(puzzleBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate puzzle: %w", err)
+	}
+	if err := p.challengeRepository.StorePuzzle(puzzle, p.cfg.PoWDifficulty, p.cfg.PoWTTL); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrChallengeUnavailable, err)
+	}
+	return &Challenge{
+		Provider:   config.ChallengeProviderPoW,
+		Puzzle:     puzzle,
+		Difficulty: p.cfg.PoWDifficulty,
+		ExpiresAt:  time.Now().Add(p.cfg.PoWTTL),
+	}, nil
+}
+
+// Verify consumes the puzzle before checking the work, so every puzzle gets a single attempt.
+// The difficulty stored with the puzzle applies, so a config change does not void issued puzzles.
+func (p *ProofOfWork) Verify(_ context.Context, response string, _ string) error {
+	puzzle, nonce, found := strings.Cut(response, ":")
+	if !found || puzzle == "" || nonce == "" || len(nonce) > maxNonceLength {
+		return ErrChallengeFailed
+	}
+	difficulty, ok, err := p.challengeRepository.ConsumePuzzle(puzzle)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrChallengeUnavailable, err)
+	}
+	if !ok {
+		return ErrChallengeFailed
+	}
+	if leadingZeroBits(sha256.Sum256([]byte(response))) < difficulty {
+		return ErrChallengeFailed
+	}
+	return nil
+}
+
+func leadingZeroBits(digest [sha256.Size]byte) int {
+	count := 0
+	for _, b := range digest {
+		if b != 0 {
+			return count + bits.LeadingZeros8(b)
+		}
+		count += 8
+	}
+	return count
+}
+
+var ProofOfWorkProviderSet = wire.NewSet(NewProofOfWork)
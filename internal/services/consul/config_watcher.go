@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/SilentPlaces/basicauth.git/internal/config"
+	appLogger "github.com/SilentPlaces/basicauth.git/internal/shared/logger"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	watchRetryBaseDelay = time.Second
+	watchRetryMaxDelay  = time.Minute
+)
+
+var (
+	configReloadsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "config_reloads_total",
+			Help: "Runtime config reloads by outcome (applied, rejected, error).",
+		},
+		[]string{"outcome"},
+	)
+	configLastReload = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "config_last_reload_timestamp_seconds",
+		Help: "Unix time of the last applied runtime config.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(configReloadsTotal, configLastReload)
+}
+
+// ConfigWatcher keeps the runtime config in sync with the config provider, using blocking queries
+// on ConfigPrefix with Consul and polling otherwise. Updates that fail validation are rejected and
+// the last good config stays active.
+type ConfigWatcher struct {
+	consul  ConsulService
+	logger  appLogger.Logger
+	current atomic.Pointer[config.RuntimeConfig]
+
+	mu          sync.Mutex
+	subscribers []func(cfg *config.RuntimeConfig)
+}
+
+// NewConfigWatcher loads the initial runtime config. It fails when that config is invalid, as
+// there is no last good config to fall back to yet.
+func NewConfigWatcher(consul ConsulService, logger appLogger.Logger) (*ConfigWatcher, error) {
+	initial, err := consul.GetRuntimeConfig()
+	if err != nil {
+		return nil, err
+	}
+	w := &ConfigWatcher{consul: consul, logger: logger}
+	w.current.Store(initial)
+	configLastReload.SetToCurrentTime()
+	return w, nil
+}
+
+// Current returns the active snapshot. It must not be modified.
+func (w *ConfigWatcher) Current() *config.RuntimeConfig {
+	return w.current.Load()
+}
+
+// Subscribe registers fn to be called with every newly applied snapshot. Subscribers run on the
+// watcher goroutine and should return quickly.
+func (w *ConfigWatcher) Subscribe(fn func(cfg *config.RuntimeConfig)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subscribers = append(w.subscribers, fn)
+}
+
+// Run watches the config provider until ctx is cancelled.
+func (w *ConfigWatcher) Run(ctx context.Context) {
+	var index uint64
+	delay := watchRetryBaseDelay
+
+	for ctx.Err() == nil {
+		next, err := w.consul.WaitForChange(ctx, ConfigPrefix, index)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			configReloadsTotal.WithLabelValues("error").Inc()
+			w.logger.Error(ctx, "config watch failed", err, map[string]interface{}{"retry_in": delay.String()})
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+			delay = min(delay*2, watchRetryMaxDelay)
+			continue
+		}
+		delay = watchRetryBaseDelay
+
+		// Consul may reset its index (e.g. after a snapshot restore); start over in that case.
+		if next < index {
+			index = 0
+			continue
+		}
+		if next == index {
+			continue
+		}
+		index = next
+		w.apply(ctx, index)
+	}
+}
+
+func (w *ConfigWatcher) apply(ctx context.Context, index uint64) {
+	next, err := w.consul.GetRuntimeConfig()
+	if err != nil {
+		configReloadsTotal.WithLabelValues("rejected").Inc()
+		w.logger.Error(ctx, "config update rejected, keeping last good config", err, map[string]interface{}{"index": index})
+		return
+	}
+	// Changes to keys that are not reloadable also move the index.
+	if reflect.DeepEqual(next, w.current.Load()) {
+		return
+	}
+
+	w.current.Store(next)
+	w.mu.Lock()
+	subscribers := append([]func(cfg *config.RuntimeConfig){}, w.subscribers...)
+	w.mu.Unlock()
+	for _, subscriber := range subscribers {
+		subscriber(next)
+	}
+
+	configReloadsTotal.WithLabelValues("applied").Inc()
+	configLastReload.SetToCurrentTime()
+	w.logger.Info(ctx, "runtime config reloaded", map[string]interface{}{"index": index})
+}
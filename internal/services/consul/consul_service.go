@@ -1,32 +1,59 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	helpers "github.com/SilentPlaces/basicauth.git/pkg/helper/convertor"
 	"log"
+	"net"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/SilentPlaces/basicauth.git/internal/config"
+	"github.com/SilentPlaces/basicauth.git/internal/config/provider"
 	"github.com/SilentPlaces/basicauth.git/pkg/constants"
 	"github.com/google/wire"
-	consulapi "github.com/hashicorp/consul/api"
 )
 
-// ConsulService interface updated to return dedicated config structs.
+// ConsulService maps raw configuration values to the typed config structs. The values come from
+// a provider.ConfigProvider, which is Consul, a file, the environment or a layered combination;
+// the name predates the non-Consul providers.
 type ConsulService interface {
-	getConfigValue(key string) (string, error)
-	getConfigForKeys(keys []string) (map[string]string, error)
 	GetMySQLConfig() (*config.MySQLConfig, error)
 	GetRedisConfig() (*config.RedisConfig, error)
 	GetSMTPConfig() (*config.SMTPConfig, error)
+	GetMailTransportConfig() (*config.MailTransportConfig, error)
 	GetGeneralConfig() (*config.GeneralConfig, error)
-	GetRegistrationConfig() *config.RegistrationConfig
-	GetRegistrationPasswordConfig() *config.RegistrationPasswordConfig
+	GetRegistrationConfig() (*config.RegistrationConfig, error)
+	GetRegistrationPasswordConfig() (*config.RegistrationPasswordConfig, error)
+	GetWebhookConfig() (*config.WebhookConfig, error)
+	GetEventBusConfig() (*config.EventBusConfig, error)
+	GetMailQueueConfig() (*config.MailQueueConfig, error)
+	GetTokenSignerConfig() (*config.TokenSignerConfig, error)
+	GetReaperConfig() (*config.ReaperConfig, error)
+	GetEmailNormalizationConfig() (*config.EmailNormalizationConfig, error)
+	GetInvitationConfig() (*config.InvitationConfig, error)
+	GetChallengeConfig() (*config.ChallengeConfig, error)
+	GetBreachedPasswordConfig() (*config.BreachedPasswordConfig, error)
+	GetAccountDeletionConfig() (*config.AccountDeletionConfig, error)
+	GetRuntimeConfig() (*config.RuntimeConfig, error)
+	WaitForChange(ctx context.Context, prefix string, waitIndex uint64) (uint64, error)
+	GetOptionalValue(key string) (string, bool, error)
+	ListKeys(prefix string) ([]string, error)
+	ProviderName() string
+	Validate() error
+	Effective() (config.Values, error)
 }
 
 type consulService struct {
-	Client *consulapi.Client
+	provider provider.ConfigProvider
+
+	// Change tracking for providers that cannot watch.
+	pollMu    sync.Mutex
+	pollIndex uint64
+	pollState string
 }
 
 var (
@@ -34,52 +61,92 @@ var (
 	once    sync.Once
 )
 
-// NewConsulService creates a singleton ConsulService.
+// NewConsulService creates a singleton ConsulService over the sources named in cfg.
 // It panics on error to ensure the application does not continue if the connection fails.
 func NewConsulService(cfg *config.AppConfig) ConsulService {
 	once.Do(func() {
-		consulConfig := consulapi.DefaultConfig()
-		consulConfig.Address = cfg.ConsulAddress
-		consulConfig.Scheme = cfg.ConsulScheme
-
-		client, err := consulapi.NewClient(consulConfig)
+		p, err := provider.New(cfg)
 		if err != nil {
-			log.Panicf("Error connecting to Consul server: %v", err)
+			log.Panicf("Error initializing config provider: %v", err)
 		}
-		service = &consulService{Client: client}
+		service = &consulService{provider: p}
 	})
 	return service
 }
 
-// getConfigValue retrieves a single key from Consul's KV store.
-func (cs *consulService) getConfigValue(key string) (string, error) {
-	kv := cs.Client.KV()
-	pair, _, err := kv.Get(key, nil)
+// NewConfigService creates a ConsulService over an explicit provider, e.g. a file in tests.
+func NewConfigService(p provider.ConfigProvider) ConsulService {
+	return &consulService{provider: p}
+}
+
+func (cs *consulService) ProviderName() string {
+	return cs.provider.Name()
+}
+
+// resolve reads keys and checks them against config.Schema, applying defaults. Every invalid or
+// missing key is reported in the returned error.
+func (cs *consulService) resolve(keys ...string) (config.Values, error) {
+	return config.Resolve(keys, cs.provider.Get)
+}
+
+// GetOptionalValue retrieves a single key and reports whether it exists.
+func (cs *consulService) GetOptionalValue(key string) (string, bool, error) {
+	return cs.provider.Get(key)
+}
+
+// ListKeys returns all keys below prefix.
+func (cs *consulService) ListKeys(prefix string) ([]string, error) {
+	values, err := cs.provider.List(prefix)
 	if err != nil {
-		return "", fmt.Errorf("error retrieving key %s: %v", key, err)
+		return nil, err
 	}
-	if pair == nil {
-		return "", fmt.Errorf("key %s not found", key)
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
 	}
-	return string(pair.Value), nil
+	sort.Strings(keys)
+	return keys, nil
 }
 
-// getConfigForKeys retrieves multiple configuration values.
-func (cs *consulService) getConfigForKeys(keys []string) (map[string]string, error) {
-	configMap := make(map[string]string)
-	for _, key := range keys {
-		value, err := cs.getConfigValue(key)
+// WaitForChange blocks until keys below prefix change and returns the new change index. Providers
+// that cannot watch are polled every pollInterval.
+func (cs *consulService) WaitForChange(ctx context.Context, prefix string, waitIndex uint64) (uint64, error) {
+	if w, ok := cs.provider.(provider.Watcher); ok {
+		index, err := w.Watch(ctx, prefix, waitIndex)
+		if err != provider.ErrWatchUnsupported {
+			return index, err
+		}
+	}
+
+	for {
+		values, err := cs.provider.List(prefix)
 		if err != nil {
-			return nil, fmt.Errorf("unable to fetch config for key %s: %v", key, err)
+			return 0, err
+		}
+		state := fmt.Sprint(values)
+
+		cs.pollMu.Lock()
+		if cs.pollIndex == 0 || state != cs.pollState {
+			cs.pollIndex++
+			cs.pollState = state
+		}
+		index := cs.pollIndex
+		cs.pollMu.Unlock()
+
+		if index != waitIndex {
+			return index, nil
+		}
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(pollInterval):
 		}
-		configMap[key] = value
 	}
-	return configMap, nil
 }
 
-// GetMySQLConfig retrieves MySQL configuration from Consul.
+// GetMySQLConfig retrieves the MySQL connection settings.
 func (cs *consulService) GetMySQLConfig() (*config.MySQLConfig, error) {
-	keys := []string{
+	values, err := cs.resolve(
 		constants.MySQLHostKey,
 		constants.MySQLPortKey,
 		constants.MySQLUserKey,
@@ -88,149 +155,458 @@ func (cs *consulService) GetMySQLConfig() (*config.MySQLConfig, error) {
 		constants.MySQLMaxLifetimeSecondsKey,
 		constants.MySQLMaxOpenConnectionsKey,
 		constants.MySQLIdleConnectionsKey,
+		constants.MySQLCredentialSourceKey,
+		constants.MySQLVaultMountKey,
+		constants.MySQLVaultRoleKey,
+		constants.MySQLRotateBeforeSecondsKey,
+	)
+	problems := []error{err}
+
+	cfg := &config.MySQLConfig{
+		Host:               values.String(constants.MySQLHostKey),
+		Port:               values.String(constants.MySQLPortKey),
+		User:               values.String(constants.MySQLUserKey),
+		Password:           values.String(constants.MySQLPasswordKey),
+		DB:                 values.String(constants.MySQLDBKey),
+		MaxLifetime:        time.Duration(values.Int(constants.MySQLMaxLifetimeSecondsKey)) * time.Second,
+		MaxOpenConnections: values.Int(constants.MySQLMaxOpenConnectionsKey),
+		IdleConnections:    values.Int(constants.MySQLIdleConnectionsKey),
+		CredentialSource:   values.String(constants.MySQLCredentialSourceKey),
+		VaultMount:         values.String(constants.MySQLVaultMountKey),
+		VaultRole:          values.String(constants.MySQLVaultRoleKey),
+		RotateBefore:       time.Duration(values.Int(constants.MySQLRotateBeforeSecondsKey)) * time.Second,
 	}
-	configMap, err := cs.getConfigForKeys(keys)
-	if err != nil {
-		return nil, err
+	switch cfg.CredentialSource {
+	case config.MySQLCredentialsStatic:
+		if cfg.User == "" {
+			problems = append(problems, fmt.Errorf("%s is required for static credentials", constants.MySQLUserKey))
+		}
+		if cfg.Password == "" {
+			problems = append(problems, fmt.Errorf("%s is required for static credentials", constants.MySQLPasswordKey))
+		}
+	case config.MySQLCredentialsVault:
+		if cfg.VaultRole == "" {
+			problems = append(problems, fmt.Errorf("%s is required for vault credentials", constants.MySQLVaultRoleKey))
+		}
 	}
-	cfg := &config.MySQLConfig{
-		Host:               configMap[constants.MySQLHostKey],
-		Port:               configMap[constants.MySQLPortKey],
-		User:               configMap[constants.MySQLUserKey],
-		Password:           configMap[constants.MySQLPasswordKey],
-		DB:                 configMap[constants.MySQLDBKey],
-		MaxLifetimeSeconds: configMap[constants.MySQLMaxLifetimeSecondsKey],
-		MaxOpenConnections: configMap[constants.MySQLMaxOpenConnectionsKey],
-		IdleConnections:    configMap[constants.MySQLIdleConnectionsKey],
+	if err := errors.Join(problems...); err != nil {
+		return nil, err
 	}
 	return cfg, nil
 }
 
-// GetRedisConfig retrieves Redis configuration from Consul.
+// GetRedisConfig retrieves the Redis connection settings.
 func (cs *consulService) GetRedisConfig() (*config.RedisConfig, error) {
-	keys := []string{
+	values, err := cs.resolve(
 		constants.RedisHostKey,
 		constants.RedisPortKey,
 		constants.RedisPasswordKey,
-	}
-	configMap, err := cs.getConfigForKeys(keys)
+	)
 	if err != nil {
 		return nil, err
 	}
-	cfg := &config.RedisConfig{
-		Host:     configMap[constants.RedisHostKey],
-		Port:     configMap[constants.RedisPortKey],
-		Password: configMap[constants.RedisPasswordKey],
-	}
-	return cfg, nil
+	return &config.RedisConfig{
+		Host:     values.String(constants.RedisHostKey),
+		Port:     values.String(constants.RedisPortKey),
+		Password: values.String(constants.RedisPasswordKey),
+	}, nil
 }
 
-// GetSMTPConfig retrieves SMTP configuration from Consul.
+// GetSMTPConfig retrieves the SMTP connection settings.
 func (cs *consulService) GetSMTPConfig() (*config.SMTPConfig, error) {
-	keys := []string{
+	values, err := cs.resolve(
 		constants.SMTPHostKey,
 		constants.SMTPPortKey,
 		constants.SMTPUsernameKey,
 		constants.SMTPPasswordKey,
-	}
-	configMap, err := cs.getConfigForKeys(keys)
+		constants.SMTPTLSModeKey,
+		constants.SMTPCAFileKey,
+		constants.SMTPServerNameKey,
+		constants.SMTPPoolSizeKey,
+		constants.SMTPIdleTimeoutSecondsKey,
+	)
 	if err != nil {
 		return nil, err
 	}
-	cfg := &config.SMTPConfig{
-		Host:     configMap[constants.SMTPHostKey],
-		Port:     configMap[constants.SMTPPortKey],
-		Username: configMap[constants.SMTPUsernameKey],
-		Password: configMap[constants.SMTPPasswordKey],
+	return &config.SMTPConfig{
+		Host:        values.String(constants.SMTPHostKey),
+		Port:        values.String(constants.SMTPPortKey),
+		Username:    values.String(constants.SMTPUsernameKey),
+		Password:    values.String(constants.SMTPPasswordKey),
+		TLSMode:     values.String(constants.SMTPTLSModeKey),
+		CAFile:      values.String(constants.SMTPCAFileKey),
+		ServerName:  values.String(constants.SMTPServerNameKey),
+		PoolSize:    values.Int(constants.SMTPPoolSizeKey),
+		IdleTimeout: time.Duration(values.Int(constants.SMTPIdleTimeoutSecondsKey)) * time.Second,
+	}, nil
+}
+
+// GetMailTransportConfig retrieves the mail transport driver and its settings. SMTP settings are
+// only read, and required, for the smtp driver.
+func (cs *consulService) GetMailTransportConfig() (*config.MailTransportConfig, error) {
+	values, err := cs.resolve(
+		constants.MailTransportDriverKey,
+		constants.MailTransportFilePathKey,
+		constants.MailTransportHTTPURLKey,
+		constants.MailTransportHTTPTokenKey,
+		constants.MailTransportHTTPTimeoutSecKey,
+	)
+	problems := []error{err}
+
+	cfg := &config.MailTransportConfig{
+		Driver:      values.String(constants.MailTransportDriverKey),
+		FilePath:    values.String(constants.MailTransportFilePathKey),
+		HTTPURL:     values.String(constants.MailTransportHTTPURLKey),
+		HTTPToken:   values.String(constants.MailTransportHTTPTokenKey),
+		HTTPTimeout: time.Duration(values.Int(constants.MailTransportHTTPTimeoutSecKey)) * time.Second,
+	}
+	switch cfg.Driver {
+	case config.MailTransportSMTP:
+		var smtpErr error
+		cfg.SMTP, smtpErr = cs.GetSMTPConfig()
+		problems = append(problems, smtpErr)
+	case config.MailTransportHTTP:
+		if cfg.HTTPURL == "" {
+			problems = append(problems, fmt.Errorf("%s is required for the %s mail transport", constants.MailTransportHTTPURLKey, cfg.Driver))
+		}
+	}
+	if err := errors.Join(problems...); err != nil {
+		return nil, err
 	}
 	return cfg, nil
 }
 
-// GetGeneralConfig retrieves general application configuration from Consul.
+// GetGeneralConfig retrieves general application settings.
 func (cs *consulService) GetGeneralConfig() (*config.GeneralConfig, error) {
-	keys := []string{
+	values, err := cs.resolve(
 		constants.GeneralDomainKey,
 		constants.GeneralHTTPListenerPortKey,
+		constants.GeneralTrustedProxiesKey,
+	)
+	if err != nil {
+		return nil, err
 	}
-	configMap, err := cs.getConfigForKeys(keys)
+	var trustedProxies []string
+	for _, proxy := range strings.Split(values.String(constants.GeneralTrustedProxiesKey), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy == "" {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return nil, fmt.Errorf("%s: %q is not an IP or CIDR", constants.GeneralTrustedProxiesKey, proxy)
+		}
+		trustedProxies = append(trustedProxies, proxy)
+	}
+	return &config.GeneralConfig{
+		Domain:           values.String(constants.GeneralDomainKey),
+		HTTPListenerPort: values.String(constants.GeneralHTTPListenerPortKey),
+		TrustedProxies:   trustedProxies,
+	}, nil
+}
+
+// GetRegistrationConfig retrieves the registration settings.
+func (cs *consulService) GetRegistrationConfig() (*config.RegistrationConfig, error) {
+	values, err := cs.resolve(registrationKeys...)
 	if err != nil {
 		return nil, err
 	}
-	cfg := &config.GeneralConfig{
-		Domain:           configMap[constants.GeneralDomainKey],
-		HTTPListenerPort: configMap[constants.GeneralHTTPListenerPortKey],
+	return registrationConfig(values), nil
+}
+
+// GetRegistrationPasswordConfig retrieves the registration password policy.
+func (cs *consulService) GetRegistrationPasswordConfig() (*config.RegistrationPasswordConfig, error) {
+	values, err := cs.resolve(registrationPasswordKeys...)
+	if err != nil {
+		return nil, err
+	}
+	return registrationPasswordConfig(values), nil
+}
+
+// GetWebhookConfig retrieves webhook delivery settings.
+func (cs *consulService) GetWebhookConfig() (*config.WebhookConfig, error) {
+	values, err := cs.resolve(
+		constants.WebhookMaxAttemptsKey,
+		constants.WebhookRequestTimeoutSecKey,
+		constants.WebhookPollIntervalSecondsKey,
+		constants.WebhookBatchSizeKey,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &config.WebhookConfig{
+		MaxAttempts:    values.Int(constants.WebhookMaxAttemptsKey),
+		RequestTimeout: time.Duration(values.Int(constants.WebhookRequestTimeoutSecKey)) * time.Second,
+		PollInterval:   time.Duration(values.Int(constants.WebhookPollIntervalSecondsKey)) * time.Second,
+		BatchSize:      values.Int(constants.WebhookBatchSizeKey),
+	}, nil
+}
+
+// GetEventBusConfig retrieves the event bus driver and outbox relay settings.
+func (cs *consulService) GetEventBusConfig() (*config.EventBusConfig, error) {
+	values, err := cs.resolve(
+		constants.EventBusDriverKey,
+		constants.EventBusTopicKey,
+		constants.EventBusNATSURLKey,
+		constants.EventBusKafkaRESTURLKey,
+		constants.EventBusPollIntervalSecondsKey,
+		constants.EventBusBatchSizeKey,
+		constants.EventBusMaxAttemptsKey,
+		constants.EventBusRetentionHoursKey,
+	)
+	problems := []error{err}
+
+	cfg := &config.EventBusConfig{
+		Driver:       values.String(constants.EventBusDriverKey),
+		Topic:        values.String(constants.EventBusTopicKey),
+		NATSURL:      values.String(constants.EventBusNATSURLKey),
+		KafkaRESTURL: values.String(constants.EventBusKafkaRESTURLKey),
+		PollInterval: time.Duration(values.Int(constants.EventBusPollIntervalSecondsKey)) * time.Second,
+		BatchSize:    values.Int(constants.EventBusBatchSizeKey),
+		MaxAttempts:  values.Int(constants.EventBusMaxAttemptsKey),
+		Retention:    time.Duration(values.Int(constants.EventBusRetentionHoursKey)) * time.Hour,
+	}
+	if cfg.Driver == config.EventBusDriverNATS && cfg.NATSURL == "" {
+		problems = append(problems, fmt.Errorf("%s is required for the %s event bus", constants.EventBusNATSURLKey, cfg.Driver))
+	}
+	if cfg.Driver == config.EventBusDriverKafka && cfg.KafkaRESTURL == "" {
+		problems = append(problems, fmt.Errorf("%s is required for the %s event bus", constants.EventBusKafkaRESTURLKey, cfg.Driver))
+	}
+	if err := errors.Join(problems...); err != nil {
+		return nil, err
 	}
 	return cfg, nil
 }
 
-// GetRegistrationConfig retrieves registration configuration from Consul.
-func (cs *consulService) GetRegistrationConfig() *config.RegistrationConfig {
-	keys := []string{
-		constants.GeneralRegisterMailVerificationTimeInSecondsKey,
-		constants.GeneralRegisterHostVerificationMailAddressKey,
-		constants.GeneralRegisterVerificationMailTextKey,
-		constants.GeneralMaxVerificationMailCountInDay,
+// GetMailQueueConfig retrieves mail queue worker settings.
+func (cs *consulService) GetMailQueueConfig() (*config.MailQueueConfig, error) {
+	values, err := cs.resolve(
+		constants.MailQueueWorkersKey,
+		constants.MailQueueMaxAttemptsKey,
+		constants.MailQueueBatchSizeKey,
+		constants.MailQueueClaimIdleSecondsKey,
+		constants.MailQueueIdempotencyHoursKey,
+	)
+	if err != nil {
+		return nil, err
 	}
-	tokenExpirySeconds := 600
-	maxGenerationCount := 5
-	configMap, err := cs.getConfigForKeys(keys)
+	return &config.MailQueueConfig{
+		Workers:        values.Int(constants.MailQueueWorkersKey),
+		MaxAttempts:    values.Int(constants.MailQueueMaxAttemptsKey),
+		BatchSize:      values.Int(constants.MailQueueBatchSizeKey),
+		ClaimIdle:      time.Duration(values.Int(constants.MailQueueClaimIdleSecondsKey)) * time.Second,
+		IdempotencyTTL: time.Duration(values.Int(constants.MailQueueIdempotencyHoursKey)) * time.Hour,
+	}, nil
+}
+
+// GetTokenSignerConfig retrieves the JWT signer settings.
+func (cs *consulService) GetTokenSignerConfig() (*config.TokenSignerConfig, error) {
+	values, err := cs.resolve(
+		constants.AuthSignerKey,
+		constants.AuthTransitMountKey,
+		constants.AuthTransitAccessKeyKey,
+		constants.AuthTransitRefreshKeyKey,
+		constants.AuthTransitKeyRefreshSecondsKey,
+	)
 	if err != nil {
-		log.Panic("Failed to get registration config")
-		return nil
+		return nil, err
+	}
+	cfg := &config.TokenSignerConfig{
+		Signer:             values.String(constants.AuthSignerKey),
+		TransitMount:       values.String(constants.AuthTransitMountKey),
+		TransitAccessKey:   values.String(constants.AuthTransitAccessKeyKey),
+		TransitRefreshKey:  values.String(constants.AuthTransitRefreshKeyKey),
+		KeyRefreshInterval: time.Duration(values.Int(constants.AuthTransitKeyRefreshSecondsKey)) * time.Second,
 	}
+	if cfg.Signer == config.TokenSignerTransit && cfg.TransitAccessKey == cfg.TransitRefreshKey {
+		// With one key a refresh token would pass as an access token.
+		return nil, fmt.Errorf("%s and %s must name different keys", constants.AuthTransitAccessKeyKey, constants.AuthTransitRefreshKeyKey)
+	}
+	return cfg, nil
+}
+
+// GetReaperConfig retrieves the unverified account reaper settings.
+func (cs *consulService) GetReaperConfig() (*config.ReaperConfig, error) {
+	values, err := cs.resolve(
+		constants.ReaperEnabledKey,
+		constants.ReaperMaxAgeHoursKey,
+		constants.ReaperIntervalMinutesKey,
+		constants.ReaperBatchSizeKey,
+		constants.GeneralRegisterMailVerificationTimeInSecondsKey,
+	)
+	problems := []error{err}
 
-	// Parse the MailVerificationTimeInSeconds
-	if val, err := helpers.ParseInt("token expireTime", configMap[constants.GeneralRegisterMailVerificationTimeInSecondsKey]); err == nil {
-		tokenExpirySeconds = val
+	cfg := &config.ReaperConfig{
+		Enabled:   values.Bool(constants.ReaperEnabledKey),
+		MaxAge:    time.Duration(values.Int(constants.ReaperMaxAgeHoursKey)) * time.Hour,
+		Interval:  time.Duration(values.Int(constants.ReaperIntervalMinutesKey)) * time.Minute,
+		BatchSize: values.Int(constants.ReaperBatchSizeKey),
 	}
+	verificationTTL := time.Duration(values.Int(constants.GeneralRegisterMailVerificationTimeInSecondsKey)) * time.Second
+	if cfg.Enabled && cfg.MaxAge <= verificationTTL {
+		// Otherwise accounts would be deleted while their first verification link still works.
+		problems = append(problems, fmt.Errorf("%s must be longer than %s", constants.ReaperMaxAgeHoursKey, constants.GeneralRegisterMailVerificationTimeInSecondsKey))
+	}
+	if err := errors.Join(problems...); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
 
-	// Parse the MaxVerificationMailCountInDay
-	if val, err := helpers.ParseInt("count of max token generation", configMap[constants.GeneralMaxVerificationMailCountInDay]); err == nil {
-		maxGenerationCount = val
+// GetEmailNormalizationConfig retrieves the provider lists used to canonicalize emails.
+func (cs *consulService) GetEmailNormalizationConfig() (*config.EmailNormalizationConfig, error) {
+	values, err := cs.resolve(constants.EmailPlusTagDomainsKey, constants.EmailDotInsensitiveDomainsKey)
+	if err != nil {
+		return nil, err
 	}
+	return &config.EmailNormalizationConfig{
+		PlusTagDomains:        splitDomains(values.String(constants.EmailPlusTagDomainsKey)),
+		DotInsensitiveDomains: splitDomains(values.String(constants.EmailDotInsensitiveDomainsKey)),
+	}, nil
+}
 
-	return &config.RegistrationConfig{
-		MailVerificationTimeInSeconds:        time.Duration(tokenExpirySeconds) * time.Second,
-		HostVerificationMailAddress:          configMap[constants.GeneralRegisterHostVerificationMailAddressKey],
-		VerificationMailText:                 configMap[constants.GeneralRegisterVerificationMailTextKey],
-		MaxVerificationMailGenerationInHours: int64(maxGenerationCount),
+// GetInvitationConfig retrieves the invitation expiry and quota settings.
+func (cs *consulService) GetInvitationConfig() (*config.InvitationConfig, error) {
+	values, err := cs.resolve(
+		constants.InvitationDefaultExpiryHoursKey,
+		constants.InvitationMaxExpiryHoursKey,
+		constants.InvitationUserQuotaKey,
+		constants.InvitationUserQuotaWindowKey,
+	)
+	problems := []error{err}
+
+	cfg := &config.InvitationConfig{
+		DefaultExpiry:   time.Duration(values.Int(constants.InvitationDefaultExpiryHoursKey)) * time.Hour,
+		MaxExpiry:       time.Duration(values.Int(constants.InvitationMaxExpiryHoursKey)) * time.Hour,
+		UserQuota:       values.Int(constants.InvitationUserQuotaKey),
+		UserQuotaWindow: time.Duration(values.Int(constants.InvitationUserQuotaWindowKey)) * time.Hour,
+	}
+	if cfg.DefaultExpiry > cfg.MaxExpiry {
+		problems = append(problems, fmt.Errorf("%s must not exceed %s", constants.InvitationDefaultExpiryHoursKey, constants.InvitationMaxExpiryHoursKey))
 	}
+	if err := errors.Join(problems...); err != nil {
+		return nil, err
+	}
+	return cfg, nil
 }
 
-// GetRegistrationPasswordConfig retrieves and parses the registration password configuration from Consul.
-func (cs *consulService) GetRegistrationPasswordConfig() *config.RegistrationPasswordConfig {
-	keys := []string{
-		constants.KeyRegistrationPasswordMinLength,
-		constants.KeyRegistrationPasswordRequireUpper,
-		constants.KeyRegistrationPasswordRequireLower,
-		constants.KeyRegistrationPasswordRequireNumber,
-		constants.KeyRegistrationPasswordRequireSpecial,
+// GetChallengeConfig retrieves the challenge provider settings. Which routes require a challenge
+// is part of the runtime config.
+func (cs *consulService) GetChallengeConfig() (*config.ChallengeConfig, error) {
+	values, err := cs.resolve(
+		constants.ChallengeProviderKey,
+		constants.ChallengeSecretKey,
+		constants.ChallengeSiteKeyKey,
+		constants.ChallengeVerifyURLKey,
+		constants.ChallengeTimeoutSecondsKey,
+		constants.ChallengePoWDifficultyKey,
+		constants.ChallengePoWTTLSecondsKey,
+		constants.ChallengePoWMaxPerMinuteKey,
+	)
+	problems := []error{err}
+
+	cfg := &config.ChallengeConfig{
+		Provider:        values.String(constants.ChallengeProviderKey),
+		Secret:          values.String(constants.ChallengeSecretKey),
+		SiteKey:         values.String(constants.ChallengeSiteKeyKey),
+		VerifyURL:       values.String(constants.ChallengeVerifyURLKey),
+		Timeout:         time.Duration(values.Int(constants.ChallengeTimeoutSecondsKey)) * time.Second,
+		PoWDifficulty:   values.Int(constants.ChallengePoWDifficultyKey),
+		PoWTTL:          time.Duration(values.Int(constants.ChallengePoWTTLSecondsKey)) * time.Second,
+		PoWMaxPerMinute: values.Int(constants.ChallengePoWMaxPerMinuteKey),
+	}
+	if cfg.Provider != config.ChallengeProviderPoW && (cfg.Secret == "" || cfg.SiteKey == "") {
+		problems = append(problems, fmt.Errorf("%s and %s are required for challenge provider %s", constants.ChallengeSecretKey, constants.ChallengeSiteKeyKey, cfg.Provider))
+	}
+	if err := errors.Join(problems...); err != nil {
+		return nil, err
 	}
+	return cfg, nil
+}
 
-	configMap, err := cs.getConfigForKeys(keys)
-	if err != nil {
-		return nil
+// GetBreachedPasswordConfig retrieves where breached password hashes are loaded from.
+func (cs *consulService) GetBreachedPasswordConfig() (*config.BreachedPasswordConfig, error) {
+	values, err := cs.resolve(
+		constants.BreachedPasswordSourceKey,
+		constants.BreachedPasswordPathKey,
+		constants.BreachedPasswordMinCountKey,
+	)
+	problems := []error{err}
+
+	cfg := &config.BreachedPasswordConfig{
+		Source:   values.String(constants.BreachedPasswordSourceKey),
+		Path:     values.String(constants.BreachedPasswordPathKey),
+		MinCount: values.Int(constants.BreachedPasswordMinCountKey),
 	}
+	if cfg.Source != config.BreachedPasswordSourceNone && cfg.Path == "" {
+		problems = append(problems, fmt.Errorf("%s is required for breached password source %s", constants.BreachedPasswordPathKey, cfg.Source))
+	}
+	if err := errors.Join(problems...); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
 
-	minLength, err := helpers.ParseInt("minLength", configMap[constants.KeyRegistrationPasswordMinLength])
+// GetAccountDeletionConfig retrieves the grace period and schedule of account deletion.
+func (cs *consulService) GetAccountDeletionConfig() (*config.AccountDeletionConfig, error) {
+	values, err := cs.resolve(
+		constants.AccountDeletionGraceHoursKey,
+		constants.AccountDeletionIntervalMinutesKey,
+		constants.AccountDeletionBatchSizeKey,
+	)
 	if err != nil {
-		minLength = 8
+		return nil, err
 	}
+	return &config.AccountDeletionConfig{
+		GracePeriod: time.Duration(values.Int(constants.AccountDeletionGraceHoursKey)) * time.Hour,
+		Interval:    time.Duration(values.Int(constants.AccountDeletionIntervalMinutesKey)) * time.Minute,
+		BatchSize:   values.Int(constants.AccountDeletionBatchSizeKey),
+	}, nil
+}
 
-	parseBool := func(key, fieldName string) bool {
-		if v, err := helpers.ParseBool(fieldName, configMap[key]); err == nil {
-			return v
+// splitDomains parses a comma-separated domain list.
+func splitDomains(raw string) []string {
+	var domains []string
+	for _, domain := range strings.Split(raw, ",") {
+		if domain = strings.ToLower(strings.TrimSpace(domain)); domain != "" {
+			domains = append(domains, domain)
 		}
-		return false
 	}
+	return domains
+}
+
+// Validate reads every config struct and returns all problems at once, so a misconfigured
+// deployment fails at startup with the complete list instead of one key per restart.
+func (cs *consulService) Validate() error {
+	var problems []error
+	collect := func(_ any, err error) {
+		problems = append(problems, err)
+	}
+	collect(cs.GetMySQLConfig())
+	collect(cs.GetRedisConfig())
+	collect(cs.GetMailTransportConfig())
+	collect(cs.GetGeneralConfig())
+	collect(cs.GetRuntimeConfig())
+	collect(cs.GetWebhookConfig())
+	collect(cs.GetEventBusConfig())
+	collect(cs.GetMailQueueConfig())
+	collect(cs.GetTokenSignerConfig())
+	collect(cs.GetReaperConfig())
+	collect(cs.GetEmailNormalizationConfig())
+	collect(cs.GetInvitationConfig())
+	collect(cs.GetChallengeConfig())
+	collect(cs.GetBreachedPasswordConfig())
+	collect(cs.GetAccountDeletionConfig())
+	collect(cs.resolve(constants.MailDefaultLocaleKey))
+	return errors.Join(problems...)
+}
 
-	return &config.RegistrationPasswordConfig{
-		MinLength:      minLength,
-		RequireUpper:   parseBool(constants.KeyRegistrationPasswordRequireUpper, "requireUpper"),
-		RequireLower:   parseBool(constants.KeyRegistrationPasswordRequireLower, "requireLower"),
-		RequireNumber:  parseBool(constants.KeyRegistrationPasswordRequireNumber, "requireNumber"),
-		RequireSpecial: parseBool(constants.KeyRegistrationPasswordRequireSpecial, "requireSpecial"),
+// Effective returns every schema key with the value the service would use, defaults applied.
+// Invalid values are reported in the error and shown as their default.
+func (cs *consulService) Effective() (config.Values, error) {
+	keys := make([]string, 0, len(config.Schema))
+	for _, spec := range config.Schema {
+		keys = append(keys, spec.Key)
 	}
+	return cs.resolve(keys...)
 }
 
 var ConsulProviderSet = wire.NewSet(NewConsulService)
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/SilentPlaces/basicauth.git/internal/config"
+	"github.com/SilentPlaces/basicauth.git/pkg/constants"
+)
+
+// ConfigPrefix is the KV prefix watched for runtime changes.
+const ConfigPrefix = "config/"
+
+// pollInterval is how often providers that cannot watch are checked for changes.
+const pollInterval = 15 * time.Second
+
+var registrationKeys = []string{
+	constants.GeneralRegisterMailVerificationTimeInSecondsKey,
+	constants.GeneralRegisterHostVerificationMailAddressKey,
+	constants.GeneralMaxVerificationMailCountInDay,
+	constants.GeneralMaxVerificationAttemptsKey,
+	constants.GeneralRegisterVerificationModeKey,
+	constants.GeneralRegisterCodeLengthKey,
+	constants.GeneralRegisterMaxCodeAttemptsKey,
+	constants.RegistrationModeKey,
+}
+
+var registrationPasswordKeys = []string{
+	constants.KeyRegistrationPasswordMinLength,
+	constants.KeyRegistrationPasswordRequireUpper,
+	constants.KeyRegistrationPasswordRequireLower,
+	constants.KeyRegistrationPasswordRequireNumber,
+	constants.KeyRegistrationPasswordRequireSpecial,
+	constants.KeyRegistrationPasswordMaxLength,
+	constants.KeyRegistrationPasswordMinScore,
+	constants.KeyRegistrationPasswordHistorySize,
+	constants.KeyRegistrationPasswordMaxAgeDays,
+	constants.KeyRegistrationPasswordWarningDays,
+}
+
+var signupPolicyKeys = []string{
+	constants.SignupPolicyAllowedDomainsKey,
+	constants.SignupPolicyDeniedDomainsKey,
+	constants.SignupPolicyBlockDisposableKey,
+	constants.SignupPolicyDisposableDomainsKey,
+	constants.SignupPolicyDisposableDomainsFileKey,
+	constants.SignupPolicyRequireMXKey,
+	constants.SignupPolicyMXTimeoutMillisKey,
+}
+
+var challengeRouteKeys = []string{
+	constants.ChallengeSignupEnabledKey,
+	constants.ChallengeLoginEnabledKey,
+	constants.ChallengeResendEnabledKey,
+}
+
+// runtimeKeys lists every reloadable key.
+func runtimeKeys() []string {
+	return slices.Concat(registrationKeys, registrationPasswordKeys, signupPolicyKeys, challengeRouteKeys)
+}
+
+// GetRuntimeConfig reads and validates the reloadable settings.
+func (cs *consulService) GetRuntimeConfig() (*config.RuntimeConfig, error) {
+	values, err := cs.resolve(runtimeKeys()...)
+	if err != nil {
+		return nil, err
+	}
+	return validRuntimeConfig(values)
+}
+
+// ParseRuntimeConfig builds a RuntimeConfig from raw KV values. Every invalid value is reported,
+// so a bad edit can be rejected as a whole.
+func ParseRuntimeConfig(raw map[string]string) (*config.RuntimeConfig, error) {
+	lookup := func(key string) (string, bool, error) {
+		value, ok := raw[key]
+		return value, ok, nil
+	}
+	values, err := config.Resolve(runtimeKeys(), lookup)
+	if err != nil {
+		return nil, err
+	}
+	return validRuntimeConfig(values)
+}
+
+// validRuntimeConfig builds the RuntimeConfig and checks the settings that depend on each other.
+func validRuntimeConfig(values config.Values) (*config.RuntimeConfig, error) {
+	cfg := runtimeConfig(values)
+	if cfg.RegistrationPassword.MinLength > cfg.RegistrationPassword.MaxLength {
+		return nil, fmt.Errorf("%s must not exceed %s", constants.KeyRegistrationPasswordMinLength, constants.KeyRegistrationPasswordMaxLength)
+	}
+	return cfg, nil
+}
+
+func runtimeConfig(values config.Values) *config.RuntimeConfig {
+	return &config.RuntimeConfig{
+		Registration:         *registrationConfig(values),
+		RegistrationPassword: *registrationPasswordConfig(values),
+		SignupPolicy:         *signupPolicyConfig(values),
+		ChallengeRoutes: config.ChallengeRoutesConfig{
+			Signup:             values.Bool(constants.ChallengeSignupEnabledKey),
+			Login:              values.Bool(constants.ChallengeLoginEnabledKey),
+			ResendVerification: values.Bool(constants.ChallengeResendEnabledKey),
+		},
+	}
+}
+
+func registrationConfig(values config.Values) *config.RegistrationConfig {
+	return &config.RegistrationConfig{
+		MailVerificationTimeInSeconds:        time.Duration(values.Int(constants.GeneralRegisterMailVerificationTimeInSecondsKey)) * time.Second,
+		HostVerificationMailAddress:          values.String(constants.GeneralRegisterHostVerificationMailAddressKey),
+		MaxVerificationMailGenerationInHours: int64(values.Int(constants.GeneralMaxVerificationMailCountInDay)),
+		MaxVerificationAttempts:              values.Int(constants.GeneralMaxVerificationAttemptsKey),
+		VerificationMode:                     values.String(constants.GeneralRegisterVerificationModeKey),
+		CodeLength:                           values.Int(constants.GeneralRegisterCodeLengthKey),
+		MaxCodeAttempts:                      values.Int(constants.GeneralRegisterMaxCodeAttemptsKey),
+		Mode:                                 values.String(constants.RegistrationModeKey),
+	}
+}
+
+func registrationPasswordConfig(values config.Values) *config.RegistrationPasswordConfig {
+	return &config.RegistrationPasswordConfig{
+		MinLength:      values.Int(constants.KeyRegistrationPasswordMinLength),
+		MaxLength:      values.Int(constants.KeyRegistrationPasswordMaxLength),
+		RequireUpper:   values.Bool(constants.KeyRegistrationPasswordRequireUpper),
+		RequireLower:   values.Bool(constants.KeyRegistrationPasswordRequireLower),
+		RequireNumber:  values.Bool(constants.KeyRegistrationPasswordRequireNumber),
+		RequireSpecial: values.Bool(constants.KeyRegistrationPasswordRequireSpecial),
+		MinScore:       values.Int(constants.KeyRegistrationPasswordMinScore),
+		HistorySize:    values.Int(constants.KeyRegistrationPasswordHistorySize),
+		MaxAge:         time.Duration(values.Int(constants.KeyRegistrationPasswordMaxAgeDays)) * 24 * time.Hour,
+		ExpiryWarning:  time.Duration(values.Int(constants.KeyRegistrationPasswordWarningDays)) * 24 * time.Hour,
+	}
+}
+
+func signupPolicyConfig(values config.Values) *config.SignupPolicyConfig {
+	return &config.SignupPolicyConfig{
+		AllowedDomains:        splitDomains(values.String(constants.SignupPolicyAllowedDomainsKey)),
+		DeniedDomains:         splitDomains(values.String(constants.SignupPolicyDeniedDomainsKey)),
+		BlockDisposable:       values.Bool(constants.SignupPolicyBlockDisposableKey),
+		DisposableDomains:     splitDomains(values.String(constants.SignupPolicyDisposableDomainsKey)),
+		DisposableDomainsFile: values.String(constants.SignupPolicyDisposableDomainsFileKey),
+		RequireMX:             values.Bool(constants.SignupPolicyRequireMXKey),
+		MXTimeout:             time.Duration(values.Int(constants.SignupPolicyMXTimeoutMillisKey)) * time.Millisecond,
+	}
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/SilentPlaces/basicauth.git/internal/config"
+	"github.com/SilentPlaces/basicauth.git/internal/db/mysql"
+	"github.com/SilentPlaces/basicauth.git/internal/models/models"
+	repository "github.com/SilentPlaces/basicauth.git/internal/repositories/invitation"
+	helpers "github.com/SilentPlaces/basicauth.git/pkg/helper/strings"
+	"github.com/google/uuid"
+	"github.com/google/wire"
+)
+
+var (
+	// ErrInvitationInvalid covers unknown, expired, revoked and used up codes alike, so the response
+	// does not tell which codes exist.
+	ErrInvitationInvalid       = errors.New("invitation code is invalid or expired")
+	ErrInvitationEmailMismatch = errors.New("invitation was issued for a different email")
+	ErrInvitationQuotaExceeded = errors.New("too many invitations created recently")
+	ErrInvitationNotAllowed    = errors.New("only admins can set roles, uses or expiry of invitations")
+	ErrInvalidInvitation       = errors.New("invalid invitation")
+)
+
+// invitationCodeBytes is the random length of an invitation code.
+const invitationCodeBytes = 16
+
+var rolePattern = regexp.MustCompile(`^[a-z0-9_\-]{1,64}$`)
+
+type (
+	InvitationService interface {
+		// Create stores a new invitation and returns it with its code, which is not stored.
+		Create(request CreateRequest) (*models.Invitation, string, error)
+		// List returns invitations of createdBy, or of everyone when createdBy is empty.
+		List(createdBy string, limit int, offset int) ([]models.Invitation, error)
+		// Revoke revokes an invitation of createdBy, or any invitation when createdBy is empty.
+		Revoke(id string, createdBy string) error
+		// Resolve returns the usable invitation for code. An email-bound invitation must match
+		// canonicalEmail.
+		Resolve(code string, canonicalEmail string) (*models.Invitation, error)
+	}
+
+	// CreateRequest describes an invitation. Zero MaxUses and ExpiresIn pick the defaults: one use
+	// and the configured default expiry.
+	CreateRequest struct {
+		CreatedBy string
+		// Admin creators may set roles, uses and expiry and are not bound by the user quota.
+		Admin bool
+		// Email is the canonical email to bind the invitation to, empty for an open invitation.
+		Email     string
+		Roles     []string
+		MaxUses   int
+		ExpiresIn time.Duration
+	}
+
+	invitationService struct {
+		invitationRepository repository.InvitationRepository
+		transactor           mysql.Transactor
+		cfg                  *config.InvitationConfig
+	}
+)
+
+func NewInvitationService(invitationRepository repository.InvitationRepository, transactor mysql.Transactor, cfg *config.InvitationConfig) InvitationService {
+	return &invitationService{invitationRepository: invitationRepository, transactor: transactor, cfg: cfg}
+}
+
+func (s *invitationService) Create(request CreateRequest) (*models.Invitation, string, error) {
+	if request.MaxUses == 0 {
+		request.MaxUses = 1
+	}
+	if request.ExpiresIn == 0 {
+		request.ExpiresIn = s.cfg.DefaultExpiry
+	}
+
+	if !request.Admin {
+		if len(request.Roles) > 0 || request.MaxUses != 1 || request.ExpiresIn != s.cfg.DefaultExpiry {
+			return nil, "", ErrInvitationNotAllowed
+		}
+	}
+	if request.MaxUses < 1 {
+		return nil, "", fmt.Errorf("%w: max uses must be at least 1", ErrInvalidInvitation)
+	}
+	if request.Email != "" && request.MaxUses != 1 {
+		return nil, "", fmt.Errorf("%w: an invitation bound to an email has a single use", ErrInvalidInvitation)
+	}
+	if request.ExpiresIn < 0 || request.ExpiresIn > s.cfg.MaxExpiry {
+		return nil, "", fmt.Errorf("%w: expiry must be between 1 and %d hours", ErrInvalidInvitation, int(s.cfg.MaxExpiry.Hours()))
+	}
+	for _, role := range request.Roles {
+		if !rolePattern.MatchString(role) {
+			return nil, "", fmt.Errorf("%w: invalid role %q", ErrInvalidInvitation, role)
+		}
+	}
+
+	code, err := helpers.GenerateRandomString(invitationCodeBytes)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate invitation code: %w", err)
+	}
+	invitation := models.Invitation{
+		ID:        uuid.NewString(),
+		CodeHash:  hashCode(code),
+		Email:     request.Email,
+		Roles:     request.Roles,
+		MaxUses:   request.MaxUses,
+		ExpiresAt: time.Now().Add(request.ExpiresIn),
+		CreatedBy: request.CreatedBy,
+		CreatedAt: time.Now(),
+	}
+	if request.Admin {
+		if err := s.invitationRepository.InsertInvitation(invitation); err != nil {
+			return nil, "", err
+		}
+		return &invitation, code, nil
+	}
+
+	// Revoked and used up invitations count too, or revoking and creating again would send any
+	// number of mails. The creator's row lock keeps concurrent requests from passing the count
+	// together.
+	err = s.transactor.WithinTransaction(func(tx *sql.Tx) error {
+		repo := s.invitationRepository.WithTx(tx)
+		if err := repo.LockCreator(request.CreatedBy); err != nil {
+			return err
+		}
+		created, err := repo.CountInvitationsSince(request.CreatedBy, invitation.CreatedAt.Add(-s.cfg.UserQuotaWindow))
+		if err != nil {
+			return err
+		}
+		if created >= s.cfg.UserQuota {
+			return ErrInvitationQuotaExceeded
+		}
+		return repo.InsertInvitation(invitation)
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return &invitation, code, nil
+}
+
+func (s *invitationService) List(createdBy string, limit int, offset int) ([]models.Invitation, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return s.invitationRepository.ListInvitations(createdBy, limit, offset)
+}
+
+func (s *invitationService) Revoke(id string, createdBy string) error {
+	return s.invitationRepository.RevokeInvitation(id, createdBy)
+}
+
+// Resolve does not use the invitation up; registration redeems it in the signup transaction.
+func (s *invitationService) Resolve(code string, canonicalEmail string) (*models.Invitation, error) {
+	invitation, err := s.invitationRepository.GetInvitationByCodeHash(hashCode(code))
+	if errors.Is(err, repository.ErrInvitationNotFound) {
+		return nil, ErrInvitationInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !invitation.Usable(time.Now()) {
+		return nil, ErrInvitationInvalid
+	}
+	if invitation.Email != "" && invitation.Email != canonicalEmail {
+		return nil, ErrInvitationEmailMismatch
+	}
+	return invitation, nil
+}
+
+// hashCode returns the form an invitation code is stored in. Codes carry 128 bits of randomness,
+// so an unsalted hash is enough.
+func hashCode(code string) string {
+	digest := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(digest[:])
+}
+
+var InvitationServiceProviderSet = wire.NewSet(NewInvitationService)
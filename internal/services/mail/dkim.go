@@ -0,0 +1,253 @@
+package service
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DKIM canonicalization algorithms (RFC 6376 section 3.4).
+const (
+	DKIMCanonicalizationSimple  = "simple"
+	DKIMCanonicalizationRelaxed = "relaxed"
+)
+
+// dkimSignedHeaders lists the headers covered by the signature, in signing order. From is listed
+// twice: the second entry matches no header and signs its absence, so a From header added in
+// transit breaks the signature.
+var dkimSignedHeaders = []string{
+	"From", "To", "Subject", "Date", "Message-ID", "MIME-Version",
+	"Content-Type", "Content-Transfer-Encoding", "From",
+}
+
+// DKIMSigner adds a DKIM-Signature header to rendered messages. It signs with rsa-sha256 or
+// ed25519-sha256 (RFC 8463), depending on the key.
+type DKIMSigner struct {
+	domain      string
+	selector    string
+	signer      crypto.Signer
+	algorithm   string
+	headerCanon string
+	bodyCanon   string
+	now         func() time.Time
+}
+
+// NewDKIMSigner parses a PEM private key (PKCS#1 RSA, or PKCS#8 RSA/Ed25519) and signs with
+// relaxed/relaxed canonicalization, which survives the header rewrapping many relays do.
+func NewDKIMSigner(domain, selector string, privateKeyPEM []byte) (*DKIMSigner, error) {
+	if domain == "" || selector == "" {
+		return nil, errors.New("dkim domain and selector are required")
+	}
+	key, err := parseDKIMKey(privateKeyPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &DKIMSigner{
+		domain:      domain,
+		selector:    selector,
+		signer:      key,
+		headerCanon: DKIMCanonicalizationRelaxed,
+		bodyCanon:   DKIMCanonicalizationRelaxed,
+		now:         time.Now,
+	}
+	switch key.(type) {
+	case *rsa.PrivateKey:
+		s.algorithm = "rsa-sha256"
+	case ed25519.PrivateKey:
+		s.algorithm = "ed25519-sha256"
+	}
+	return s, nil
+}
+
+// WithCanonicalization switches the header and body canonicalization.
+func (s *DKIMSigner) WithCanonicalization(header, body string) (*DKIMSigner, error) {
+	for _, c := range []string{header, body} {
+		if c != DKIMCanonicalizationSimple && c != DKIMCanonicalizationRelaxed {
+			return nil, fmt.Errorf("unknown dkim canonicalization %q", c)
+		}
+	}
+	copied := *s
+	copied.headerCanon = header
+	copied.bodyCanon = body
+	return &copied, nil
+}
+
+// Sign returns raw with a DKIM-Signature header prepended. raw must use CRLF line endings.
+func (s *DKIMSigner) Sign(raw []byte) ([]byte, error) {
+	headerBlock, body, found := bytes.Cut(raw, []byte("\r\n\r\n"))
+	if !found {
+		return nil, errors.New("dkim: message has no header/body separator")
+	}
+	headers := splitHeaderFields(string(headerBlock) + "\r\n")
+
+	bodyHash := sha256.Sum256(canonicalizeBody(string(body), s.bodyCanon))
+
+	// Pick header instances bottom-up, as verifiers do (RFC 6376 section 5.4.2).
+	used := make(map[int]bool)
+	var signedNames []string
+	var signedData strings.Builder
+	for _, name := range dkimSignedHeaders {
+		signedNames = append(signedNames, strings.ToLower(name))
+		for i := len(headers) - 1; i >= 0; i-- {
+			if used[i] || !strings.EqualFold(headerName(headers[i]), name) {
+				continue
+			}
+			used[i] = true
+			signedData.WriteString(canonicalizeHeader(headers[i], s.headerCanon))
+			break
+		}
+	}
+
+	tags := []string{
+		"v=1",
+		"a=" + s.algorithm,
+		"c=" + s.headerCanon + "/" + s.bodyCanon,
+		"d=" + s.domain,
+		"s=" + s.selector,
+		fmt.Sprintf("t=%d", s.now().Unix()),
+		"h=" + strings.Join(signedNames, ":"),
+		"bh=" + base64.StdEncoding.EncodeToString(bodyHash[:]),
+	}
+	unsigned := "DKIM-Signature: " + strings.Join(tags, ";\r\n\t") + ";\r\n\tb="
+
+	// The signature header is signed last, with an empty b= and without its trailing CRLF.
+	signedData.WriteString(strings.TrimSuffix(canonicalizeHeader(unsigned+"\r\n", s.headerCanon), "\r\n"))
+	digest := sha256.Sum256([]byte(signedData.String()))
+
+	var signature []byte
+	var err error
+	switch key := s.signer.(type) {
+	case *rsa.PrivateKey:
+		signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	case ed25519.PrivateKey:
+		// RFC 8463 signs the SHA-256 digest with PureEdDSA.
+		signature = ed25519.Sign(key, digest[:])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("dkim signing failed: %w", err)
+	}
+
+	var out bytes.Buffer
+	out.WriteString(unsigned)
+	out.WriteString(base64.StdEncoding.EncodeToString(signature))
+	out.WriteString("\r\n")
+	out.Write(raw)
+	return out.Bytes(), nil
+}
+
+func parseDKIMKey(privateKeyPEM []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(privateKeyPEM)
+	if block == nil {
+		return nil, errors.New("dkim private key is not PEM encoded")
+	}
+	if block.Type == "RSA PRIVATE KEY" {
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dkim rsa key: %w", err)
+		}
+		return checkDKIMRSAKey(key)
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid dkim private key: %w", err)
+	}
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return checkDKIMRSAKey(k)
+	case ed25519.PrivateKey:
+		return k, nil
+	default:
+		return nil, fmt.Errorf("unsupported dkim key type %T", key)
+	}
+}
+
+// checkDKIMRSAKey rejects RSA keys shorter than RFC 8301 allows.
+func checkDKIMRSAKey(key *rsa.PrivateKey) (*rsa.PrivateKey, error) {
+	if key.N.BitLen() < 1024 {
+		return nil, errors.New("dkim rsa key must be at least 1024 bits")
+	}
+	return key, nil
+}
+
+// splitHeaderFields splits a CRLF terminated header block into fields, keeping continuation lines
+// with the field they belong to. Each field keeps its trailing CRLF.
+func splitHeaderFields(block string) []string {
+	var fields []string
+	for _, line := range strings.SplitAfter(block, "\r\n") {
+		if line == "" {
+			continue
+		}
+		if (line[0] == ' ' || line[0] == '\t') && len(fields) > 0 {
+			fields[len(fields)-1] += line
+			continue
+		}
+		fields = append(fields, line)
+	}
+	return fields
+}
+
+func headerName(field string) string {
+	name, _, _ := strings.Cut(field, ":")
+	return strings.TrimRight(name, " \t")
+}
+
+// canonicalizeHeader implements RFC 6376 section 3.4.1 and 3.4.2. The result ends with CRLF.
+func canonicalizeHeader(field, canonicalization string) string {
+	if canonicalization == DKIMCanonicalizationSimple {
+		return field
+	}
+	name, value, _ := strings.Cut(field, ":")
+	// Unfold, then reduce every run of whitespace to a single space.
+	value = strings.ReplaceAll(value, "\r\n", "")
+	value = strings.Join(strings.FieldsFunc(value, isWSP), " ")
+	return strings.ToLower(strings.TrimRight(name, " \t")) + ":" + value + "\r\n"
+}
+
+// canonicalizeBody implements RFC 6376 section 3.4.3 and 3.4.4.
+func canonicalizeBody(body, canonicalization string) []byte {
+	lines := strings.Split(body, "\r\n")
+	if canonicalization == DKIMCanonicalizationRelaxed {
+		for i, line := range lines {
+			line = strings.TrimRight(line, " \t")
+			if line == "" {
+				lines[i] = ""
+				continue
+			}
+			// Keep a single space where a whitespace run was, including at the start of the line.
+			leading := ""
+			if isWSP(rune(line[0])) {
+				leading = " "
+			}
+			lines[i] = leading + strings.Join(strings.FieldsFunc(line, isWSP), " ")
+		}
+	}
+
+	// Drop trailing empty lines.
+	end := len(lines)
+	for end > 0 && lines[end-1] == "" {
+		end--
+	}
+	if end == 0 {
+		if canonicalization == DKIMCanonicalizationRelaxed {
+			return nil
+		}
+		return []byte("\r\n")
+	}
+	return []byte(strings.Join(lines[:end], "\r\n") + "\r\n")
+}
+
+func isWSP(r rune) bool {
+	return r == ' ' || r == '\t'
+}
@@ -0,0 +1,217 @@
+//go:debug rsa1024min=0
+
+package service
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	"github.com/emersion/go-msgauth/dkim"
+)
+
+const (
+	testDKIMDomain   = "example.com"
+	testDKIMSelector = "mail"
+)
+
+// testDKIMMessage has folded headers, runs of whitespace, trailing whitespace and trailing empty
+// lines, so the canonicalizations differ.
+const testDKIMMessage = "From: Basic Auth <no-reply@example.com>\r\n" +
+	"To: alice@example.org\r\n" +
+	"Subject:  Verify   your\r\n" +
+	"\temail address \r\n" +
+	"Date: Mon, 03 Mar 2025 10:00:00 +0000\r\n" +
+	"Message-ID: <1@example.com>\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"\r\n" +
+	"Hello  Alice, \r\n" +
+	"\r\n" +
+	"\tyour code is 123456.\r\n" +
+	"\r\n" +
+	"\r\n"
+
+type testDKIMKey struct {
+	name string
+	pem  []byte
+	// record is the DNS TXT record publishing the public key.
+	record string
+}
+
+func generateDKIMKeys(t *testing.T) []testDKIMKey {
+	t.Helper()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPublic, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edPublic, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return []testDKIMKey{
+		{
+			name:   "rsa-2048 pkcs1",
+			pem:    pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}),
+			record: "v=DKIM1; k=rsa; p=" + base64.StdEncoding.EncodeToString(rsaPublic),
+		},
+		{
+			name:   "rsa-2048 pkcs8",
+			pem:    marshalPKCS8(t, rsaKey),
+			record: "v=DKIM1; k=rsa; p=" + base64.StdEncoding.EncodeToString(rsaPublic),
+		},
+		{
+			name:   "ed25519",
+			pem:    marshalPKCS8(t, edKey),
+			record: "v=DKIM1; k=ed25519; p=" + base64.StdEncoding.EncodeToString(edPublic),
+		},
+	}
+}
+
+func marshalPKCS8(t *testing.T, key crypto.Signer) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func verifyDKIM(t *testing.T, signed []byte, record string) *dkim.Verification {
+	t.Helper()
+	verifications, err := dkim.VerifyWithOptions(bytes.NewReader(signed), &dkim.VerifyOptions{
+		LookupTXT: func(domain string) ([]string, error) {
+			if domain != testDKIMSelector+"._domainkey."+testDKIMDomain {
+				t.Errorf("unexpected lookup of %q", domain)
+			}
+			return []string{record}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if len(verifications) != 1 {
+		t.Fatalf("got %d signatures, want 1", len(verifications))
+	}
+	return verifications[0]
+}
+
+func TestDKIMSignerSignaturesVerify(t *testing.T) {
+	canonicalizations := []struct{ header, body string }{
+		{DKIMCanonicalizationRelaxed, DKIMCanonicalizationRelaxed},
+		{DKIMCanonicalizationSimple, DKIMCanonicalizationSimple},
+		{DKIMCanonicalizationRelaxed, DKIMCanonicalizationSimple},
+		{DKIMCanonicalizationSimple, DKIMCanonicalizationRelaxed},
+	}
+
+	for _, key := range generateDKIMKeys(t) {
+		for _, c := range canonicalizations {
+			t.Run(key.name+" "+c.header+"/"+c.body, func(t *testing.T) {
+				signer, err := NewDKIMSigner(testDKIMDomain, testDKIMSelector, key.pem)
+				if err != nil {
+					t.Fatal(err)
+				}
+				signer, err = signer.WithCanonicalization(c.header, c.body)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				signed, err := signer.Sign([]byte(testDKIMMessage))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !strings.Contains(string(signed), "c="+c.header+"/"+c.body+";") {
+					t.Fatalf("signature does not declare c=%s/%s", c.header, c.body)
+				}
+
+				verification := verifyDKIM(t, signed, key.record)
+				if verification.Err != nil {
+					t.Fatalf("signature rejected: %v", verification.Err)
+				}
+				if verification.Domain != testDKIMDomain {
+					t.Errorf("domain = %q, want %q", verification.Domain, testDKIMDomain)
+				}
+
+				tampered := bytes.Replace(signed, []byte("123456"), []byte("654321"), 1)
+				if verifyDKIM(t, tampered, key.record).Err == nil {
+					t.Error("signature of a modified body verified")
+				}
+			})
+		}
+	}
+}
+
+func TestDKIMSignerRelaxedSurvivesRewrapping(t *testing.T) {
+	key := generateDKIMKeys(t)[0]
+	signer, err := NewDKIMSigner(testDKIMDomain, testDKIMSelector, key.pem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := signer.Sign([]byte(testDKIMMessage))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A relay refolding a header and dropping trailing whitespace keeps a relaxed signature valid.
+	rewrapped := strings.Replace(string(signed), "Subject:  Verify   your\r\n\temail address \r\n", "Subject: Verify your email\r\n address\r\n", 1)
+	rewrapped = strings.Replace(rewrapped, "Hello  Alice, \r\n", "Hello Alice,\r\n", 1)
+	if rewrapped == string(signed) {
+		t.Fatal("test message was not rewrapped")
+	}
+	if verification := verifyDKIM(t, []byte(rewrapped), key.record); verification.Err != nil {
+		t.Fatalf("rewrapped message rejected: %v", verification.Err)
+	}
+}
+
+func TestNewDKIMSignerRejectsKeys(t *testing.T) {
+	shortKey, err := rsa.GenerateKey(rand.Reader, 768)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		pem  []byte
+	}{
+		{"not pem", []byte("not a key")},
+		{"short rsa pkcs1", pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(shortKey)})},
+		{"short rsa pkcs8", marshalPKCS8(t, shortKey)},
+		{"corrupt pkcs8", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("garbage")})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewDKIMSigner(testDKIMDomain, testDKIMSelector, tt.pem); err == nil {
+				t.Error("key accepted")
+			}
+		})
+	}
+
+	if _, err := NewDKIMSigner("", testDKIMSelector, marshalPKCS8(t, edKey)); err == nil {
+		t.Error("missing domain accepted")
+	}
+	signer, err := NewDKIMSigner(testDKIMDomain, testDKIMSelector, marshalPKCS8(t, edKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := signer.WithCanonicalization("relaxed", "nowsp"); err == nil {
+		t.Error("unknown canonicalization accepted")
+	}
+}
@@ -2,54 +2,83 @@ package service
 
 import (
 	"fmt"
-	"net/smtp"
 
-	"github.com/SilentPlaces/basicauth.git/internal/config"
 	consulService "github.com/SilentPlaces/basicauth.git/internal/services/consul"
+	"github.com/SilentPlaces/basicauth.git/pkg/constants"
 	"github.com/google/wire"
 )
 
+const defaultLocale = "en"
+
 type (
 	MailService interface {
-		SendVerificationEmail(from string, to string, subject string, body string) error
+		// Send delivers a fully composed message.
+		Send(message *Message) error
+		// SendTemplate renders the named template in the given locale and sends it.
+		SendTemplate(from string, to string, locale string, name string, data interface{}) error
+		// MatchLocale picks the best supported locale for the given preferences.
+		MatchLocale(preferences ...string) string
+	}
+
+	// MailTransport hands a composed message to whatever delivers it (SMTP server, HTTP API, file...).
+	MailTransport interface {
+		Send(message *Message) error
+		Close() error
 	}
 
 	mailService struct {
-		auth      smtp.Auth
-		smtpHost  string
-		smtpPort  string
-		appConfig *config.AppConfig
+		transport MailTransport
+		renderer  TemplateRenderer
+		signer    *DKIMSigner
 	}
 )
 
-// NewMailService retrieves SMTP configuration from Consul and creates a new MailService.
-func NewMailService(consul consulService.ConsulService, appConfig *config.AppConfig) (MailService, error) {
-	cfg, err := consul.GetSMTPConfig()
+// NewMailService creates a MailService that delivers through transport and DKIM-signs every
+// message when signer is not nil. Templates are read from Consul first and fall back to the ones
+// embedded in the binary.
+func NewMailService(consul consulService.ConsulService, transport MailTransport, signer *DKIMSigner) (MailService, error) {
+	locale, ok, err := consul.GetOptionalValue(constants.MailDefaultLocaleKey)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get SMTP config: %w", err)
+		return nil, fmt.Errorf("failed to get default mail locale: %w", err)
 	}
-
-	var auth smtp.Auth
-
-	// Conditionally set authentication only in production
-	if appConfig.Environment == "production" {
-		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	if !ok || locale == "" {
+		locale = defaultLocale
 	}
 
+	source := NewLayeredTemplateSource(NewConsulTemplateSource(consul), NewEmbeddedTemplateSource())
 	return &mailService{
-		auth:      auth,
-		smtpHost:  cfg.Host,
-		smtpPort:  cfg.Port,
-		appConfig: appConfig,
+		transport: transport,
+		renderer:  NewTemplateRenderer(source, locale),
+		signer:    signer,
 	}, nil
 }
 
-// SendVerificationEmail sends an email using the configured SMTP server.
-func (ms *mailService) SendVerificationEmail(from string, to string, subject string, body string) error {
-	msg := []byte(fmt.Sprintf("Subject: %s\r\n\r\n%s", subject, body))
-	addr := fmt.Sprintf("%s:%s", ms.smtpHost, ms.smtpPort)
+// Send sends a message through the configured transport.
+func (ms *mailService) Send(message *Message) error {
+	if ms.signer != nil {
+		if err := message.Sign(ms.signer); err != nil {
+			return err
+		}
+	}
+	return ms.transport.Send(message)
+}
+
+func (ms *mailService) SendTemplate(from string, to string, locale string, name string, data interface{}) error {
+	rendered, err := ms.renderer.Render(locale, name, data)
+	if err != nil {
+		return err
+	}
+	return ms.Send(&Message{
+		From:     from,
+		To:       []string{to},
+		Subject:  rendered.Subject,
+		TextBody: rendered.Text,
+		HTMLBody: rendered.HTML,
+	})
+}
 
-	return smtp.SendMail(addr, ms.auth, from, []string{to}, msg)
+func (ms *mailService) MatchLocale(preferences ...string) string {
+	return ms.renderer.MatchLocale(preferences...)
 }
 
 var MailServiceProviderSet = wire.NewSet(NewMailService)
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Message is an outgoing email with a plain-text and an optional HTML body.
+type Message struct {
+	From      string
+	To        []string
+	Subject   string
+	TextBody  string
+	HTMLBody  string
+	Date      time.Time
+	MessageID string
+
+	// raw holds the signed rendering once Sign was called.
+	raw []byte
+}
+
+// Bytes renders the message as RFC 5322 with a multipart/alternative body.
+// Date and Message-ID are filled in when empty. A signed message returns the signed bytes.
+func (m *Message) Bytes() ([]byte, error) {
+	if m.raw != nil {
+		return m.raw, nil
+	}
+	return m.render()
+}
+
+// Sign renders the message and adds a DKIM signature. Later calls to Bytes return the signed
+// message, so the content must not be changed afterwards.
+func (m *Message) Sign(signer *DKIMSigner) error {
+	raw, err := m.render()
+	if err != nil {
+		return err
+	}
+	signed, err := signer.Sign(raw)
+	if err != nil {
+		return err
+	}
+	m.raw = signed
+	return nil
+}
+
+func (m *Message) render() ([]byte, error) {
+	from, err := mail.ParseAddress(m.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from address %q: %w", m.From, err)
+	}
+	recipients := make([]string, 0, len(m.To))
+	for _, to := range m.To {
+		addr, err := mail.ParseAddress(to)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recipient address %q: %w", to, err)
+		}
+		recipients = append(recipients, addr.String())
+	}
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("message has no recipients")
+	}
+
+	if m.Date.IsZero() {
+		m.Date = time.Now()
+	}
+	if m.MessageID == "" {
+		m.MessageID = fmt.Sprintf("<%s@%s>", uuid.NewString(), domainOf(from.Address))
+	}
+
+	var buf bytes.Buffer
+	writeHeader(&buf, "From", from.String())
+	writeHeader(&buf, "To", strings.Join(recipients, ", "))
+	writeHeader(&buf, "Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	writeHeader(&buf, "Date", m.Date.Format(time.RFC1123Z))
+	writeHeader(&buf, "Message-ID", m.MessageID)
+	writeHeader(&buf, "MIME-Version", "1.0")
+
+	if m.HTMLBody == "" {
+		writeHeader(&buf, "Content-Type", "text/plain; charset=UTF-8")
+		writeHeader(&buf, "Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		if err := writeQuotedPrintable(&buf, m.TextBody); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	writer := multipart.NewWriter(&buf)
+	writeHeader(&buf, "Content-Type", "multipart/alternative; boundary="+writer.Boundary())
+	buf.WriteString("\r\n")
+
+	// Clients show the last part they understand, so HTML goes after plain text.
+	for _, part := range []struct {
+		contentType string
+		body        string
+	}{
+		{"text/plain; charset=UTF-8", m.TextBody},
+		{"text/html; charset=UTF-8", m.HTMLBody},
+	} {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", part.contentType)
+		header.Set("Content-Transfer-Encoding", "quoted-printable")
+		partWriter, err := writer.CreatePart(header)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create mime part: %w", err)
+		}
+		qp := quotedprintable.NewWriter(partWriter)
+		if _, err := qp.Write([]byte(normalizeNewlines(part.body))); err != nil {
+			return nil, fmt.Errorf("failed to encode mime part: %w", err)
+		}
+		if err := qp.Close(); err != nil {
+			return nil, fmt.Errorf("failed to encode mime part: %w", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close multipart body: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Sender returns the bare envelope sender address.
+func (m *Message) Sender() (string, error) {
+	addr, err := mail.ParseAddress(m.From)
+	if err != nil {
+		return "", err
+	}
+	return addr.Address, nil
+}
+
+// Recipients returns the bare envelope recipient addresses.
+func (m *Message) Recipients() ([]string, error) {
+	recipients := make([]string, 0, len(m.To))
+	for _, to := range m.To {
+		addr, err := mail.ParseAddress(to)
+		if err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, addr.Address)
+	}
+	return recipients, nil
+}
+
+func writeHeader(buf *bytes.Buffer, key, value string) {
+	buf.WriteString(key)
+	buf.WriteString(": ")
+	buf.WriteString(value)
+	buf.WriteString("\r\n")
+}
+
+func writeQuotedPrintable(buf *bytes.Buffer, body string) error {
+	qp := quotedprintable.NewWriter(buf)
+	if _, err := qp.Write([]byte(normalizeNewlines(body))); err != nil {
+		return fmt.Errorf("failed to encode body: %w", err)
+	}
+	return qp.Close()
+}
+
+// normalizeNewlines converts bare LF line endings to CRLF as required by SMTP.
+func normalizeNewlines(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.ReplaceAll(s, "\n", "\r\n")
+}
+
+func domainOf(address string) string {
+	if at := strings.LastIndex(address, "@"); at >= 0 {
+		return address[at+1:]
+	}
+	return "localhost"
+}
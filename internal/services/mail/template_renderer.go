@@ -0,0 +1,307 @@
+package service
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"sort"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+	"time"
+
+	consulService "github.com/SilentPlaces/basicauth.git/internal/services/consul"
+	"github.com/SilentPlaces/basicauth.git/pkg/constants"
+	"golang.org/x/text/language"
+)
+
+// Template names known to the application.
+const (
+	TemplateVerification = "verification"
+	TemplateInvitation   = "invitation"
+)
+
+// templateCacheTTL bounds how long an edited template in Consul takes to show up.
+const templateCacheTTL = time.Minute
+
+//go:embed templates
+var embeddedTemplates embed.FS
+
+var ErrTemplateNotFound = errors.New("mail template not found")
+
+type (
+	// TemplateSource provides raw template parts for a locale.
+	TemplateSource interface {
+		// Read returns the template part and whether the source has it.
+		Read(locale, name, part string) (string, bool, error)
+		Locales() ([]string, error)
+	}
+
+	// RenderedMail holds the rendered subject and bodies of a template.
+	RenderedMail struct {
+		Subject string
+		Text    string
+		HTML    string
+	}
+
+	TemplateRenderer interface {
+		Render(locale, name string, data interface{}) (*RenderedMail, error)
+		// MatchLocale picks the best supported locale. Each preference may be a
+		// stored locale or a raw Accept-Language header; the first one that matches wins.
+		MatchLocale(preferences ...string) string
+	}
+
+	templateRenderer struct {
+		source        TemplateSource
+		defaultLocale string
+
+		mu       sync.Mutex
+		cache    map[string]*compiledTemplate
+		locales  []string
+		loadedAt time.Time
+	}
+
+	compiledTemplate struct {
+		subject  *texttemplate.Template
+		text     *texttemplate.Template
+		html     *htmltemplate.Template
+		loadedAt time.Time
+	}
+
+	embeddedTemplateSource struct {
+		files fs.FS
+	}
+
+	consulTemplateSource struct {
+		consul consulService.ConsulService
+	}
+
+	layeredTemplateSource []TemplateSource
+)
+
+func NewTemplateRenderer(source TemplateSource, defaultLocale string) TemplateRenderer {
+	return &templateRenderer{
+		source:        source,
+		defaultLocale: defaultLocale,
+		cache:         make(map[string]*compiledTemplate),
+	}
+}
+
+// NewEmbeddedTemplateSource serves the templates compiled into the binary.
+func NewEmbeddedTemplateSource() TemplateSource {
+	files, _ := fs.Sub(embeddedTemplates, "templates")
+	return &embeddedTemplateSource{files: files}
+}
+
+// NewConsulTemplateSource serves templates stored under constants.MailTemplatesPrefix.
+func NewConsulTemplateSource(consul consulService.ConsulService) TemplateSource {
+	return &consulTemplateSource{consul: consul}
+}
+
+// NewLayeredTemplateSource asks each source in order and uses the first one that has a part.
+func NewLayeredTemplateSource(sources ...TemplateSource) TemplateSource {
+	return layeredTemplateSource(sources)
+}
+
+func (r *templateRenderer) Render(locale, name string, data interface{}) (*RenderedMail, error) {
+	tmpl, err := r.compiled(locale, name)
+	if errors.Is(err, ErrTemplateNotFound) && locale != r.defaultLocale {
+		tmpl, err = r.compiled(r.defaultLocale, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var subject, text bytes.Buffer
+	if err := tmpl.subject.Execute(&subject, data); err != nil {
+		return nil, fmt.Errorf("failed to render %s subject: %w", name, err)
+	}
+	if err := tmpl.text.Execute(&text, data); err != nil {
+		return nil, fmt.Errorf("failed to render %s text body: %w", name, err)
+	}
+	rendered := &RenderedMail{
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    text.String(),
+	}
+	if tmpl.html != nil {
+		var html bytes.Buffer
+		if err := tmpl.html.Execute(&html, data); err != nil {
+			return nil, fmt.Errorf("failed to render %s html body: %w", name, err)
+		}
+		rendered.HTML = html.String()
+	}
+	return rendered, nil
+}
+
+func (r *templateRenderer) MatchLocale(preferences ...string) string {
+	supported := r.supportedLocales()
+	tags := make([]language.Tag, 0, len(supported))
+	for _, locale := range supported {
+		tags = append(tags, language.Make(locale))
+	}
+	matcher := language.NewMatcher(tags)
+
+	for _, preference := range preferences {
+		if preference == "" {
+			continue
+		}
+		wanted, _, err := language.ParseAcceptLanguage(preference)
+		if err != nil || len(wanted) == 0 {
+			continue
+		}
+		_, index, confidence := matcher.Match(wanted...)
+		if confidence != language.No {
+			return supported[index]
+		}
+	}
+	return r.defaultLocale
+}
+
+// supportedLocales lists the available locales with the default one first, as the matcher expects.
+func (r *templateRenderer) supportedLocales() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.locales != nil && time.Since(r.loadedAt) < templateCacheTTL {
+		return r.locales
+	}
+
+	locales := []string{r.defaultLocale}
+	found, err := r.source.Locales()
+	if err == nil {
+		for _, locale := range found {
+			if locale != r.defaultLocale {
+				locales = append(locales, locale)
+			}
+		}
+	}
+	r.locales = locales
+	r.loadedAt = time.Now()
+	return locales
+}
+
+func (r *templateRenderer) compiled(locale, name string) (*compiledTemplate, error) {
+	key := locale + "/" + name
+
+	r.mu.Lock()
+	cached, ok := r.cache[key]
+	r.mu.Unlock()
+	if ok && time.Since(cached.loadedAt) < templateCacheTTL {
+		return cached, nil
+	}
+
+	subjectRaw, hasSubject, err := r.source.Read(locale, name, constants.MailTemplatePartSubject)
+	if err != nil {
+		return nil, err
+	}
+	textRaw, hasText, err := r.source.Read(locale, name, constants.MailTemplatePartPlainText)
+	if err != nil {
+		return nil, err
+	}
+	if !hasSubject || !hasText {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, key)
+	}
+	htmlRaw, hasHTML, err := r.source.Read(locale, name, constants.MailTemplatePartHTML)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl := &compiledTemplate{loadedAt: time.Now()}
+	if tmpl.subject, err = texttemplate.New(key + "/subject").Parse(subjectRaw); err != nil {
+		return nil, fmt.Errorf("invalid subject template %s: %w", key, err)
+	}
+	if tmpl.text, err = texttemplate.New(key + "/text").Parse(textRaw); err != nil {
+		return nil, fmt.Errorf("invalid text template %s: %w", key, err)
+	}
+	if hasHTML {
+		if tmpl.html, err = htmltemplate.New(key + "/html").Parse(htmlRaw); err != nil {
+			return nil, fmt.Errorf("invalid html template %s: %w", key, err)
+		}
+	}
+
+	r.mu.Lock()
+	r.cache[key] = tmpl
+	r.mu.Unlock()
+	return tmpl, nil
+}
+
+func (s *embeddedTemplateSource) Read(locale, name, part string) (string, bool, error) {
+	raw, err := fs.ReadFile(s.files, fmt.Sprintf("%s/%s.%s.tmpl", locale, name, part))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return string(raw), true, nil
+}
+
+func (s *embeddedTemplateSource) Locales() ([]string, error) {
+	entries, err := fs.ReadDir(s.files, ".")
+	if err != nil {
+		return nil, err
+	}
+	var locales []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			locales = append(locales, entry.Name())
+		}
+	}
+	return locales, nil
+}
+
+func (s *consulTemplateSource) Read(locale, name, part string) (string, bool, error) {
+	return s.consul.GetOptionalValue(constants.MailTemplatesPrefix + locale + "/" + name + "/" + part)
+}
+
+func (s *consulTemplateSource) Locales() ([]string, error) {
+	keys, err := s.consul.ListKeys(constants.MailTemplatesPrefix)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var locales []string
+	for _, key := range keys {
+		locale, _, found := strings.Cut(strings.TrimPrefix(key, constants.MailTemplatesPrefix), "/")
+		if found && locale != "" && !seen[locale] {
+			seen[locale] = true
+			locales = append(locales, locale)
+		}
+	}
+	return locales, nil
+}
+
+func (s layeredTemplateSource) Read(locale, name, part string) (string, bool, error) {
+	for _, source := range s {
+		value, ok, err := source.Read(locale, name, part)
+		if err != nil {
+			return "", false, err
+		}
+		if ok {
+			return value, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+func (s layeredTemplateSource) Locales() ([]string, error) {
+	seen := make(map[string]bool)
+	var locales []string
+	for _, source := range s {
+		found, err := source.Locales()
+		if err != nil {
+			return nil, err
+		}
+		for _, locale := range found {
+			if !seen[locale] {
+				seen[locale] = true
+				locales = append(locales, locale)
+			}
+		}
+	}
+	sort.Strings(locales)
+	return locales, nil
+}
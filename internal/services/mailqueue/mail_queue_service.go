@@ -0,0 +1,96 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/SilentPlaces/basicauth.git/internal/config"
+	"github.com/SilentPlaces/basicauth.git/internal/models/models"
+	repository "github.com/SilentPlaces/basicauth.git/internal/repositories/mailqueue"
+	"github.com/google/uuid"
+	"github.com/google/wire"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var ErrInvalidMailJob = errors.New("mail job needs a sender, a recipient and a template")
+
+var mailQueueJobsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "mail_queue_jobs_total",
+		Help: "Mail queue jobs by template and outcome (enqueued, duplicate, sent, retry, dead, replayed).",
+	},
+	[]string{"template", "outcome"},
+)
+
+func init() {
+	prometheus.MustRegister(mailQueueJobsTotal)
+}
+
+type (
+	MailQueueService interface {
+		// Enqueue adds a job to the queue. A job whose idempotency key was already enqueued within
+		// the idempotency window is dropped and reported as not queued.
+		Enqueue(job models.MailJob) (bool, error)
+		ListDeadLetters(limit int) ([]models.MailJob, error)
+		Replay(id string) (*models.MailJob, error)
+	}
+
+	mailQueueService struct {
+		mailQueueRepository repository.MailQueueRepository
+		cfg                 *config.MailQueueConfig
+	}
+)
+
+func NewMailQueueService(mailQueueRepository repository.MailQueueRepository, cfg *config.MailQueueConfig) MailQueueService {
+	return &mailQueueService{mailQueueRepository: mailQueueRepository, cfg: cfg}
+}
+
+func (s *mailQueueService) Enqueue(job models.MailJob) (bool, error) {
+	if job.From == "" || job.To == "" || job.Template == "" {
+		return false, ErrInvalidMailJob
+	}
+	if job.IdempotencyKey == "" {
+		job.IdempotencyKey = uuid.NewString()
+	}
+
+	reserved, err := s.mailQueueRepository.Reserve(job.IdempotencyKey, s.cfg.IdempotencyTTL)
+	if err != nil {
+		return false, err
+	}
+	if !reserved {
+		mailQueueJobsTotal.WithLabelValues(job.Template, "duplicate").Inc()
+		return false, nil
+	}
+
+	job.Attempts = 0
+	job.EnqueuedAt = time.Now().UTC()
+	if _, err := s.mailQueueRepository.Add(job); err != nil {
+		// Free the key so the caller can try again.
+		if releaseErr := s.mailQueueRepository.Release(job.IdempotencyKey); releaseErr != nil {
+			return false, fmt.Errorf("%w (releasing idempotency key also failed: %v)", err, releaseErr)
+		}
+		return false, err
+	}
+
+	mailQueueJobsTotal.WithLabelValues(job.Template, "enqueued").Inc()
+	return true, nil
+}
+
+func (s *mailQueueService) ListDeadLetters(limit int) ([]models.MailJob, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	return s.mailQueueRepository.ListDead(limit)
+}
+
+func (s *mailQueueService) Replay(id string) (*models.MailJob, error) {
+	job, err := s.mailQueueRepository.Replay(id)
+	if err != nil {
+		return nil, err
+	}
+	mailQueueJobsTotal.WithLabelValues(job.Template, "replayed").Inc()
+	return job, nil
+}
+
+var MailQueueServiceProviderSet = wire.NewSet(NewMailQueueService)
@@ -0,0 +1,232 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/SilentPlaces/basicauth.git/internal/config"
+	"github.com/SilentPlaces/basicauth.git/internal/models/models"
+	repository "github.com/SilentPlaces/basicauth.git/internal/repositories/mailqueue"
+	mailservice "github.com/SilentPlaces/basicauth.git/internal/services/mail"
+	appLogger "github.com/SilentPlaces/basicauth.git/internal/shared/logger"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	retryBaseDelay = 15 * time.Second
+	retryMaxDelay  = time.Hour
+	readBlock      = 5 * time.Second
+	// maintenanceInterval is how often due retries are promoted and stale jobs reclaimed.
+	maintenanceInterval = time.Second
+	maxErrorLength      = 512
+)
+
+var mailSendDuration = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name:    "mail_send_duration_seconds",
+		Help:    "Time spent handing a queued mail to the mail transport.",
+		Buckets: prometheus.DefBuckets,
+	},
+	[]string{"template"},
+)
+
+func init() {
+	prometheus.MustRegister(mailSendDuration)
+}
+
+// Worker consumes the mail queue with a pool of goroutines. Failed sends are retried with
+// exponential backoff and end up in the dead-letter stream after MaxAttempts.
+type Worker struct {
+	mailQueueRepository repository.MailQueueRepository
+	mailService         mailservice.MailService
+	cfg                 *config.MailQueueConfig
+	logger              appLogger.Logger
+	consumerPrefix      string
+}
+
+func NewWorker(mailQueueRepository repository.MailQueueRepository, mailService mailservice.MailService, cfg *config.MailQueueConfig, logger appLogger.Logger) *Worker {
+	host, _ := os.Hostname()
+	return &Worker{
+		mailQueueRepository: mailQueueRepository,
+		mailService:         mailService,
+		cfg:                 cfg,
+		logger:              logger,
+		consumerPrefix:      fmt.Sprintf("%s-%d", host, os.Getpid()),
+	}
+}
+
+// Run starts the consumers and the retry scheduler and blocks until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context) {
+	for {
+		err := w.mailQueueRepository.EnsureGroup()
+		if err == nil {
+			break
+		}
+		w.logger.Error(ctx, "mail queue setup failed", err, nil)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(5 * time.Second):
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < w.cfg.Workers; i++ {
+		wg.Add(1)
+		go func(consumer string) {
+			defer wg.Done()
+			w.consume(ctx, consumer)
+		}(fmt.Sprintf("%s-%d", w.consumerPrefix, i))
+	}
+	w.maintain(ctx)
+	wg.Wait()
+}
+
+func (w *Worker) consume(ctx context.Context, consumer string) {
+	for ctx.Err() == nil {
+		jobs, err := w.mailQueueRepository.Read(consumer, w.cfg.BatchSize, readBlock)
+		if err != nil {
+			w.logger.Error(ctx, "mail queue read failed", err, map[string]interface{}{"consumer": consumer})
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(readBlock):
+			}
+			continue
+		}
+		for _, job := range jobs {
+			w.process(ctx, job)
+		}
+	}
+}
+
+// maintain promotes due retries and reclaims jobs left behind by crashed consumers.
+func (w *Worker) maintain(ctx context.Context) {
+	ticker := time.NewTicker(maintenanceInterval)
+	defer ticker.Stop()
+
+	consumer := w.consumerPrefix + "-reclaim"
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if _, err := w.mailQueueRepository.PromoteDue(time.Now(), 100); err != nil {
+			w.logger.Error(ctx, "mail retry promotion failed", err, nil)
+		}
+		stale, err := w.mailQueueRepository.ClaimStale(consumer, w.cfg.ClaimIdle, w.cfg.BatchSize)
+		if err != nil {
+			w.logger.Error(ctx, "mail queue reclaim failed", err, nil)
+			continue
+		}
+		for _, job := range stale {
+			w.process(ctx, job)
+		}
+	}
+}
+
+func (w *Worker) process(ctx context.Context, job models.MailJob) {
+	fields := map[string]interface{}{
+		"job_id":          job.ID,
+		"idempotency_key": job.IdempotencyKey,
+		"template":        job.Template,
+		"attempt":         job.Attempts + 1,
+	}
+
+	if job.Template == "" {
+		cause := job.LastError
+		if cause == "" {
+			cause = "mail job has no template"
+		}
+		w.bury(ctx, job, errors.New(cause), fields)
+		return
+	}
+
+	// A consumer may have sent the mail and died before acknowledging it.
+	sent, err := w.mailQueueRepository.IsSent(job.IdempotencyKey)
+	if err != nil {
+		w.logger.Error(ctx, "mail idempotency check failed", err, fields)
+		return
+	}
+	if sent {
+		if err := w.mailQueueRepository.Complete(job.ID); err != nil {
+			w.logger.Error(ctx, "mail job completion failed", err, fields)
+		}
+		mailQueueJobsTotal.WithLabelValues(job.Template, "duplicate").Inc()
+		return
+	}
+
+	start := time.Now()
+	sendErr := w.mailService.SendTemplate(job.From, job.To, job.Locale, job.Template, job.Data)
+	mailSendDuration.WithLabelValues(job.Template).Observe(time.Since(start).Seconds())
+
+	if sendErr == nil {
+		if err := w.mailQueueRepository.MarkSent(job.IdempotencyKey); err != nil {
+			w.logger.Error(ctx, "mail sent marker failed", err, fields)
+		}
+		if err := w.mailQueueRepository.Complete(job.ID); err != nil {
+			w.logger.Error(ctx, "mail job completion failed", err, fields)
+		}
+		mailQueueJobsTotal.WithLabelValues(job.Template, "sent").Inc()
+		w.logger.Debug(ctx, "mail sent", fields)
+		return
+	}
+
+	// Missing or broken templates will not fix themselves by retrying.
+	if errors.Is(sendErr, mailservice.ErrTemplateNotFound) || job.Attempts+1 >= w.cfg.MaxAttempts {
+		w.bury(ctx, job, sendErr, fields)
+		return
+	}
+
+	job.Attempts++
+	job.LastError = truncate(sendErr.Error())
+	if err := w.mailQueueRepository.Retry(job, time.Now().Add(backoff(job.Attempts))); err != nil {
+		w.logger.Error(ctx, "mail retry scheduling failed", err, fields)
+		return
+	}
+	mailQueueJobsTotal.WithLabelValues(job.Template, "retry").Inc()
+	fields["error"] = job.LastError
+	w.logger.Warn(ctx, "mail send failed, retrying", fields)
+}
+
+func (w *Worker) bury(ctx context.Context, job models.MailJob, cause error, fields map[string]interface{}) {
+	now := time.Now().UTC()
+	job.Attempts++
+	job.LastError = truncate(cause.Error())
+	job.FailedAt = &now
+	// Dead letters are kept for a long time and the secrets would outlive what they grant.
+	if job.Sensitive {
+		job.Data = nil
+	}
+	if err := w.mailQueueRepository.Bury(job); err != nil {
+		w.logger.Error(ctx, "mail dead-lettering failed", err, fields)
+		return
+	}
+	mailQueueJobsTotal.WithLabelValues(job.Template, "dead").Inc()
+	w.logger.Error(ctx, "mail moved to dead-letter stream", cause, fields)
+}
+
+// backoff doubles the delay for every attempt, starting at retryBaseDelay and capped at retryMaxDelay.
+func backoff(attempt int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= retryMaxDelay {
+			return retryMaxDelay
+		}
+	}
+	return delay
+}
+
+func truncate(message string) string {
+	if len(message) > maxErrorLength {
+		return message[:maxErrorLength]
+	}
+	return message
+}
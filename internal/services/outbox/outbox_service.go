@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/SilentPlaces/basicauth.git/internal/models/models"
+	repository "github.com/SilentPlaces/basicauth.git/internal/repositories/outbox"
+	"github.com/google/uuid"
+	"github.com/google/wire"
+)
+
+type (
+	// OutboxService records domain events that are not tied to a state change in MySQL.
+	// Events produced together with a state change are written through OutboxRepository.WithTx instead.
+	OutboxService interface {
+		Record(ctx context.Context, eventType string, aggregateID string, payload map[string]interface{}) error
+	}
+
+	outboxService struct {
+		outboxRepository repository.OutboxRepository
+	}
+
+	// EventBus is the outbound port the relay publishes to.
+	EventBus interface {
+		Publish(ctx context.Context, event models.OutboxEvent) error
+		Close() error
+	}
+
+	// Envelope is the wire format of an event on the bus.
+	Envelope struct {
+		ID            string          `json:"id"`
+		Type          string          `json:"type"`
+		AggregateType string          `json:"aggregate_type"`
+		AggregateID   string          `json:"aggregate_id"`
+		OccurredAt    time.Time       `json:"occurred_at"`
+		Data          json.RawMessage `json:"data"`
+	}
+)
+
+func NewOutboxService(outboxRepository repository.OutboxRepository) OutboxService {
+	return &outboxService{outboxRepository: outboxRepository}
+}
+
+func (s *outboxService) Record(_ context.Context, eventType string, aggregateID string, payload map[string]interface{}) error {
+	event, err := NewUserEvent(eventType, aggregateID, payload)
+	if err != nil {
+		return err
+	}
+	return s.outboxRepository.Add(event)
+}
+
+// NewUserEvent builds an outbox event for the user aggregate.
+func NewUserEvent(eventType string, userID string, payload map[string]interface{}) (models.OutboxEvent, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return models.OutboxEvent{}, fmt.Errorf("failed to encode %s payload: %w", eventType, err)
+	}
+	return models.OutboxEvent{
+		EventID:       uuid.NewString(),
+		AggregateType: models.AggregateUser,
+		AggregateID:   userID,
+		EventType:     eventType,
+		Payload:       string(raw),
+		OccurredAt:    time.Now().UTC().Truncate(time.Microsecond),
+	}, nil
+}
+
+// EnvelopeOf converts a stored event to its bus representation.
+func EnvelopeOf(event models.OutboxEvent) Envelope {
+	return Envelope{
+		ID:            event.EventID,
+		Type:          event.EventType,
+		AggregateType: event.AggregateType,
+		AggregateID:   event.AggregateID,
+		OccurredAt:    event.OccurredAt,
+		Data:          json.RawMessage(event.Payload),
+	}
+}
+
+var OutboxServiceProviderSet = wire.NewSet(NewOutboxService)
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/SilentPlaces/basicauth.git/internal/config"
+	"github.com/SilentPlaces/basicauth.git/internal/models/models"
+	repository "github.com/SilentPlaces/basicauth.git/internal/repositories/outbox"
+	appLogger "github.com/SilentPlaces/basicauth.git/internal/shared/logger"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const pruneInterval = time.Hour
+
+var outboxPublishedTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "outbox_events_published_total",
+		Help: "Outbox events handed to the event bus by outcome.",
+	},
+	[]string{"event_type", "outcome"},
+)
+
+func init() {
+	prometheus.MustRegister(outboxPublishedTotal)
+}
+
+// Relay moves committed outbox events to the event bus. Delivery is at-least-once:
+// consumers must deduplicate on the event ID.
+type Relay struct {
+	outboxRepository repository.OutboxRepository
+	bus              EventBus
+	cfg              *config.EventBusConfig
+	logger           appLogger.Logger
+}
+
+func NewRelay(outboxRepository repository.OutboxRepository, bus EventBus, cfg *config.EventBusConfig, logger appLogger.Logger) *Relay {
+	return &Relay{
+		outboxRepository: outboxRepository,
+		bus:              bus,
+		cfg:              cfg,
+		logger:           logger,
+	}
+}
+
+// Run relays events until ctx is cancelled, then closes the bus.
+func (r *Relay) Run(ctx context.Context) {
+	defer r.bus.Close()
+
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+	lastPrune := time.Time{}
+
+	for {
+		r.drain(ctx)
+
+		if time.Since(lastPrune) >= pruneInterval {
+			lastPrune = time.Now()
+			if removed, err := r.outboxRepository.DeleteSettledBefore(time.Now().Add(-r.cfg.Retention)); err != nil {
+				r.logger.Error(ctx, "outbox prune failed", err, nil)
+			} else if removed > 0 {
+				r.logger.Debug(ctx, "outbox pruned", map[string]interface{}{"removed": removed})
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// drain publishes full batches back to back so a backlog is cleared without waiting for the ticker.
+func (r *Relay) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		published, err := r.outboxRepository.ProcessBatch(r.cfg.BatchSize, r.cfg.MaxAttempts, func(event models.OutboxEvent) error {
+			if err := r.bus.Publish(ctx, event); err != nil {
+				fields := map[string]interface{}{
+					"event_id":   event.EventID,
+					"event_type": event.EventType,
+					"attempt":    event.Attempts + 1,
+					"error":      err.Error(),
+				}
+				if event.Attempts+1 >= r.cfg.MaxAttempts {
+					outboxPublishedTotal.WithLabelValues(event.EventType, "dead").Inc()
+					r.logger.Error(ctx, "outbox event is dead, giving up", err, fields)
+					return err
+				}
+				outboxPublishedTotal.WithLabelValues(event.EventType, "failed").Inc()
+				r.logger.Warn(ctx, "outbox event publish failed", fields)
+				return err
+			}
+			outboxPublishedTotal.WithLabelValues(event.EventType, "published").Inc()
+			return nil
+		})
+		if err != nil {
+			r.logger.Error(ctx, "outbox relay batch failed", err, nil)
+			return
+		}
+		if published < r.cfg.BatchSize {
+			return
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/SilentPlaces/basicauth.git/internal/config"
+	"github.com/SilentPlaces/basicauth.git/internal/db/mysql"
+	custom_error "github.com/SilentPlaces/basicauth.git/internal/errors"
+	"github.com/SilentPlaces/basicauth.git/internal/models/models"
+	outboxRepo "github.com/SilentPlaces/basicauth.git/internal/repositories/outbox"
+	historyRepo "github.com/SilentPlaces/basicauth.git/internal/repositories/passwordhistory"
+	userRepo "github.com/SilentPlaces/basicauth.git/internal/repositories/user"
+	breachedPasswordService "github.com/SilentPlaces/basicauth.git/internal/services/breachedpassword"
+	outboxService "github.com/SilentPlaces/basicauth.git/internal/services/outbox"
+	sessionService "github.com/SilentPlaces/basicauth.git/internal/services/session"
+	validation "github.com/SilentPlaces/basicauth.git/internal/validation/user"
+	helpers "github.com/SilentPlaces/basicauth.git/pkg/helper/hash"
+	"github.com/google/wire"
+)
+
+var (
+	ErrUserNotFound = errors.New("user not found")
+	// ErrWrongPassword is returned when the current password given to confirm a change is wrong.
+	ErrWrongPassword = errors.New("current password is wrong")
+)
+
+// Reasons a password must be changed before the user gets a session.
+const (
+	ChangeReasonExpired       = "password_expired"
+	ChangeReasonResetRequired = "password_reset_required"
+)
+
+type (
+	PasswordService interface {
+		// CheckPolicy returns a *custom_error.PasswordPolicyError when password may not be set:
+		// it breaks the password policy, is too easy to guess given userInputs, or is known from
+		// a data breach.
+		CheckPolicy(ctx context.Context, password string, userInputs ...string) error
+		// ChangePassword sets a new password after confirming the current one and ends the user's
+		// sessions. The new password must pass CheckPolicy and differ from the user's recent
+		// passwords.
+		ChangePassword(ctx context.Context, userID string, currentPassword string, newPassword string) error
+		// VerifyPassword returns ErrWrongPassword unless password is the user's current password.
+		VerifyPassword(ctx context.Context, userID string, password string) error
+		// Status reports whether the user's password expired or must be changed for another reason.
+		Status(ctx context.Context, userID string) (*PasswordStatus, error)
+		// RequireChange forces the user to set a new password on the next login and ends the
+		// user's sessions, so neither their refresh nor their access tokens keep working.
+		RequireChange(ctx context.Context, userID string) error
+	}
+
+	// PasswordStatus tells whether a user may keep using the current password.
+	PasswordStatus struct {
+		// ChangeReason is ChangeReasonExpired or ChangeReasonResetRequired when the password must
+		// be changed, empty otherwise.
+		ChangeReason string
+		// ExpiresAt is when the password expires, zero when passwords do not expire.
+		ExpiresAt time.Time
+		// ExpiresSoon is set when ExpiresAt falls within the warning window.
+		ExpiresSoon bool
+	}
+
+	passwordService struct {
+		userRepository            userRepo.UserRepository
+		passwordHistoryRepository historyRepo.PasswordHistoryRepository
+		breachedPasswords         breachedPasswordService.BreachedPasswordService
+		outboxRepository          outboxRepo.OutboxRepository
+		transactor                mysql.Transactor
+		sessions                  sessionService.SessionService
+		policy                    *config.Value[config.RegistrationPasswordConfig]
+	}
+)
+
+// NewPasswordService reads the password policy from policy on every call.
+func NewPasswordService(
+	userRepository userRepo.UserRepository,
+	passwordHistoryRepository historyRepo.PasswordHistoryRepository,
+	breachedPasswords breachedPasswordService.BreachedPasswordService,
+	outboxRepository outboxRepo.OutboxRepository,
+	transactor mysql.Transactor,
+	sessions sessionService.SessionService,
+	policy *config.Value[config.RegistrationPasswordConfig],
+) PasswordService {
+	return &passwordService{
+		userRepository:            userRepository,
+		passwordHistoryRepository: passwordHistoryRepository,
+		breachedPasswords:         breachedPasswords,
+		outboxRepository:          outboxRepository,
+		transactor:                transactor,
+		sessions:                  sessions,
+		policy:                    policy,
+	}
+}
+
+func (s *passwordService) CheckPolicy(ctx context.Context, password string, userInputs ...string) error {
+	if err := validation.ValidatePassword(password, s.policy.Load(), userInputs...); err != nil {
+		return err
+	}
+	if s.breachedPasswords.IsBreached(ctx, password) {
+		return custom_error.NewPasswordPolicyError(custom_error.PasswordPolicyBreached, "this password has appeared in a data breach, choose a different one")
+	}
+	return nil
+}
+
+// ChangePassword keeps the replaced password in the history, trimmed to the configured size. The
+// current password counts as the first entry, so a history size of 1 only forbids keeping it. A
+// PasswordChanged event is written to the outbox in the same transaction. Once the change is
+// committed, all of the user's sessions end, including any an attacker holds with the old password.
+func (s *passwordService) ChangePassword(ctx context.Context, userID string, currentPassword string, newPassword string) error {
+	user, err := s.userRepository.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+	if !hashMatches(user.Password, currentPassword) {
+		return ErrWrongPassword
+	}
+	if err := s.CheckPolicy(ctx, newPassword, user.Name, user.Email); err != nil {
+		return err
+	}
+
+	historySize := s.policy.Load().HistorySize
+	if historySize > 0 {
+		reused := hashMatches(user.Password, newPassword)
+		if !reused && historySize > 1 {
+			previous, err := s.passwordHistoryRepository.RecentPasswordHashes(userID, historySize-1)
+			if err != nil {
+				return err
+			}
+			for _, hash := range previous {
+				reused = reused || hashMatches(hash, newPassword)
+			}
+		}
+		if reused {
+			return custom_error.NewPasswordPolicyError(custom_error.PasswordPolicyReused, "password was used recently, choose a different one")
+		}
+	}
+
+	err = s.transactor.WithinTransaction(func(tx *sql.Tx) error {
+		if err := s.userRepository.WithTx(tx).UpdatePassword(userID, newPassword); err != nil {
+			return err
+		}
+		history := s.passwordHistoryRepository.WithTx(tx)
+		if historySize > 1 {
+			if err := history.AddPasswordHash(userID, user.Password); err != nil {
+				return err
+			}
+		}
+		if err := history.PrunePasswordHistory(userID, max(historySize-1, 0)); err != nil {
+			return err
+		}
+
+		event, err := outboxService.NewUserEvent(models.EventPasswordChanged, userID, map[string]interface{}{
+			"user_id": userID,
+		})
+		if err != nil {
+			return err
+		}
+		return s.outboxRepository.WithTx(tx).Add(event)
+	})
+	if err != nil {
+		return err
+	}
+	if err := s.sessions.RevokeAll(ctx, userID); err != nil {
+		return fmt.Errorf("password changed, but ending the sessions failed: %w", err)
+	}
+	return nil
+}
+
+func (s *passwordService) VerifyPassword(ctx context.Context, userID string, password string) error {
+	user, err := s.userRepository.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+	if !hashMatches(user.Password, password) {
+		return ErrWrongPassword
+	}
+	return nil
+}
+
+func (s *passwordService) Status(ctx context.Context, userID string) (*PasswordStatus, error) {
+	user, err := s.userRepository.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	policy := s.policy.Load()
+	status := &PasswordStatus{}
+	if policy.MaxAge > 0 {
+		status.ExpiresAt = user.PasswordChangedAt.Add(policy.MaxAge)
+		now := time.Now()
+		if !now.Before(status.ExpiresAt) {
+			status.ChangeReason = ChangeReasonExpired
+		}
+		status.ExpiresSoon = now.Add(policy.ExpiryWarning).After(status.ExpiresAt)
+	}
+	if user.PasswordChangeRequired {
+		status.ChangeReason = ChangeReasonResetRequired
+	}
+	return status, nil
+}
+
+func (s *passwordService) RequireChange(ctx context.Context, userID string) error {
+	user, err := s.userRepository.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+	if err := s.userRepository.SetPasswordChangeRequired(userID, true); err != nil {
+		return err
+	}
+	return s.sessions.RevokeAll(ctx, userID)
+}
+
+// ChangeRequired reports whether the user must set a new password before getting a session.
+func (s *PasswordStatus) ChangeRequired() bool {
+	return s.ChangeReason != ""
+}
+
+func hashMatches(hash string, password string) bool {
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(helpers.TextToSHA1(password))) == 1
+}
+
+var PasswordServiceProviderSet = wire.NewSet(NewPasswordService)
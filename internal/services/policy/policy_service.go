@@ -0,0 +1,244 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/SilentPlaces/basicauth.git/internal/models/models"
+	repository "github.com/SilentPlaces/basicauth.git/internal/repositories/policy"
+	"github.com/SilentPlaces/basicauth.git/internal/shared/observability"
+	"github.com/google/uuid"
+	"github.com/google/wire"
+)
+
+var (
+	ErrInvalidDocument = errors.New("invalid policy document")
+	ErrDocumentExists  = errors.New("policy document version already exists")
+	// ErrPoliciesNotAccepted is returned when a version that must be accepted is missing, or an
+	// accepted version is not the one in force.
+	ErrPoliciesNotAccepted = errors.New("the policies in force must be accepted")
+)
+
+const maxDocumentURLLength = 2048
+
+var versionPattern = regexp.MustCompile(`^[A-Za-z0-9._\-]{1,64}$`)
+
+type (
+	PolicyService interface {
+		// Publish stores a new document version. It takes effect at PublishAt, or right away when
+		// PublishAt is zero.
+		Publish(request PublishRequest) (*models.PolicyDocument, error)
+		// List returns all document versions, the latest published first.
+		List(limit int, offset int) ([]models.PolicyDocument, error)
+		// Current returns the document in force for each kind that has one.
+		Current() ([]models.PolicyDocument, error)
+		// Accept returns the consents of a signup accepting the versions in accepted, keyed by
+		// kind. Every kind with a document in force must be accepted in its current version.
+		Accept(ctx context.Context, accepted map[string]string) ([]models.UserConsent, error)
+		// Consent stores the consents of userID to the versions in accepted, keyed by kind. The
+		// pending kinds must be included, in their current version.
+		Consent(ctx context.Context, userID string, accepted map[string]string) ([]models.UserConsent, error)
+		// Pending returns the documents in force userID must accept before getting tokens: those of
+		// the kinds with a mandatory version published after the last version the user accepted.
+		Pending(userID string) ([]models.PolicyDocument, error)
+		// Consents returns the consents of userID, latest first.
+		Consents(userID string) ([]models.UserConsent, error)
+	}
+
+	PublishRequest struct {
+		Kind      string
+		Version   string
+		URL       string
+		Mandatory bool
+		PublishAt time.Time
+		CreatedBy string
+	}
+
+	policyService struct {
+		policyRepository repository.PolicyRepository
+	}
+)
+
+func NewPolicyService(policyRepository repository.PolicyRepository) PolicyService {
+	return &policyService{policyRepository: policyRepository}
+}
+
+func (s *policyService) Publish(request PublishRequest) (*models.PolicyDocument, error) {
+	now := time.Now()
+	if request.PublishAt.IsZero() {
+		request.PublishAt = now
+	}
+	if !slices.Contains(models.PolicyKinds, request.Kind) {
+		return nil, fmt.Errorf("%w: kind must be one of %v", ErrInvalidDocument, models.PolicyKinds)
+	}
+	if !versionPattern.MatchString(request.Version) {
+		return nil, fmt.Errorf("%w: invalid version %q", ErrInvalidDocument, request.Version)
+	}
+	if u, err := url.Parse(request.URL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || len(request.URL) > maxDocumentURLLength {
+		return nil, fmt.Errorf("%w: url must be an absolute http(s) URL", ErrInvalidDocument)
+	}
+	// A version published in the past would change which versions users have already agreed to.
+	if request.PublishAt.Before(now.Add(-time.Minute)) {
+		return nil, fmt.Errorf("%w: publish time must not be in the past", ErrInvalidDocument)
+	}
+
+	_, err := s.policyRepository.GetDocument(request.Kind, request.Version)
+	if err == nil {
+		return nil, ErrDocumentExists
+	}
+	if !errors.Is(err, repository.ErrDocumentNotFound) {
+		return nil, err
+	}
+
+	document := models.PolicyDocument{
+		ID:          uuid.NewString(),
+		Kind:        request.Kind,
+		Version:     request.Version,
+		URL:         request.URL,
+		Mandatory:   request.Mandatory,
+		PublishedAt: request.PublishAt.UTC().Truncate(time.Second),
+		CreatedBy:   request.CreatedBy,
+		CreatedAt:   now,
+	}
+	if err := s.policyRepository.InsertDocument(document); err != nil {
+		return nil, err
+	}
+	return &document, nil
+}
+
+func (s *policyService) List(limit int, offset int) ([]models.PolicyDocument, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return s.policyRepository.ListDocuments(limit, offset)
+}
+
+func (s *policyService) Current() ([]models.PolicyDocument, error) {
+	published, err := s.published()
+	if err != nil {
+		return nil, err
+	}
+	var current []models.PolicyDocument
+	for _, kind := range models.PolicyKinds {
+		if versions := published[kind]; len(versions) > 0 {
+			current = append(current, versions[0])
+		}
+	}
+	return current, nil
+}
+
+func (s *policyService) Accept(ctx context.Context, accepted map[string]string) ([]models.UserConsent, error) {
+	current, err := s.Current()
+	if err != nil {
+		return nil, err
+	}
+	return consentsFor(ctx, current, current, accepted)
+}
+
+func (s *policyService) Consent(ctx context.Context, userID string, accepted map[string]string) ([]models.UserConsent, error) {
+	current, err := s.Current()
+	if err != nil {
+		return nil, err
+	}
+	pending, err := s.Pending(userID)
+	if err != nil {
+		return nil, err
+	}
+	consents, err := consentsFor(ctx, current, pending, accepted)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.policyRepository.AddConsents(userID, consents); err != nil {
+		return nil, err
+	}
+	return consents, nil
+}
+
+func (s *policyService) Pending(userID string) ([]models.PolicyDocument, error) {
+	published, err := s.published()
+	if err != nil {
+		return nil, err
+	}
+	consents, err := s.policyRepository.ListConsents(userID)
+	if err != nil {
+		return nil, err
+	}
+	acceptedIDs := make(map[string]bool, len(consents))
+	for _, consent := range consents {
+		acceptedIDs[consent.DocumentID] = true
+	}
+
+	var pending []models.PolicyDocument
+	for _, kind := range models.PolicyKinds {
+		// versions are ordered latest first, so the user is up to date when they accepted a version
+		// no older than the latest mandatory one.
+		for _, version := range published[kind] {
+			if acceptedIDs[version.ID] {
+				break
+			}
+			if version.Mandatory {
+				pending = append(pending, published[kind][0])
+				break
+			}
+		}
+	}
+	return pending, nil
+}
+
+func (s *policyService) Consents(userID string) ([]models.UserConsent, error) {
+	return s.policyRepository.ListConsents(userID)
+}
+
+// published returns the documents published so far per kind, latest first.
+func (s *policyService) published() (map[string][]models.PolicyDocument, error) {
+	documents, err := s.policyRepository.ListPublishedDocuments(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	byKind := make(map[string][]models.PolicyDocument, len(models.PolicyKinds))
+	for _, document := range documents {
+		byKind[document.Kind] = append(byKind[document.Kind], document)
+	}
+	return byKind, nil
+}
+
+// consentsFor checks accepted against the documents in force and returns the consents to them.
+// Every kind of required must be accepted.
+func consentsFor(ctx context.Context, current, required []models.PolicyDocument, accepted map[string]string) ([]models.UserConsent, error) {
+	for _, document := range required {
+		if _, ok := accepted[document.Kind]; !ok {
+			return nil, fmt.Errorf("%w: accept %s version %s", ErrPoliciesNotAccepted, document.Kind, document.Version)
+		}
+	}
+
+	now := time.Now()
+	ip := observability.ClientIPFromContext(ctx)
+	consents := make([]models.UserConsent, 0, len(accepted))
+	for kind, version := range accepted {
+		i := slices.IndexFunc(current, func(document models.PolicyDocument) bool { return document.Kind == kind })
+		if i < 0 {
+			return nil, fmt.Errorf("%w: no %q document is in force", ErrPoliciesNotAccepted, kind)
+		}
+		if current[i].Version != version {
+			return nil, fmt.Errorf("%w: accept %s version %s", ErrPoliciesNotAccepted, kind, current[i].Version)
+		}
+		consents = append(consents, models.UserConsent{
+			DocumentID: current[i].ID,
+			Kind:       kind,
+			Version:    version,
+			AcceptedAt: now,
+			IP:         ip,
+		})
+	}
+	return consents, nil
+}
+
+var PolicyServiceProviderSet = wire.NewSet(NewPolicyService)
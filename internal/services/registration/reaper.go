@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/SilentPlaces/basicauth.git/internal/config"
+	lockRepo "github.com/SilentPlaces/basicauth.git/internal/repositories/lock"
+	repository "github.com/SilentPlaces/basicauth.git/internal/repositories/registration"
+	userRepo "github.com/SilentPlaces/basicauth.git/internal/repositories/user"
+	appLogger "github.com/SilentPlaces/basicauth.git/internal/shared/logger"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const reaperLockName = "unverified-reaper"
+
+var unverifiedUsersReapedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "unverified_users_reaped_total",
+	Help: "Unverified accounts deleted after exceeding the maximum age.",
+})
+
+func init() {
+	prometheus.MustRegister(unverifiedUsersReapedTotal)
+}
+
+// UnverifiedReaper deletes accounts that stayed unverified for longer than the configured age, so
+// an abandoned signup does not block the email for good. Accounts that still have a verification
+// token or code pending are kept. A Redis lock makes sure only one replica reaps at a time.
+type UnverifiedReaper struct {
+	userRepository         userRepo.UserRepository
+	registrationRepository repository.RegistrationRepository
+	lockRepository         lockRepo.LockRepository
+	cfg                    *config.ReaperConfig
+	logger                 appLogger.Logger
+}
+
+func NewUnverifiedReaper(
+	userRepository userRepo.UserRepository,
+	registrationRepository repository.RegistrationRepository,
+	lockRepository lockRepo.LockRepository,
+	cfg *config.ReaperConfig,
+	logger appLogger.Logger,
+) *UnverifiedReaper {
+	return &UnverifiedReaper{
+		userRepository:         userRepository,
+		registrationRepository: registrationRepository,
+		lockRepository:         lockRepository,
+		cfg:                    cfg,
+		logger:                 logger,
+	}
+}
+
+// Run reaps once at startup and then every interval until ctx is cancelled.
+func (r *UnverifiedReaper) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		r.reap(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *UnverifiedReaper) reap(ctx context.Context) {
+	// The lock expires after one interval, so a crashed replica holds it at most that long. Deletes
+	// are conditional, so a run overlapping an overlong one does no harm.
+	token, acquired, err := r.lockRepository.Acquire(reaperLockName, r.cfg.Interval)
+	if err != nil {
+		r.logger.Error(ctx, "unverified reaper lock failed", err, nil)
+		return
+	}
+	if !acquired {
+		r.logger.Debug(ctx, "unverified reaper running on another replica", nil)
+		return
+	}
+	defer func() {
+		if err := r.lockRepository.Release(reaperLockName, token); err != nil {
+			r.logger.Warn(ctx, "unverified reaper unlock failed", map[string]interface{}{"error": err.Error()})
+		}
+	}()
+
+	cutoff := time.Now().Add(-r.cfg.MaxAge)
+	reaped, kept := 0, 0
+	afterID := ""
+	for ctx.Err() == nil {
+		users, err := r.userRepository.ListUnverifiedBefore(cutoff, afterID, r.cfg.BatchSize)
+		if err != nil {
+			r.logger.Error(ctx, "unverified reaper listing failed", err, nil)
+			break
+		}
+		for _, user := range users {
+			afterID = user.ID
+			// A resend may have issued a new token after the account reached the maximum age.
+			// Accounts without a canonical email cannot have one, as resends are keyed by it.
+			pending := false
+			if user.CanonicalEmail != "" {
+				pending, err = r.registrationRepository.HasPendingVerification(user.CanonicalEmail)
+			}
+			if err != nil {
+				r.logger.Error(ctx, "unverified reaper verification check failed", err, map[string]interface{}{"user_id": user.ID})
+				continue
+			}
+			if pending {
+				kept++
+				continue
+			}
+			deleted, err := r.userRepository.DeleteUnverifiedUser(user.ID, cutoff)
+			if err != nil {
+				r.logger.Error(ctx, "unverified reaper delete failed", err, map[string]interface{}{"user_id": user.ID})
+				continue
+			}
+			if deleted {
+				reaped++
+				unverifiedUsersReapedTotal.Inc()
+			}
+		}
+		if len(users) < r.cfg.BatchSize {
+			break
+		}
+	}
+
+	if reaped > 0 || kept > 0 {
+		r.logger.Info(ctx, "unverified accounts reaped", map[string]interface{}{"reaped": reaped, "kept_pending": kept})
+	}
+}
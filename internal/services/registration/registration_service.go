@@ -1,13 +1,22 @@
 package service
 
 import (
+	"crypto/sha256"
+	"crypto/subtle"
 	"database/sql"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"github.com/SilentPlaces/basicauth.git/internal/config"
+	"github.com/SilentPlaces/basicauth.git/internal/db/mysql"
 	custom_error "github.com/SilentPlaces/basicauth.git/internal/errors"
 	"github.com/SilentPlaces/basicauth.git/internal/models/models"
+	invitationRepo "github.com/SilentPlaces/basicauth.git/internal/repositories/invitation"
+	outboxRepo "github.com/SilentPlaces/basicauth.git/internal/repositories/outbox"
+	policyRepo "github.com/SilentPlaces/basicauth.git/internal/repositories/policy"
 	repository "github.com/SilentPlaces/basicauth.git/internal/repositories/registration"
 	userRepo "github.com/SilentPlaces/basicauth.git/internal/repositories/user"
+	outboxService "github.com/SilentPlaces/basicauth.git/internal/services/outbox"
 	helpers "github.com/SilentPlaces/basicauth.git/pkg/helper/strings"
 	"github.com/google/wire"
 	"github.com/redis/go-redis/v9"
@@ -15,105 +24,254 @@ import (
 	"time"
 )
 
+var (
+	// ErrEmailInUse is returned when signing up with the email of a verified account.
+	ErrEmailInUse    = errors.New("this email is already in use")
+	ErrTokenNotFound = errors.New("token does not exist")
+	ErrTokenMismatch = errors.New("token does not match")
+	// ErrTokenBurned is returned for the wrong guess that uses up the last verification attempt.
+	ErrTokenBurned = errors.New("too many wrong verification attempts, request a new token")
+	// ErrInvitationUsedUp is returned when the invitation was used up, revoked or expired after it
+	// was resolved.
+	ErrInvitationUsedUp = errors.New("invitation is no longer usable")
+)
+
+// verificationTokenBytes is the random length of a verification token.
+const verificationTokenBytes = 32
+
 type (
 	RegistrationService interface {
-		Signup(email string, name string, password string) (string, error)
+		// Signup stores email as the address and keys the account, its Redis state and its limits by
+		// canonicalEmail. The other methods take the canonical email. A non-nil invitation is redeemed
+		// and its roles are assigned along with the new user, and consents are stored for it.
+		Signup(email string, canonicalEmail string, name string, password string, locale string, invitation *models.Invitation, consents []models.UserConsent) (*Verification, error)
+		GetPreferredLocale(email string) string
 		VerifyToken(email, token string) error
+		VerifyCode(email, code string) error
 		SetUserVerified(email string) error
-		ReloadToken(email string) (string, error)
+		ReloadToken(email string) (*Verification, error)
+	}
+
+	// Verification holds what a verification mail carries. Token or Code is empty when the
+	// configured verification mode does not use it.
+	Verification struct {
+		Token string
+		Code  string
 	}
 
 	registrationService struct {
 		registrationRepository repository.RegistrationRepository
 		userRepository         userRepo.UserRepository
+		roleRepository         userRepo.RoleRepository
+		invitationRepository   invitationRepo.InvitationRepository
+		outboxRepository       outboxRepo.OutboxRepository
+		policyRepository       policyRepo.PolicyRepository
+		transactor             mysql.Transactor
+		registrationConfig     *config.Value[config.RegistrationConfig]
 	}
 )
 
-func NewUserRegistrationService(verificationRepo repository.RegistrationRepository, userRepository userRepo.UserRepository) RegistrationService {
+// NewUserRegistrationService reads the verification mode from registrationConfig on every call.
+func NewUserRegistrationService(
+	verificationRepo repository.RegistrationRepository,
+	userRepository userRepo.UserRepository,
+	roleRepository userRepo.RoleRepository,
+	invitationRepository invitationRepo.InvitationRepository,
+	outboxRepository outboxRepo.OutboxRepository,
+	policyRepository policyRepo.PolicyRepository,
+	transactor mysql.Transactor,
+	registrationConfig *config.Value[config.RegistrationConfig],
+) RegistrationService {
 	return &registrationService{
 		registrationRepository: verificationRepo,
 		userRepository:         userRepository,
+		roleRepository:         roleRepository,
+		invitationRepository:   invitationRepository,
+		outboxRepository:       outboxRepository,
+		policyRepository:       policyRepository,
+		transactor:             transactor,
+		registrationConfig:     registrationConfig,
 	}
 }
 
-// Signup handles user registration, checks if the email exists, and generates a resend_verification token
-func (s *registrationService) Signup(email string, name string, password string) (string, error) {
+// Signup handles user registration, checks if the email exists, and generates a resend_verification token.
+// Signing up again with the email of an unverified account restarts the flow: the old row is
+// replaced, so the new name and password apply and the account's age starts over.
+func (s *registrationService) Signup(email string, canonicalEmail string, name string, password string, locale string, invitation *models.Invitation, consents []models.UserConsent) (*Verification, error) {
 	// Check if user already exists by email
-	existingUser, err := s.userRepository.GetUserByMail(email)
+	existingUser, err := s.userRepository.GetUserByMail(canonicalEmail)
 	if err != nil {
 		logError("Error getting user by mail: %v", err)
-		if existingUser != nil {
-			return "", errors.New("this email is already in use, please auth")
-		}
-		return "", err
+		return nil, err
+	}
+	if existingUser != nil && existingUser.IsVerified {
+		return nil, ErrEmailInUse
 	}
 
 	// Check if the user has already generated too many tokens in pas 24 hours
-	canGenerate, err := s.registrationRepository.CanGenerateToken(email)
+	canGenerate, err := s.registrationRepository.CanGenerateToken(canonicalEmail)
 	if err != nil {
-		return "", fmt.Errorf("error checking token generation limit: %w", err)
+		return nil, fmt.Errorf("error checking token generation limit: %w", err)
 	}
 	if !canGenerate {
-		return "", custom_error.NewTokenGenerationError("you cannot generate more than 5 tokens in the past 24 hours")
+		return nil, custom_error.NewTokenGenerationError("you cannot generate more than 5 tokens in the past 24 hours")
 	}
 
-	// Insert new user
-	dbUser, err := s.userRepository.InsertUser(models.User{
-		Name:     name,
-		Email:    email,
-		Password: password,
-	})
+	verification, err := s.newVerification()
 	if err != nil {
-		logError("Error inserting user: %v", err)
-		return "", err
+		return nil, err
 	}
 
-	// Generate and save resend_verification token
-	token, err := generateToken()
-	if err != nil {
-		// Rollback user insert on failure
-		_ = s.userRepository.DeleteUserByID(dbUser.ID)
-		return "", err
-	}
+	// Insert the user and its UserRegistered event in one transaction. The Redis token is written
+	// last inside the transaction, so a Redis failure rolls the user row and the event back.
+	err = s.transactor.WithinTransaction(func(tx *sql.Tx) error {
+		if existingUser != nil {
+			// Only delete the row if it was not verified in the meantime.
+			deleted, err := s.userRepository.WithTx(tx).DeleteUnverifiedUser(existingUser.ID, time.Now())
+			if err != nil {
+				logError("Error replacing unverified user: %v", err)
+				return err
+			}
+			if !deleted {
+				return ErrEmailInUse
+			}
+		}
+		dbUser, err := s.userRepository.WithTx(tx).InsertUser(models.User{
+			Name:           name,
+			Email:          email,
+			CanonicalEmail: canonicalEmail,
+			Password:       password,
+			Locale:         locale,
+		})
+		if err != nil {
+			logError("Error inserting user: %v", err)
+			return err
+		}
+		if invitation != nil {
+			if err := s.redeemInvitation(tx, invitation, dbUser.ID); err != nil {
+				return err
+			}
+		}
+		if err := s.policyRepository.WithTx(tx).AddConsents(dbUser.ID, consents); err != nil {
+			logError("Error storing user consents: %v", err)
+			return err
+		}
 
-	err = s.registrationRepository.SetVerifyToken(email, token)
+		event, err := outboxService.NewUserEvent(models.EventUserRegistered, dbUser.ID, map[string]interface{}{
+			"user_id": dbUser.ID,
+			"email":   email,
+			"name":    name,
+		})
+		if err != nil {
+			return err
+		}
+		if err := s.outboxRepository.WithTx(tx).Add(event); err != nil {
+			logError("Error writing outbox event: %v", err)
+			return err
+		}
+
+		if err := s.storeVerification(canonicalEmail, verification); err != nil {
+			logError("Error setting resend_verification token: %v", err)
+			return err
+		}
+		return nil
+	})
 	if err != nil {
-		// Rollback user insert on failure
-		_ = s.userRepository.DeleteUserByID(dbUser.ID)
-		logError("Error setting resend_verification token: %v", err)
-		return "", err
+		return nil, err
 	}
 
 	// Track the token generation timestamp
-	err = s.registrationRepository.TrackTokenGeneration(email)
+	err = s.registrationRepository.TrackTokenGeneration(canonicalEmail)
 	if err != nil {
-		return "", fmt.Errorf("failed to track token generation: %w", err)
+		return nil, fmt.Errorf("failed to track token generation: %w", err)
 	}
 
-	return token, nil
+	return verification, nil
+}
+
+// redeemInvitation uses up one use of the invitation and assigns its roles to the user.
+func (s *registrationService) redeemInvitation(tx *sql.Tx, invitation *models.Invitation, userID string) error {
+	redeemed, err := s.invitationRepository.WithTx(tx).RedeemInvitation(invitation.ID)
+	if err != nil {
+		logError("Error redeeming invitation: %v", err)
+		return err
+	}
+	if !redeemed {
+		return ErrInvitationUsedUp
+	}
+	for _, role := range invitation.Roles {
+		if err := s.roleRepository.WithTx(tx).AssignRole(userID, role); err != nil {
+			logError("Error assigning invitation role: %v", err)
+			return err
+		}
+	}
+	return nil
 }
 
-// VerifyToken validates the provided token for the given email
+// VerifyToken checks the token against the stored hash and consumes it. Wrong guesses are counted,
+// and the token is deleted once the configured number of attempts is used up.
 func (s *registrationService) VerifyToken(email, token string) error {
-	result, err := s.registrationRepository.GetVerifyToken(email)
+	return s.verify(email, hashToken(token), verificationSecret{
+		get:           s.registrationRepository.GetVerifyToken,
+		recordFailure: s.registrationRepository.RecordFailedAttempt,
+		consume:       s.registrationRepository.ConsumeToken,
+		deleteOther:   s.registrationRepository.DeleteCode,
+	})
+}
+
+// VerifyCode works like VerifyToken for the numeric code, which has its own, lower attempt limit.
+func (s *registrationService) VerifyCode(email, code string) error {
+	return s.verify(email, hashToken(code), verificationSecret{
+		get:           s.registrationRepository.GetVerifyCode,
+		recordFailure: s.registrationRepository.RecordFailedCodeAttempt,
+		consume:       s.registrationRepository.ConsumeCode,
+		deleteOther:   s.registrationRepository.DeleteToken,
+	})
+}
+
+// verificationSecret binds verify to the repository methods of a token or a code.
+type verificationSecret struct {
+	get           func(email string) (string, error)
+	recordFailure func(email string) (bool, error)
+	consume       func(email, secretHash string) (bool, error)
+	// deleteOther removes the other secret of the same mail once one has been used.
+	deleteOther func(email string) error
+}
+
+func (s *registrationService) verify(email, secretHash string, secret verificationSecret) error {
+	storedHash, err := secret.get(email)
 	if errors.Is(err, redis.Nil) {
-		return errors.New("token does not exist")
+		return ErrTokenNotFound
 	}
 	if err != nil {
 		logError("Error getting resend_verification token: %v", err)
 		return err
 	}
 
-	if result != token {
-		return errors.New("token does not match")
+	if subtle.ConstantTimeCompare([]byte(storedHash), []byte(secretHash)) != 1 {
+		burned, err := secret.recordFailure(email)
+		if err != nil {
+			logError("Error recording verification attempt: %v", err)
+			return err
+		}
+		if burned {
+			return ErrTokenBurned
+		}
+		return ErrTokenMismatch
 	}
 
-	// Delete the token after resend_verification
-	err = s.registrationRepository.DeleteToken(email)
+	consumed, err := secret.consume(email, secretHash)
 	if err != nil {
-		logError("Error deleting token: %v", err)
+		logError("Error consuming token: %v", err)
+		return err
+	}
+	if !consumed {
+		return ErrTokenNotFound
 	}
 
+	if err := secret.deleteOther(email); err != nil {
+		logError("Error deleting verification secret: %v", err)
+	}
 	err = s.registrationRepository.DeleteVerificationCount(email)
 	if err != nil {
 		logError("Error deleting verification count: %v", err)
@@ -132,42 +290,61 @@ func (s *registrationService) SetUserVerified(email string) error {
 	user.VerifiedAt = sql.NullTime{Time: time.Now(), Valid: true}
 	user.IsVerified = true
 
-	_, err = s.userRepository.UpdateUser(user)
-	if err != nil {
-		logError("Error updating user resend_verification status: %v", err)
-	}
-	return err
+	return s.transactor.WithinTransaction(func(tx *sql.Tx) error {
+		if _, err := s.userRepository.WithTx(tx).UpdateUser(user); err != nil {
+			logError("Error updating user resend_verification status: %v", err)
+			return err
+		}
+
+		event, err := outboxService.NewUserEvent(models.EventEmailVerified, user.ID, map[string]interface{}{
+			"user_id": user.ID,
+			"email":   user.Email,
+		})
+		if err != nil {
+			return err
+		}
+		return s.outboxRepository.WithTx(tx).Add(event)
+	})
 }
 
 // ReloadToken generates a new resend_verification token and resets it
-func (s *registrationService) ReloadToken(mail string) (string, error) {
+func (s *registrationService) ReloadToken(mail string) (*Verification, error) {
 	// Check if the user has already generated too many tokens in pas 24 hours
 	canGenerate, err := s.registrationRepository.CanGenerateToken(mail)
 	if err != nil {
-		return "", fmt.Errorf("error checking token generation limit: %w", err)
+		return nil, fmt.Errorf("error checking token generation limit: %w", err)
 	}
 	if !canGenerate {
-		return "", custom_error.NewTokenGenerationError("you cannot generate more than 5 tokens in the past 24 hours")
+		return nil, custom_error.NewTokenGenerationError("you cannot generate more than 5 tokens in the past 24 hours")
 	}
 	// Generate a new token
-	token, err := helpers.GenerateRandomString(64)
+	verification, err := s.newVerification()
 	if err != nil {
-		return "", fmt.Errorf("failed to generate token: %w", err)
+		return nil, fmt.Errorf("failed to generate token: %w", err)
 	}
 
 	// Update the token in Redis
-	err = s.registrationRepository.SetVerifyToken(mail, token)
+	err = s.storeVerification(mail, verification)
 	if err != nil {
-		return "", fmt.Errorf("failed to reset token: %w", err)
+		return nil, fmt.Errorf("failed to reset token: %w", err)
 	}
 
 	// Track the token generation timestamp
 	err = s.registrationRepository.TrackTokenGeneration(mail)
 	if err != nil {
-		return "", fmt.Errorf("failed to track token generation: %w", err)
+		return nil, fmt.Errorf("failed to track token generation: %w", err)
 	}
 
-	return token, nil
+	return verification, nil
+}
+
+// GetPreferredLocale returns the stored mail locale of the user, or an empty string when unknown
+func (s *registrationService) GetPreferredLocale(email string) string {
+	user, err := s.userRepository.GetUserByMail(email)
+	if err != nil || user == nil {
+		return ""
+	}
+	return user.Locale
 }
 
 // Utility function to log errors
@@ -177,13 +354,52 @@ func logError(message string, err error) {
 	}
 }
 
-// Utility function to handle token generation
-func generateToken() (string, error) {
-	token, err := helpers.GenerateRandomString(64)
-	if err != nil {
-		logError("Error generating token: %v", err)
+// newVerification generates the token and code the configured verification mode asks for.
+func (s *registrationService) newVerification() (*Verification, error) {
+	cfg := s.registrationConfig.Load()
+	verification := &Verification{}
+	if cfg.UsesLink() {
+		token, err := helpers.GenerateRandomString(verificationTokenBytes)
+		if err != nil {
+			logError("Error generating token: %v", err)
+			return nil, err
+		}
+		verification.Token = token
+	}
+	if cfg.UsesCode() {
+		code, err := helpers.GenerateRandomDigits(cfg.CodeLength)
+		if err != nil {
+			logError("Error generating code: %v", err)
+			return nil, err
+		}
+		verification.Code = code
 	}
-	return token, err
+	return verification, nil
+}
+
+// storeVerification stores the hashes of a new verification, replacing earlier ones and their
+// attempt counters. A secret the verification does not carry is deleted, so a mode change leaves
+// nothing usable behind.
+func (s *registrationService) storeVerification(email string, verification *Verification) error {
+	if verification.Token != "" {
+		if err := s.registrationRepository.SetVerifyToken(email, hashToken(verification.Token)); err != nil {
+			return err
+		}
+	} else if err := s.registrationRepository.DeleteToken(email); err != nil {
+		return err
+	}
+	if verification.Code != "" {
+		return s.registrationRepository.SetVerifyCode(email, hashToken(verification.Code))
+	}
+	return s.registrationRepository.DeleteCode(email)
+}
+
+// hashToken returns the form a token or code is stored in. Tokens carry 256 bits of randomness, so
+// an unsalted hash is enough. A short code can be recovered from its hash by trying them all; it is
+// protected by its lifetime and attempt limit, the hash only keeps it out of plain sight.
+func hashToken(token string) string {
+	digest := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(digest[:])
 }
 
 var UserRegistrationServiceProvider = wire.NewSet(NewUserRegistrationService)
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/SilentPlaces/basicauth.git/internal/models/models"
+	repository "github.com/SilentPlaces/basicauth.git/internal/repositories/session"
+	"github.com/SilentPlaces/basicauth.git/internal/shared/observability"
+	"github.com/google/uuid"
+	"github.com/google/wire"
+)
+
+type (
+	// SessionService tracks logins, so their refresh tokens can be listed and revoked.
+	SessionService interface {
+		// Start records a new session for the user and returns its ID. The client IP is taken from
+		// the context.
+		Start(ctx context.Context, userID string) (string, error)
+		// Touch reports whether the session is still live and extends it.
+		Touch(ctx context.Context, userID string, sessionID string) (bool, error)
+		// Exists reports whether the session is still live without extending it.
+		Exists(ctx context.Context, userID string, sessionID string) (bool, error)
+		List(ctx context.Context, userID string) ([]models.Session, error)
+		// RevokeAll ends every session of the user.
+		RevokeAll(ctx context.Context, userID string) error
+	}
+
+	sessionService struct {
+		sessionRepository repository.SessionRepository
+	}
+)
+
+func NewSessionService(sessionRepository repository.SessionRepository) SessionService {
+	return &sessionService{sessionRepository: sessionRepository}
+}
+
+func (s *sessionService) Start(ctx context.Context, userID string) (string, error) {
+	now := time.Now().UTC()
+	session := models.Session{
+		ID:         uuid.NewString(),
+		IP:         observability.ClientIPFromContext(ctx),
+		CreatedAt:  now,
+		LastUsedAt: now,
+	}
+	if err := s.sessionRepository.Add(userID, session); err != nil {
+		return "", err
+	}
+	return session.ID, nil
+}
+
+func (s *sessionService) Touch(ctx context.Context, userID string, sessionID string) (bool, error) {
+	return s.sessionRepository.Touch(userID, sessionID)
+}
+
+func (s *sessionService) Exists(ctx context.Context, userID string, sessionID string) (bool, error) {
+	return s.sessionRepository.Exists(userID, sessionID)
+}
+
+func (s *sessionService) List(ctx context.Context, userID string) ([]models.Session, error) {
+	return s.sessionRepository.List(userID)
+}
+
+func (s *sessionService) RevokeAll(ctx context.Context, userID string) error {
+	return s.sessionRepository.DeleteAll(userID)
+}
+
+var SessionServiceProviderSet = wire.NewSet(NewSessionService)
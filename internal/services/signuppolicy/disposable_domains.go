@@ -0,0 +1,129 @@
+package service
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// disposableFileCheckInterval bounds how long a replaced disposable domains file takes to be picked up.
+const disposableFileCheckInterval = time.Minute
+
+//go:embed disposable_domains.txt
+var bundledDisposableDomains string
+
+// disposableList holds the bundled disposable domains, or those of the configured file once it
+// has been read.
+type disposableList struct {
+	mu      sync.Mutex
+	bundled map[string]bool
+	domains map[string]bool
+	// path and modTime identify the file domains were read from; path is empty for the bundled list.
+	path    string
+	modTime time.Time
+	// checkedPath and checkedAt throttle stat calls, including for a file that failed to load.
+	checkedPath string
+	checkedAt   time.Time
+}
+
+func newDisposableList() *disposableList {
+	bundled, err := parseDomainList(strings.NewReader(bundledDisposableDomains))
+	if err != nil {
+		panic(fmt.Sprintf("bundled disposable domain list is invalid: %v", err))
+	}
+	return &disposableList{bundled: bundled, domains: bundled}
+}
+
+// get returns the domains for path, or the bundled ones when path is empty. The file is re-read
+// when its modification time changes. When it cannot be read the previous list is returned along
+// with the error.
+func (l *disposableList) get(path string) (map[string]bool, error) {
+	if path == "" {
+		return l.bundled, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if path == l.checkedPath && time.Since(l.checkedAt) < disposableFileCheckInterval {
+		return l.domains, nil
+	}
+	l.checkedPath, l.checkedAt = path, time.Now()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return l.domains, fmt.Errorf("failed to stat disposable domains file: %w", err)
+	}
+	if path == l.path && info.ModTime().Equal(l.modTime) {
+		return l.domains, nil
+	}
+	domains, err := readDomainFile(path)
+	if err != nil {
+		return l.domains, err
+	}
+	l.domains, l.path, l.modTime = domains, path, info.ModTime()
+	return l.domains, nil
+}
+
+func readDomainFile(path string) (map[string]bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open disposable domains file: %w", err)
+	}
+	defer file.Close()
+	domains, err := parseDomainList(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read disposable domains file %s: %w", path, err)
+	}
+	return domains, nil
+}
+
+// parseDomainList reads one domain per line. Blank lines and lines starting with # are skipped.
+// An empty list is an error, as it would silently turn the check off.
+func parseDomainList(r io.Reader) (map[string]bool, error) {
+	domains := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		domains[strings.TrimSuffix(line, ".")] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(domains) == 0 {
+		return nil, errors.New("list contains no domains")
+	}
+	return domains, nil
+}
+
+// inDomainSet reports whether domain or one of its parent domains is in set.
+func inDomainSet(set map[string]bool, domain string) bool {
+	for {
+		if set[domain] {
+			return true
+		}
+		_, parent, found := strings.Cut(domain, ".")
+		if !found {
+			return false
+		}
+		domain = parent
+	}
+}
+
+// inDomainList reports whether domain is one of list or a subdomain of one.
+func inDomainList(list []string, domain string) bool {
+	for _, entry := range list {
+		if domain == entry || strings.HasSuffix(domain, "."+entry) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+)
+
+// MXResolver looks up where mail for a domain is delivered. *net.Resolver implements it.
+type MXResolver interface {
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// hasMailServer reports whether domain can receive mail. A domain without MX records falls back to
+// its address records (RFC 5321 implicit MX), and a null MX (RFC 7505) means it accepts no mail.
+// Lookup failures other than "not found" are returned, so a DNS outage is not taken as a verdict.
+func hasMailServer(ctx context.Context, resolver MXResolver, domain string) (bool, error) {
+	records, err := resolver.LookupMX(ctx, domain)
+	if err == nil && len(records) > 0 {
+		if len(records) == 1 && (records[0].Host == "." || records[0].Host == "") {
+			return false, nil
+		}
+		return true, nil
+	}
+	if err != nil && !isNotFound(err) {
+		return false, err
+	}
+
+	addresses, err := resolver.LookupHost(ctx, domain)
+	if err != nil {
+		if isNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return len(addresses) > 0, nil
+}
+
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
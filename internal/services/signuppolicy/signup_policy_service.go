@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/SilentPlaces/basicauth.git/internal/config"
+	custom_error "github.com/SilentPlaces/basicauth.git/internal/errors"
+	appLogger "github.com/SilentPlaces/basicauth.git/internal/shared/logger"
+	"github.com/google/wire"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var signupPolicyRejectionsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "signup_policy_rejections_total",
+		Help: "Signups rejected by the signup policy, by error code.",
+	},
+	[]string{"code"},
+)
+
+func init() {
+	prometheus.MustRegister(signupPolicyRejectionsTotal)
+}
+
+type (
+	// SignupPolicyService decides whether an email may be used to sign up.
+	SignupPolicyService interface {
+		// Check returns a *custom_error.SignupPolicyError when the policy rejects email. Lookups
+		// that fail are logged and do not reject, so an outage of DNS or of the domain list
+		// does not stop signups.
+		Check(ctx context.Context, email string) error
+	}
+
+	signupPolicyService struct {
+		cfg        *config.Value[config.SignupPolicyConfig]
+		resolver   MXResolver
+		disposable *disposableList
+		logger     appLogger.Logger
+	}
+)
+
+// NewSignupPolicyService reads the policy from cfg on every check. A configured disposable domains
+// file is read right away, so a wrong path fails at startup.
+func NewSignupPolicyService(
+	cfg *config.Value[config.SignupPolicyConfig],
+	resolver MXResolver,
+	logger appLogger.Logger,
+) (SignupPolicyService, error) {
+	s := &signupPolicyService{
+		cfg:        cfg,
+		resolver:   resolver,
+		disposable: newDisposableList(),
+		logger:     logger,
+	}
+	if _, err := s.disposable.get(cfg.Load().DisposableDomainsFile); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Check applies the rules in order: the deny list, the allow list, disposable domains and mail
+// servers. A domain on the allow list is trusted and skips the last two.
+func (s *signupPolicyService) Check(ctx context.Context, email string) error {
+	cfg := s.cfg.Load()
+	domain := strings.ToLower(email[strings.LastIndex(email, "@")+1:])
+
+	if inDomainList(cfg.DeniedDomains, domain) {
+		return reject(custom_error.SignupPolicyDomainDenied, "this email domain is not allowed to sign up")
+	}
+	if len(cfg.AllowedDomains) > 0 {
+		if !inDomainList(cfg.AllowedDomains, domain) {
+			return reject(custom_error.SignupPolicyDomainNotAllowed, "sign up is limited to approved email domains")
+		}
+		return nil
+	}
+
+	if cfg.BlockDisposable && s.isDisposable(ctx, cfg, domain) {
+		return reject(custom_error.SignupPolicyDisposableEmail, "disposable email addresses are not accepted")
+	}
+
+	if cfg.RequireMX {
+		lookupCtx, cancel := context.WithTimeout(ctx, cfg.MXTimeout)
+		defer cancel()
+		ok, err := hasMailServer(lookupCtx, s.resolver, domain)
+		if err != nil {
+			s.logger.Warn(ctx, "signup policy mx lookup failed, accepting email", map[string]interface{}{"domain": domain, "error": err.Error()})
+			return nil
+		}
+		if !ok {
+			return reject(custom_error.SignupPolicyNoMailServer, "this email domain cannot receive mail")
+		}
+	}
+	return nil
+}
+
+func (s *signupPolicyService) isDisposable(ctx context.Context, cfg *config.SignupPolicyConfig, domain string) bool {
+	if inDomainList(cfg.DisposableDomains, domain) {
+		return true
+	}
+	domains, err := s.disposable.get(cfg.DisposableDomainsFile)
+	if err != nil {
+		// The previous list stays in use.
+		s.logger.Warn(ctx, "disposable domains file reload failed", map[string]interface{}{"path": cfg.DisposableDomainsFile, "error": err.Error()})
+	}
+	return inDomainSet(domains, domain)
+}
+
+func reject(code, message string) error {
+	signupPolicyRejectionsTotal.WithLabelValues(code).Inc()
+	return custom_error.NewSignupPolicyError(code, message)
+}
+
+var SignupPolicyServiceProviderSet = wire.NewSet(NewSignupPolicyService)
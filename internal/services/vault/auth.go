@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/SilentPlaces/basicauth.git/pkg/constants"
+	vault "github.com/hashicorp/vault/api"
+)
+
+// Vault auth methods selected with VAULT_AUTH_METHOD.
+const (
+	AuthMethodToken      = "token"
+	AuthMethodAppRole    = "approle"
+	AuthMethodKubernetes = "kubernetes"
+)
+
+// authMethod logs the client in. It returns the auth secret to renew, or nil when the token does
+// not expire.
+type authMethod interface {
+	name() string
+	login(ctx context.Context, client *vault.Client) (*vault.Secret, error)
+}
+
+// newAuthMethodFromEnv builds the auth method named in VAULT_AUTH_METHOD, token by default.
+func newAuthMethodFromEnv() (authMethod, error) {
+	method := os.Getenv(constants.EnvKeyVaultAuthMethod)
+	if method == "" {
+		method = AuthMethodToken
+	}
+	mount := os.Getenv(constants.EnvKeyVaultAuthMount)
+	if mount == "" {
+		mount = method
+	}
+
+	switch method {
+	case AuthMethodToken:
+		token := os.Getenv(constants.EnvKeyVaultToken)
+		if token == "" {
+			return nil, errors.New("VAULT_TOKEN is not set")
+		}
+		return tokenAuth{token: token}, nil
+	case AuthMethodAppRole:
+		roleID := os.Getenv(constants.EnvKeyVaultAppRoleRoleID)
+		if roleID == "" {
+			return nil, errors.New("VAULT_APPROLE_ROLE_ID is not set")
+		}
+		secretID := os.Getenv(constants.EnvKeyVaultAppRoleSecretID)
+		secretIDFile := os.Getenv(constants.EnvKeyVaultAppRoleSecretIDFile)
+		if secretID == "" && secretIDFile == "" {
+			return nil, errors.New("VAULT_APPROLE_SECRET_ID or VAULT_APPROLE_SECRET_ID_FILE is not set")
+		}
+		return appRoleAuth{mount: mount, roleID: roleID, secretID: secretID, secretIDFile: secretIDFile}, nil
+	case AuthMethodKubernetes:
+		role := os.Getenv(constants.EnvKeyVaultKubernetesRole)
+		if role == "" {
+			return nil, errors.New("VAULT_KUBERNETES_ROLE is not set")
+		}
+		tokenPath := os.Getenv(constants.EnvKeyVaultKubernetesTokenPath)
+		if tokenPath == "" {
+			tokenPath = constants.DefaultVaultKubernetesTokenPath
+		}
+		return kubernetesAuth{mount: mount, role: role, tokenPath: tokenPath}, nil
+	default:
+		return nil, fmt.Errorf("unknown vault auth method %q", method)
+	}
+}
+
+// tokenAuth uses a static token. A renewable token is renewed; it cannot be replaced once it
+// expires, so prefer AppRole or Kubernetes auth in production.
+type tokenAuth struct {
+	token string
+}
+
+func (tokenAuth) name() string {
+	return AuthMethodToken
+}
+
+func (a tokenAuth) login(ctx context.Context, client *vault.Client) (*vault.Secret, error) {
+	client.SetToken(a.token)
+	self, err := client.Auth().Token().LookupSelfWithContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("vault token lookup failed: %w", err)
+	}
+	renewable, _ := self.TokenIsRenewable()
+	ttl, _ := self.TokenTTL()
+	if ttl == 0 {
+		// Tokens without a TTL, such as root tokens, never expire.
+		return nil, nil
+	}
+	if !renewable {
+		// The watcher then just waits for expiry, after which the lookup above fails.
+		return &vault.Secret{Auth: &vault.SecretAuth{ClientToken: a.token, LeaseDuration: int(ttl.Seconds())}}, nil
+	}
+	// Renewing returns the auth block the lifetime watcher works on.
+	return client.Auth().Token().RenewSelfWithContext(ctx, 0)
+}
+
+// appRoleAuth logs in with a role ID and secret ID. A secret ID file is re-read on every login, so
+// a rotated secret ID is picked up.
+type appRoleAuth struct {
+	mount        string
+	roleID       string
+	secretID     string
+	secretIDFile string
+}
+
+func (appRoleAuth) name() string {
+	return AuthMethodAppRole
+}
+
+func (a appRoleAuth) login(ctx context.Context, client *vault.Client) (*vault.Secret, error) {
+	secretID := a.secretID
+	if a.secretIDFile != "" {
+		raw, err := os.ReadFile(a.secretIDFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read approle secret id: %w", err)
+		}
+		secretID = strings.TrimSpace(string(raw))
+	}
+	return writeLogin(ctx, client, a.mount, map[string]interface{}{
+		"role_id":   a.roleID,
+		"secret_id": secretID,
+	})
+}
+
+// kubernetesAuth logs in with the pod's service account token.
+type kubernetesAuth struct {
+	mount     string
+	role      string
+	tokenPath string
+}
+
+func (kubernetesAuth) name() string {
+	return AuthMethodKubernetes
+}
+
+func (a kubernetesAuth) login(ctx context.Context, client *vault.Client) (*vault.Secret, error) {
+	// Projected service account tokens rotate, so read the file on every login.
+	jwt, err := os.ReadFile(a.tokenPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read kubernetes service account token: %w", err)
+	}
+	return writeLogin(ctx, client, a.mount, map[string]interface{}{
+		"role": a.role,
+		"jwt":  strings.TrimSpace(string(jwt)),
+	})
+}
+
+func writeLogin(ctx context.Context, client *vault.Client, mount string, data map[string]interface{}) (*vault.Secret, error) {
+	// Login requests must not carry a previous, possibly expired token. They go through a clone, so
+	// requests running concurrently on the shared client keep their token until the new one is set.
+	loginClient, err := client.Clone()
+	if err != nil {
+		return nil, fmt.Errorf("vault %s login failed: %w", mount, err)
+	}
+	loginClient.ClearToken()
+	secret, err := loginClient.Logical().WriteWithContext(ctx, "auth/"+mount+"/login", data)
+	if err != nil {
+		return nil, fmt.Errorf("vault %s login failed: %w", mount, err)
+	}
+	if secret == nil || secret.Auth == nil || secret.Auth.ClientToken == "" {
+		return nil, fmt.Errorf("vault %s login returned no token", mount)
+	}
+	client.SetToken(secret.Auth.ClientToken)
+	if secret.Auth.LeaseDuration == 0 {
+		return nil, nil
+	}
+	return secret, nil
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	appLogger "github.com/SilentPlaces/basicauth.git/internal/shared/logger"
+)
+
+const (
+	credentialsTimeout = 10 * time.Second
+	// minRenewInterval keeps a failing renewal from spinning.
+	minRenewInterval = time.Second
+)
+
+// DatabaseCredentials is a username and password leased from a database secrets engine.
+type DatabaseCredentials struct {
+	Username      string
+	Password      string
+	LeaseID       string
+	LeaseDuration time.Duration
+	Renewable     bool
+}
+
+// GetDatabaseCredentials generates credentials at <mount>/creds/<role>.
+func (s *vaultService) GetDatabaseCredentials(ctx context.Context, mount, role string) (*DatabaseCredentials, error) {
+	secret, err := s.client.Logical().ReadWithContext(ctx, mount+"/creds/"+role)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read database credentials from Vault: %w", err)
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, fmt.Errorf("no database credentials at %s/creds/%s", mount, role)
+	}
+	username, _ := secret.Data["username"].(string)
+	password, _ := secret.Data["password"].(string)
+	if username == "" || password == "" {
+		return nil, errors.New("database credentials are missing username or password")
+	}
+	return &DatabaseCredentials{
+		Username:      username,
+		Password:      password,
+		LeaseID:       secret.LeaseID,
+		LeaseDuration: time.Duration(secret.LeaseDuration) * time.Second,
+		Renewable:     secret.Renewable,
+	}, nil
+}
+
+func (s *vaultService) RenewLease(ctx context.Context, leaseID string) (time.Duration, error) {
+	secret, err := s.client.Sys().RenewWithContext(ctx, leaseID, 0)
+	if err != nil {
+		return 0, fmt.Errorf("unable to renew lease: %w", err)
+	}
+	return time.Duration(secret.LeaseDuration) * time.Second, nil
+}
+
+// DatabaseCredentialRotator keeps a set of dynamic database credentials valid. It renews the lease
+// while Vault allows it and fetches new credentials once the lease would end within rotateBefore.
+// The old lease is left to expire, so connections that still use it can finish their work.
+type DatabaseCredentialRotator struct {
+	vault        SecureVaultService
+	mount        string
+	role         string
+	rotateBefore time.Duration
+	logger       appLogger.Logger
+
+	current atomic.Pointer[DatabaseCredentials]
+	expires time.Time
+
+	mu        sync.Mutex
+	listeners []func()
+}
+
+// NewDatabaseCredentialRotator fetches the first credentials, so the connection pool can open.
+func NewDatabaseCredentialRotator(vault SecureVaultService, mount, role string, rotateBefore time.Duration, logger appLogger.Logger) (*DatabaseCredentialRotator, error) {
+	r := &DatabaseCredentialRotator{vault: vault, mount: mount, role: role, rotateBefore: rotateBefore, logger: logger}
+	if err := r.rotate(context.Background()); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Credentials returns the current username and password.
+func (r *DatabaseCredentialRotator) Credentials() (string, string) {
+	creds := r.current.Load()
+	return creds.Username, creds.Password
+}
+
+// OnRotate registers fn to run after new credentials are in place.
+func (r *DatabaseCredentialRotator) OnRotate(fn func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.listeners = append(r.listeners, fn)
+}
+
+// Run renews and rotates until ctx is cancelled.
+func (r *DatabaseCredentialRotator) Run(ctx context.Context) {
+	for {
+		remaining := time.Until(r.expires)
+		if remaining <= r.rotateBefore {
+			if err := r.rotate(ctx); err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				r.logger.Error(ctx, "database credential rotation failed", err, map[string]interface{}{"expires_in": time.Until(r.expires).String()})
+				if !sleep(ctx, minRenewInterval*5) {
+					return
+				}
+			}
+			continue
+		}
+
+		// Renew halfway to the rotation point.
+		if !sleep(ctx, max((remaining-r.rotateBefore)/2, minRenewInterval)) {
+			return
+		}
+		creds := r.current.Load()
+		if !creds.Renewable {
+			continue
+		}
+		renewCtx, cancel := context.WithTimeout(ctx, credentialsTimeout)
+		duration, err := r.vault.RenewLease(renewCtx, creds.LeaseID)
+		cancel()
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			r.logger.Warn(ctx, "database credential renewal failed", map[string]interface{}{"error": err.Error()})
+			continue
+		}
+		// Near the max TTL Vault grants less than asked; rotation then takes over.
+		r.expires = time.Now().Add(duration)
+		r.logger.Debug(ctx, "database credentials renewed", map[string]interface{}{"lease_seconds": duration.Seconds()})
+	}
+}
+
+func (r *DatabaseCredentialRotator) rotate(ctx context.Context) error {
+	fetchCtx, cancel := context.WithTimeout(ctx, credentialsTimeout)
+	defer cancel()
+	creds, err := r.vault.GetDatabaseCredentials(fetchCtx, r.mount, r.role)
+	if err != nil {
+		return err
+	}
+	if creds.LeaseDuration > 0 && creds.LeaseDuration <= r.rotateBefore {
+		return fmt.Errorf("database credential ttl %s is not longer than the rotation window %s", creds.LeaseDuration, r.rotateBefore)
+	}
+
+	first := r.current.Load() == nil
+	r.current.Store(creds)
+	r.expires = time.Now().Add(creds.LeaseDuration)
+	if creds.LeaseDuration == 0 {
+		// Without a lease the credentials do not expire.
+		r.expires = time.Now().Add(100 * 365 * 24 * time.Hour)
+	}
+	if first {
+		return nil
+	}
+
+	r.mu.Lock()
+	listeners := append([]func(){}, r.listeners...)
+	r.mu.Unlock()
+	for _, listener := range listeners {
+		listener()
+	}
+	r.logger.Info(ctx, "database credentials rotated", map[string]interface{}{"username": creds.Username, "lease_seconds": creds.LeaseDuration.Seconds()})
+	return nil
+}
+
+func sleep(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	vault "github.com/hashicorp/vault/api"
+)
+
+const (
+	loginTimeout        = 10 * time.Second
+	loginRetryBaseDelay = time.Second
+	loginRetryMaxDelay  = time.Minute
+)
+
+// login authenticates and stores the auth secret for renewal. Failures are kept for Health.
+func (s *vaultService) login(ctx context.Context) error {
+	secret, err := s.auth.login(ctx, s.client)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.healthErr = err
+	if err == nil {
+		s.authSecret = secret
+	}
+	return err
+}
+
+func (s *vaultService) Health() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.healthErr
+}
+
+func (s *vaultService) setHealth(err error) {
+	s.mu.Lock()
+	s.healthErr = err
+	s.mu.Unlock()
+}
+
+// Run renews the token until it reaches its max TTL or renewal fails, then logs in again. Login
+// failures are retried with backoff and reported through Health in the meantime.
+func (s *vaultService) Run(ctx context.Context) {
+	fields := map[string]interface{}{"method": s.auth.name()}
+	for ctx.Err() == nil {
+		s.mu.Lock()
+		secret := s.authSecret
+		s.mu.Unlock()
+		if secret == nil {
+			// The token does not expire; nothing to renew.
+			<-ctx.Done()
+			return
+		}
+
+		if err := s.watch(ctx, secret); err != nil {
+			s.logger.Warn(ctx, "vault token renewal failed, logging in again", map[string]interface{}{"method": s.auth.name(), "error": err.Error()})
+		} else if ctx.Err() == nil {
+			s.logger.Info(ctx, "vault token reached its max ttl, logging in again", fields)
+		}
+
+		delay := loginRetryBaseDelay
+		for ctx.Err() == nil {
+			loginCtx, cancel := context.WithTimeout(ctx, loginTimeout)
+			err := s.login(loginCtx)
+			cancel()
+			if err == nil {
+				s.logger.Info(ctx, "vault login succeeded", fields)
+				break
+			}
+			s.logger.Error(ctx, "vault login failed", err, map[string]interface{}{"method": s.auth.name(), "retry_in": delay.String()})
+			select {
+			case <-ctx.Done():
+			case <-time.After(delay):
+			}
+			delay = min(delay*2, loginRetryMaxDelay)
+		}
+	}
+}
+
+// watch renews secret until renewal stops. It returns nil when the token simply ran out of TTL.
+func (s *vaultService) watch(ctx context.Context, secret *vault.Secret) error {
+	watcher, err := s.client.NewLifetimeWatcher(&vault.LifetimeWatcherInput{Secret: secret})
+	if err != nil {
+		return err
+	}
+	go watcher.Start()
+	defer watcher.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-watcher.DoneCh():
+			if err != nil {
+				s.setHealth(err)
+			}
+			return err
+		case renewal := <-watcher.RenewCh():
+			s.setHealth(nil)
+			if renewal.Secret != nil && renewal.Secret.Auth != nil {
+				s.logger.Debug(ctx, "vault token renewed", map[string]interface{}{"lease_seconds": renewal.Secret.Auth.LeaseDuration})
+			}
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// TransitKey is the public part of a Transit signing key.
+type TransitKey struct {
+	// Type is the Transit key type, e.g. rsa-2048, ecdsa-p256 or ed25519.
+	Type          string
+	LatestVersion int
+	// PublicKeys maps key versions to PEM public keys. Ed25519 keys are base64 encoded raw keys.
+	PublicKeys map[int]string
+}
+
+// TransitSignRequest is a Transit sign call. Algorithm fields map to the Transit API parameters
+// and are omitted when empty.
+type TransitSignRequest struct {
+	Mount               string
+	Key                 string
+	Version             int
+	Input               []byte
+	HashAlgorithm       string
+	SignatureAlgorithm  string
+	MarshalingAlgorithm string
+}
+
+// GetTransitKey reads the public keys of a Transit key.
+func (s *vaultService) GetTransitKey(ctx context.Context, mount, name string) (*TransitKey, error) {
+	secret, err := s.client.Logical().ReadWithContext(ctx, mount+"/keys/"+name)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read transit key %s: %w", name, err)
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, fmt.Errorf("transit key %s not found", name)
+	}
+
+	key := &TransitKey{PublicKeys: make(map[int]string)}
+	key.Type, _ = secret.Data["type"].(string)
+	key.LatestVersion = intFromJSON(secret.Data["latest_version"])
+	versions, _ := secret.Data["keys"].(map[string]interface{})
+	for version, raw := range versions {
+		v, err := strconv.Atoi(version)
+		if err != nil {
+			continue
+		}
+		entry, _ := raw.(map[string]interface{})
+		if publicKey, _ := entry["public_key"].(string); publicKey != "" {
+			key.PublicKeys[v] = publicKey
+		}
+	}
+	if key.LatestVersion == 0 || len(key.PublicKeys) == 0 {
+		return nil, fmt.Errorf("transit key %s is not an asymmetric signing key", name)
+	}
+	return key, nil
+}
+
+// TransitSign signs req.Input and returns the raw signature. With the jws marshaling algorithm
+// Vault returns base64url, otherwise standard base64.
+func (s *vaultService) TransitSign(ctx context.Context, req TransitSignRequest) ([]byte, error) {
+	data := map[string]interface{}{
+		"input":       base64.StdEncoding.EncodeToString(req.Input),
+		"key_version": req.Version,
+	}
+	if req.HashAlgorithm != "" {
+		data["hash_algorithm"] = req.HashAlgorithm
+	}
+	if req.SignatureAlgorithm != "" {
+		data["signature_algorithm"] = req.SignatureAlgorithm
+	}
+	if req.MarshalingAlgorithm != "" {
+		data["marshaling_algorithm"] = req.MarshalingAlgorithm
+	}
+
+	secret, err := s.client.Logical().WriteWithContext(ctx, req.Mount+"/sign/"+req.Key, data)
+	if err != nil {
+		return nil, fmt.Errorf("transit sign with %s failed: %w", req.Key, err)
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, errors.New("transit sign returned no signature")
+	}
+	signature, _ := secret.Data["signature"].(string)
+	// The signature has the form vault:v<version>:<encoded>.
+	parts := strings.SplitN(signature, ":", 3)
+	if len(parts) != 3 || parts[0] != "vault" {
+		return nil, fmt.Errorf("unexpected transit signature format %q", signature)
+	}
+	if req.MarshalingAlgorithm == "jws" {
+		return base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[2], "="))
+	}
+	return base64.StdEncoding.DecodeString(parts[2])
+}
+
+func intFromJSON(value interface{}) int {
+	switch v := value.(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	case interface{ Int64() (int64, error) }:
+		n, _ := v.Int64()
+		return int(n)
+	}
+	return 0
+}
@@ -4,20 +4,38 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	appLogger "github.com/SilentPlaces/basicauth.git/internal/shared/logger"
 	"github.com/SilentPlaces/basicauth.git/pkg/constants"
 	"github.com/google/wire"
 	vault "github.com/hashicorp/vault/api"
 	"log"
 	"os"
 	"sync"
+	"time"
 )
 
 type SecureVaultService interface {
 	GetJWTConfig() (*VaultJWTSecretConfig, error)
+	GetDKIMConfig() (*VaultDKIMConfig, error)
+	GetDatabaseCredentials(ctx context.Context, mount, role string) (*DatabaseCredentials, error)
+	GetTransitKey(ctx context.Context, mount, name string) (*TransitKey, error)
+	TransitSign(ctx context.Context, req TransitSignRequest) ([]byte, error)
+	// RenewLease extends a lease and returns its new duration.
+	RenewLease(ctx context.Context, leaseID string) (time.Duration, error)
+	// Run keeps the Vault token alive until ctx is cancelled.
+	Run(ctx context.Context)
+	// Health returns the last authentication problem, or nil while the token is valid.
+	Health() error
 }
 
 type vaultService struct {
 	client *vault.Client
+	auth   authMethod
+	logger appLogger.Logger
+
+	mu         sync.Mutex
+	authSecret *vault.Secret
+	healthErr  error
 }
 
 type VaultJWTSecretConfig struct {
@@ -25,31 +43,60 @@ type VaultJWTSecretConfig struct {
 	JwtRefreshSecret []byte
 }
 
+// VaultDKIMConfig holds the DKIM signing domain, selector and PEM private key.
+type VaultDKIMConfig struct {
+	Domain     string
+	Selector   string
+	PrivateKey []byte
+}
+
+// ErrDKIMNotConfigured is returned when Vault holds no DKIM secret; mail is then sent unsigned.
+var ErrDKIMNotConfigured = errors.New("dkim secret not found in vault")
+
 var (
-	service *vaultService
-	once    sync.Once
+	service    *vaultService
+	serviceErr error
+	once       sync.Once
 )
 
-func NewSecureVaultService() SecureVaultService {
+// NewSecureVaultService creates a singleton SecureVaultService and logs in with the method named
+// in VAULT_AUTH_METHOD (token, approle or kubernetes).
+func NewSecureVaultService(logger appLogger.Logger) (SecureVaultService, error) {
 	once.Do(func() {
 		vaultAddr := os.Getenv(constants.EnvKeyVaultAddr)
-		vaultToken := os.Getenv(constants.EnvKeyVaultToken)
-		if vaultAddr == "" || vaultToken == "" {
-			log.Fatal("Vault address or token is not set in environment variables")
+		if vaultAddr == "" {
+			serviceErr = errors.New("VAULT_ADDR is not set")
+			return
+		}
+		auth, err := newAuthMethodFromEnv()
+		if err != nil {
+			serviceErr = err
+			return
 		}
 
 		config := vault.DefaultConfig()
 		config.Address = vaultAddr
 		client, err := vault.NewClient(config)
 		if err != nil {
-			log.Fatalf("Unable to initialize Vault client: %v", err)
+			serviceErr = fmt.Errorf("unable to initialize Vault client: %w", err)
+			return
 		}
-		client.SetToken(vaultToken)
-		service = &vaultService{client: client}
-	})
 
-	return service
+		s := &vaultService{client: client, auth: auth, logger: logger}
+		ctx, cancel := context.WithTimeout(context.Background(), loginTimeout)
+		defer cancel()
+		if err := s.login(ctx); err != nil {
+			serviceErr = err
+			return
+		}
+		service = s
+	})
+	if serviceErr != nil {
+		return nil, serviceErr
+	}
+	return service, nil
 }
+
 func (s *vaultService) GetJWTConfig() (*VaultJWTSecretConfig, error) {
 	mountPath := os.Getenv(constants.EnvKeyMountPath)
 	secretPath := os.Getenv(constants.EnvKeySecretPath)
@@ -86,4 +133,39 @@ func (s *vaultService) GetJWTConfig() (*VaultJWTSecretConfig, error) {
 	}, nil
 }
 
+// GetDKIMConfig reads the DKIM secret from VAULT_DKIM_SECRET_PATH (default "dkim") in the KV mount.
+func (s *vaultService) GetDKIMConfig() (*VaultDKIMConfig, error) {
+	mountPath := os.Getenv(constants.EnvKeyMountPath)
+	secretPath := os.Getenv(constants.EnvKeyDKIMSecretPath)
+	if secretPath == "" {
+		secretPath = constants.DefaultVaultDKIMSecretPath
+	}
+	secret, err := s.client.KVv2(mountPath).Get(context.Background(), secretPath)
+	if errors.Is(err, vault.ErrSecretNotFound) {
+		return nil, ErrDKIMNotConfigured
+	}
+	if err != nil {
+		log.Printf("Error reading DKIM secret from Vault at %s: %v", mountPath, err)
+		return nil, fmt.Errorf("unable to read dkim secret from Vault: %w", err)
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, ErrDKIMNotConfigured
+	}
+
+	values := make(map[string]string)
+	for _, key := range []string{constants.VaultDKIMDomainKey, constants.VaultDKIMSelectorKey, constants.VaultDKIMPrivateKeyKey} {
+		value, ok := secret.Data[key].(string)
+		if !ok || value == "" {
+			return nil, fmt.Errorf("dkim %s not found or not a string", key)
+		}
+		values[key] = value
+	}
+
+	return &VaultDKIMConfig{
+		Domain:     values[constants.VaultDKIMDomainKey],
+		Selector:   values[constants.VaultDKIMSelectorKey],
+		PrivateKey: []byte(values[constants.VaultDKIMPrivateKeyKey]),
+	}, nil
+}
+
 var VaultServiceProviderSet = wire.NewSet(NewSecureVaultService)
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/SilentPlaces/basicauth.git/internal/config"
+	"github.com/SilentPlaces/basicauth.git/internal/models/models"
+	repository "github.com/SilentPlaces/basicauth.git/internal/repositories/webhook"
+	appLogger "github.com/SilentPlaces/basicauth.git/internal/shared/logger"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	retryBaseDelay = 30 * time.Second
+	retryMaxDelay  = 6 * time.Hour
+	// maxErrorLength keeps response bodies of failing receivers out of the delivery log.
+	maxErrorLength = 512
+)
+
+var webhookDeliveriesTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "webhook_deliveries_total",
+		Help: "Webhook delivery attempts by event type and outcome.",
+	},
+	[]string{"event_type", "outcome"},
+)
+
+func init() {
+	prometheus.MustRegister(webhookDeliveriesTotal)
+}
+
+// Dispatcher polls the delivery queue and posts signed payloads to subscribers.
+type Dispatcher struct {
+	webhookRepository repository.WebhookRepository
+	cfg               *config.WebhookConfig
+	client            *http.Client
+	logger            appLogger.Logger
+}
+
+func NewDispatcher(webhookRepository repository.WebhookRepository, cfg *config.WebhookConfig, logger appLogger.Logger) *Dispatcher {
+	return &Dispatcher{
+		webhookRepository: webhookRepository,
+		cfg:               cfg,
+		client:            &http.Client{Timeout: cfg.RequestTimeout},
+		logger:            logger,
+	}
+}
+
+// Run processes due deliveries until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.dispatchDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *Dispatcher) dispatchDue(ctx context.Context) {
+	// The lease must outlast one full batch of requests timing out.
+	lease := d.cfg.RequestTimeout*time.Duration(d.cfg.BatchSize) + time.Minute
+	deliveries, err := d.webhookRepository.ClaimDueDeliveries(d.cfg.BatchSize, lease)
+	if err != nil {
+		d.logger.Error(ctx, "webhook claim failed", err, nil)
+		return
+	}
+
+	subscriptions := make(map[string]*models.WebhookSubscription)
+	for _, delivery := range deliveries {
+		if ctx.Err() != nil {
+			return
+		}
+		subscription, ok := subscriptions[delivery.SubscriptionID]
+		if !ok {
+			subscription, err = d.webhookRepository.GetSubscription(delivery.SubscriptionID)
+			if err != nil {
+				d.logger.Error(ctx, "webhook subscription lookup failed", err, map[string]interface{}{"delivery_id": delivery.ID})
+				continue
+			}
+			subscriptions[delivery.SubscriptionID] = subscription
+		}
+		d.deliver(ctx, subscription, delivery)
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, subscription *models.WebhookSubscription, delivery models.WebhookDelivery) {
+	fields := map[string]interface{}{
+		"delivery_id":     delivery.ID,
+		"subscription_id": subscription.ID,
+		"event_type":      delivery.EventType,
+		"attempt":         delivery.Attempts + 1,
+	}
+
+	statusCode, err := d.post(ctx, subscription, delivery)
+	if err == nil {
+		if markErr := d.webhookRepository.MarkDelivered(delivery.ID, statusCode); markErr != nil {
+			d.logger.Error(ctx, "webhook delivery status update failed", markErr, fields)
+		}
+		webhookDeliveriesTotal.WithLabelValues(delivery.EventType, "delivered").Inc()
+		d.logger.Debug(ctx, "webhook delivered", fields)
+		return
+	}
+
+	attempt := delivery.Attempts + 1
+	status := models.WebhookDeliveryPending
+	nextAttemptAt := time.Now().Add(backoff(attempt))
+	outcome := "retry"
+	if attempt >= d.cfg.MaxAttempts {
+		status = models.WebhookDeliveryDead
+		outcome = "dead"
+	}
+
+	if markErr := d.webhookRepository.MarkFailed(delivery.ID, status, nextAttemptAt, statusCode, truncate(err.Error())); markErr != nil {
+		d.logger.Error(ctx, "webhook delivery status update failed", markErr, fields)
+	}
+	webhookDeliveriesTotal.WithLabelValues(delivery.EventType, outcome).Inc()
+	fields["status"] = status
+	d.logger.Warn(ctx, "webhook delivery failed", fields)
+}
+
+func (d *Dispatcher) post(ctx context.Context, subscription *models.WebhookSubscription, delivery models.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	now := time.Now()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "basicauth-webhooks/1")
+	req.Header.Set(HeaderWebhookID, delivery.EventID)
+	req.Header.Set(HeaderWebhookEvent, delivery.EventType)
+	req.Header.Set(HeaderWebhookTimestamp, fmt.Sprintf("%d", now.Unix()))
+	req.Header.Set(HeaderWebhookSignature, Sign(subscription.Secret, now, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorLength))
+		return resp.StatusCode, fmt.Errorf("receiver responded with %d: %s", resp.StatusCode, snippet)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, nil
+}
+
+// backoff doubles the delay for every attempt, starting at retryBaseDelay and capped at retryMaxDelay.
+func backoff(attempt int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= retryMaxDelay {
+			return retryMaxDelay
+		}
+	}
+	return delay
+}
+
+func truncate(message string) string {
+	if len(message) > maxErrorLength {
+		return message[:maxErrorLength]
+	}
+	return message
+}
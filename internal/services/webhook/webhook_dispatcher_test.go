@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SilentPlaces/basicauth.git/internal/config"
+	"github.com/SilentPlaces/basicauth.git/internal/models/models"
+	repository "github.com/SilentPlaces/basicauth.git/internal/repositories/webhook"
+)
+
+const testWebhookSecret = "s3cret"
+
+// fakeWebhookRepository serves one subscription and records how deliveries were settled.
+// Methods the tests do not reach panic through the nil embedded interface.
+type fakeWebhookRepository struct {
+	repository.WebhookRepository
+
+	subscriptions []models.WebhookSubscription
+	due           []models.WebhookDelivery
+
+	inserted  []models.WebhookDelivery
+	delivered []string
+	failed    []failedDelivery
+}
+
+type failedDelivery struct {
+	id            string
+	status        string
+	nextAttemptAt time.Time
+	statusCode    int
+	lastError     string
+}
+
+func (r *fakeWebhookRepository) GetSubscription(id string) (*models.WebhookSubscription, error) {
+	for _, subscription := range r.subscriptions {
+		if subscription.ID == id {
+			return &subscription, nil
+		}
+	}
+	return nil, repository.ErrWebhookNotFound
+}
+
+func (r *fakeWebhookRepository) ListSubscriptions() ([]models.WebhookSubscription, error) {
+	return r.subscriptions, nil
+}
+
+func (r *fakeWebhookRepository) InsertDeliveries(deliveries []models.WebhookDelivery) error {
+	r.inserted = append(r.inserted, deliveries...)
+	return nil
+}
+
+func (r *fakeWebhookRepository) ClaimDueDeliveries(limit int, _ time.Duration) ([]models.WebhookDelivery, error) {
+	due := r.due
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	r.due = r.due[len(due):]
+	return due, nil
+}
+
+func (r *fakeWebhookRepository) MarkDelivered(id string, _ int) error {
+	r.delivered = append(r.delivered, id)
+	return nil
+}
+
+func (r *fakeWebhookRepository) MarkFailed(id string, status string, nextAttemptAt time.Time, statusCode int, lastError string) error {
+	r.failed = append(r.failed, failedDelivery{id: id, status: status, nextAttemptAt: nextAttemptAt, statusCode: statusCode, lastError: lastError})
+	return nil
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(context.Context, string, map[string]interface{})        {}
+func (nopLogger) Info(context.Context, string, map[string]interface{})         {}
+func (nopLogger) Warn(context.Context, string, map[string]interface{})         {}
+func (nopLogger) Error(context.Context, string, error, map[string]interface{}) {}
+
+// dispatchOnce runs one dispatch round for a single delivery posted to handler.
+func dispatchOnce(t *testing.T, handler http.HandlerFunc, attempts int) *fakeWebhookRepository {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	repo := &fakeWebhookRepository{
+		subscriptions: []models.WebhookSubscription{{ID: "sub-1", URL: server.URL, Secret: testWebhookSecret, Active: true}},
+		due: []models.WebhookDelivery{{
+			ID:             "delivery-1",
+			SubscriptionID: "sub-1",
+			EventID:        "event-1",
+			EventType:      models.WebhookEventUserSignedUp,
+			Payload:        `{"id":"event-1","type":"user.signed_up"}`,
+			Status:         models.WebhookDeliveryPending,
+			Attempts:       attempts,
+		}},
+	}
+	cfg := &config.WebhookConfig{MaxAttempts: 3, RequestTimeout: 5 * time.Second, PollInterval: time.Second, BatchSize: 10}
+	NewDispatcher(repo, cfg, nopLogger{}).dispatchDue(context.Background())
+	return repo
+}
+
+func TestDispatcherSignsRequests(t *testing.T) {
+	var header http.Header
+	var body []byte
+	repo := dispatchOnce(t, func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}, 0)
+
+	if len(repo.delivered) != 1 || len(repo.failed) != 0 {
+		t.Fatalf("delivered %v, failed %v; want one delivery", repo.delivered, repo.failed)
+	}
+	if got := header.Get(HeaderWebhookID); got != "event-1" {
+		t.Errorf("%s = %q, want event-1", HeaderWebhookID, got)
+	}
+
+	// Verify the way a receiver would, without going through Sign.
+	timestamp := header.Get(HeaderWebhookTimestamp)
+	if _, err := strconv.ParseInt(timestamp, 10, 64); err != nil {
+		t.Fatalf("%s = %q is not a unix timestamp", HeaderWebhookTimestamp, timestamp)
+	}
+	mac := hmac.New(sha256.New, []byte(testWebhookSecret))
+	mac.Write([]byte(timestamp + "." + string(body)))
+	want := "v1=" + hex.EncodeToString(mac.Sum(nil))
+	if got := header.Get(HeaderWebhookSignature); !hmac.Equal([]byte(got), []byte(want)) {
+		t.Errorf("%s = %q, want %q", HeaderWebhookSignature, got, want)
+	}
+}
+
+func TestDispatcherRetriesFailedDeliveryWithBackoff(t *testing.T) {
+	before := time.Now()
+	repo := dispatchOnce(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "try later", http.StatusServiceUnavailable)
+	}, 1)
+
+	if len(repo.delivered) != 0 || len(repo.failed) != 1 {
+		t.Fatalf("delivered %v, failed %v; want one failure", repo.delivered, repo.failed)
+	}
+	failure := repo.failed[0]
+	if failure.status != models.WebhookDeliveryPending {
+		t.Errorf("status = %q, want %q", failure.status, models.WebhookDeliveryPending)
+	}
+	if failure.statusCode != http.StatusServiceUnavailable {
+		t.Errorf("status code = %d, want %d", failure.statusCode, http.StatusServiceUnavailable)
+	}
+	if !strings.Contains(failure.lastError, "503") {
+		t.Errorf("last error = %q, want it to mention the status", failure.lastError)
+	}
+	// The second attempt failed, so the next one waits twice the base delay.
+	if delay := failure.nextAttemptAt.Sub(before); delay < 2*retryBaseDelay || delay > 2*retryBaseDelay+time.Minute {
+		t.Errorf("next attempt in %s, want about %s", delay, 2*retryBaseDelay)
+	}
+}
+
+func TestDispatcherMarksDeliveryDeadAtMaxAttempts(t *testing.T) {
+	repo := dispatchOnce(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "gone", http.StatusGone)
+	}, 2)
+
+	if len(repo.failed) != 1 {
+		t.Fatalf("failed %v, want one failure", repo.failed)
+	}
+	if repo.failed[0].status != models.WebhookDeliveryDead {
+		t.Errorf("status = %q, want %q", repo.failed[0].status, models.WebhookDeliveryDead)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, retryBaseDelay},
+		{2, 2 * retryBaseDelay},
+		{4, 8 * retryBaseDelay},
+		{20, retryMaxDelay},
+	}
+	for _, tt := range tests {
+		if got := backoff(tt.attempt); got != tt.want {
+			t.Errorf("backoff(%d) = %s, want %s", tt.attempt, got, tt.want)
+		}
+	}
+}
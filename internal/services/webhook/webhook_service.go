@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/SilentPlaces/basicauth.git/internal/models/models"
+	repository "github.com/SilentPlaces/basicauth.git/internal/repositories/webhook"
+	"github.com/google/uuid"
+	"github.com/google/wire"
+)
+
+// Headers sent with every webhook request.
+const (
+	HeaderWebhookID        = "X-Webhook-Id"
+	HeaderWebhookEvent     = "X-Webhook-Event"
+	HeaderWebhookTimestamp = "X-Webhook-Timestamp"
+	HeaderWebhookSignature = "X-Webhook-Signature"
+)
+
+var (
+	ErrInvalidWebhookURL       = errors.New("webhook url must be an absolute http or https url")
+	ErrInvalidWebhookEventType = errors.New("unknown webhook event type")
+)
+
+type (
+	WebhookService interface {
+		Publish(ctx context.Context, eventType string, data map[string]interface{}) error
+		CreateSubscription(rawURL string, eventTypes []string, secret string) (*models.WebhookSubscription, error)
+		ListSubscriptions() ([]models.WebhookSubscription, error)
+		DeleteSubscription(id string) error
+		ListDeliveries(subscriptionID string, status string, limit int, offset int) ([]models.WebhookDelivery, error)
+		RetryDelivery(id string) error
+	}
+
+	webhookService struct {
+		webhookRepository repository.WebhookRepository
+	}
+
+	// Envelope is the JSON body posted to subscribers.
+	Envelope struct {
+		ID         string                 `json:"id"`
+		Type       string                 `json:"type"`
+		OccurredAt time.Time              `json:"occurred_at"`
+		Data       map[string]interface{} `json:"data"`
+	}
+)
+
+func NewWebhookService(webhookRepository repository.WebhookRepository) WebhookService {
+	return &webhookService{webhookRepository: webhookRepository}
+}
+
+// Publish enqueues one delivery per active subscription that listens to eventType.
+func (s *webhookService) Publish(_ context.Context, eventType string, data map[string]interface{}) error {
+	subscriptions, err := s.webhookRepository.ListSubscriptions()
+	if err != nil {
+		return err
+	}
+
+	envelope := Envelope{
+		ID:         uuid.NewString(),
+		Type:       eventType,
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+	}
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	var deliveries []models.WebhookDelivery
+	for _, subscription := range subscriptions {
+		if !subscription.Active || !subscription.Accepts(eventType) {
+			continue
+		}
+		deliveries = append(deliveries, models.WebhookDelivery{
+			ID:             uuid.NewString(),
+			SubscriptionID: subscription.ID,
+			EventID:        envelope.ID,
+			EventType:      eventType,
+			Subject:        eventSubject(data),
+			Payload:        string(payload),
+		})
+	}
+	return s.webhookRepository.InsertDeliveries(deliveries)
+}
+
+// eventSubject returns whom an event is about: the user ID, or the email for events sent before
+// the user is known by ID.
+func eventSubject(data map[string]interface{}) string {
+	for _, key := range []string{"user_id", "email"} {
+		if value, ok := data[key].(string); ok && value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// CreateSubscription stores a new subscription. A random secret is generated when none is given.
+func (s *webhookService) CreateSubscription(rawURL string, eventTypes []string, secret string) (*models.WebhookSubscription, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || !parsed.IsAbs() || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, ErrInvalidWebhookURL
+	}
+	if len(eventTypes) == 0 {
+		return nil, ErrInvalidWebhookEventType
+	}
+	for _, eventType := range eventTypes {
+		if !isKnownEventType(eventType) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidWebhookEventType, eventType)
+		}
+	}
+
+	if secret == "" {
+		secret, err = generateSecret()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	subscription := models.WebhookSubscription{
+		ID:         uuid.NewString(),
+		URL:        rawURL,
+		EventTypes: eventTypes,
+		Secret:     secret,
+		Active:     true,
+		CreatedAt:  time.Now().UTC(),
+	}
+	if err := s.webhookRepository.InsertSubscription(subscription); err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+func (s *webhookService) ListSubscriptions() ([]models.WebhookSubscription, error) {
+	return s.webhookRepository.ListSubscriptions()
+}
+
+func (s *webhookService) DeleteSubscription(id string) error {
+	return s.webhookRepository.DeleteSubscription(id)
+}
+
+func (s *webhookService) ListDeliveries(subscriptionID string, status string, limit int, offset int) ([]models.WebhookDelivery, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return s.webhookRepository.ListDeliveries(subscriptionID, status, limit, offset)
+}
+
+// RetryDelivery moves a dead-lettered (or pending) delivery back to the front of the queue.
+func (s *webhookService) RetryDelivery(id string) error {
+	return s.webhookRepository.Requeue(id)
+}
+
+// Sign returns the signature header value for a request body sent at timestamp.
+// Receivers recompute HMAC-SHA256(secret, "<timestamp>.<body>") and compare it in constant time.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func isKnownEventType(eventType string) bool {
+	for _, known := range models.WebhookEventTypes {
+		if known == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func generateSecret() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+var WebhookServiceProviderSet = wire.NewSet(NewWebhookService)
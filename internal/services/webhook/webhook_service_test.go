@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/SilentPlaces/basicauth.git/internal/models/models"
+)
+
+func TestSign(t *testing.T) {
+	// HMAC-SHA256("secret", "1700000000.{}"), computed with openssl.
+	const want = "v1=b8569b78799ff9e3cbff0fc2d63a33a2b57f3282abd07c37ae5e8e7d79a5f163"
+	got := Sign("secret", time.Unix(1700000000, 0), []byte("{}"))
+	if got != want {
+		t.Errorf("Sign = %q, want %q", got, want)
+	}
+	if other := Sign("other", time.Unix(1700000000, 0), []byte("{}")); other == got {
+		t.Error("signatures with different secrets match")
+	}
+	if later := Sign("secret", time.Unix(1700000001, 0), []byte("{}")); later == got {
+		t.Error("signatures with different timestamps match")
+	}
+}
+
+func TestPublishQueuesOneDeliveryPerListeningSubscription(t *testing.T) {
+	repo := &fakeWebhookRepository{subscriptions: []models.WebhookSubscription{
+		{ID: "listening", EventTypes: []string{models.WebhookEventUserSignedUp}, Active: true},
+		{ID: "other-event", EventTypes: []string{models.WebhookEventUserDeleted}, Active: true},
+		{ID: "inactive", EventTypes: []string{models.WebhookEventUserSignedUp}, Active: false},
+	}}
+
+	err := NewWebhookService(repo).Publish(context.Background(), models.WebhookEventUserSignedUp,
+		map[string]interface{}{"user_id": "user-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(repo.inserted) != 1 {
+		t.Fatalf("queued %d deliveries, want 1", len(repo.inserted))
+	}
+	delivery := repo.inserted[0]
+	if delivery.SubscriptionID != "listening" || delivery.Subject != "user-1" {
+		t.Errorf("delivery = %+v, want one for subscription listening about user-1", delivery)
+	}
+	var envelope Envelope
+	if err := json.Unmarshal([]byte(delivery.Payload), &envelope); err != nil {
+		t.Fatal(err)
+	}
+	if envelope.ID != delivery.EventID || envelope.Type != models.WebhookEventUserSignedUp {
+		t.Errorf("envelope = %+v, want ID %q and type %q", envelope, delivery.EventID, models.WebhookEventUserSignedUp)
+	}
+}
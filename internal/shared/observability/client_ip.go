@@ -0,0 +1,20 @@
+package observability
+
+import "context"
+
+const clientIPKey contextKey = "client_ip"
+
+func WithClientIP(ctx context.Context, clientIP string) context.Context {
+	return context.WithValue(ctx, clientIPKey, clientIP)
+}
+
+func ClientIPFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	value, ok := ctx.Value(clientIPKey).(string)
+	if !ok {
+		return ""
+	}
+	return value
+}
@@ -0,0 +1,82 @@
+package validation
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/SilentPlaces/basicauth.git/internal/config"
+	"golang.org/x/net/idna"
+)
+
+// domainAliases maps domains that deliver to the same mailboxes as another domain.
+var domainAliases = map[string]string{
+	"googlemail.com": "gmail.com",
+}
+
+// EmailNormalizer turns user input into the address mail is sent to and the canonical form that
+// identifies an account. Two inputs with the same canonical form reach the same mailbox, so they
+// must not become two accounts or get separate rate limits.
+type EmailNormalizer struct {
+	allPlusTags           bool
+	plusTagDomains        map[string]bool
+	dotInsensitiveDomains map[string]bool
+}
+
+func NewEmailNormalizer(cfg *config.EmailNormalizationConfig) *EmailNormalizer {
+	n := &EmailNormalizer{
+		plusTagDomains:        make(map[string]bool),
+		dotInsensitiveDomains: make(map[string]bool),
+	}
+	for _, domain := range cfg.PlusTagDomains {
+		if domain == "*" {
+			n.allPlusTags = true
+		}
+		n.plusTagDomains[domain] = true
+	}
+	for _, domain := range cfg.DotInsensitiveDomains {
+		n.dotInsensitiveDomains[domain] = true
+	}
+	return n
+}
+
+// Normalize validates email and returns its normalized address and canonical form.
+//
+// The address keeps the local part as typed and has the domain lower-cased and, for international
+// domains, converted to punycode. The canonical form also lower-cases the local part, resolves
+// domain aliases, and drops plus tags and dots for the configured providers.
+func (n *EmailNormalizer) Normalize(email string) (address string, canonical string, err error) {
+	email = strings.TrimSpace(email)
+	at := strings.LastIndex(email, "@")
+	if at <= 0 || at == len(email)-1 {
+		return "", "", errors.New("invalid email format")
+	}
+	local, domain := email[:at], strings.TrimSuffix(email[at+1:], ".")
+
+	// The lookup profile applies the IDNA mapping, which includes case folding.
+	asciiDomain, err := idna.Lookup.ToASCII(domain)
+	if err != nil {
+		return "", "", errors.New("invalid email domain")
+	}
+	address = local + "@" + strings.ToLower(asciiDomain)
+	if err := ValidateEmail(address); err != nil {
+		return "", "", err
+	}
+
+	// ValidateEmail only lets ASCII local parts through, so lower-casing is a full case fold.
+	canonicalLocal := strings.ToLower(local)
+	canonicalDomain := strings.ToLower(asciiDomain)
+	if alias, ok := domainAliases[canonicalDomain]; ok {
+		canonicalDomain = alias
+	}
+	if n.allPlusTags || n.plusTagDomains[canonicalDomain] {
+		if tagged, _, found := strings.Cut(canonicalLocal, "+"); found && tagged != "" {
+			canonicalLocal = tagged
+		}
+	}
+	if n.dotInsensitiveDomains[canonicalDomain] {
+		if undotted := strings.ReplaceAll(canonicalLocal, ".", ""); undotted != "" {
+			canonicalLocal = undotted
+		}
+	}
+	return address, canonicalLocal + "@" + canonicalDomain, nil
+}
@@ -0,0 +1,715 @@
+package validation
+
+import (
+	_ "embed"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// The approach follows zxcvbn: the password is split into the sequence of known patterns that is
+// cheapest to guess, and the guesses of the parts are combined into an estimate for the whole.
+// Guess counts are kept as base 10 logarithms, as they overflow quickly.
+
+// maxEstimatedRunes bounds the part of the password that is analysed; the search is cubic in the
+// length, and a longer password is not weak because of what follows.
+const maxEstimatedRunes = 100
+
+// Score thresholds, in log10 guesses: a password needs more guesses than 10^threshold for each
+// point. A score of 3 resists an online attack for good; 4 also slows down an offline one.
+var scoreThresholds = []float64{3, 6, 8, 10}
+
+// scoreThresholdMargin is how many guesses over a threshold a password needs to pass it, so a
+// sequence of parts that only just reaches the minimum of the sequence length does not count.
+const scoreThresholdMargin = 5
+
+const (
+	// bruteforceCardinality is the guesses per character not covered by a pattern.
+	bruteforceCardinality = 10
+	// minSegmentGuessesLog10 is added per extra segment, so splitting into many small patterns
+	// does not look cheaper than it is.
+	minSegmentGuessesLog10 = 4
+	minDictionaryWordRunes = 3
+	referenceYear          = 2025
+	minYearSpace           = 20
+)
+
+//go:embed common_passwords.txt
+var bundledCommonPasswords string
+
+//go:embed common_words.txt
+var bundledCommonWords string
+
+var (
+	commonPasswords = rankedList(bundledCommonPasswords)
+	commonWords     = rankedList(bundledCommonWords)
+)
+
+// l33tSubstitutions maps characters to the letters they commonly stand for. Characters with two
+// meanings are tried both ways.
+var l33tSubstitutions = map[rune][]rune{
+	'4': {'a'}, '@': {'a'}, '8': {'b'}, '(': {'c'}, '3': {'e'}, '6': {'g'}, '9': {'g'},
+	'1': {'i', 'l'}, '!': {'i'}, '|': {'l', 'i'}, '0': {'o'}, '$': {'s'}, '5': {'s'},
+	'7': {'t'}, '+': {'t'}, '2': {'z'},
+}
+
+// keyboardLayouts are the keyboard layouts checked for patterns: US, German and French, each as
+// its unshifted rows from the top.
+var keyboardLayouts = [][]string{
+	{"`1234567890-=", "qwertyuiop[]\\", "asdfghjkl;'", "zxcvbnm,./"},
+	{"^1234567890ß´", "qwertzuiopü+", "asdfghjklöä#", "<yxcvbnm,.-"},
+	{"²&é\"'(-è_çà)=", "azertyuiop^$", "qsdfghjklmù*", "<wxcvbn,;:!"},
+}
+
+// shiftedKeys maps shifted US characters to their key.
+var shiftedKeys = map[rune]rune{
+	'~': '`', '!': '1', '@': '2', '#': '3', '$': '4', '%': '5', '^': '6', '&': '7', '*': '8',
+	'(': '9', ')': '0', '_': '-', '+': '=', '{': '[', '}': ']', '|': '\\', ':': ';', '"': '\'',
+	'<': ',', '>': '.', '?': '/',
+}
+
+// Pattern names of a match.
+const (
+	patternBruteforce = "bruteforce"
+	patternDictionary = "dictionary"
+	patternUserInput  = "user_input"
+	patternSpatial    = "spatial"
+	patternRepeat     = "repeat"
+	patternSequence   = "sequence"
+	patternYear       = "year"
+)
+
+// PasswordStrength is the estimate for a password. Score goes from 0, guessed within a thousand
+// tries, to 4, practically not guessable. Warning and Suggestions explain a low score.
+type PasswordStrength struct {
+	Score        int
+	GuessesLog10 float64
+	Warning      string
+	Suggestions  []string
+}
+
+type match struct {
+	pattern      string
+	i, j         int // rune positions, inclusive
+	token        string
+	guessesLog10 float64
+
+	// Details of dictionary, spatial and repeat matches, used for feedback.
+	rank     int
+	list     string
+	reversed bool
+	l33t     bool
+	turns    int
+	baseLen  int
+}
+
+// EstimatePasswordStrength scores password. userInputs, such as the user's name and email, count
+// as known words, so a password built from them scores low.
+func EstimatePasswordStrength(password string, userInputs ...string) *PasswordStrength {
+	runes := []rune(password)
+	if len(runes) > maxEstimatedRunes {
+		runes = runes[:maxEstimatedRunes]
+	}
+	sequence, guessesLog10 := mostGuessableSequence(runes, userInputDictionary(userInputs))
+
+	strength := &PasswordStrength{GuessesLog10: guessesLog10}
+	for _, threshold := range scoreThresholds {
+		if guessesLog10 >= math.Log10(math.Pow(10, threshold)+scoreThresholdMargin) {
+			strength.Score++
+		}
+	}
+	strength.Warning, strength.Suggestions = feedback(strength.Score, sequence)
+	return strength
+}
+
+// mostGuessableSequence finds the split of the password into matches that needs the fewest
+// guesses in total. With l parts the total is l! * product(guesses) + 10^(4*(l-1)): the order of
+// the parts is unknown, and every extra part adds a minimum.
+func mostGuessableSequence(runes []rune, userInputs map[string]int) ([]match, float64) {
+	n := len(runes)
+	if n == 0 {
+		return nil, 0
+	}
+	matchesByEnd := make([][]match, n)
+	for _, m := range findMatches(runes, userInputs) {
+		matchesByEnd[m.j] = append(matchesByEnd[m.j], m)
+	}
+
+	// best[k][l] is the lowest log10 product of guesses covering runes[:k] with l parts.
+	inf := math.Inf(1)
+	best := make([][]float64, n+1)
+	prev := make([][]match, n+1)
+	for k := range best {
+		best[k] = make([]float64, n+1)
+		prev[k] = make([]match, n+1)
+		for l := range best[k] {
+			best[k][l] = inf
+		}
+	}
+	best[0][0] = 0
+
+	for k := 1; k <= n; k++ {
+		candidates := matchesByEnd[k-1]
+		for i := 0; i < k; i++ {
+			candidates = append(candidates, bruteforceMatch(runes, i, k-1))
+		}
+		for _, m := range candidates {
+			for l := 1; l <= k; l++ {
+				if best[m.i][l-1] == inf {
+					continue
+				}
+				if cost := best[m.i][l-1] + m.guessesLog10; cost < best[k][l] {
+					best[k][l] = cost
+					prev[k][l] = m
+				}
+			}
+		}
+	}
+
+	bestTotal, bestL := inf, 0
+	for l := 1; l <= n; l++ {
+		if best[n][l] == inf {
+			continue
+		}
+		total := addLog10(logFactorial(l)+best[n][l], float64(minSegmentGuessesLog10*(l-1)))
+		if total < bestTotal {
+			bestTotal, bestL = total, l
+		}
+	}
+
+	sequence := make([]match, bestL)
+	for k, l := n, bestL; l > 0; l-- {
+		m := prev[k][l]
+		sequence[l-1] = m
+		k = m.i
+	}
+	return sequence, bestTotal
+}
+
+func findMatches(runes []rune, userInputs map[string]int) []match {
+	var matches []match
+	matches = append(matches, dictionaryMatches(runes, userInputs)...)
+	matches = append(matches, spatialMatches(runes)...)
+	matches = append(matches, repeatMatches(runes, userInputs)...)
+	matches = append(matches, sequenceMatches(runes)...)
+	matches = append(matches, yearMatches(runes)...)
+	return matches
+}
+
+func bruteforceMatch(runes []rune, i, j int) match {
+	length := j - i + 1
+	guessesLog10 := float64(length) * math.Log10(bruteforceCardinality)
+	// A single character is never cheaper than a pattern of it.
+	if length == 1 {
+		guessesLog10 = math.Log10(bruteforceCardinality + 1)
+	}
+	return match{pattern: patternBruteforce, i: i, j: j, token: string(runes[i : j+1]), guessesLog10: guessesLog10}
+}
+
+// dictionaryMatches finds words of the bundled lists and of the user inputs, also spelled
+// backwards or with l33t substitutions. Guesses are the word's rank, times the ways to vary it.
+func dictionaryMatches(runes []rune, userInputs map[string]int) []match {
+	lower := []rune(strings.ToLower(string(runes)))
+	var matches []match
+	for _, variant := range l33tVariants(lower) {
+		for i := range variant {
+			for j := i + minDictionaryWordRunes - 1; j < len(variant); j++ {
+				word := string(variant[i : j+1])
+				for _, reversed := range []bool{false, true} {
+					lookup := word
+					if reversed {
+						lookup = reverse(word)
+					}
+					list, rank := lookupWord(lookup, userInputs)
+					if rank == 0 {
+						continue
+					}
+					token := string(runes[i : j+1])
+					m := match{
+						pattern:  patternDictionary,
+						i:        i,
+						j:        j,
+						token:    token,
+						rank:     rank,
+						list:     list,
+						reversed: reversed,
+						l33t:     word != string(lower[i:j+1]),
+					}
+					if list == patternUserInput {
+						m.pattern = patternUserInput
+					}
+					guesses := math.Log10(float64(rank)) + uppercaseVariationsLog10(token)
+					if m.l33t {
+						guesses += math.Log10(2)
+					}
+					if reversed {
+						guesses += math.Log10(2)
+					}
+					m.guessesLog10 = guesses
+					matches = append(matches, m)
+				}
+			}
+		}
+	}
+	return matches
+}
+
+// lookupWord returns the list word is in and its rank, 0 when it is in none. User inputs come
+// first, as they are the most likely guesses.
+func lookupWord(word string, userInputs map[string]int) (string, int) {
+	if rank, ok := userInputs[word]; ok {
+		return patternUserInput, rank
+	}
+	if rank, ok := commonPasswords[word]; ok {
+		return "passwords", rank
+	}
+	if rank, ok := commonWords[word]; ok {
+		return "words", rank
+	}
+	return "", 0
+}
+
+// l33tVariants returns lower with every l33t character replaced by its letter, once per meaning
+// of the ambiguous ones, plus lower itself.
+func l33tVariants(lower []rune) [][]rune {
+	variants := [][]rune{lower}
+	for _, choice := range []int{0, 1} {
+		variant := make([]rune, len(lower))
+		changed := false
+		for i, r := range lower {
+			variant[i] = r
+			if subs, ok := l33tSubstitutions[r]; ok {
+				variant[i] = subs[min(choice, len(subs)-1)]
+				changed = true
+			}
+		}
+		if changed {
+			variants = append(variants, variant)
+		}
+	}
+	return variants
+}
+
+// uppercaseVariationsLog10 counts the ways to capitalize a word. Capitalizing the first or last
+// letter, or all of them, is what most people do and adds little.
+func uppercaseVariationsLog10(token string) float64 {
+	upper, lower := 0, 0
+	for _, r := range token {
+		switch {
+		case unicode.IsUpper(r):
+			upper++
+		case unicode.IsLower(r):
+			lower++
+		}
+	}
+	if upper == 0 {
+		return 0
+	}
+	runes := []rune(token)
+	if lower == 0 || (upper == 1 && (unicode.IsUpper(runes[0]) || unicode.IsUpper(runes[len(runes)-1]))) {
+		return math.Log10(2)
+	}
+	variations := 0.0
+	for k := 1; k <= min(upper, lower); k++ {
+		variations += binomial(upper+lower, k)
+	}
+	return math.Log10(variations)
+}
+
+// spatialMatches finds runs of at least three adjacent keys on one of the keyboard layouts.
+func spatialMatches(runes []rune) []match {
+	var matches []match
+	for _, layout := range keyboardLayouts {
+		positions := keyPositions(layout)
+		i := 0
+		for i < len(runes)-2 {
+			j, turns, lastDirection := i, 1, -1
+			for j+1 < len(runes) {
+				direction, ok := keyDirection(positions, runes[j], runes[j+1])
+				if !ok {
+					break
+				}
+				if lastDirection != -1 && direction != lastDirection {
+					turns++
+				}
+				lastDirection = direction
+				j++
+			}
+			if j-i+1 >= 3 {
+				length := j - i + 1
+				// About 94 starting keys, 4 likely neighbours, and a choice of where to turn.
+				guesses := math.Log10(94) + float64(turns)*math.Log10(4) + math.Log10(float64(length))
+				guesses += shiftedVariationsLog10(runes[i : j+1])
+				matches = append(matches, match{
+					pattern:      patternSpatial,
+					i:            i,
+					j:            j,
+					token:        string(runes[i : j+1]),
+					turns:        turns,
+					guessesLog10: guesses,
+				})
+				i = j
+				continue
+			}
+			i++
+		}
+	}
+	return matches
+}
+
+type keyPosition struct{ row, col int }
+
+func keyPositions(layout []string) map[rune]keyPosition {
+	positions := make(map[rune]keyPosition)
+	for row, keys := range layout {
+		for col, key := range []rune(keys) {
+			positions[key] = keyPosition{row: row, col: col}
+		}
+	}
+	return positions
+}
+
+// keyDirection returns which of the six neighbours b is of a: left, right, or one of the two keys
+// above and below. Rows are offset by half a key, so col and col+1 above and col-1 and col below
+// touch a key.
+func keyDirection(positions map[rune]keyPosition, a, b rune) (int, bool) {
+	pa, okA := keyPositionOf(positions, a)
+	pb, okB := keyPositionOf(positions, b)
+	if !okA || !okB || pa == pb {
+		return 0, false
+	}
+	dRow, dCol := pb.row-pa.row, pb.col-pa.col
+	switch {
+	case dRow == 0 && dCol == -1:
+		return 0, true
+	case dRow == 0 && dCol == 1:
+		return 1, true
+	case dRow == -1 && dCol == 0:
+		return 2, true
+	case dRow == -1 && dCol == 1:
+		return 3, true
+	case dRow == 1 && dCol == -1:
+		return 4, true
+	case dRow == 1 && dCol == 0:
+		return 5, true
+	}
+	return 0, false
+}
+
+// keyPositionOf finds the key of r, also when it is typed with shift on a US keyboard.
+func keyPositionOf(positions map[rune]keyPosition, r rune) (keyPosition, bool) {
+	if position, ok := positions[unicode.ToLower(r)]; ok {
+		return position, true
+	}
+	position, ok := positions[shiftedKeys[r]]
+	return position, ok
+}
+
+func shiftedVariationsLog10(keys []rune) float64 {
+	for _, r := range keys {
+		if _, ok := shiftedKeys[r]; ok || unicode.IsUpper(r) {
+			return math.Log10(2)
+		}
+	}
+	return 0
+}
+
+// repeatMatches finds a base repeated at least twice, like "aaa" or "abcabc". Guesses are the
+// guesses of the base times the number of repeats.
+func repeatMatches(runes []rune, userInputs map[string]int) []match {
+	var matches []match
+	n := len(runes)
+	for i := 0; i < n-1; {
+		bestEnd, bestBase := -1, 0
+		for baseLen := 1; i+2*baseLen <= n; baseLen++ {
+			end := i + baseLen
+			for end+baseLen <= n && equalRunes(runes[i:i+baseLen], runes[end:end+baseLen]) {
+				end += baseLen
+			}
+			if end-i >= 2*baseLen && end-1 > bestEnd {
+				bestEnd, bestBase = end-1, baseLen
+			}
+		}
+		if bestEnd < 0 || (bestBase == 1 && bestEnd-i+1 < 3) {
+			i++
+			continue
+		}
+		base := runes[i : i+bestBase]
+		_, baseGuesses := mostGuessableSequence(base, userInputs)
+		repeats := (bestEnd - i + 1) / bestBase
+		matches = append(matches, match{
+			pattern:      patternRepeat,
+			i:            i,
+			j:            bestEnd,
+			token:        string(runes[i : bestEnd+1]),
+			baseLen:      bestBase,
+			guessesLog10: baseGuesses + math.Log10(float64(repeats)),
+		})
+		i = bestEnd + 1
+	}
+	return matches
+}
+
+// sequenceMatches finds runs of at least three characters with a constant step of 1 or 2, like
+// "abc", "7531" or "acegi".
+func sequenceMatches(runes []rune) []match {
+	var matches []match
+	n := len(runes)
+	for i := 0; i < n-2; {
+		delta := runes[i+1] - runes[i]
+		if delta == 0 || delta > 2 || delta < -2 || !sameClass(runes[i], runes[i+1]) {
+			i++
+			continue
+		}
+		j := i + 1
+		for j+1 < n && runes[j+1]-runes[j] == delta && sameClass(runes[j], runes[j+1]) {
+			j++
+		}
+		if j-i+1 < 3 {
+			i++
+			continue
+		}
+		first := unicode.ToLower(runes[i])
+		var base float64
+		switch {
+		case strings.ContainsRune("az019", first):
+			base = 4
+		case unicode.IsDigit(first):
+			base = 10
+		default:
+			base = 26
+		}
+		if delta < 0 {
+			base *= 2
+		}
+		if delta == 2 || delta == -2 {
+			base *= 2
+		}
+		matches = append(matches, match{
+			pattern:      patternSequence,
+			i:            i,
+			j:            j,
+			token:        string(runes[i : j+1]),
+			guessesLog10: math.Log10(base * float64(j-i+1)),
+		})
+		i = j
+	}
+	return matches
+}
+
+func sameClass(a, b rune) bool {
+	switch {
+	case unicode.IsDigit(a):
+		return unicode.IsDigit(b)
+	case unicode.IsLower(a):
+		return unicode.IsLower(b)
+	case unicode.IsUpper(a):
+		return unicode.IsUpper(b)
+	}
+	return false
+}
+
+// yearMatches finds four digit years from 1900 to 2099. Years close to now are guessed first.
+func yearMatches(runes []rune) []match {
+	var matches []match
+	for i := 0; i+4 <= len(runes); i++ {
+		token := string(runes[i : i+4])
+		if !isDigits(token) || !(strings.HasPrefix(token, "19") || strings.HasPrefix(token, "20")) {
+			continue
+		}
+		year := 0
+		for _, r := range token {
+			year = year*10 + int(r-'0')
+		}
+		space := max(int(math.Abs(float64(year-referenceYear))), minYearSpace)
+		matches = append(matches, match{
+			pattern:      patternYear,
+			i:            i,
+			j:            i + 3,
+			token:        token,
+			guessesLog10: math.Log10(float64(space)),
+		})
+	}
+	return matches
+}
+
+// feedback explains a score below 3 with the weakest part of the password. Stronger passwords get
+// no feedback.
+func feedback(score int, sequence []match) (string, []string) {
+	if len(sequence) == 0 {
+		return "", []string{"Use a few words, avoid common phrases.", "No need for symbols, digits, or uppercase letters."}
+	}
+	if score >= 3 {
+		return "", nil
+	}
+
+	suggestions := []string{"Add another word or two. Uncommon words are better."}
+	var longest *match
+	for k := range sequence {
+		if sequence[k].pattern == patternBruteforce {
+			continue
+		}
+		if longest == nil || len([]rune(sequence[k].token)) > len([]rune(longest.token)) {
+			longest = &sequence[k]
+		}
+	}
+	if longest == nil {
+		return "", suggestions
+	}
+
+	switch longest.pattern {
+	case patternUserInput:
+		return "Passwords containing your name or email address are easy to guess.", append(suggestions, dictionarySuggestions(longest)...)
+	case patternDictionary:
+		return dictionaryWarning(longest, len(sequence) == 1), append(suggestions, dictionarySuggestions(longest)...)
+	case patternSpatial:
+		warning := "Short keyboard patterns are easy to guess."
+		if longest.turns == 1 {
+			warning = "Straight rows of keys are easy to guess."
+		}
+		return warning, append(suggestions, "Use a longer keyboard pattern with more turns.")
+	case patternRepeat:
+		warning := `Repeats like "abcabcabc" are only slightly harder to guess than "abc".`
+		if longest.baseLen == 1 {
+			warning = `Repeats like "aaa" are easy to guess.`
+		}
+		return warning, append(suggestions, "Avoid repeated words and characters.")
+	case patternSequence:
+		return "Sequences like abc or 6543 are easy to guess.", append(suggestions, "Avoid sequences.")
+	case patternYear:
+		return "Recent years are easy to guess.", append(suggestions, "Avoid recent years.", "Avoid years that are associated with you.")
+	}
+	return "", suggestions
+}
+
+func dictionaryWarning(m *match, whole bool) string {
+	switch {
+	case m.list == "passwords" && whole && !m.l33t && !m.reversed && m.rank <= 10:
+		return "This is a top-10 common password."
+	case m.list == "passwords" && whole && !m.l33t && !m.reversed && m.rank <= 100:
+		return "This is a top-100 common password."
+	case m.list == "passwords":
+		return "This is similar to a commonly used password."
+	case whole:
+		return "A word by itself is easy to guess."
+	}
+	return "Common words are easy to guess."
+}
+
+func dictionarySuggestions(m *match) []string {
+	var suggestions []string
+	runes := []rune(m.token)
+	switch {
+	case strings.ToUpper(m.token) == m.token && strings.ToLower(m.token) != m.token:
+		suggestions = append(suggestions, "All-uppercase is almost as easy to guess as all-lowercase.")
+	case unicode.IsUpper(runes[0]):
+		suggestions = append(suggestions, "Capitalization doesn't help very much.")
+	}
+	if m.reversed {
+		suggestions = append(suggestions, "Reversed words aren't much harder to guess.")
+	}
+	if m.l33t {
+		suggestions = append(suggestions, "Predictable substitutions like '@' instead of 'a' don't help very much.")
+	}
+	return suggestions
+}
+
+// userInputDictionary ranks the words of the user inputs: the inputs themselves, and the words
+// of names and the parts of email addresses.
+func userInputDictionary(inputs []string) map[string]int {
+	dictionary := make(map[string]int)
+	add := func(word string) {
+		word = strings.ToLower(strings.TrimSpace(word))
+		if len([]rune(word)) < minDictionaryWordRunes {
+			return
+		}
+		if _, ok := dictionary[word]; !ok {
+			dictionary[word] = len(dictionary) + 1
+		}
+	}
+	for _, input := range inputs {
+		add(input)
+		local, domain, isEmail := strings.Cut(input, "@")
+		parts := strings.FieldsFunc(local, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		if isEmail {
+			add(local)
+			// The top level domain is too common to count.
+			labels := strings.Split(domain, ".")
+			parts = append(parts, labels[:max(len(labels)-1, 0)]...)
+		}
+		for _, part := range parts {
+			add(part)
+		}
+	}
+	return dictionary
+}
+
+// rankedList parses a word list with one word per line, most common first, skipping comments.
+func rankedList(raw string) map[string]int {
+	list := make(map[string]int)
+	for _, line := range strings.Split(raw, "\n") {
+		word := strings.TrimSpace(line)
+		if word == "" || strings.HasPrefix(word, "#") {
+			continue
+		}
+		if _, ok := list[word]; !ok {
+			list[word] = len(list) + 1
+		}
+	}
+	return list
+}
+
+func equalRunes(a, b []rune) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func reverse(s string) string {
+	runes := []rune(s)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes)
+}
+
+func binomial(n, k int) float64 {
+	result := 1.0
+	for i := 1; i <= k; i++ {
+		result = result * float64(n-k+i) / float64(i)
+	}
+	return result
+}
+
+func logFactorial(n int) float64 {
+	result := 0.0
+	for i := 2; i <= n; i++ {
+		result += math.Log10(float64(i))
+	}
+	return result
+}
+
+// addLog10 returns log10(10^a + 10^b).
+func addLog10(a, b float64) float64 {
+	if a < b {
+		a, b = b, a
+	}
+	return a + math.Log10(1+math.Pow(10, b-a))
+}
@@ -3,25 +3,34 @@ package validation
 import (
 	"errors"
 	"fmt"
-	"github.com/SilentPlaces/basicauth.git/internal/config"
 	"regexp"
 	"unicode"
+	"unicode/utf8"
+
+	"github.com/SilentPlaces/basicauth.git/internal/config"
+	custom_error "github.com/SilentPlaces/basicauth.git/internal/errors"
 )
 
-// ValidateEmail checks if the provided email matches a basic regex pattern.
+// ValidateEmail checks if the provided email matches a basic regex pattern. International domains
+// must be in punycode, see EmailNormalizer.
 func ValidateEmail(email string) error {
-	regex := regexp.MustCompile(`^[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}$`)
+	regex := regexp.MustCompile(`^[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.([a-zA-Z]{2,}|xn--[a-zA-Z0-9\-]+)$`)
 	if !regex.MatchString(email) {
 		return errors.New("invalid email format")
 	}
 	return nil
 }
 
-// ValidatePassword checks whether the provided password meets the requirements of the given strength level.
-func ValidatePassword(password string, strength *config.RegistrationPasswordConfig) error {
-
-	if len(password) < strength.MinLength {
-		return errors.New(fmt.Sprintf("password must be at least %d characters long", strength.MinLength))
+// ValidatePassword checks the password against the policy and returns a
+// *custom_error.PasswordPolicyError when it does not comply. userInputs, such as the user's name
+// and email, make a password built from them score lower.
+func ValidatePassword(password string, policy *config.RegistrationPasswordConfig, userInputs ...string) error {
+	length := utf8.RuneCountInString(password)
+	if length < policy.MinLength {
+		return custom_error.NewPasswordPolicyError(custom_error.PasswordPolicyTooShort, fmt.Sprintf("password must be at least %d characters long", policy.MinLength))
+	}
+	if policy.MaxLength > 0 && length > policy.MaxLength {
+		return custom_error.NewPasswordPolicyError(custom_error.PasswordPolicyTooLong, fmt.Sprintf("password must be at most %d characters long", policy.MaxLength))
 	}
 
 	var hasUpper, hasLower, hasDigit, hasSpecial bool
@@ -38,18 +47,30 @@ func ValidatePassword(password string, strength *config.RegistrationPasswordConf
 		}
 	}
 
-	if strength.RequireUpper && !hasUpper {
-		return errors.New("password must contain at least one uppercase letter")
+	if policy.RequireUpper && !hasUpper {
+		return custom_error.NewPasswordPolicyError(custom_error.PasswordPolicyCharacterClass, "password must contain at least one uppercase letter")
 	}
-	if strength.RequireLower && !hasLower {
-		return errors.New("password must contain at least one lowercase letter")
+	if policy.RequireLower && !hasLower {
+		return custom_error.NewPasswordPolicyError(custom_error.PasswordPolicyCharacterClass, "password must contain at least one lowercase letter")
 	}
-	if strength.RequireNumber && !hasDigit {
-		return errors.New("password must contain at least one digit")
+	if policy.RequireNumber && !hasDigit {
+		return custom_error.NewPasswordPolicyError(custom_error.PasswordPolicyCharacterClass, "password must contain at least one digit")
 	}
-	if strength.RequireSpecial && !hasSpecial {
-		return errors.New("password must contain at least one special character")
+	if policy.RequireSpecial && !hasSpecial {
+		return custom_error.NewPasswordPolicyError(custom_error.PasswordPolicyCharacterClass, "password must contain at least one special character")
 	}
 
+	if policy.MinScore > 0 {
+		strength := EstimatePasswordStrength(password, userInputs...)
+		if strength.Score < policy.MinScore {
+			return &custom_error.PasswordPolicyError{
+				Code:        custom_error.PasswordPolicyTooWeak,
+				Message:     "password is too easy to guess",
+				Score:       strength.Score,
+				Warning:     strength.Warning,
+				Suggestions: strength.Suggestions,
+			}
+		}
+	}
 	return nil
 }
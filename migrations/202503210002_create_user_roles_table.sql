@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE user_roles
+(
+    user_id    VARCHAR(255) NOT NULL,
+    role       VARCHAR(64)  NOT NULL,
+    created_at TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, role),
+    CONSTRAINT fk_user_roles_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+
+-- +goose Down
+DROP TABLE IF EXISTS user_roles;
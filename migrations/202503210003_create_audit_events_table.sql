@@ -0,0 +1,59 @@
+-- +goose Up
+CREATE TABLE audit_events
+(
+    id             BIGINT       PRIMARY KEY,
+    occurred_at    TIMESTAMP(6) NOT NULL,
+    actor          VARCHAR(255) NOT NULL DEFAULT '',
+    subject        VARCHAR(255) NOT NULL DEFAULT '',
+    action         VARCHAR(64)  NOT NULL,
+    outcome        VARCHAR(16)  NOT NULL,
+    ip             VARCHAR(64)  NOT NULL DEFAULT '',
+    correlation_id VARCHAR(128) NOT NULL DEFAULT '',
+    metadata       TEXT         NULL,
+    payload_hash   CHAR(64)     NOT NULL,
+    prev_hash      CHAR(64)     NOT NULL,
+    hash           CHAR(64)     NOT NULL,
+    INDEX idx_audit_events_actor (actor),
+    INDEX idx_audit_events_subject (subject),
+    INDEX idx_audit_events_action (action),
+    INDEX idx_audit_events_occurred_at (occurred_at)
+);
+
+-- audit_chain_head holds the tip of the hash chain. Appenders lock this single row
+-- so events are chained in a strict order even with several replicas writing.
+CREATE TABLE audit_chain_head
+(
+    id        TINYINT  PRIMARY KEY,
+    last_seq  BIGINT   NOT NULL,
+    last_hash CHAR(64) NOT NULL
+);
+
+INSERT INTO audit_chain_head (id, last_seq, last_hash)
+VALUES (1, 0, '0000000000000000000000000000000000000000000000000000000000000000');
+
+-- +goose StatementBegin
+CREATE TRIGGER audit_events_no_update
+    BEFORE UPDATE
+    ON audit_events
+    FOR EACH ROW
+BEGIN
+    SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_events is append-only';
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER audit_events_no_delete
+    BEFORE DELETE
+    ON audit_events
+    FOR EACH ROW
+BEGIN
+    SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_events is append-only';
+END;
+-- +goose StatementEnd
+
+
+-- +goose Down
+DROP TRIGGER IF EXISTS audit_events_no_delete;
+DROP TRIGGER IF EXISTS audit_events_no_update;
+DROP TABLE IF EXISTS audit_chain_head;
+DROP TABLE IF EXISTS audit_events;
@@ -0,0 +1,35 @@
+-- +goose Up
+CREATE TABLE webhook_subscriptions
+(
+    id          VARCHAR(255)  PRIMARY KEY,
+    url         VARCHAR(2048) NOT NULL,
+    event_types TEXT          NOT NULL,
+    secret      VARCHAR(255)  NOT NULL,
+    active      BOOLEAN       NOT NULL DEFAULT TRUE,
+    created_at  TIMESTAMP     NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- webhook_deliveries is both the durable delivery queue and the delivery log.
+CREATE TABLE webhook_deliveries
+(
+    id               VARCHAR(255) PRIMARY KEY,
+    subscription_id  VARCHAR(255) NOT NULL,
+    event_id         VARCHAR(255) NOT NULL,
+    event_type       VARCHAR(64)  NOT NULL,
+    payload          MEDIUMTEXT   NOT NULL,
+    status           VARCHAR(16)  NOT NULL DEFAULT 'pending',
+    attempts         INT          NOT NULL DEFAULT 0,
+    next_attempt_at  TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_status_code INT          NULL,
+    last_error       TEXT         NULL,
+    created_at       TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    delivered_at     TIMESTAMP    NULL DEFAULT NULL,
+    INDEX idx_webhook_deliveries_due (status, next_attempt_at),
+    INDEX idx_webhook_deliveries_subscription (subscription_id, created_at),
+    CONSTRAINT fk_webhook_deliveries_subscription FOREIGN KEY (subscription_id) REFERENCES webhook_subscriptions (id) ON DELETE CASCADE
+);
+
+
+-- +goose Down
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhook_subscriptions;
@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE outbox_events
+(
+    id             BIGINT       AUTO_INCREMENT PRIMARY KEY,
+    event_id       VARCHAR(255) NOT NULL UNIQUE,
+    aggregate_type VARCHAR(64)  NOT NULL,
+    aggregate_id   VARCHAR(255) NOT NULL,
+    event_type     VARCHAR(64)  NOT NULL,
+    payload        MEDIUMTEXT   NOT NULL,
+    occurred_at    TIMESTAMP(6) NOT NULL,
+    published_at   TIMESTAMP(6) NULL DEFAULT NULL,
+    attempts       INT          NOT NULL DEFAULT 0,
+    last_error     TEXT         NULL,
+    INDEX idx_outbox_events_unpublished (published_at, id)
+);
+
+
+-- +goose Down
+DROP TABLE IF EXISTS outbox_events;
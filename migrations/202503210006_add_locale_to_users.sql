@@ -0,0 +1,8 @@
+-- +goose Up
+ALTER TABLE users
+    ADD COLUMN locale VARCHAR(35) NOT NULL DEFAULT '';
+
+
+-- +goose Down
+ALTER TABLE users
+    DROP COLUMN locale;
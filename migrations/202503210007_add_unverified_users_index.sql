@@ -0,0 +1,6 @@
+-- +goose Up
+CREATE INDEX idx_users_unverified ON users (is_verified, created_at);
+
+
+-- +goose Down
+DROP INDEX idx_users_unverified ON users;
@@ -0,0 +1,69 @@
+-- +goose Up
+-- Compute the canonical emails with the default normalization rules first: lower case, plus tags
+-- of the default providers dropped, dots ignored and googlemail.com folded for Gmail. International
+-- domains are stored as typed; accounts with such domains get their canonical form on their next
+-- signup. Working on a temporary table leaves users untouched if the collision check below fails.
+CREATE TEMPORARY TABLE users_canonical_email
+(
+    id              VARCHAR(255) PRIMARY KEY,
+    canonical_email VARCHAR(255) NOT NULL
+);
+
+INSERT INTO users_canonical_email (id, canonical_email)
+SELECT id, LOWER(TRIM(TRAILING '.' FROM TRIM(email)))
+FROM users;
+
+UPDATE users_canonical_email
+SET canonical_email = CONCAT(SUBSTRING_INDEX(SUBSTRING_INDEX(canonical_email, '@', 1), '+', 1), '@',
+                             SUBSTRING_INDEX(canonical_email, '@', -1))
+WHERE SUBSTRING_INDEX(canonical_email, '@', -1) IN
+      ('gmail.com', 'googlemail.com', 'outlook.com', 'hotmail.com', 'live.com', 'icloud.com', 'me.com',
+       'fastmail.com', 'proton.me', 'protonmail.com')
+  AND canonical_email NOT LIKE '+%';
+
+UPDATE users_canonical_email
+SET canonical_email = CONCAT(REPLACE(SUBSTRING_INDEX(canonical_email, '@', 1), '.', ''), '@gmail.com')
+WHERE SUBSTRING_INDEX(canonical_email, '@', -1) IN ('gmail.com', 'googlemail.com');
+
+-- Accounts that collide would lock all but one of them out, so the migration stops and names them
+-- for an operator to resolve; see the README.
+DROP PROCEDURE IF EXISTS check_canonical_email_collisions;
+
+-- +goose StatementBegin
+CREATE PROCEDURE check_canonical_email_collisions()
+BEGIN
+    DECLARE colliding TEXT;
+    SELECT GROUP_CONCAT(ids SEPARATOR '; ')
+    INTO colliding
+    FROM (SELECT GROUP_CONCAT(id ORDER BY id) AS ids
+          FROM users_canonical_email
+          GROUP BY canonical_email
+          HAVING COUNT(*) > 1) collisions;
+    IF colliding IS NOT NULL THEN
+        SET colliding = LEFT(CONCAT('canonical email collisions between users ', colliding), 128);
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = colliding;
+    END IF;
+END;
+-- +goose StatementEnd
+
+CALL check_canonical_email_collisions();
+
+DROP PROCEDURE check_canonical_email_collisions;
+
+ALTER TABLE users
+    ADD COLUMN canonical_email VARCHAR(255) NULL AFTER email;
+
+UPDATE users u
+    JOIN users_canonical_email c ON c.id = u.id
+SET u.canonical_email = c.canonical_email;
+
+DROP TEMPORARY TABLE users_canonical_email;
+
+CREATE UNIQUE INDEX idx_users_canonical_email ON users (canonical_email);
+
+
+-- +goose Down
+DROP INDEX idx_users_canonical_email ON users;
+
+ALTER TABLE users
+    DROP COLUMN canonical_email;
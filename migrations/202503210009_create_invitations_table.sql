@@ -0,0 +1,21 @@
+-- +goose Up
+-- code_hash is the SHA-256 of the invitation code; the code itself is only shown once.
+-- email holds the canonical email an invitation is bound to, NULL for open invitations.
+CREATE TABLE invitations
+(
+    id         VARCHAR(255) PRIMARY KEY,
+    code_hash  CHAR(64)     NOT NULL UNIQUE,
+    email      VARCHAR(255) NULL DEFAULT NULL,
+    roles      VARCHAR(1024) NOT NULL DEFAULT '',
+    max_uses   INT          NOT NULL,
+    uses       INT          NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP    NOT NULL,
+    created_by VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    revoked_at TIMESTAMP    NULL DEFAULT NULL,
+    INDEX idx_invitations_created_by (created_by, created_at)
+);
+
+
+-- +goose Down
+DROP TABLE IF EXISTS invitations;
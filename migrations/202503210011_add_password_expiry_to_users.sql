@@ -0,0 +1,14 @@
+-- +goose Up
+ALTER TABLE users
+    ADD COLUMN password_changed_at      TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP AFTER password,
+    ADD COLUMN password_change_required BOOLEAN   NOT NULL DEFAULT FALSE AFTER password_changed_at;
+
+-- Existing passwords date from signup.
+UPDATE users
+SET password_changed_at = created_at;
+
+
+-- +goose Down
+ALTER TABLE users
+    DROP COLUMN password_change_required,
+    DROP COLUMN password_changed_at;
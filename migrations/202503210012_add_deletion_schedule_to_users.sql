@@ -0,0 +1,12 @@
+-- +goose Up
+-- deletion_scheduled_at is when a user's deletion request becomes final and the account is purged,
+-- NULL for accounts that are not being deleted.
+ALTER TABLE users
+    ADD COLUMN deletion_scheduled_at TIMESTAMP NULL DEFAULT NULL,
+    ADD INDEX idx_users_deletion_scheduled_at (deletion_scheduled_at);
+
+
+-- +goose Down
+ALTER TABLE users
+    DROP INDEX idx_users_deletion_scheduled_at,
+    DROP COLUMN deletion_scheduled_at;
@@ -0,0 +1,50 @@
+-- +goose Up
+-- Deleted accounts have their personal data removed from the audit log. The chain links the digest
+-- of actor, subject, ip and metadata rather than the values, so those columns may be blanked as long
+-- as redacted_at is set: actor and subject may only become 'redacted', ip only '' and metadata only
+-- NULL. Every other column stays immutable.
+ALTER TABLE audit_events
+    ADD COLUMN redacted_at TIMESTAMP(6) NULL DEFAULT NULL;
+
+DROP TRIGGER IF EXISTS audit_events_no_update;
+
+-- +goose StatementBegin
+CREATE TRIGGER audit_events_redact_only
+    BEFORE UPDATE
+    ON audit_events
+    FOR EACH ROW
+BEGIN
+    IF NEW.redacted_at IS NULL
+        OR NEW.actor NOT IN (OLD.actor, 'redacted')
+        OR NEW.subject NOT IN (OLD.subject, 'redacted')
+        OR NEW.ip NOT IN (OLD.ip, '')
+        OR NEW.metadata IS NOT NULL
+        OR NEW.id <> OLD.id
+        OR NEW.occurred_at <> OLD.occurred_at
+        OR NEW.action <> OLD.action
+        OR NEW.outcome <> OLD.outcome
+        OR NEW.correlation_id <> OLD.correlation_id
+        OR NEW.payload_hash <> OLD.payload_hash
+        OR NEW.prev_hash <> OLD.prev_hash
+        OR NEW.hash <> OLD.hash THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_events only allows redaction';
+    END IF;
+END;
+-- +goose StatementEnd
+
+
+-- +goose Down
+DROP TRIGGER IF EXISTS audit_events_redact_only;
+
+-- +goose StatementBegin
+CREATE TRIGGER audit_events_no_update
+    BEFORE UPDATE
+    ON audit_events
+    FOR EACH ROW
+BEGIN
+    SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_events is append-only';
+END;
+-- +goose StatementEnd
+
+ALTER TABLE audit_events
+    DROP COLUMN redacted_at;
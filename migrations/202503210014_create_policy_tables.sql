@@ -0,0 +1,35 @@
+-- +goose Up
+-- policy_documents holds the versions of the terms of service (kind 'tos') and the privacy policy
+-- (kind 'privacy'). The version in force for a kind is the one with the latest published_at that
+-- has passed, so a version can be published ahead of time.
+CREATE TABLE policy_documents
+(
+    id           VARCHAR(255)  PRIMARY KEY,
+    kind         VARCHAR(32)   NOT NULL,
+    version      VARCHAR(64)   NOT NULL,
+    url          VARCHAR(2048) NOT NULL,
+    mandatory    BOOLEAN       NOT NULL DEFAULT FALSE,
+    published_at TIMESTAMP     NOT NULL,
+    created_by   VARCHAR(255)  NOT NULL,
+    created_at   TIMESTAMP     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_policy_documents_kind_version (kind, version),
+    INDEX idx_policy_documents_published_at (published_at)
+);
+
+-- user_consents records which policy document versions a user accepted, when and from which IP.
+CREATE TABLE user_consents
+(
+    id          BIGINT       NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    user_id     VARCHAR(255) NOT NULL,
+    document_id VARCHAR(255) NOT NULL,
+    accepted_at TIMESTAMP    NOT NULL,
+    ip          VARCHAR(45)  NOT NULL DEFAULT '',
+    UNIQUE KEY uq_user_consents_user_document (user_id, document_id),
+    CONSTRAINT fk_user_consents_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_user_consents_document FOREIGN KEY (document_id) REFERENCES policy_documents (id)
+);
+
+
+-- +goose Down
+DROP TABLE IF EXISTS user_consents;
+DROP TABLE IF EXISTS policy_documents;
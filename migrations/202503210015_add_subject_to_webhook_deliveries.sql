@@ -0,0 +1,16 @@
+-- +goose Up
+-- subject is the user ID or email an event is about, so an account export or deletion finds the
+-- deliveries that carry the user's data. Existing deliveries take it from their payload.
+ALTER TABLE webhook_deliveries
+    ADD COLUMN subject VARCHAR(255) NULL DEFAULT NULL AFTER event_type,
+    ADD INDEX idx_webhook_deliveries_subject (subject);
+
+UPDATE webhook_deliveries
+SET subject = COALESCE(JSON_UNQUOTE(JSON_EXTRACT(payload, '$.data.user_id')),
+                       JSON_UNQUOTE(JSON_EXTRACT(payload, '$.data.email')));
+
+
+-- +goose Down
+ALTER TABLE webhook_deliveries
+    DROP INDEX idx_webhook_deliveries_subject,
+    DROP COLUMN subject;
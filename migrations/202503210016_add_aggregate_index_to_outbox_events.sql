@@ -0,0 +1,9 @@
+-- +goose Up
+-- Account deletion removes a user's events, which it finds by aggregate.
+ALTER TABLE outbox_events
+    ADD INDEX idx_outbox_events_aggregate (aggregate_type, aggregate_id);
+
+
+-- +goose Down
+ALTER TABLE outbox_events
+    DROP INDEX idx_outbox_events_aggregate;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- Events that fail maxAttempts times are parked as dead so they no longer hold up the relay.
+ALTER TABLE outbox_events
+    ADD COLUMN dead_at TIMESTAMP(6) NULL DEFAULT NULL AFTER published_at,
+    DROP INDEX idx_outbox_events_unpublished,
+    ADD INDEX idx_outbox_events_pending (published_at, dead_at, id),
+    ADD INDEX idx_outbox_events_dead (dead_at);
+
+
+-- +goose Down
+ALTER TABLE outbox_events
+    DROP INDEX idx_outbox_events_dead,
+    DROP INDEX idx_outbox_events_pending,
+    ADD INDEX idx_outbox_events_unpublished (published_at, id),
+    DROP COLUMN dead_at;
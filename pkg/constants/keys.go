@@ -10,6 +10,11 @@ const (
 	MySQLMaxLifetimeSecondsKey = "config/mysql/connection/maxLifeTime"
 	MySQLIdleConnectionsKey    = "config/mysql/connection/idleConnections"
 	MySQLMaxOpenConnectionsKey = "config/mysql/connection/maxOpenConnections"
+
+	MySQLCredentialSourceKey    = "config/mysql/credentials/source"
+	MySQLVaultMountKey          = "config/mysql/credentials/vaultMount"
+	MySQLVaultRoleKey           = "config/mysql/credentials/vaultRole"
+	MySQLRotateBeforeSecondsKey = "config/mysql/credentials/rotateBeforeSeconds"
 )
 
 // Redis configuration keys in Consul
@@ -25,25 +30,156 @@ const (
 	SMTPPortKey     = "config/mail/connection/port"
 	SMTPUsernameKey = "config/mail/connection/username"
 	SMTPPasswordKey = "config/mail/connection/password"
+
+	SMTPTLSModeKey            = "config/mail/connection/tlsMode"
+	SMTPCAFileKey             = "config/mail/connection/caFile"
+	SMTPServerNameKey         = "config/mail/connection/serverName"
+	SMTPPoolSizeKey           = "config/mail/connection/poolSize"
+	SMTPIdleTimeoutSecondsKey = "config/mail/connection/idleTimeoutSeconds"
+
+	MailTransportDriverKey         = "config/mail/transport/driver"
+	MailTransportFilePathKey       = "config/mail/transport/filePath"
+	MailTransportHTTPURLKey        = "config/mail/transport/httpURL"
+	MailTransportHTTPTokenKey      = "config/mail/transport/httpToken"
+	MailTransportHTTPTimeoutSecKey = "config/mail/transport/httpTimeoutSeconds"
 )
 
 // General config keys of application
 const (
 	GeneralDomainKey                                = "config/general/domain"
 	GeneralHTTPListenerPortKey                      = "config/general/httpListenerPort"
+	GeneralTrustedProxiesKey                        = "config/general/trustedProxies"
 	GeneralRegisterMailVerificationTimeInSecondsKey = "config/general/register/mailVerificationTimeInSeconds"
 	GeneralRegisterHostVerificationMailAddressKey   = "config/general/register/hostVerificationMailAddress"
-	GeneralRegisterVerificationMailTextKey          = "config/general/register/verificationMailText"
 	GeneralMaxVerificationMailCountInDay            = "config/general/register/maxVerificationMailInCountInDay"
+	GeneralMaxVerificationAttemptsKey               = "config/general/register/maxVerificationAttempts"
+	GeneralRegisterVerificationModeKey              = "config/general/register/verificationMode"
+	GeneralRegisterCodeLengthKey                    = "config/general/register/codeLength"
+	GeneralRegisterMaxCodeAttemptsKey               = "config/general/register/maxCodeAttempts"
+)
+
+// Registration mode and invitation config keys
+const (
+	RegistrationModeKey             = "config/registration/mode"
+	InvitationDefaultExpiryHoursKey = "config/registration/invitations/defaultExpiryHours"
+	InvitationMaxExpiryHoursKey     = "config/registration/invitations/maxExpiryHours"
+	InvitationUserQuotaKey          = "config/registration/invitations/userQuota"
+	InvitationUserQuotaWindowKey    = "config/registration/invitations/userQuotaWindowHours"
 )
 
 // Registration password config keys
 const (
-	KeyRegistrationPasswordMinLength      = "/config/registration/password/minLength"
-	KeyRegistrationPasswordRequireUpper   = "/config/registration/password/requireUpper"
-	KeyRegistrationPasswordRequireLower   = "/config/registration/password/requireLower"
-	KeyRegistrationPasswordRequireNumber  = "/config/registration/password/requireNumber"
-	KeyRegistrationPasswordRequireSpecial = "/config/registration/password/requireSpecial"
+	KeyRegistrationPasswordMinLength      = "config/registration/password/minLength"
+	KeyRegistrationPasswordRequireUpper   = "config/registration/password/requireUpper"
+	KeyRegistrationPasswordRequireLower   = "config/registration/password/requireLower"
+	KeyRegistrationPasswordRequireNumber  = "config/registration/password/requireNumber"
+	KeyRegistrationPasswordRequireSpecial = "config/registration/password/requireSpecial"
+	KeyRegistrationPasswordMaxLength      = "config/registration/password/maxLength"
+	KeyRegistrationPasswordMinScore       = "config/registration/password/minScore"
+	KeyRegistrationPasswordHistorySize    = "config/registration/password/historySize"
+	KeyRegistrationPasswordMaxAgeDays     = "config/registration/password/maxAgeDays"
+	KeyRegistrationPasswordWarningDays    = "config/registration/password/expiryWarningDays"
+)
+
+// Breached password screening config keys
+const (
+	BreachedPasswordSourceKey   = "config/registration/password/breached/source"
+	BreachedPasswordPathKey     = "config/registration/password/breached/path"
+	BreachedPasswordMinCountKey = "config/registration/password/breached/minCount"
+)
+
+// Mail template keys. Templates live at <prefix><locale>/<name>/<part>, part being subject, text or html.
+const (
+	MailTemplatesPrefix       = "config/mail/templates/"
+	MailDefaultLocaleKey      = "config/mail/defaultLocale"
+	MailTemplatePartSubject   = "subject"
+	MailTemplatePartPlainText = "text"
+	MailTemplatePartHTML      = "html"
+)
+
+// Webhook delivery config keys
+const (
+	WebhookMaxAttemptsKey         = "config/webhook/maxAttempts"
+	WebhookRequestTimeoutSecKey   = "config/webhook/requestTimeoutSeconds"
+	WebhookPollIntervalSecondsKey = "config/webhook/pollIntervalSeconds"
+	WebhookBatchSizeKey           = "config/webhook/batchSize"
+)
+
+// Mail queue config keys
+const (
+	MailQueueWorkersKey          = "config/mailqueue/workers"
+	MailQueueMaxAttemptsKey      = "config/mailqueue/maxAttempts"
+	MailQueueBatchSizeKey        = "config/mailqueue/batchSize"
+	MailQueueClaimIdleSecondsKey = "config/mailqueue/claimIdleSeconds"
+	MailQueueIdempotencyHoursKey = "config/mailqueue/idempotencyHours"
+)
+
+// Event bus and outbox relay config keys
+const (
+	EventBusDriverKey              = "config/eventbus/driver"
+	EventBusTopicKey               = "config/eventbus/topic"
+	EventBusNATSURLKey             = "config/eventbus/natsURL"
+	EventBusKafkaRESTURLKey        = "config/eventbus/kafkaRestURL"
+	EventBusPollIntervalSecondsKey = "config/eventbus/pollIntervalSeconds"
+	EventBusBatchSizeKey           = "config/eventbus/batchSize"
+	EventBusMaxAttemptsKey         = "config/eventbus/maxAttempts"
+	EventBusRetentionHoursKey      = "config/eventbus/retentionHours"
+)
+
+// Token signer config keys
+const (
+	AuthSignerKey                   = "config/auth/signer"
+	AuthTransitMountKey             = "config/auth/transit/mount"
+	AuthTransitAccessKeyKey         = "config/auth/transit/accessKey"
+	AuthTransitRefreshKeyKey        = "config/auth/transit/refreshKey"
+	AuthTransitKeyRefreshSecondsKey = "config/auth/transit/keyRefreshSeconds"
+)
+
+// Email normalization config keys
+const (
+	EmailPlusTagDomainsKey        = "config/registration/email/plusTagDomains"
+	EmailDotInsensitiveDomainsKey = "config/registration/email/dotInsensitiveDomains"
+)
+
+// Signup policy config keys
+const (
+	SignupPolicyAllowedDomainsKey        = "config/registration/policy/allowedDomains"
+	SignupPolicyDeniedDomainsKey         = "config/registration/policy/deniedDomains"
+	SignupPolicyBlockDisposableKey       = "config/registration/policy/blockDisposable"
+	SignupPolicyDisposableDomainsKey     = "config/registration/policy/disposableDomains"
+	SignupPolicyDisposableDomainsFileKey = "config/registration/policy/disposableDomainsFile"
+	SignupPolicyRequireMXKey             = "config/registration/policy/requireMX"
+	SignupPolicyMXTimeoutMillisKey       = "config/registration/policy/mxTimeoutMillis"
+)
+
+// Challenge config keys
+const (
+	ChallengeProviderKey        = "config/challenge/provider"
+	ChallengeSignupEnabledKey   = "config/challenge/routes/signup"
+	ChallengeLoginEnabledKey    = "config/challenge/routes/login"
+	ChallengeResendEnabledKey   = "config/challenge/routes/resendVerification"
+	ChallengeSecretKey          = "config/challenge/secret"
+	ChallengeSiteKeyKey         = "config/challenge/siteKey"
+	ChallengeVerifyURLKey       = "config/challenge/verifyURL"
+	ChallengeTimeoutSecondsKey  = "config/challenge/timeoutSeconds"
+	ChallengePoWDifficultyKey   = "config/challenge/pow/difficulty"
+	ChallengePoWTTLSecondsKey   = "config/challenge/pow/ttlSeconds"
+	ChallengePoWMaxPerMinuteKey = "config/challenge/pow/maxPerMinute"
+)
+
+// Unverified account reaper config keys
+const (
+	ReaperEnabledKey         = "config/registration/reaper/enabled"
+	ReaperMaxAgeHoursKey     = "config/registration/reaper/maxAgeHours"
+	ReaperIntervalMinutesKey = "config/registration/reaper/intervalMinutes"
+	ReaperBatchSizeKey       = "config/registration/reaper/batchSize"
+)
+
+// Account deletion config keys
+const (
+	AccountDeletionGraceHoursKey      = "config/account/deletion/graceHours"
+	AccountDeletionIntervalMinutesKey = "config/account/deletion/intervalMinutes"
+	AccountDeletionBatchSizeKey       = "config/account/deletion/batchSize"
 )
 
 // Environment variable keys
@@ -53,14 +189,34 @@ const (
 	EnvKeyVaultAddr      = "VAULT_ADDR"
 	EnvKeyMountPath      = "VAULT_MOUNT_PATH"
 	EnvKeySecretPath     = "VAULT_SECRET_PATH"
+	EnvKeyDKIMSecretPath = "VAULT_DKIM_SECRET_PATH"
 	EnvKeyVaultToken     = "VAULT_TOKEN"
+
+	EnvKeyVaultAuthMethod          = "VAULT_AUTH_METHOD"
+	EnvKeyVaultAuthMount           = "VAULT_AUTH_MOUNT"
+	EnvKeyVaultAppRoleRoleID       = "VAULT_APPROLE_ROLE_ID"
+	EnvKeyVaultAppRoleSecretID     = "VAULT_APPROLE_SECRET_ID"
+	EnvKeyVaultAppRoleSecretIDFile = "VAULT_APPROLE_SECRET_ID_FILE"
+	EnvKeyVaultKubernetesRole      = "VAULT_KUBERNETES_ROLE"
+	EnvKeyVaultKubernetesTokenPath = "VAULT_KUBERNETES_TOKEN_PATH"
+
 	EnvKeyAppEnvironment = "APP_ENV"
 	EnvKeyLogLevel       = "LOG_LEVEL"
 	EnvKeyLogFormat      = "LOG_FORMAT"
+	EnvKeyConfigSources  = "CONFIG_SOURCES"
+	EnvKeyConfigFile     = "CONFIG_FILE"
 )
 
 // Security configs keys fetched from vault
 const (
 	VaultJWTSecretKey        = "jwtSecret"
 	VaultJWTRefreshSecretKey = "jwtRefreshSecret"
+
+	VaultDKIMDomainKey     = "domain"
+	VaultDKIMSelectorKey   = "selector"
+	VaultDKIMPrivateKeyKey = "privateKey"
+	// DefaultVaultDKIMSecretPath is used when VAULT_DKIM_SECRET_PATH is not set.
+	DefaultVaultDKIMSecretPath = "dkim"
+	// DefaultVaultKubernetesTokenPath is where Kubernetes mounts the service account token.
+	DefaultVaultKubernetesTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
 )
@@ -3,14 +3,27 @@ package strings
 import (
 	"crypto/rand"
 	"encoding/base64"
+	"fmt"
+	"math/big"
 )
 
-// GenerateRandomString generate random base64 string with lengthInBytes size
+// GenerateRandomString generates an unpadded, URL-safe base64 string from lengthInBytes random bytes,
+// so it can be put in a link without escaping
 func GenerateRandomString(lengthInBytes int) (string, error) {
 	tokenBytes := make([]byte, lengthInBytes)
 	if _, err := rand.Read(tokenBytes); err != nil {
 		return "", err
 	}
 
-	return base64.StdEncoding.EncodeToString(tokenBytes), nil
+	return base64.RawURLEncoding.EncodeToString(tokenBytes), nil
+}
+
+// GenerateRandomDigits generates a uniformly random string of length decimal digits, leading zeros
+// included. length must be at most 18
+func GenerateRandomDigits(length int) (string, error) {
+	n, err := rand.Int(rand.Reader, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(length)), nil))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", length, n.Int64()), nil
 }